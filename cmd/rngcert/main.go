@@ -0,0 +1,146 @@
+// Command rngcert runs the RNG certification tests (chi-square uniformity, runs, serial
+// correlation, gap and poker) against the RGS random source and its game mappings, and writes
+// a JSON and a text report for submission to a test lab.
+//
+//	rngcert -samples 10000000 -json report.json -text report.txt
+//	rngcert -store data/game_math.json -model lucky_star
+//	rngcert -bundle games/123/math.json -seed 00ff...   (repeatable)
+//
+// The exit status is 2 when any test fails.
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rngcert"
+)
+
+// referenceMath is used for the PickTier subject when no model is given.
+var referenceMath = &gamemath.GameMath{
+	ModelID:      "reference",
+	ModelVersion: "1",
+	PrizeTable: []gamemath.PrizeTier{
+		{Tier: "LOSE", Multiplier: 0, Weight: 700},
+		{Tier: "T1", Multiplier: 2, Weight: 250},
+		{Tier: "T2", Multiplier: 5, Weight: 45},
+		{Tier: "T3", Multiplier: 20, Weight: 5},
+	},
+}
+
+func main() {
+	samples := flag.Int("samples", 1_000_000, "draws per subject")
+	alpha := flag.Float64("alpha", rngcert.DefaultAlpha, "significance level; a test fails when p < alpha")
+	seed := flag.String("seed", "", "hex seed: test the deterministic seeded stream instead of crypto/rand")
+	storePath := flag.String("store", "", "PickTier: path to a game_math.json store")
+	bundlePath := flag.String("bundle", "", "PickTier: path to a math.json in GameMath (RGS) schema")
+	modelID := flag.String("model", "", "PickTier: model_id to load from -store")
+	houseEdge := flag.Float64("house-edge", gamemath.DefaultCrashHouseEdge, "GenerateCrashStep: house edge of the crash math")
+	maxMult := flag.Float64("max-multiplier", gamemath.DefaultCrashMaxMultiplier, "GenerateCrashStep: highest crash point")
+	cells := flag.Int("cells", 9, "DistinctIndices: reveal grid size")
+	picks := flag.Int("picks", 3, "DistinctIndices: cells picked (match count)")
+	jsonPath := flag.String("json", "", "write the JSON report to this file (- for stdout)")
+	textPath := flag.String("text", "", "write the text report to this file (default stdout when -json is not -)")
+	flag.Parse()
+
+	var src rng.Source = rng.Crypto()
+	source := "crypto/rand"
+	if *seed != "" {
+		s, err := rng.NewSeededHex(*seed)
+		if err != nil {
+			fail(err.Error())
+		}
+		src, source = s, "hmac-sha256 seeded stream (seed "+*seed+")"
+	}
+
+	m, err := loadMath(*storePath, *bundlePath, *modelID)
+	if err != nil {
+		fail(err.Error())
+	}
+	pick, err := rngcert.PickTierSubject(m)
+	if err != nil {
+		fail(err.Error())
+	}
+	crashMath := gamemath.CrashMath{HouseEdge: *houseEdge, MaxMultiplier: *maxMult}.WithDefaults()
+	if err := crashMath.Validate(); err != nil {
+		fail(err.Error())
+	}
+	distinct, err := rngcert.DistinctIndicesSubject(*cells, *picks)
+	if err != nil {
+		fail(err.Error())
+	}
+	subjects := []rngcert.Subject{
+		rngcert.RawSubject(256),
+		pick,
+		rngcert.CrashStepSubject(crashMath),
+		rngcert.NextNumberSubject(),
+		distinct,
+	}
+
+	report, err := rngcert.Run(src, subjects, rngcert.Options{Samples: *samples, Alpha: *alpha, Source: source})
+	if err != nil {
+		fail(err.Error())
+	}
+
+	if *jsonPath != "" {
+		data, _ := json.MarshalIndent(report, "", "  ")
+		data = append(data, '\n')
+		if err := writeOut(*jsonPath, data); err != nil {
+			fail(err.Error())
+		}
+	}
+	if *textPath != "" || *jsonPath != "-" {
+		if err := writeOut(*textPath, []byte(report.String())); err != nil {
+			fail(err.Error())
+		}
+	}
+	if !report.Passed {
+		os.Exit(2)
+	}
+}
+
+func fail(msg string) {
+	fmt.Fprintln(os.Stderr, "rngcert:", msg)
+	os.Exit(1)
+}
+
+// writeOut writes data to path, or to stdout for "" and "-".
+func writeOut(path string, data []byte) error {
+	if path == "" || path == "-" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// loadMath resolves the PickTier model from -bundle or -store, or falls back to referenceMath.
+func loadMath(storePath, bundlePath, modelID string) (*gamemath.GameMath, error) {
+	switch {
+	case bundlePath != "":
+		data, err := os.ReadFile(bundlePath)
+		if err != nil {
+			return nil, err
+		}
+		var m gamemath.GameMath
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", bundlePath, err)
+		}
+		return &m, nil
+	case storePath != "":
+		if modelID == "" {
+			return nil, fmt.Errorf("-model is required with -store")
+		}
+		m := gamemath.NewStore(filepath.Dir(storePath)).Get(modelID)
+		if m == nil {
+			return nil, fmt.Errorf("model %q not found in %s", modelID, storePath)
+		}
+		return m, nil
+	default:
+		return referenceMath, nil
+	}
+}
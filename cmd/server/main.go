@@ -15,7 +15,10 @@ func main() {
 	_ = godotenv.Load("rgs/.env")
 	_ = godotenv.Load("../.env")
 	_ = godotenv.Load("../.env.local")
-	cfg := config.Load()
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("config: %v", err)
+	}
 	srv := server.New(cfg)
 	if err := srv.Run(); err != nil {
 		log.Fatal(err)
@@ -0,0 +1,162 @@
+// Command simulate runs Monte Carlo simulations of the RGS games and prints an RTP report.
+//
+//	simulate -game scratch -store data/game_math.json -model lucky_star -rounds 10000000
+//	simulate -game scratch -bundle games/123/math.json
+//	simulate -game scratch -db -model 130300089_default -version 1.1
+//	simulate -game crash -cashout 2.00
+//	simulate -game crash -cashout 10 -house-edge 0.02 -max-multiplier 500
+//	simulate -game hilo -strategy optimal -json
+//	simulate -game crash -seed 00ff... -workers 8   (repeatable)
+//
+// The exit status is 2 when the expected RTP falls outside the observed 95% confidence
+// interval, so a release pipeline can gate on it.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	rgs "github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games/crash"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/simulation"
+
+	"github.com/joho/godotenv"
+)
+
+func main() {
+	game := flag.String("game", "scratch", "game to simulate: scratch, crash or hilo")
+	rounds := flag.Int64("rounds", 1_000_000, "number of rounds")
+	workers := flag.Int("workers", 0, "parallel workers (0 = GOMAXPROCS)")
+	storePath := flag.String("store", "", "scratch: path to a game_math.json store")
+	bundlePath := flag.String("bundle", "", "scratch: path to a math.json in GameMath (RGS) schema")
+	fromDB := flag.Bool("db", false, "scratch: load the model from the game_math table (DATABASE_URL)")
+	modelID := flag.String("model", "", "scratch: model_id to load from -store or -db")
+	version := flag.String("version", "", "scratch: model_version (default: the active version)")
+	cashout := flag.Float64("cashout", 2.0, "crash: cash-out multiplier the simulated player targets")
+	houseEdge := flag.Float64("house-edge", gamemath.DefaultCrashHouseEdge, "crash: house edge of the crash math")
+	maxMult := flag.Float64("max-multiplier", gamemath.DefaultCrashMaxMultiplier, "crash: highest crash point")
+	strategy := flag.String("strategy", simulation.HiLoOptimal, "hilo: optimal, higher or lower")
+	seed := flag.String("seed", "", "hex seed for a repeatable run (same seed and -workers = same report)")
+	asJSON := flag.Bool("json", false, "print the report as JSON")
+	flag.Parse()
+
+	if *rounds <= 0 {
+		fail("-rounds must be positive")
+	}
+
+	opts := simulation.Options{Rounds: *rounds, Workers: *workers, Seed: *seed}
+	start := time.Now()
+	var report simulation.Report
+	var err error
+	switch *game {
+	case "scratch":
+		var m *gamemath.GameMath
+		m, err = loadMath(*storePath, *bundlePath, *fromDB, *modelID, *version)
+		if err == nil {
+			report, err = simulation.Scratch(m, opts)
+		}
+	case "crash":
+		m := gamemath.CrashMath{HouseEdge: *houseEdge, MaxMultiplier: *maxMult}.WithDefaults()
+		report, err = simulation.Crash(m, crash.TargetStep(*cashout), opts)
+	case "hilo":
+		report, err = simulation.HiLo(*strategy, opts)
+	default:
+		err = fmt.Errorf("unknown -game %q", *game)
+	}
+	if err != nil {
+		fail(err.Error())
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		_ = enc.Encode(report)
+	} else {
+		fmt.Print(report.String())
+		fmt.Printf("elapsed:               %s\n", time.Since(start).Round(time.Millisecond))
+	}
+	if report.ExpectedRTP != nil && !report.Contains(*report.ExpectedRTP) {
+		os.Exit(2)
+	}
+}
+
+func fail(msg string) {
+	fmt.Fprintln(os.Stderr, "simulate:", msg)
+	os.Exit(1)
+}
+
+// loadMath resolves the scratch model from exactly one of -store, -bundle or -db.
+func loadMath(storePath, bundlePath string, fromDB bool, modelID, version string) (*gamemath.GameMath, error) {
+	switch {
+	case bundlePath != "":
+		data, err := os.ReadFile(bundlePath)
+		if err != nil {
+			return nil, err
+		}
+		var m gamemath.GameMath
+		if err := json.Unmarshal(data, &m); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", bundlePath, err)
+		}
+		return &m, nil
+	case storePath != "":
+		if modelID == "" {
+			return nil, fmt.Errorf("-model is required with -store")
+		}
+		store := gamemath.NewStore(filepath.Dir(storePath))
+		m := store.Get(modelID)
+		if version != "" {
+			m = store.GetVersion(modelID, version)
+		}
+		if m == nil {
+			return nil, fmt.Errorf("model %q (version %q) not found in %s", modelID, version, storePath)
+		}
+		return m, nil
+	case fromDB:
+		if modelID == "" {
+			return nil, fmt.Errorf("-model is required with -db")
+		}
+		return loadMathFromDB(modelID, version)
+	default:
+		return nil, fmt.Errorf("scratch needs -store, -bundle or -db")
+	}
+}
+
+func loadMathFromDB(modelID, version string) (*gamemath.GameMath, error) {
+	_ = godotenv.Load(".env")
+	db, err := rgs.GetDB()
+	if err != nil {
+		return nil, fmt.Errorf("connect db: %w", err)
+	}
+	if db == nil {
+		return nil, fmt.Errorf("DATABASE_URL is not set; cannot connect to DB")
+	}
+	var data []byte
+	if version == "" {
+		err = db.QueryRowContext(context.Background(), `
+      SELECT math FROM game_math WHERE model_id = $1 AND status = 'ACTIVE'
+      ORDER BY updated_at DESC LIMIT 1
+    `, modelID).Scan(&data)
+	} else {
+		err = db.QueryRowContext(context.Background(), `
+      SELECT math FROM game_math WHERE model_id = $1 AND COALESCE(math->>'model_version', '') = $2
+      ORDER BY updated_at DESC LIMIT 1
+    `, modelID, version).Scan(&data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load model %q: %w", modelID, err)
+	}
+	var m gamemath.GameMath
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("parse game_math.math: %w", err)
+	}
+	if m.ModelID == "" {
+		m.ModelID = modelID
+	}
+	return &m, nil
+}
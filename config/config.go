@@ -1,8 +1,14 @@
 package config
 
 import (
+	"fmt"
+	"math"
 	"os"
 	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
 )
 
 type Config struct {
@@ -15,9 +21,44 @@ type Config struct {
 	GamesDir         string // Root dir for game bundles (e.g. "games" under rgs/)
 	OperatorEndpoint string
 	OperatorSecret   string
+	// RNGSeed (hex) makes the server's RNG deterministic. Tests and replay environments only;
+	// empty in production, where every round seed comes from crypto/rand.
+	RNGSeed string
+	// ProvablyFair draws scratch outcomes from per-session server/client seeds and nonces
+	// (package fair) so players can verify them. Shared crash rounds always draw from the crash
+	// seed chain (see CrashSalt).
+	ProvablyFair bool
+	// ReloadPollInterval is how often game_math, scratch_games and games are checked for changes
+	// that LISTEN/NOTIFY did not deliver. 0 disables polling.
+	ReloadPollInterval time.Duration
+	// TargetRTP is the operator-wide RTP (e.g. 0.96) that win-only bundle prize tables are
+	// fitted to when the bundle declares none. GameTargetRTP sets it per game_id and takes
+	// precedence over both.
+	TargetRTP     float64
+	GameTargetRTP map[string]float64
+	// PickTimeout is how long a Pick-One / Pick-N round waits for picks before the remaining
+	// cells are opened automatically.
+	PickTimeout time.Duration
+	// CrashBettingWindow is how long bets are taken on a shared crash round before its
+	// multiplier starts to grow.
+	CrashBettingWindow time.Duration
+	// CrashHouseEdge, CrashMaxMultiplier and CrashGrowthRate set the crash math
+	// (gamemath.CrashMath): every cashout target returns 1 - CrashHouseEdge, no round crashes
+	// above CrashMaxMultiplier, and the multiplier grows as e^(CrashGrowthRate*t). The model is
+	// registered as CrashMathVersion, which must change whenever they do.
+	CrashHouseEdge     float64
+	CrashMaxMultiplier float64
+	CrashGrowthRate    float64
+	CrashMathVersion   string
+	// CrashSalt salts the crash seed chain (fair.Chain) if it is not salted yet. It must be a
+	// public value nobody knew when the chain's commitment was published, such as the hash of a
+	// later block; the chain can also be salted with POST /rgs/admin/crash/salt.
+	CrashSalt string
 }
 
-func Load() *Config {
+// Load reads the configuration from the environment. Values that are set but malformed or out
+// of range are an error, so a typo cannot silently fall back to a default.
+func Load() (*Config, error) {
 	platformURL := os.Getenv("PLATFORM_URL")
 	if platformURL == "" {
 		platformURL = "http://localhost:3000"
@@ -55,15 +96,128 @@ func Load() *Config {
 	}
 	operatorEndpoint := os.Getenv("OPERATOR_ENDPOINT")
 	operatorSecret := os.Getenv("OPERATOR_SECRET")
+	rngSeed := os.Getenv("RGS_RNG_SEED")
+	provablyFair, _ := strconv.ParseBool(os.Getenv("RGS_PROVABLY_FAIR"))
+	reloadPoll := 30 * time.Second
+	if v := os.Getenv("RGS_RELOAD_POLL_INTERVAL"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d >= 0 {
+			reloadPoll = d
+		}
+	}
+	var targetRTP float64
+	if v := strings.TrimSpace(os.Getenv("RGS_TARGET_RTP")); v != "" {
+		rtp, err := parseRTP(v)
+		if err != nil {
+			return nil, fmt.Errorf("RGS_TARGET_RTP: %w", err)
+		}
+		targetRTP = rtp
+	}
+	gameRTP, err := parseGameRTP(os.Getenv("RGS_GAME_TARGET_RTP"))
+	if err != nil {
+		return nil, fmt.Errorf("RGS_GAME_TARGET_RTP: %w", err)
+	}
+	pickTimeout := 10 * time.Minute
+	if v := os.Getenv("RGS_PICK_TIMEOUT"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			pickTimeout = d
+		}
+	}
+	crashBetting := 5 * time.Second
+	if v := os.Getenv("RGS_CRASH_BETTING_WINDOW"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil && d > 0 {
+			crashBetting = d
+		}
+	}
+	crashMath, err := parseCrashMath()
+	if err != nil {
+		return nil, err
+	}
+	crashVersion := strings.TrimSpace(os.Getenv("RGS_CRASH_MATH_VERSION"))
+	if crashVersion == "" {
+		crashVersion = gamemath.DefaultCrashModelVersion
+	}
 	return &Config{
-		PlatformURL:      platformURL,
-		RGSBaseURL:       rgsBaseURL,
-		RGSPort:          port,
-		GameName:         gameName,
-		GameProvider:     gameProvider,
-		DataDir:          dataDir,
-		GamesDir:         gamesDir,
-		OperatorEndpoint: operatorEndpoint,
-		OperatorSecret:   operatorSecret,
+		PlatformURL:        platformURL,
+		RGSBaseURL:         rgsBaseURL,
+		RGSPort:            port,
+		GameName:           gameName,
+		GameProvider:       gameProvider,
+		DataDir:            dataDir,
+		GamesDir:           gamesDir,
+		OperatorEndpoint:   operatorEndpoint,
+		OperatorSecret:     operatorSecret,
+		RNGSeed:            rngSeed,
+		ProvablyFair:       provablyFair,
+		ReloadPollInterval: reloadPoll,
+		TargetRTP:          targetRTP,
+		GameTargetRTP:      gameRTP,
+		PickTimeout:        pickTimeout,
+		CrashBettingWindow: crashBetting,
+		CrashHouseEdge:     crashMath.HouseEdge,
+		CrashMaxMultiplier: crashMath.MaxMultiplier,
+		CrashGrowthRate:    crashMath.GrowthRate,
+		CrashMathVersion:   crashVersion,
+		CrashSalt:          strings.TrimSpace(os.Getenv("RGS_CRASH_SALT")),
+	}, nil
+}
+
+// parseCrashMath reads RGS_CRASH_HOUSE_EDGE, RGS_CRASH_MAX_MULTIPLIER and RGS_CRASH_GROWTH_RATE
+// over the default crash math. Each one that is set must be a number the model accepts.
+func parseCrashMath() (gamemath.CrashMath, error) {
+	m := gamemath.DefaultCrashMath()
+	for _, f := range []struct {
+		env string
+		v   *float64
+	}{
+		{"RGS_CRASH_HOUSE_EDGE", &m.HouseEdge},
+		{"RGS_CRASH_MAX_MULTIPLIER", &m.MaxMultiplier},
+		{"RGS_CRASH_GROWTH_RATE", &m.GrowthRate},
+	} {
+		s := strings.TrimSpace(os.Getenv(f.env))
+		if s == "" {
+			continue
+		}
+		v, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return m, fmt.Errorf("%s: %q is not a number", f.env, s)
+		}
+		*f.v = v
+		// The other values are defaults or already checked, so a failure is this one's.
+		if err := m.Validate(); err != nil {
+			return m, fmt.Errorf("%s: %w", f.env, err)
+		}
+	}
+	return m, nil
+}
+
+// parseGameRTP parses "game_id=0.97,other=0.94". Empty entries are allowed (e.g. a trailing
+// comma); an entry without a game id or with an RTP outside (0, 1] is an error.
+func parseGameRTP(s string) (map[string]float64, error) {
+	out := make(map[string]float64)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		gameID, v, ok := strings.Cut(entry, "=")
+		gameID = strings.TrimSpace(gameID)
+		if !ok || gameID == "" {
+			return nil, fmt.Errorf("malformed entry %q (want game_id=rtp)", entry)
+		}
+		rtp, err := parseRTP(strings.TrimSpace(v))
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", gameID, err)
+		}
+		out[gameID] = rtp
+	}
+	return out, nil
+}
+
+// parseRTP parses an RTP, which must be in (0, 1].
+func parseRTP(s string) (float64, error) {
+	rtp, err := strconv.ParseFloat(s, 64)
+	if err != nil || math.IsNaN(rtp) || rtp <= 0 || rtp > 1 {
+		return 0, fmt.Errorf("RTP %q is not in (0, 1]", s)
 	}
+	return rtp, nil
 }
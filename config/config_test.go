@@ -0,0 +1,60 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseGameRTP(t *testing.T) {
+	got, err := parseGameRTP(" scratch=0.97, pick=1 ,")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got["scratch"] != 0.97 || got["pick"] != 1 {
+		t.Errorf("parsed %v", got)
+	}
+	for _, bad := range []string{"scratch", "=0.9", "scratch=0.9x", "scratch=0", "scratch=1.01", "scratch=-0.5", "scratch=NaN"} {
+		if _, err := parseGameRTP(bad); err == nil {
+			t.Errorf("%q should be rejected", bad)
+		}
+	}
+}
+
+func TestLoad_CrashMath(t *testing.T) {
+	for _, env := range []string{"RGS_CRASH_HOUSE_EDGE", "RGS_CRASH_MAX_MULTIPLIER", "RGS_CRASH_GROWTH_RATE", "RGS_CRASH_MATH_VERSION"} {
+		t.Setenv(env, "")
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CrashHouseEdge != 0.01 || cfg.CrashMaxMultiplier != 1000 || cfg.CrashGrowthRate != 0.06 || cfg.CrashMathVersion != "1" {
+		t.Errorf("defaults %+v", cfg)
+	}
+
+	t.Setenv("RGS_CRASH_HOUSE_EDGE", "0.02")
+	t.Setenv("RGS_CRASH_MAX_MULTIPLIER", "500")
+	t.Setenv("RGS_CRASH_GROWTH_RATE", "0.1")
+	t.Setenv("RGS_CRASH_MATH_VERSION", "2")
+	if cfg, err = Load(); err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CrashHouseEdge != 0.02 || cfg.CrashMaxMultiplier != 500 || cfg.CrashGrowthRate != 0.1 || cfg.CrashMathVersion != "2" {
+		t.Errorf("configured %+v", cfg)
+	}
+
+	for env, bad := range map[string][]string{
+		"RGS_CRASH_HOUSE_EDGE":     {"1%", "-0.01", "1", "NaN"},
+		"RGS_CRASH_MAX_MULTIPLIER": {"x", "1", "0", "+Inf"},
+		"RGS_CRASH_GROWTH_RATE":    {"fast", "0", "-1", "Inf"},
+	} {
+		for _, v := range bad {
+			t.Run(env+"="+v, func(t *testing.T) {
+				t.Setenv(env, v)
+				if _, err := Load(); err == nil || !strings.Contains(err.Error(), env) {
+					t.Errorf("got %v, want an error naming %s", err, env)
+				}
+			})
+		}
+	}
+}
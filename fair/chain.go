@@ -0,0 +1,246 @@
+package fair
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
+)
+
+const (
+	// DefaultChainLength is how many rounds a seed chain lasts.
+	DefaultChainLength = 10000
+	// MaxChainLength bounds chains, and the hashing VerifyChainSeed does for a claimed index.
+	MaxChainLength = 1000000
+	// MaxSaltLen bounds chain salts.
+	MaxSaltLen = 128
+)
+
+var (
+	ErrChainUnsalted = errors.New("fair: the seed chain is not salted yet")
+	ErrChainSalted   = errors.New("fair: the seed chain is already salted with another value")
+	ErrInvalidSalt   = errors.New("fair: salt must be 1-128 printable characters")
+)
+
+// Chain is the public record of a seed chain: the server seeds of rounds that belong to no
+// player session (shared crash rounds), each the hash of the next (HashServerSeed). The server
+// draws the last seed, hashes its way back to the first and publishes Commitment, the hash of
+// the first seed, before any round is played. Round i uses seed i, so revealing it tells nothing
+// about the rounds to come, and hashing it i times gives Commitment (VerifyChainSeed): every
+// seed was fixed when the chain was published.
+//
+// The server could still draw chains until it got one it liked, so each seed is mixed with Salt
+// (ChainSource), a public value it cannot pick and does not know when it publishes the chain,
+// such as the hash of a block mined after CreatedAt. No round is played before the chain is
+// salted.
+type Chain struct {
+	ID         string     `json:"id"`
+	Commitment string     `json:"commitment"`
+	Length     int        `json:"length"`
+	Used       int        `json:"used"` // rounds played: the next one uses seed Used+1
+	CreatedAt  time.Time  `json:"createdAt"`
+	Salt       string     `json:"salt,omitempty"`
+	SaltedAt   *time.Time `json:"saltedAt,omitempty"`
+}
+
+// ChainProof identifies the seed of one round: seed Index of the chain published as Commitment,
+// mixed with Salt. The seed itself is revealed once the round is over.
+type ChainProof struct {
+	ChainID    string `json:"chainId"`
+	Commitment string `json:"commitment"`
+	Index      int    `json:"index"`
+	Salt       string `json:"salt"`
+}
+
+// ChainSource returns the random stream of the round drawn from seed and salt. Block i of the
+// stream is
+//
+//	HMAC-SHA256(key = seed, message = salt ":" uint64be(i))
+//
+// read like Source.
+func ChainSource(seed, salt string) rng.Source {
+	return rng.NewHMAC([]byte(seed), []byte(salt+":"))
+}
+
+// VerifyChainSeed reports whether seed is seed index (from 1) of the chain published as
+// commitment.
+func VerifyChainSeed(seed, commitment string, index int) bool {
+	if index < 1 || index > MaxChainLength {
+		return false
+	}
+	h := seed
+	for i := 0; i < index; i++ {
+		h = HashServerSeed(h)
+	}
+	return strings.EqualFold(h, commitment)
+}
+
+// chainSeed returns seed index of the length-seed chain whose last seed is last.
+func chainSeed(last string, length, index int) string {
+	seed := last
+	for i := length; i > index; i-- {
+		seed = HashServerSeed(seed)
+	}
+	return seed
+}
+
+// chainRecord is the saved chain: its public record and its secret last seed.
+type chainRecord struct {
+	Chain
+	Last string `json:"last"`
+}
+
+// ChainStore keeps the current seed chain, with its secret last seed, in data/seed_chain.json.
+// A chain used up is replaced by a new one, which must be salted before its first round.
+type ChainStore struct {
+	mu      sync.Mutex
+	chain   chainRecord
+	dataDir string
+	src     rng.Source
+	length  int
+}
+
+// NewChainStore loads the chain from dataDir, drawing a new one of length seeds (0 for
+// DefaultChainLength) from src (rng.Crypto in production) when there is none.
+func NewChainStore(dataDir string, src rng.Source, length int) *ChainStore {
+	if dataDir == "" {
+		dataDir = "data"
+	}
+	if src == nil {
+		src = rng.Default
+	}
+	if length <= 0 || length > MaxChainLength {
+		length = DefaultChainLength
+	}
+	s := &ChainStore{dataDir: dataDir, src: src, length: length}
+	s.load()
+	return s
+}
+
+func (s *ChainStore) path() string {
+	return filepath.Join(s.dataDir, "seed_chain.json")
+}
+
+func (s *ChainStore) load() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if data, err := os.ReadFile(s.path()); err == nil {
+		var rec chainRecord
+		if err := json.Unmarshal(data, &rec); err == nil && rec.Last != "" && rec.Used < rec.Length {
+			s.chain = rec
+			return
+		}
+	}
+	s.newChainLocked()
+}
+
+func (s *ChainStore) save() error {
+	data, err := json.MarshalIndent(s.chain, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dataDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path(), data, 0600)
+}
+
+// newChainLocked draws and saves a new chain. A chain that cannot be saved is still used: it is
+// unsalted, so no round is played from it until SetSalt saves it.
+func (s *ChainStore) newChainLocked() {
+	last := rng.NewSeed(s.src)
+	s.chain = chainRecord{
+		Chain: Chain{
+			ID:         uuid.New().String(),
+			Commitment: HashServerSeed(chainSeed(last, s.length, 1)),
+			Length:     s.length,
+			CreatedAt:  time.Now().UTC(),
+		},
+		Last: last,
+	}
+	if err := s.save(); err != nil {
+		log.Printf("fair: save seed chain %s: %v", s.chain.ID, err)
+		return
+	}
+	log.Printf("fair: new seed chain %s (%d rounds), commitment %s: salt it to start playing", s.chain.ID, s.chain.Length, s.chain.Commitment)
+}
+
+// Current returns the public record of the current chain.
+func (s *ChainStore) Current() Chain {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.chain.Chain
+}
+
+// SetSalt salts the current chain. Salting it again with the same value is a no-op; with
+// another, ErrChainSalted.
+func (s *ChainStore) SetSalt(salt string) (Chain, error) {
+	salt = strings.TrimSpace(salt)
+	if !validSalt(salt) {
+		return Chain{}, ErrInvalidSalt
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.chain.Salt == salt {
+		return s.chain.Chain, nil
+	}
+	if s.chain.Salt != "" {
+		return Chain{}, ErrChainSalted
+	}
+	now := time.Now().UTC()
+	s.chain.Salt, s.chain.SaltedAt = salt, &now
+	if err := s.save(); err != nil {
+		s.chain.Salt, s.chain.SaltedAt = "", nil
+		return Chain{}, err
+	}
+	return s.chain.Chain, nil
+}
+
+// Next hands out the seed of the next round with its proof. The round is counted as played
+// before the seed is returned: if that cannot be saved, Next fails and no round may be played,
+// or a restart could hand the same seed out again. The last seed of a chain brings in a new,
+// unsalted one.
+func (s *ChainStore) Next() (ChainProof, string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.chain.Salt == "" {
+		return ChainProof{}, "", ErrChainUnsalted
+	}
+	index := s.chain.Used + 1
+	s.chain.Used = index
+	if err := s.save(); err != nil {
+		s.chain.Used = index - 1
+		return ChainProof{}, "", err
+	}
+	proof := ChainProof{
+		ChainID:    s.chain.ID,
+		Commitment: s.chain.Commitment,
+		Index:      index,
+		Salt:       s.chain.Salt,
+	}
+	seed := chainSeed(s.chain.Last, s.chain.Length, index)
+	if index >= s.chain.Length {
+		s.newChainLocked()
+	}
+	return proof, seed, nil
+}
+
+func validSalt(salt string) bool {
+	if salt == "" || len(salt) > MaxSaltLen {
+		return false
+	}
+	for _, r := range salt {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
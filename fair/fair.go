@@ -0,0 +1,45 @@
+// Package fair implements provably fair outcomes with a server seed, a client seed and a nonce.
+//
+// Before play the player sees HashServerSeed(serverSeed), a SHA-256 commitment to a secret
+// server seed. Each round uses the next nonce and draws from Source(serverSeed, clientSeed,
+// nonce), an HMAC-SHA256 stream keyed by the server seed. When the player rotates seeds the old
+// server seed is revealed, and every round played with it can be recomputed and checked
+// against the commitment.
+//
+// Rounds shared by every player, which no client seed belongs to, draw from a salted seed chain
+// instead (Chain).
+package fair
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strconv"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
+)
+
+// Proof identifies the seeds of one round. The server seed itself is only revealed after
+// rotation, so a Proof can be shown to the player while the seed is still in use.
+type Proof struct {
+	ServerSeedHash string `json:"serverSeedHash"`
+	ClientSeed     string `json:"clientSeed"`
+	Nonce          uint64 `json:"nonce"`
+}
+
+// HashServerSeed returns the commitment to serverSeed: hex(SHA-256(serverSeed)), hashing the
+// seed string as published.
+func HashServerSeed(serverSeed string) string {
+	sum := sha256.Sum256([]byte(serverSeed))
+	return hex.EncodeToString(sum[:])
+}
+
+// Source returns the random stream of one round. Block i of the stream is
+//
+//	HMAC-SHA256(key = serverSeed, message = clientSeed ":" nonce ":" uint64be(i))
+//
+// read as big-endian uint64 words; a value in [0, n) is word mod n, skipping words at or
+// above the largest multiple of n (rejection sampling, see rng.Seeded).
+func Source(serverSeed, clientSeed string, nonce uint64) rng.Source {
+	prefix := clientSeed + ":" + strconv.FormatUint(nonce, 10) + ":"
+	return rng.NewHMAC([]byte(serverSeed), []byte(prefix))
+}
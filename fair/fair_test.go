@@ -0,0 +1,178 @@
+package fair
+
+import (
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games/crash"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
+)
+
+func TestHashServerSeed(t *testing.T) {
+	const want = "ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad" // SHA-256("abc")
+	if got := HashServerSeed("abc"); got != want {
+		t.Errorf("HashServerSeed = %s", got)
+	}
+}
+
+func TestSource_DependsOnEveryInput(t *testing.T) {
+	draw := func(server, client string, nonce uint64) int64 {
+		return Source(server, client, nonce).Int63n(1 << 62)
+	}
+	base := draw("s", "c", 1)
+	if draw("s", "c", 1) != base {
+		t.Fatal("same inputs gave different draws")
+	}
+	if draw("s2", "c", 1) == base || draw("s", "c2", 1) == base || draw("s", "c", 2) == base {
+		t.Error("changing an input should change the stream")
+	}
+}
+
+func TestStore_CommitPlayRotateVerify(t *testing.T) {
+	dir := t.TempDir()
+	st := NewStore(dir, rng.NewSeeded([]byte("store")))
+
+	if _, ok := st.Get("sess"); ok {
+		t.Fatal("Get must not create sessions")
+	}
+	before, err := st.Open("sess")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if before.Active.ServerSeed != "" || before.Active.ServerSeedHash == "" {
+		t.Fatalf("active pair must expose only the hash: %+v", before.Active)
+	}
+	if before.NextServerSeed != "" || before.NextServerSeedHash == "" {
+		t.Fatalf("next server seed must be committed by hash only: %+v", before)
+	}
+
+	var steps []int
+	for i := uint64(0); i < 3; i++ {
+		proof, src, err := st.Next("sess")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if proof.Nonce != i || proof.ServerSeedHash != before.Active.ServerSeedHash {
+			t.Fatalf("round %d proof %+v", i, proof)
+		}
+		steps = append(steps, crash.GenerateCrashStepFrom(gamemath.DefaultCrashMath(), src))
+	}
+
+	// Reload from disk: the nonce survives a restart.
+	st = NewStore(dir, rng.NewSeeded([]byte("other")))
+	if got, _ := st.Get("sess"); got.Active.Nonce != 3 || got.NextServerSeedHash != before.NextServerSeedHash {
+		t.Fatalf("after reload: nonce %d want 3, next hash %s want %s", got.Active.Nonce, got.NextServerSeedHash, before.NextServerSeedHash)
+	}
+
+	revealed, next, err := st.Rotate("sess", "my-seed")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if HashServerSeed(revealed.ServerSeed) != before.Active.ServerSeedHash {
+		t.Fatal("revealed seed does not match the commitment")
+	}
+	for i, want := range steps {
+		if got := crash.GenerateCrashStepFrom(gamemath.DefaultCrashMath(), Source(revealed.ServerSeed, revealed.ClientSeed, uint64(i))); got != want {
+			t.Errorf("nonce %d: recomputed step %d, played %d", i, got, want)
+		}
+	}
+	if next.ServerSeed != "" || next.ClientSeed != "my-seed" || next.Nonce != 0 || next.ServerSeedHash != before.NextServerSeedHash {
+		t.Errorf("next pair %+v must use the server seed committed before the client seed", next)
+	}
+	after, _ := st.Get("sess")
+	if after.NextServerSeedHash == "" || after.NextServerSeedHash == next.ServerSeedHash {
+		t.Errorf("rotation must commit a new next server seed: %+v", after)
+	}
+	hist := after.History
+	if len(hist) != 1 || hist[0].ServerSeed != revealed.ServerSeed || hist[0].RevealedAt == nil {
+		t.Errorf("history %+v", hist)
+	}
+
+	if _, _, err := st.Rotate("sess", "has space"); err != ErrInvalidClientSeed {
+		t.Errorf("expected ErrInvalidClientSeed, got %v", err)
+	}
+}
+
+func TestStore_NextFailsWhenNonceCannotBeSaved(t *testing.T) {
+	dir := t.TempDir()
+	st := NewStore(dir, rng.NewSeeded([]byte("store")))
+	if _, _, err := st.Next("sess"); err != nil {
+		t.Fatal(err)
+	}
+	// Replace the log with a directory so appends fail.
+	if err := os.Remove(st.path()); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(st.path(), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := st.Next("sess"); err == nil {
+		t.Fatal("Next must fail when the nonce cannot be saved")
+	}
+	if got, _ := st.Get("sess"); got.Active.Nonce != 1 {
+		t.Errorf("failed round advanced the nonce to %d", got.Active.Nonce)
+	}
+}
+
+func TestChainStore_SaltNextVerify(t *testing.T) {
+	dir := t.TempDir()
+	st := NewChainStore(dir, rng.NewSeeded([]byte("chain")), 3)
+	chain := st.Current()
+	if chain.Length != 3 || chain.Commitment == "" || chain.Salt != "" {
+		t.Fatalf("new chain %+v", chain)
+	}
+	if _, _, err := st.Next(); !errors.Is(err, ErrChainUnsalted) {
+		t.Fatalf("unsalted chain played: %v", err)
+	}
+	if _, err := st.SetSalt("bad salt"); !errors.Is(err, ErrInvalidSalt) {
+		t.Errorf("salt with a space: %v", err)
+	}
+	if _, err := st.SetSalt("block-1"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.SetSalt("block-2"); !errors.Is(err, ErrChainSalted) {
+		t.Errorf("salted twice: %v", err)
+	}
+
+	// The chain, and the rounds played from it, survive a restart.
+	proof, first, err := st.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st = NewChainStore(dir, rng.NewSeeded([]byte("other")), 3)
+	if got := st.Current(); got.ID != chain.ID || got.Used != 1 {
+		t.Fatalf("after reload: %+v", got)
+	}
+	seeds := []string{first}
+	for i := 2; i <= 3; i++ {
+		p, seed, err := st.Next()
+		if err != nil || p.Index != i || p.Commitment != chain.Commitment || p.Salt != "block-1" {
+			t.Fatalf("round %d: %+v, %v", i, p, err)
+		}
+		seeds = append(seeds, seed)
+	}
+	if proof.Index != 1 {
+		t.Errorf("first round index %d", proof.Index)
+	}
+	for i, seed := range seeds {
+		if !VerifyChainSeed(seed, chain.Commitment, i+1) {
+			t.Errorf("seed %d does not verify", i+1)
+		}
+		if i > 0 && HashServerSeed(seed) != seeds[i-1] {
+			t.Errorf("seed %d does not hash to seed %d", i+1, i)
+		}
+	}
+	if VerifyChainSeed(seeds[1], chain.Commitment, 1) {
+		t.Error("seed verified at the wrong index")
+	}
+
+	// A used-up chain is replaced by a new, unsalted one.
+	if next := st.Current(); next.ID == chain.ID || next.Salt != "" {
+		t.Errorf("after the last round: %+v", next)
+	}
+	if ChainSource(first, "block-1").Int63n(1<<62) == ChainSource(first, "block-2").Int63n(1<<62) {
+		t.Error("the salt should change the stream")
+	}
+}
@@ -0,0 +1,319 @@
+package fair
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
+)
+
+// MaxClientSeedLen bounds player-chosen client seeds.
+const MaxClientSeedLen = 64
+
+var ErrInvalidClientSeed = errors.New("fair: client seed must be 1-64 printable characters")
+
+// SeedPair is one server seed / client seed pair of a session. ServerSeed is secret while the
+// pair is active; Public strips it.
+type SeedPair struct {
+	ServerSeed     string     `json:"serverSeed,omitempty"`
+	ServerSeedHash string     `json:"serverSeedHash"`
+	ClientSeed     string     `json:"clientSeed"`
+	Nonce          uint64     `json:"nonce"` // next nonce; rounds played = Nonce
+	CreatedAt      time.Time  `json:"createdAt"`
+	RevealedAt     *time.Time `json:"revealedAt,omitempty"`
+}
+
+// Public returns the pair without the server seed unless it has been revealed.
+func (p SeedPair) Public() SeedPair {
+	if p.RevealedAt == nil {
+		p.ServerSeed = ""
+	}
+	return p
+}
+
+// Session is the seed state of one player session: the active pair, the commitment to the next
+// server seed and the revealed history.
+type Session struct {
+	SessionID string   `json:"sessionId"`
+	Active    SeedPair `json:"active"`
+	// NextServerSeed is the server seed of the next pair. It is drawn, and NextServerSeedHash
+	// published, before the player picks the client seed that goes with it, so the server cannot
+	// pick a server seed to suit the client seed.
+	NextServerSeed     string     `json:"nextServerSeed,omitempty"`
+	NextServerSeedHash string     `json:"nextServerSeedHash"`
+	History            []SeedPair `json:"history"`
+}
+
+// public returns the session without its secret server seeds.
+func (sess *Session) public() Session {
+	out := *sess
+	out.Active = sess.Active.Public()
+	out.NextServerSeed = ""
+	out.History = append([]SeedPair(nil), sess.History...)
+	return out
+}
+
+// logRecord is one line of fair_seeds.jsonl: a whole session (on creation and rotation), or the
+// next nonce of a session's active pair (every round).
+type logRecord struct {
+	Session *Session     `json:"session,omitempty"`
+	Nonce   *nonceRecord `json:"nonce,omitempty"`
+}
+
+type nonceRecord struct {
+	SessionID string `json:"sessionId"`
+	Nonce     uint64 `json:"nonce"`
+}
+
+// Store keeps per-session seeds in data/fair_seeds.jsonl, an append-only log: a round appends
+// one short nonce record instead of rewriting every session. The log is compacted to one record
+// per session when the store opens.
+type Store struct {
+	mu       sync.Mutex
+	sessions map[string]*Session
+	dataDir  string
+	src      rng.Source
+}
+
+// NewStore loads the store from dataDir. New server seeds are drawn from src (rng.Crypto in
+// production).
+func NewStore(dataDir string, src rng.Source) *Store {
+	if dataDir == "" {
+		dataDir = "data"
+	}
+	if src == nil {
+		src = rng.Default
+	}
+	s := &Store{sessions: make(map[string]*Session), dataDir: dataDir, src: src}
+	s.load()
+	return s
+}
+
+func (s *Store) path() string {
+	return filepath.Join(s.dataDir, "fair_seeds.jsonl")
+}
+
+// legacyPath is the JSON array file written by earlier versions; it is read once and compacted
+// into the log.
+func (s *Store) legacyPath() string {
+	return filepath.Join(s.dataDir, "fair_seeds.json")
+}
+
+func (s *Store) load() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if data, err := os.ReadFile(s.legacyPath()); err == nil {
+		var list []*Session
+		if err := json.Unmarshal(data, &list); err != nil {
+			log.Printf("fair: %s: %v", s.legacyPath(), err)
+		}
+		for _, sess := range list {
+			if sess != nil && sess.SessionID != "" {
+				s.sessions[sess.SessionID] = sess
+			}
+		}
+	}
+	if f, err := os.Open(s.path()); err == nil {
+		sc := bufio.NewScanner(f)
+		sc.Buffer(make([]byte, 64*1024), 16<<20)
+		for sc.Scan() {
+			var rec logRecord
+			if err := json.Unmarshal(sc.Bytes(), &rec); err != nil {
+				// A torn last line (crash mid-write); its round never started.
+				log.Printf("fair: %s: skipping bad record: %v", s.path(), err)
+				continue
+			}
+			switch {
+			case rec.Session != nil && rec.Session.SessionID != "":
+				s.sessions[rec.Session.SessionID] = rec.Session
+			case rec.Nonce != nil:
+				if sess := s.sessions[rec.Nonce.SessionID]; sess != nil && rec.Nonce.Nonce > sess.Active.Nonce {
+					sess.Active.Nonce = rec.Nonce.Nonce
+				}
+			}
+		}
+		f.Close()
+	}
+	for _, sess := range s.sessions {
+		if sess.NextServerSeed == "" {
+			s.commitNext(sess)
+		}
+	}
+	if err := s.compactLocked(); err != nil {
+		log.Printf("fair: compact %s: %v", s.path(), err)
+		return
+	}
+	if err := os.Remove(s.legacyPath()); err != nil && !os.IsNotExist(err) {
+		log.Printf("fair: %v", err)
+	}
+}
+
+// compactLocked rewrites the log with one record per session. Caller must hold s.mu.
+func (s *Store) compactLocked() error {
+	if len(s.sessions) == 0 {
+		return nil
+	}
+	var buf bytes.Buffer
+	for _, sess := range s.sessions {
+		line, err := json.Marshal(logRecord{Session: sess})
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if err := os.MkdirAll(s.dataDir, 0755); err != nil {
+		return err
+	}
+	tmp := s.path() + ".tmp"
+	if err := os.WriteFile(tmp, buf.Bytes(), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, s.path())
+}
+
+// appendLocked adds rec to the log and syncs it to disk. Caller must hold s.mu.
+func (s *Store) appendLocked(rec logRecord) error {
+	line, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dataDir, 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(s.path(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return f.Sync()
+}
+
+func (s *Store) newPair(serverSeed, clientSeed string) SeedPair {
+	if clientSeed == "" {
+		clientSeed = rng.NewSeed(s.src)[:16]
+	}
+	return SeedPair{
+		ServerSeed:     serverSeed,
+		ServerSeedHash: HashServerSeed(serverSeed),
+		ClientSeed:     clientSeed,
+		CreatedAt:      time.Now().UTC(),
+	}
+}
+
+// commitNext draws the session's next server seed.
+func (s *Store) commitNext(sess *Session) {
+	sess.NextServerSeed = rng.NewSeed(s.src)
+	sess.NextServerSeedHash = HashServerSeed(sess.NextServerSeed)
+}
+
+// sessionLocked returns the session, creating and saving it with a fresh pair. Caller must hold
+// s.mu.
+func (s *Store) sessionLocked(sessionID string) (*Session, error) {
+	if sess, ok := s.sessions[sessionID]; ok {
+		return sess, nil
+	}
+	sess := &Session{SessionID: sessionID, Active: s.newPair(rng.NewSeed(s.src), "")}
+	s.commitNext(sess)
+	if err := s.appendLocked(logRecord{Session: sess}); err != nil {
+		return nil, err
+	}
+	s.sessions[sessionID] = sess
+	return sess, nil
+}
+
+// Get returns the public view of a session, and false if it has none yet. It never creates one.
+func (s *Store) Get(sessionID string) (Session, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, ok := s.sessions[sessionID]
+	if !ok {
+		return Session{}, false
+	}
+	return sess.public(), true
+}
+
+// Open returns the public view of a session, creating it on first use, so the player sees the
+// server seed hash before the first round. Callers must have authenticated the session.
+func (s *Store) Open(sessionID string) (Session, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, err := s.sessionLocked(sessionID)
+	if err != nil {
+		return Session{}, err
+	}
+	return sess.public(), nil
+}
+
+// Next reserves the next nonce of the session's active pair and returns the round's proof and
+// random source. The nonce is saved before it is used: if it cannot be, Next fails and the
+// round must not be played, or a restart could hand the same nonce out again.
+func (s *Store) Next(sessionID string) (Proof, rng.Source, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sess, err := s.sessionLocked(sessionID)
+	if err != nil {
+		return Proof{}, nil, err
+	}
+	p := &sess.Active
+	if err := s.appendLocked(logRecord{Nonce: &nonceRecord{SessionID: sessionID, Nonce: p.Nonce + 1}}); err != nil {
+		return Proof{}, nil, err
+	}
+	proof := Proof{ServerSeedHash: p.ServerSeedHash, ClientSeed: p.ClientSeed, Nonce: p.Nonce}
+	src := Source(p.ServerSeed, p.ClientSeed, p.Nonce)
+	p.Nonce++
+	return proof, src, nil
+}
+
+// Rotate reveals the active server seed, moves the pair to the history and makes the committed
+// next server seed active with clientSeed (an empty clientSeed keeps the current one). A new
+// next server seed is then drawn and committed.
+func (s *Store) Rotate(sessionID, clientSeed string) (revealed, next SeedPair, err error) {
+	clientSeed = strings.TrimSpace(clientSeed)
+	if clientSeed != "" && !validClientSeed(clientSeed) {
+		return SeedPair{}, SeedPair{}, ErrInvalidClientSeed
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cur, err := s.sessionLocked(sessionID)
+	if err != nil {
+		return SeedPair{}, SeedPair{}, err
+	}
+	sess := *cur
+	revealed = sess.Active
+	now := time.Now().UTC()
+	revealed.RevealedAt = &now
+	if clientSeed == "" {
+		clientSeed = revealed.ClientSeed
+	}
+	sess.History = append(append([]SeedPair(nil), cur.History...), revealed)
+	sess.Active = s.newPair(sess.NextServerSeed, clientSeed)
+	s.commitNext(&sess)
+	if err := s.appendLocked(logRecord{Session: &sess}); err != nil {
+		return SeedPair{}, SeedPair{}, err
+	}
+	s.sessions[sessionID] = &sess
+	return revealed, sess.Active.Public(), nil
+}
+func validClientSeed(seed string) bool {
+	if len(seed) > MaxClientSeedLen {
+		return false
+	}
+	for _, r := range seed {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
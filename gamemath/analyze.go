@@ -0,0 +1,204 @@
+package gamemath
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// StatsTolerance is how far declared Stats may drift from the values computed from the
+// prize table. RTP and hit rate are compared absolutely (0.001 = 0.1 percentage points);
+// variance and max win are compared relative to the computed value.
+const StatsTolerance = 0.001
+
+// Report is the analysis of a prize table. Probabilities and RTP are fractions of 1;
+// multipliers, variance and max win are in units of the bet.
+type Report struct {
+	ModelID     string       `json:"model_id"`
+	TotalWeight int64        `json:"total_weight"`
+	RTP         float64      `json:"rtp"`
+	HitRate     float64      `json:"hit_rate"`
+	Variance    float64      `json:"variance"`
+	StdDev      float64      `json:"std_dev"`
+	MaxWin      float64      `json:"max_win"`
+	BoostRTP    float64      `json:"boost_rtp,omitempty"` // part of RTP paid by multiplier symbols
+	Tiers       []TierReport `json:"tiers"`
+}
+
+// TierReport is one prize tier in a Report. Odds is "1 in N"; 0 when the tier can never hit.
+type TierReport struct {
+	Tier            string  `json:"tier"`
+	Multiplier      float64 `json:"multiplier"`
+	Weight          int64   `json:"weight"`
+	Probability     float64 `json:"probability"`
+	Odds            float64 `json:"odds"`
+	RTPContribution float64 `json:"rtp_contribution"`
+	BoostRTP        float64 `json:"boost_rtp,omitempty"` // part of RTPContribution paid by multiplier symbols
+}
+
+// ValidationError lists everything wrong with a model. Report is set when the prize table
+// itself was analyzable (e.g. only the declared stats disagree).
+type ValidationError struct {
+	ModelID  string
+	Problems []string
+	Report   *Report
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("game math %q invalid: %s", e.ModelID, strings.Join(e.Problems, "; "))
+}
+
+// Analyze computes RTP, hit rate, variance, standard deviation, max win and per-tier odds
+// from the prize table.
+func Analyze(g *GameMath) (*Report, error) {
+	if g == nil || len(g.PrizeTable) == 0 {
+		return nil, fmt.Errorf("prize_table is empty")
+	}
+	var total int64
+	for _, t := range g.PrizeTable {
+		if t.Weight < 0 {
+			return nil, fmt.Errorf("tier %q: negative weight %d", t.Tier, t.Weight)
+		}
+		if t.Multiplier < 0 || math.IsNaN(t.Multiplier) || math.IsInf(t.Multiplier, 0) {
+			return nil, fmt.Errorf("tier %q: invalid multiplier %v", t.Tier, t.Multiplier)
+		}
+		for _, c := range t.Wins() {
+			if c.Boost < 0 || (c.Boost > 0 && c.Boost < 1) || math.IsNaN(c.Boost) || math.IsInf(c.Boost, 0) {
+				return nil, fmt.Errorf("tier %q: invalid boost %v (want 0 or at least 1)", t.Tier, c.Boost)
+			}
+		}
+		if total > math.MaxInt64-t.Weight {
+			return nil, fmt.Errorf("total weight overflows int64")
+		}
+		total += t.Weight
+	}
+	if total <= 0 {
+		return nil, fmt.Errorf("total weight is zero")
+	}
+
+	r := &Report{
+		ModelID:     g.ModelID,
+		TotalWeight: total,
+		Tiers:       make([]TierReport, 0, len(g.PrizeTable)),
+	}
+	var second float64 // E[X^2]
+	for _, t := range g.PrizeTable {
+		p := float64(t.Weight) / float64(total)
+		tr := TierReport{
+			Tier:            t.Tier,
+			Multiplier:      t.Multiplier,
+			Weight:          t.Weight,
+			Probability:     p,
+			RTPContribution: p * t.Multiplier,
+		}
+		for _, c := range t.Wins() {
+			tr.BoostRTP += p * (c.Multiplier - c.Base())
+		}
+		if t.Weight > 0 {
+			tr.Odds = float64(total) / float64(t.Weight)
+			if t.Multiplier > 0 {
+				r.HitRate += p
+			}
+			if t.Multiplier > r.MaxWin {
+				r.MaxWin = t.Multiplier
+			}
+		}
+		r.RTP += tr.RTPContribution
+		r.BoostRTP += tr.BoostRTP
+		second += p * t.Multiplier * t.Multiplier
+		r.Tiers = append(r.Tiers, tr)
+	}
+	r.Variance = second - r.RTP*r.RTP
+	if r.Variance < 0 {
+		r.Variance = 0 // rounding on single-outcome tables
+	}
+	r.StdDev = math.Sqrt(r.Variance)
+	return r, nil
+}
+
+// Validate analyzes g and checks it is usable: tier ids are unique, the prize table is
+// analyzable, and any declared Stats agree with the computed values within tolerance.
+// The report is returned even when only the declared stats are off.
+func Validate(g *GameMath, tolerance float64) (*Report, error) {
+	if g == nil {
+		return nil, &ValidationError{Problems: []string{"game math is nil"}}
+	}
+	verr := &ValidationError{ModelID: g.ModelID}
+	seen := make(map[string]bool, len(g.PrizeTable))
+	for _, t := range g.PrizeTable {
+		if seen[t.Tier] {
+			verr.Problems = append(verr.Problems, fmt.Sprintf("duplicate tier %q", t.Tier))
+		}
+		seen[t.Tier] = true
+	}
+	for _, t := range g.PrizeTable {
+		verr.Problems = append(verr.Problems, componentProblems(g, t)...)
+	}
+	report, err := Analyze(g)
+	if err != nil {
+		verr.Problems = append(verr.Problems, err.Error())
+		return nil, verr
+	}
+	verr.Report = report
+	if st := g.Stats; st != nil {
+		check := func(field string, declared, computed float64, relative bool) {
+			diff := math.Abs(declared - computed)
+			limit := tolerance
+			if relative {
+				limit = tolerance * math.Max(math.Abs(computed), 1)
+			}
+			if diff > limit {
+				verr.Problems = append(verr.Problems, fmt.Sprintf("%s: declared %g, computed %g", field, declared, computed))
+			}
+		}
+		// Fields left at zero are treated as not declared (older payloads omit some).
+		if st.ComputedRTP != 0 {
+			check("computed_rtp", st.ComputedRTP, report.RTP, false)
+		}
+		if st.HitRate != 0 {
+			check("hit_rate", st.HitRate, report.HitRate, false)
+		}
+		if st.Variance != 0 {
+			check("variance", st.Variance, report.Variance, true)
+		}
+		if st.MaxWin != 0 {
+			check("max_win", st.MaxWin, report.MaxWin, true)
+		}
+	}
+	if len(verr.Problems) > 0 {
+		return report, verr
+	}
+	return report, nil
+}
+
+// componentProblems checks a combination tier: components need MULTI_WIN, at least two
+// paying components, and a tier multiplier equal to their sum.
+func componentProblems(g *GameMath, t PrizeTier) []string {
+	if len(t.Components) == 0 {
+		return nil
+	}
+	if !g.IsMultiWin() {
+		return []string{fmt.Sprintf("tier %q: components require win_logic %s", t.Tier, WinLogicMulti)}
+	}
+	var problems []string
+	if t.Boost != 0 {
+		problems = append(problems, fmt.Sprintf("tier %q: a combination sets boost on its components", t.Tier))
+	}
+	if len(t.Components) < 2 {
+		problems = append(problems, fmt.Sprintf("tier %q: a combination needs at least two components", t.Tier))
+	}
+	var sum float64
+	for _, c := range t.Components {
+		if c.Tier == "" {
+			problems = append(problems, fmt.Sprintf("tier %q: component without tier id", t.Tier))
+		}
+		if c.Multiplier <= 0 || math.IsNaN(c.Multiplier) || math.IsInf(c.Multiplier, 0) {
+			problems = append(problems, fmt.Sprintf("tier %q: component %q: invalid multiplier %v", t.Tier, c.Tier, c.Multiplier))
+		}
+		sum += c.Multiplier
+	}
+	if math.Abs(sum-t.Multiplier) > 1e-9*math.Max(sum, 1) {
+		problems = append(problems, fmt.Sprintf("tier %q: multiplier %g is not the sum of its components (%g)", t.Tier, t.Multiplier, sum))
+	}
+	return problems
+}
@@ -0,0 +1,159 @@
+package gamemath
+
+import (
+	"errors"
+	"math"
+	"strings"
+	"testing"
+)
+
+func approx(a, b float64) bool { return math.Abs(a-b) < 1e-9 }
+
+func TestAnalyze(t *testing.T) {
+	g := &GameMath{
+		ModelID: "analyze_test",
+		PrizeTable: []PrizeTier{
+			{Tier: "LOSE", Multiplier: 0, Weight: 70},
+			{Tier: "T1", Multiplier: 2, Weight: 20},
+			{Tier: "T2", Multiplier: 5, Weight: 10},
+			{Tier: "NEVER", Multiplier: 1000, Weight: 0},
+		},
+	}
+	r, err := Analyze(g)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// E[X] = 0.2*2 + 0.1*5 = 0.9; E[X^2] = 0.2*4 + 0.1*25 = 3.3; Var = 3.3 - 0.81 = 2.49
+	if !approx(r.RTP, 0.9) || !approx(r.HitRate, 0.3) || !approx(r.Variance, 2.49) {
+		t.Errorf("rtp %v hit %v var %v", r.RTP, r.HitRate, r.Variance)
+	}
+	if !approx(r.StdDev, math.Sqrt(2.49)) {
+		t.Errorf("std dev %v", r.StdDev)
+	}
+	if r.MaxWin != 5 {
+		t.Errorf("max win %v want 5 (zero-weight tiers can't hit)", r.MaxWin)
+	}
+	if r.TotalWeight != 100 || len(r.Tiers) != 4 {
+		t.Fatalf("total %d tiers %d", r.TotalWeight, len(r.Tiers))
+	}
+	if !approx(r.Tiers[2].Odds, 10) || !approx(r.Tiers[2].RTPContribution, 0.5) {
+		t.Errorf("T2 report %+v", r.Tiers[2])
+	}
+	if r.Tiers[3].Odds != 0 {
+		t.Errorf("zero-weight tier odds %v want 0", r.Tiers[3].Odds)
+	}
+}
+
+func TestAnalyze_Invalid(t *testing.T) {
+	cases := map[string][]PrizeTier{
+		"empty":           nil,
+		"zero weight":     {{Tier: "A", Weight: 0}},
+		"negative weight": {{Tier: "A", Weight: -1}, {Tier: "B", Weight: 5}},
+		"negative mult":   {{Tier: "A", Multiplier: -2, Weight: 1}},
+	}
+	for name, table := range cases {
+		if _, err := Analyze(&GameMath{PrizeTable: table}); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
+
+func TestValidate_Stats(t *testing.T) {
+	g := &GameMath{
+		ModelID: "stats_test",
+		PrizeTable: []PrizeTier{
+			{Tier: "LOSE", Multiplier: 0, Weight: 70},
+			{Tier: "T1", Multiplier: 2, Weight: 20},
+			{Tier: "T2", Multiplier: 5, Weight: 10},
+		},
+		Stats: &GameStats{ComputedRTP: 0.9, HitRate: 0.3, Variance: 2.49},
+	}
+	if _, err := Validate(g, StatsTolerance); err != nil {
+		t.Fatalf("matching stats rejected: %v", err)
+	}
+
+	g.Stats.ComputedRTP = 0.96
+	report, err := Validate(g, StatsTolerance)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("got %v want ValidationError", err)
+	}
+	if report == nil || verr.Report == nil || len(verr.Problems) != 1 {
+		t.Errorf("expected report and one problem, got %+v", verr)
+	}
+}
+
+func TestValidate_DuplicateTier(t *testing.T) {
+	g := &GameMath{PrizeTable: []PrizeTier{{Tier: "A", Weight: 1}, {Tier: "A", Weight: 1}}}
+	if _, err := Validate(g, StatsTolerance); err == nil {
+		t.Error("duplicate tier ids should be rejected")
+	}
+}
+
+func TestValidate_Components(t *testing.T) {
+	combo := PrizeTier{Tier: "T1+T2", Multiplier: 7, Weight: 1, Components: []PrizeComponent{{Tier: "T1", Multiplier: 2}, {Tier: "T2", Multiplier: 5}}}
+	g := &GameMath{ModelID: "multi", WinLogic: WinLogicMulti, PrizeTable: []PrizeTier{{Tier: "LOSE", Weight: 9}, combo}}
+	if _, err := Validate(g, StatsTolerance); err != nil {
+		t.Fatalf("valid combination rejected: %v", err)
+	}
+	g.WinLogic = WinLogicSingle
+	if _, err := Validate(g, StatsTolerance); err == nil || !strings.Contains(err.Error(), "MULTI_WIN") {
+		t.Errorf("components on a SINGLE_WIN model: err = %v", err)
+	}
+	g.WinLogic = WinLogicMulti
+	g.PrizeTable[1].Multiplier = 8
+	if _, err := Validate(g, StatsTolerance); err == nil || !strings.Contains(err.Error(), "sum of its components") {
+		t.Errorf("multiplier not matching components: err = %v", err)
+	}
+}
+
+func TestStore_RegisterRejectsMismatchedStats(t *testing.T) {
+	s := NewStore(t.TempDir())
+	g := &GameMath{
+		ModelID:    "bad_stats",
+		PrizeTable: []PrizeTier{{Tier: "LOSE", Weight: 50}, {Tier: "WIN", Multiplier: 2, Weight: 50}},
+		Stats:      &GameStats{ComputedRTP: 0.96},
+	}
+	if err := s.Register(sealed(t, g)); err == nil {
+		t.Fatal("Register should reject stats that don't match the prize table")
+	}
+	if s.Get("bad_stats") != nil {
+		t.Error("rejected model must not be stored")
+	}
+}
+
+func TestAnalyze_Boost(t *testing.T) {
+	g := &GameMath{
+		ModelID:  "boost_test",
+		WinLogic: WinLogicMulti,
+		PrizeTable: []PrizeTier{
+			{Tier: "LOSE", Multiplier: 0, Weight: 80},
+			{Tier: "T10", Multiplier: 10, Weight: 10, Boost: 5}, // 2x match shown with a 5x symbol
+			{Tier: "COMBO", Multiplier: 6, Weight: 10, Components: []PrizeComponent{
+				{Tier: "T2", Multiplier: 2},
+				{Tier: "T4", Multiplier: 4, Boost: 2},
+			}},
+		},
+	}
+	r, err := Validate(g, StatsTolerance)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// RTP = 0.1*10 + 0.1*6 = 1.6; boosts add 0.1*(10-2) + 0.1*(4-2) = 1.0
+	if !approx(r.RTP, 1.6) || !approx(r.BoostRTP, 1.0) {
+		t.Errorf("rtp %v boost rtp %v", r.RTP, r.BoostRTP)
+	}
+	if !approx(r.Tiers[1].BoostRTP, 0.8) || !approx(r.Tiers[2].BoostRTP, 0.2) {
+		t.Errorf("tier boost rtp %v %v", r.Tiers[1].BoostRTP, r.Tiers[2].BoostRTP)
+	}
+
+	g.PrizeTable[1].Boost = 0.5
+	if _, err := Analyze(g); err == nil {
+		t.Error("boost below 1 should be rejected")
+	}
+	g.PrizeTable[1].Boost = 5
+	g.PrizeTable[2].Boost = 2
+	if _, err := Validate(g, StatsTolerance); err == nil || !strings.Contains(err.Error(), "boost on its components") {
+		t.Errorf("boost on a combination: %v", err)
+	}
+}
@@ -0,0 +1,221 @@
+package gamemath
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
+)
+
+// Crash math defaults: a 1% house edge, crash points up to 1000x, and a multiplier that doubles
+// in about 11.6s and reaches 10x after about 38s.
+const (
+	DefaultCrashHouseEdge     = 0.01
+	DefaultCrashMaxMultiplier = 1000.0
+	DefaultCrashGrowthRate    = 0.06
+)
+
+// CrashModelID is the model id the server's crash math is registered under, and
+// DefaultCrashModelVersion the version it has unless configured otherwise.
+const (
+	CrashModelID             = "crash"
+	DefaultCrashModelVersion = "1"
+)
+
+// Crash points are floored to a multiple of CrashTick (0.01x).
+const (
+	crashTicksPerUnit = 100
+	CrashTick         = 1.0 / crashTicksPerUnit
+)
+
+// CrashMath is the math model of a crash game.
+//
+// The crash point X of a round is (1-HouseEdge)/(1-u) for u uniform in [0, 1), floored to
+// CrashTick, capped at MaxMultiplier, and raised to 1.00x (an instant crash) when below it. So
+// P(X >= x) = (1-HouseEdge)/x for every x on the tick grid in (1, MaxMultiplier], and a player
+// who cashes out at any target x in that range, winning when X >= x, gets an RTP of exactly
+// 1-HouseEdge (see AnalyzeCrash).
+//
+// While a round runs its multiplier grows as e^(GrowthRate*t), t in seconds since it started.
+//
+// Like GameMath, a registered model has a ModelID, a ModelVersion and an Integrity.ContentHash
+// over its content (see Seal and CrashStore).
+type CrashMath struct {
+	ModelID       string     `json:"model_id,omitempty"`
+	ModelVersion  string     `json:"model_version,omitempty"`
+	HouseEdge     float64    `json:"house_edge"`
+	MaxMultiplier float64    `json:"max_multiplier"`
+	GrowthRate    float64    `json:"growth_rate"` // per second
+	Integrity     *Integrity `json:"integrity,omitempty"`
+}
+
+// DefaultCrashMath is the crash model with the default house edge, cap and growth rate.
+func DefaultCrashMath() CrashMath {
+	return CrashMath{
+		HouseEdge:     DefaultCrashHouseEdge,
+		MaxMultiplier: DefaultCrashMaxMultiplier,
+		GrowthRate:    DefaultCrashGrowthRate,
+	}
+}
+
+// WithDefaults returns m with an unset (zero) MaxMultiplier or GrowthRate set to the default.
+// A zero HouseEdge is a valid, edge-free model and is kept.
+func (m CrashMath) WithDefaults() CrashMath {
+	if m.MaxMultiplier == 0 {
+		m.MaxMultiplier = DefaultCrashMaxMultiplier
+	}
+	if m.GrowthRate == 0 {
+		m.GrowthRate = DefaultCrashGrowthRate
+	}
+	return m
+}
+
+// Validate checks the house edge is in [0, 1), the cap is at least one tick above 1.00x and the
+// growth rate is positive.
+func (m CrashMath) Validate() error {
+	switch {
+	case math.IsNaN(m.HouseEdge) || m.HouseEdge < 0 || m.HouseEdge >= 1:
+		return fmt.Errorf("crash math: house_edge %v is not in [0, 1)", m.HouseEdge)
+	case math.IsNaN(m.MaxMultiplier) || math.IsInf(m.MaxMultiplier, 0) || m.MaxMultiplier < 1+CrashTick:
+		return fmt.Errorf("crash math: max_multiplier %v is below %.2f", m.MaxMultiplier, 1+CrashTick)
+	case math.IsNaN(m.GrowthRate) || math.IsInf(m.GrowthRate, 0) || m.GrowthRate <= 0:
+		return fmt.Errorf("crash math: growth_rate %v is not positive", m.GrowthRate)
+	}
+	return nil
+}
+
+// CanonicalJSON returns the bytes covered by Integrity.ContentHash: the model JSON without
+// model_id and integrity, encoded as GameMath.CanonicalJSON encodes game math.
+func (m CrashMath) CanonicalJSON() ([]byte, error) {
+	m.ModelID = ""
+	m.Integrity = nil
+	return canonicalJSON(&m)
+}
+
+// ContentHash returns the lowercase hex SHA-256 of CanonicalJSON.
+func (m CrashMath) ContentHash() (string, error) {
+	b, err := m.CanonicalJSON()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Seal sets Integrity.ContentHash to the model's current hash.
+func (m *CrashMath) Seal() error {
+	h, err := m.ContentHash()
+	if err != nil {
+		return err
+	}
+	m.Integrity = &Integrity{ContentHash: h}
+	return nil
+}
+
+// VerifyContentHash checks Integrity.ContentHash against the model's content.
+func (m CrashMath) VerifyContentHash() error {
+	return checkContentHash(m.Integrity, m.ContentHash)
+}
+
+// maxTicks is MaxMultiplier in ticks, rounded down to the grid.
+func (m CrashMath) maxTicks() float64 {
+	return math.Floor(m.MaxMultiplier*crashTicksPerUnit + 1e-9)
+}
+
+// Cap is MaxMultiplier floored to the tick grid: the highest crash point.
+func (m CrashMath) Cap() float64 {
+	return m.maxTicks() / crashTicksPerUnit
+}
+
+// CrashPoint maps u, uniform in [0, 1), to a crash point.
+func (m CrashMath) CrashPoint(u float64) float64 {
+	ticks := math.Floor((1 - m.HouseEdge) / (1 - u) * crashTicksPerUnit)
+	if ticks < crashTicksPerUnit {
+		ticks = crashTicksPerUnit
+	}
+	if max := m.maxTicks(); ticks > max {
+		ticks = max
+	}
+	return ticks / crashTicksPerUnit
+}
+
+// CrashPointFrom draws a crash point from src.
+func (m CrashMath) CrashPointFrom(src rng.Source) float64 {
+	return m.CrashPoint(rng.Float64(src))
+}
+
+// Reach is the probability that a round's multiplier reaches x before it crashes, i.e. that the
+// crash point is at least x. Targets between ticks count as the next tick.
+func (m CrashMath) Reach(x float64) float64 {
+	x = ceilTick(x)
+	switch {
+	case x <= 1:
+		return 1
+	case x > m.Cap()+1e-9:
+		return 0
+	}
+	return math.Min(1, (1-m.HouseEdge)/x)
+}
+
+// ceilTick rounds x up to the tick grid.
+func ceilTick(x float64) float64 {
+	return math.Ceil(x*crashTicksPerUnit-1e-9) / crashTicksPerUnit
+}
+
+// MultiplierAt is the multiplier elapsed after the round started.
+func (m CrashMath) MultiplierAt(elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 1
+	}
+	return math.Exp(m.GrowthRate * elapsed.Seconds())
+}
+
+// TimeTo is how long after the start the multiplier takes to reach x, rounded up to the
+// nanosecond so MultiplierAt(TimeTo(x)) is not below x.
+func (m CrashMath) TimeTo(x float64) time.Duration {
+	if x <= 1 {
+		return 0
+	}
+	return time.Duration(math.Ceil(math.Log(x) / m.GrowthRate * float64(time.Second)))
+}
+
+// AnalyzeCrash computes the RTP, hit rate, variance and per-outcome odds of a player who cashes
+// out every round at target (rounded up to the tick grid): the round pays target times the bet
+// when its crash point is at least target, and nothing otherwise. Every target above 1.00x up
+// to the cap returns 1-HouseEdge; 1.00x returns the stake and targets above the cap never pay.
+func AnalyzeCrash(m CrashMath, target float64) (*Report, error) {
+	if err := m.Validate(); err != nil {
+		return nil, err
+	}
+	if math.IsNaN(target) || math.IsInf(target, 0) || target < 1 {
+		return nil, fmt.Errorf("crash math: cashout target %v is below 1.00", target)
+	}
+	target = ceilTick(target)
+	p := m.Reach(target)
+	r := &Report{
+		ModelID: fmt.Sprintf("crash@%.2fx", target),
+		RTP:     p * target,
+		Tiers: []TierReport{
+			{Tier: "LOSE", Probability: 1 - p},
+			{Tier: "CASHOUT", Multiplier: target, Probability: p, RTPContribution: p * target},
+		},
+	}
+	for i := range r.Tiers {
+		if tr := &r.Tiers[i]; tr.Probability > 0 {
+			tr.Odds = 1 / tr.Probability
+		}
+	}
+	if p > 0 {
+		r.HitRate = p
+		r.MaxWin = target
+	}
+	r.Variance = p*target*target - r.RTP*r.RTP
+	if r.Variance < 0 {
+		r.Variance = 0
+	}
+	r.StdDev = math.Sqrt(r.Variance)
+	return r, nil
+}
@@ -0,0 +1,126 @@
+package gamemath
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// ErrCrashVersionConflict is returned when a crash model version is registered again with other
+// content: a new house edge, cap or growth rate needs a new model_version.
+var ErrCrashVersionConflict = errors.New("crash math version already registered with other content")
+
+// CrashMathStore keeps every crash math version registered on this server in
+// data/crash_math.json, so a model_version always names the same model and the rounds in the
+// crash history, which record the math they were drawn with, can be traced back to it.
+type CrashMathStore struct {
+	mu      sync.Mutex
+	models  map[string]CrashMath // by model_id@model_version
+	dataDir string
+}
+
+func NewCrashMathStore(dataDir string) *CrashMathStore {
+	if dataDir == "" {
+		dataDir = "data"
+	}
+	s := &CrashMathStore{
+		models:  make(map[string]CrashMath),
+		dataDir: dataDir,
+	}
+	s.load()
+	return s
+}
+
+func (s *CrashMathStore) path() string {
+	return filepath.Join(s.dataDir, "crash_math.json")
+}
+
+func (s *CrashMathStore) load() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := os.ReadFile(s.path())
+	if err != nil {
+		return
+	}
+	var list []CrashMath
+	if err := json.Unmarshal(data, &list); err != nil {
+		return
+	}
+	for _, m := range list {
+		if m.ModelID != "" && m.ModelVersion != "" {
+			s.models[m.key()] = m
+		}
+	}
+}
+
+// saveLocked writes every version to disk. Caller must hold s.mu.
+func (s *CrashMathStore) saveLocked() error {
+	keys := make([]string, 0, len(s.models))
+	for k := range s.models {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	list := make([]CrashMath, 0, len(keys))
+	for _, k := range keys {
+		list = append(list, s.models[k])
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dataDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path(), data, 0644)
+}
+
+// Register stores a crash math version. The model must have a model_id and model_version, pass
+// Validate and carry its content hash (see Seal). Registering a stored version again is a no-op
+// when the hash matches and ErrCrashVersionConflict when it does not.
+func (s *CrashMathStore) Register(m CrashMath) error {
+	if m.ModelID == "" || m.ModelVersion == "" {
+		return errors.New("crash math: model_id and model_version are required")
+	}
+	if err := m.Validate(); err != nil {
+		return err
+	}
+	if err := m.VerifyContentHash(); err != nil {
+		return fmt.Errorf("crash math %s@%s: %w", m.ModelID, m.ModelVersion, err)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := m.key()
+	if cur, ok := s.models[key]; ok {
+		if cur.Integrity != nil && cur.Integrity.ContentHash == m.Integrity.ContentHash {
+			return nil
+		}
+		return fmt.Errorf("%w: %s (registered %s, now %s)", ErrCrashVersionConflict, key, cur.hash(), m.Integrity.ContentHash)
+	}
+	s.models[key] = m
+	return s.saveLocked()
+}
+
+// Get returns a registered version of a crash model.
+func (s *CrashMathStore) Get(modelID, version string) (CrashMath, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	m, ok := s.models[modelID+"@"+version]
+	return m, ok
+}
+
+// key identifies the model version: model_id@model_version.
+func (m CrashMath) key() string {
+	return m.ModelID + "@" + m.ModelVersion
+}
+
+// hash is the declared content hash, if any.
+func (m CrashMath) hash() string {
+	if m.Integrity == nil {
+		return ""
+	}
+	return m.Integrity.ContentHash
+}
@@ -0,0 +1,185 @@
+package gamemath
+
+import (
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
+)
+
+func TestAnalyzeCrash_RTPIsOneMinusEdge(t *testing.T) {
+	m := CrashMath{HouseEdge: 0.02, MaxMultiplier: 100, GrowthRate: DefaultCrashGrowthRate}
+	for _, target := range []float64{1.01, 1.5, 2, 7.77, 100} {
+		r, err := AnalyzeCrash(m, target)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(r.RTP-0.98) > 1e-12 {
+			t.Errorf("target %v: RTP %v want 0.98", target, r.RTP)
+		}
+		if want := 0.98 / target; math.Abs(r.HitRate-want) > 1e-12 {
+			t.Errorf("target %v: hit rate %v want %v", target, r.HitRate, want)
+		}
+	}
+	if r, _ := AnalyzeCrash(m, 1); r.RTP != 1 || r.Variance != 0 {
+		t.Errorf("cashing out at 1.00x returns the stake, got %+v", r)
+	}
+	if r, _ := AnalyzeCrash(m, 100.01); r.RTP != 0 || r.HitRate != 0 {
+		t.Errorf("targets above the cap never pay, got %+v", r)
+	}
+	if _, err := AnalyzeCrash(m, 0.5); err == nil {
+		t.Error("target below 1.00 should fail")
+	}
+	if _, err := AnalyzeCrash(CrashMath{HouseEdge: 1, MaxMultiplier: 10, GrowthRate: 1}, 2); err == nil {
+		t.Error("house edge 1 should fail validation")
+	}
+}
+
+func TestCrashMath_CrashPoint(t *testing.T) {
+	m := CrashMath{HouseEdge: 0.01, MaxMultiplier: 1000, GrowthRate: DefaultCrashGrowthRate}
+	cases := []struct {
+		u, want float64
+	}{
+		{0, 1},            // 0.99 is an instant crash
+		{0.005, 1},        // 0.99/0.995 < 1.00
+		{0.505, 2},        // 0.99/0.495 = 2
+		{0.67, 3},         // 0.99/0.33 = 3
+		{0.9999999, 1000}, // capped
+	}
+	for _, c := range cases {
+		if got := m.CrashPoint(c.u); math.Abs(got-c.want) > 1e-9 {
+			t.Errorf("CrashPoint(%v) = %v want %v", c.u, got, c.want)
+		}
+	}
+
+	// The drawn distribution matches Reach.
+	src := rng.NewSeeded([]byte("crash"))
+	const n = 200_000
+	counts := map[float64]int{}
+	targets := []float64{1.01, 2, 10}
+	for i := 0; i < n; i++ {
+		x := m.CrashPointFrom(src)
+		for _, target := range targets {
+			if x >= target-1e-9 {
+				counts[target]++
+			}
+		}
+	}
+	for _, target := range targets {
+		p := m.Reach(target)
+		se := math.Sqrt(p * (1 - p) / n)
+		if got := float64(counts[target]) / n; math.Abs(got-p) > 5*se {
+			t.Errorf("P(X >= %v) = %v want %v", target, got, p)
+		}
+	}
+}
+
+func TestCrashMath_Growth(t *testing.T) {
+	m := DefaultCrashMath()
+	if got := m.MultiplierAt(0); got != 1 {
+		t.Errorf("MultiplierAt(0) = %v", got)
+	}
+	for _, x := range []float64{1.01, 2, 10, 1000} {
+		d := m.TimeTo(x)
+		if got := m.MultiplierAt(d); got < x || got-x > 1e-6 {
+			t.Errorf("MultiplierAt(TimeTo(%v)) = %v", x, got)
+		}
+	}
+	if d := m.TimeTo(2); d < 11*time.Second || d > 12*time.Second {
+		t.Errorf("default growth doubles after %v, want ~11.6s", d)
+	}
+}
+
+func TestCrashMath_Validate(t *testing.T) {
+	if err := DefaultCrashMath().Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := (CrashMath{}).WithDefaults().Validate(); err != nil {
+		t.Errorf("zero model with defaults: %v", err)
+	}
+	for _, m := range []CrashMath{
+		{HouseEdge: -0.01, MaxMultiplier: 10, GrowthRate: 1},
+		{HouseEdge: 0.01, MaxMultiplier: 1, GrowthRate: 1},
+		{HouseEdge: 0.01, MaxMultiplier: 10, GrowthRate: 0},
+		{HouseEdge: math.NaN(), MaxMultiplier: 10, GrowthRate: 1},
+	} {
+		if m.Validate() == nil {
+			t.Errorf("%+v should be invalid", m)
+		}
+	}
+}
+
+func TestCrashMath_ContentHash(t *testing.T) {
+	m := CrashMath{ModelID: CrashModelID, ModelVersion: "1", HouseEdge: 0.01, MaxMultiplier: 1000, GrowthRate: 0.06}
+	b, err := m.CanonicalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := `{"growth_rate":0.06,"house_edge":0.01,"max_multiplier":1000,"model_version":"1"}`; string(b) != want {
+		t.Errorf("canonical json\n got %s\nwant %s", b, want)
+	}
+	if err := m.VerifyContentHash(); !errors.Is(err, ErrContentHashMissing) {
+		t.Fatalf("got %v want ErrContentHashMissing", err)
+	}
+	if err := m.Seal(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.VerifyContentHash(); err != nil {
+		t.Fatalf("sealed model: %v", err)
+	}
+	renamed := m
+	renamed.ModelID = "crash_b"
+	if err := renamed.VerifyContentHash(); err != nil {
+		t.Errorf("model_id is not covered by the hash: %v", err)
+	}
+	m.HouseEdge = 0.02
+	if err := m.VerifyContentHash(); !errors.Is(err, ErrContentHashMismatch) {
+		t.Errorf("got %v want ErrContentHashMismatch", err)
+	}
+}
+
+func TestCrashMathStore_Register(t *testing.T) {
+	dir := t.TempDir()
+	st := NewCrashMathStore(dir)
+	m := DefaultCrashMath()
+	m.ModelID, m.ModelVersion = CrashModelID, "1"
+	if err := st.Register(m); !errors.Is(err, ErrContentHashMissing) {
+		t.Fatalf("unsealed model: %v", err)
+	}
+	if err := m.Seal(); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Register(m); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Register(m); err != nil {
+		t.Errorf("registering the same version again: %v", err)
+	}
+
+	// A restart with a new house edge under the same version is refused; a new version is not.
+	st = NewCrashMathStore(dir)
+	changed := m
+	changed.HouseEdge = 0.02
+	if err := changed.Seal(); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.Register(changed); !errors.Is(err, ErrCrashVersionConflict) {
+		t.Errorf("changed model under version 1: %v", err)
+	}
+	changed.ModelVersion = "2"
+	changed.Seal()
+	if err := st.Register(changed); err != nil {
+		t.Errorf("version 2: %v", err)
+	}
+	if got, ok := st.Get(CrashModelID, "1"); !ok || got.HouseEdge != 0.01 || got.Integrity.ContentHash != m.Integrity.ContentHash {
+		t.Errorf("version 1 after reload: %+v", got)
+	}
+
+	invalid := CrashMath{ModelID: CrashModelID, ModelVersion: "3", HouseEdge: 1, MaxMultiplier: 10, GrowthRate: 1}
+	invalid.Seal()
+	if err := st.Register(invalid); err == nil {
+		t.Error("invalid model registered")
+	}
+}
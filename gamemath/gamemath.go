@@ -1,47 +1,121 @@
 package gamemath
 
 import (
-	"crypto/rand"
-	"math/big"
+	"strings"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
 )
 
 // GameMath is the stored game math payload (schema_version 1).
 type GameMath struct {
-	SchemaVersion int          `json:"schema_version"`
-	ModelID       string       `json:"model_id"`
-	ModelVersion  string       `json:"model_version"`
-	Mechanic      Mechanic     `json:"mechanic"`
-	MathMode      string       `json:"math_mode"`
-	WinLogic      string       `json:"win_logic"`
-	PrizeTable    []PrizeTier  `json:"prize_table"`
-	Stats         *GameStats   `json:"stats,omitempty"`
-	Integrity     *Integrity   `json:"integrity,omitempty"`
+	SchemaVersion int         `json:"schema_version"`
+	ModelID       string      `json:"model_id"`
+	ModelVersion  string      `json:"model_version"`
+	Mechanic      Mechanic    `json:"mechanic"`
+	MathMode      string      `json:"math_mode"`
+	WinLogic      string      `json:"win_logic"`
+	PrizeTable    []PrizeTier `json:"prize_table"`
+	TotalTickets  int64       `json:"total_tickets,omitempty"` // print run size for LIMITED math
+	Stats         *GameStats  `json:"stats,omitempty"`
+	Integrity     *Integrity  `json:"integrity,omitempty"`
+}
+
+// Math modes. UNLIMITED draws every ticket independently by weight; LIMITED draws
+// without replacement from a finite, persisted ticket pool (see Pool).
+const (
+	MathModeUnlimited = "UNLIMITED"
+	MathModeLimited   = "LIMITED"
+)
+
+// IsLimited reports whether the model sells a finite series of tickets.
+func (g *GameMath) IsLimited() bool {
+	return g != nil && strings.EqualFold(g.MathMode, MathModeLimited)
+}
+
+// Win logics. A SINGLE_WIN ticket awards at most one prize tier. A MULTI_WIN ticket may award
+// several (one per play area or matched symbol); each combination is a tier of its own that
+// lists the prizes it awards in Components.
+const (
+	WinLogicSingle = "SINGLE_WIN"
+	WinLogicMulti  = "MULTI_WIN"
+)
+
+// IsMultiWin reports whether tiers may award several prizes.
+func (g *GameMath) IsMultiWin() bool {
+	return g != nil && strings.EqualFold(g.WinLogic, WinLogicMulti)
 }
 
 type Mechanic struct {
 	Type       string `json:"type"`
 	MatchCount int    `json:"match_count,omitempty"`
+	// AllowWildcards lets reveal maps show wild symbols, which count toward any match (bundle
+	// math.json: match.allowWildcards).
+	AllowWildcards bool `json:"allow_wildcards,omitempty"`
 }
 
 type PrizeTier struct {
 	Tier       string  `json:"tier"`
 	Multiplier float64 `json:"multiplier"`
 	Weight     int64   `json:"weight"`
+	// Components are the prizes a MULTI_WIN combination tier awards; Multiplier is their sum.
+	// The tier is drawn by its own weight like any other.
+	Components []PrizeComponent `json:"components,omitempty"`
+	// Boost is the multiplier symbol that shows the prize: the ticket shows a win worth
+	// Multiplier/Boost and a Boost x symbol. 0 means no multiplier symbol. Combination tiers set
+	// it on their components.
+	Boost float64 `json:"boost,omitempty"`
+}
+
+// PrizeComponent is one prize of a combination tier, e.g. a play area or a matched symbol.
+// Tier usually names the prize tier the component pays as on its own.
+type PrizeComponent struct {
+	Tier       string  `json:"tier"`
+	Multiplier float64 `json:"multiplier"`
+	Boost      float64 `json:"boost,omitempty"` // multiplier symbol, as PrizeTier.Boost
+}
+
+// Base is the prize before its multiplier symbol: Multiplier/Boost, or Multiplier without one.
+func (c PrizeComponent) Base() float64 {
+	if c.Boost > 1 {
+		return c.Multiplier / c.Boost
+	}
+	return c.Multiplier
+}
+
+// Wins lists the prizes the tier awards: its components, the tier itself when it pays, or
+// nothing for a losing tier.
+func (t PrizeTier) Wins() []PrizeComponent {
+	if len(t.Components) > 0 {
+		return t.Components
+	}
+	if t.Multiplier > 0 {
+		return []PrizeComponent{{Tier: t.Tier, Multiplier: t.Multiplier, Boost: t.Boost}}
+	}
+	return nil
 }
 
 type GameStats struct {
 	ComputedRTP float64 `json:"computed_rtp"`
 	HitRate     float64 `json:"hit_rate"`
 	Variance    float64 `json:"variance"`
+	MaxWin      float64 `json:"max_win,omitempty"`
 }
 
 type Integrity struct {
 	ContentHash string `json:"content_hash"`
+	// SourceHash is the verified content hash of the certified model a server-derived model was
+	// built from (e.g. a win-only bundle table the server added a LOSE tier to).
+	SourceHash string `json:"source_hash,omitempty"`
 }
 
-// PickTier selects a tier from the prize table by weight using CSPRNG.
+// PickTier selects a tier from the prize table by weight using rng.Default (CSPRNG).
 // Returns the chosen PrizeTier and true, or zero value and false if table is empty/invalid.
 func (g *GameMath) PickTier() (PrizeTier, bool) {
+	return g.PickTierFrom(rng.Default)
+}
+
+// PickTierFrom selects a tier from the prize table by weight, drawing from src.
+func (g *GameMath) PickTierFrom(src rng.Source) (PrizeTier, bool) {
 	if g == nil || len(g.PrizeTable) == 0 {
 		return PrizeTier{}, false
 	}
@@ -55,12 +129,7 @@ func (g *GameMath) PickTier() (PrizeTier, bool) {
 	if total <= 0 {
 		return PrizeTier{}, false
 	}
-	max := big.NewInt(total)
-	v, err := rand.Int(rand.Reader, max)
-	if err != nil {
-		return PrizeTier{}, false
-	}
-	idx := v.Int64()
+	idx := src.Int63n(total)
 	var cum int64
 	for i := range g.PrizeTable {
 		t := &g.PrizeTable[i]
@@ -74,3 +143,16 @@ func (g *GameMath) PickTier() (PrizeTier, bool) {
 	}
 	return g.PrizeTable[len(g.PrizeTable)-1], true
 }
+
+// Tier returns the prize tier with the given id.
+func (g *GameMath) Tier(id string) (PrizeTier, bool) {
+	if g == nil {
+		return PrizeTier{}, false
+	}
+	for _, t := range g.PrizeTable {
+		if t.Tier == id {
+			return t, true
+		}
+	}
+	return PrizeTier{}, false
+}
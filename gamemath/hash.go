@@ -0,0 +1,112 @@
+package gamemath
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	ErrContentHashMissing  = errors.New("integrity.content_hash missing")
+	ErrContentHashMismatch = errors.New("integrity.content_hash mismatch")
+)
+
+// CanonicalJSON returns the bytes covered by Integrity.ContentHash: the GameMath JSON with
+// model_id and integrity removed, object keys sorted, numbers as Go encodes them, and no
+// insignificant whitespace. model_id is excluded so the same certified math can be
+// registered under a different id (bundles are keyed by game_id).
+func (g *GameMath) CanonicalJSON() ([]byte, error) {
+	if g == nil {
+		return nil, errors.New("game math is nil")
+	}
+	c := *g
+	c.ModelID = ""
+	c.Integrity = nil
+	return canonicalJSON(&c)
+}
+
+// canonicalJSON encodes v with its model_id removed, object keys sorted and no insignificant
+// whitespace.
+func canonicalJSON(v interface{}) ([]byte, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	// Round-trip through a generic value: encoding/json sorts map keys on output.
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.UseNumber()
+	var m map[string]interface{}
+	if err := dec.Decode(&m); err != nil {
+		return nil, err
+	}
+	delete(m, "model_id")
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(m); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
+
+// ContentHash returns the lowercase hex SHA-256 of CanonicalJSON.
+func (g *GameMath) ContentHash() (string, error) {
+	b, err := g.CanonicalJSON()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Seal sets Integrity.ContentHash to the model's current hash. Only for math the server
+// derives itself; certified math must arrive with its hash.
+func (g *GameMath) Seal() error {
+	h, err := g.ContentHash()
+	if err != nil {
+		return err
+	}
+	g.Integrity = &Integrity{ContentHash: h}
+	return nil
+}
+
+// SealLegacy seals g when it declares no content hash, as math stored before hashes were
+// required does, and reports whether it did. A declared hash is verified as usual, so only a
+// wrong one is an error.
+func (g *GameMath) SealLegacy() (bool, error) {
+	err := g.VerifyContentHash()
+	if !errors.Is(err, ErrContentHashMissing) || g == nil {
+		return false, err
+	}
+	return true, g.Seal()
+}
+
+// VerifyContentHash checks Integrity.ContentHash against the model's content.
+// An optional "sha256:" prefix on the declared hash is accepted.
+func (g *GameMath) VerifyContentHash() error {
+	if g == nil {
+		return ErrContentHashMissing
+	}
+	return checkContentHash(g.Integrity, g.ContentHash)
+}
+
+// checkContentHash compares the hash declared in in with the one contentHash computes.
+func checkContentHash(in *Integrity, contentHash func() (string, error)) error {
+	if in == nil || strings.TrimSpace(in.ContentHash) == "" {
+		return ErrContentHashMissing
+	}
+	declared := strings.ToLower(strings.TrimSpace(in.ContentHash))
+	declared = strings.TrimPrefix(declared, "sha256:")
+	computed, err := contentHash()
+	if err != nil {
+		return err
+	}
+	if declared != computed {
+		return fmt.Errorf("%w: declared %s, computed %s", ErrContentHashMismatch, declared, computed)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package gamemath
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestContentHash_CanonicalForm(t *testing.T) {
+	g := &GameMath{
+		SchemaVersion: 1,
+		ModelID:       "a",
+		Mechanic:      Mechanic{Type: "match_3", MatchCount: 3},
+		PrizeTable:    []PrizeTier{{Tier: "LOSE", Weight: 9}, {Tier: "T1", Multiplier: 2.5, Weight: 1}},
+	}
+	b, err := g.CanonicalJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"math_mode":"","mechanic":{"match_count":3,"type":"match_3"},"model_version":"","prize_table":[{"multiplier":0,"tier":"LOSE","weight":9},{"multiplier":2.5,"tier":"T1","weight":1}],"schema_version":1,"win_logic":""}`
+	if string(b) != want {
+		t.Errorf("canonical json\n got %s\nwant %s", b, want)
+	}
+}
+
+func TestContentHash_IgnoresModelIDAndKeyOrder(t *testing.T) {
+	var a, b GameMath
+	if err := json.Unmarshal([]byte(`{"model_id":"x","prize_table":[{"tier":"A","weight":1,"multiplier":2}],"schema_version":1}`), &a); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal([]byte(`{"schema_version":1,"prize_table":[{"multiplier":2,"weight":1,"tier":"A"}],"model_id":"y"}`), &b); err != nil {
+		t.Fatal(err)
+	}
+	ha, _ := a.ContentHash()
+	hb, _ := b.ContentHash()
+	if ha != hb {
+		t.Errorf("hashes differ: %s vs %s", ha, hb)
+	}
+}
+
+func TestVerifyContentHash(t *testing.T) {
+	g := &GameMath{ModelID: "v", PrizeTable: []PrizeTier{{Tier: "A", Weight: 1}}}
+	if err := g.VerifyContentHash(); !errors.Is(err, ErrContentHashMissing) {
+		t.Fatalf("got %v want ErrContentHashMissing", err)
+	}
+	if err := g.Seal(); err != nil {
+		t.Fatal(err)
+	}
+	if err := g.VerifyContentHash(); err != nil {
+		t.Fatalf("sealed model: %v", err)
+	}
+	g.Integrity.ContentHash = "sha256:" + g.Integrity.ContentHash
+	if err := g.VerifyContentHash(); err != nil {
+		t.Fatalf("sha256: prefix should be accepted: %v", err)
+	}
+	g.PrizeTable[0].Multiplier = 3
+	if err := g.VerifyContentHash(); !errors.Is(err, ErrContentHashMismatch) {
+		t.Fatalf("got %v want ErrContentHashMismatch", err)
+	}
+}
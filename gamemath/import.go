@@ -0,0 +1,551 @@
+package gamemath
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+)
+
+// CurrentSchemaVersion is the GameMath schema_version produced by Import.
+const CurrentSchemaVersion = 1
+
+// Source formats recognised by Import.
+const (
+	// FormatGameMath is the stored GameMath schema: snake_case, integer weights.
+	FormatGameMath = "gamemath"
+	// FormatRGS is the GameCrafter RGS schema: GameMath field names, but weights may be
+	// fractional or replaced by per-tier probabilities.
+	FormatRGS = "rgs"
+	// FormatBundle is the GameCrafter bundle math.json: camelCase prizeTable entries with
+	// id/value/probability/isWin (and optionally weight).
+	FormatBundle = "bundle"
+)
+
+const (
+	// maxDenominator bounds the fractions recovered from float probabilities.
+	maxDenominator = 1_000_000_000_000
+	// maxTotalWeight bounds the total weight of a converted table (fits Int63n with room).
+	maxTotalWeight = 1_000_000_000_000_000
+	// approxTotalWeight is the total used when probabilities have no exact small fraction.
+	approxTotalWeight = 1_000_000_000_000
+	// probabilityTolerance absorbs float noise when probabilities are summed.
+	probabilityTolerance = 1e-9
+)
+
+// Imported is a math document normalized to the current GameMath schema.
+type Imported struct {
+	Math   *GameMath
+	Format string
+	// FromSchemaVersion is the schema_version of the source document (before migration).
+	FromSchemaVersion int
+	// Exact reports whether the integer weights reproduce the source probabilities exactly.
+	// When false the table was scaled to approxTotalWeight with largest-remainder rounding.
+	Exact bool
+	// TargetRTP is the declared "rtp" of a bundle document; it is a design target, not a
+	// computed statistic, so it is not copied into Stats.
+	TargetRTP float64
+	// Notes describe conversions applied (implied LOSE tier, migrations, rounding).
+	Notes []string
+}
+
+// migrations upgrade a model from schema_version key to key+1.
+var migrations = map[int]func(g *GameMath){
+	// v0 documents predate schema_version and left mode and win logic implicit.
+	0: func(g *GameMath) {
+		if g.MathMode == "" {
+			g.MathMode = MathModeUnlimited
+		}
+		if g.WinLogic == "" {
+			g.WinLogic = WinLogicSingle
+		}
+	},
+}
+
+type importTier struct {
+	Tier        string      `json:"tier"`
+	ID          string      `json:"id"`
+	Multiplier  *float64    `json:"multiplier"`
+	Value       *float64    `json:"value"`
+	Weight      json.Number `json:"weight"`
+	Probability json.Number `json:"probability"`
+	IsWin       *bool       `json:"isWin"`
+	Boost       float64     `json:"boost"`
+	// Components describe a MULTI_WIN combination; in both formats a component is
+	// {"tier"|"id", "multiplier"|"value"}.
+	Components []importComponent `json:"components"`
+}
+
+type importComponent struct {
+	Tier       string   `json:"tier"`
+	ID         string   `json:"id"`
+	Multiplier *float64 `json:"multiplier"`
+	Value      *float64 `json:"value"`
+	Boost      float64  `json:"boost"`
+}
+
+// prizeComponents converts the components of a source tier and returns their total multiplier,
+// which a combination that declares no multiplier of its own pays.
+func (t importTier) prizeComponents() ([]PrizeComponent, float64) {
+	var out []PrizeComponent
+	var sum float64
+	for _, c := range t.Components {
+		pc := PrizeComponent{Tier: c.Tier, Boost: c.Boost}
+		if pc.Tier == "" {
+			pc.Tier = c.ID
+		}
+		switch {
+		case c.Multiplier != nil:
+			pc.Multiplier = *c.Multiplier
+		case c.Value != nil:
+			pc.Multiplier = *c.Value
+		}
+		sum += pc.Multiplier
+		out = append(out, pc)
+	}
+	return out, sum
+}
+
+type importMechanic struct {
+	Type                string `json:"type"`
+	MatchCount          int    `json:"match_count"`
+	MatchCountCamel     int    `json:"matchCount"`
+	AllowWildcards      bool   `json:"allow_wildcards"`
+	AllowWildcardsCamel bool   `json:"allowWildcards"`
+}
+
+// importDoc covers every supported shape. encoding/json matches keys case-insensitively,
+// so the snake_case and camelCase names below never collide.
+type importDoc struct {
+	SchemaVersion *int           `json:"schema_version"`
+	ModelID       string         `json:"model_id"`
+	ModelVersion  string         `json:"model_version"`
+	Mechanic      importMechanic `json:"mechanic"`
+	MathMode      string         `json:"math_mode"`
+	WinLogic      string         `json:"win_logic"`
+	PrizeTable    []importTier   `json:"prize_table"`
+	TotalTickets  int64          `json:"total_tickets"`
+	Stats         *GameStats     `json:"stats"`
+	Integrity     *Integrity     `json:"integrity"`
+
+	BundlePrizeTable   []importTier `json:"prizeTable"`
+	BundleMathMode     string       `json:"mathMode"`
+	BundleTotalTickets int64        `json:"totalTickets"`
+	BundleWinLogic     string       `json:"winLogic"`
+	BundleRTP          float64      `json:"rtp"`
+	BundleMatch        *struct {
+		MatchCount     int  `json:"matchCount"`
+		AllowWildcards bool `json:"allowWildcards"`
+	} `json:"match"`
+}
+
+// Import detects the format of a math document, converts it to GameMath with integer
+// weights and upgrades it to CurrentSchemaVersion.
+//
+// Probabilities are converted without loss where possible: each is read as the simplest
+// fraction that reproduces it exactly as a float64, and the weights are those fractions over
+// their least common denominator. Probabilities summing to less than 1 leave the remainder
+// to a LOSE tier. Models migrated from an older schema have their declared content hash
+// checked against the source first and are then resealed. Bundle documents must declare the
+// content hash of the model they convert to; it is checked on conversion, and documents without
+// one are rejected. ModelID is left as declared (empty for bundles).
+func Import(data []byte) (*Imported, error) {
+	return importMath(data, false)
+}
+
+// ImportLegacy is Import for math stored before content hashes were required (game_math rows,
+// bundles already on disk): a document that declares no hash is sealed with the hash of the
+// model it converts to, and a note says so. A declared hash is still checked.
+func ImportLegacy(data []byte) (*Imported, error) {
+	return importMath(data, true)
+}
+
+func importMath(data []byte, legacy bool) (*Imported, error) {
+	var doc importDoc
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parse math: %w", err)
+	}
+	switch {
+	case len(doc.BundlePrizeTable) > 0 && len(doc.PrizeTable) > 0:
+		return nil, errors.New("math: both prizeTable and prize_table present")
+	case len(doc.BundlePrizeTable) > 0:
+		return importBundle(&doc, legacy)
+	case len(doc.PrizeTable) > 0:
+		imp, err := importRGS(&doc)
+		if err != nil || !legacy {
+			return imp, err
+		}
+		if sealed, err := imp.Math.SealLegacy(); err != nil {
+			return nil, fmt.Errorf("math: %w", err)
+		} else if sealed {
+			imp.Notes = append(imp.Notes, legacySealNote(imp.Math))
+		}
+		return imp, nil
+	default:
+		return nil, errors.New("math: prize table is empty")
+	}
+}
+
+func legacySealNote(g *GameMath) string {
+	return fmt.Sprintf("no integrity.content_hash (stored before hashes were required); sealed as %s", g.Integrity.ContentHash)
+}
+
+// importBundle converts a bundle document and checks its declared content hash against the
+// converted model. A legacy document without a hash is sealed instead (see ImportLegacy).
+func importBundle(doc *importDoc, legacy bool) (*Imported, error) {
+	imp, err := convertBundle(doc)
+	if err != nil {
+		return nil, err
+	}
+	imp.Math.Integrity = doc.Integrity
+	if legacy {
+		if sealed, err := imp.Math.SealLegacy(); err != nil {
+			return nil, fmt.Errorf("math: bundle %w", err)
+		} else if sealed {
+			imp.Notes = append(imp.Notes, legacySealNote(imp.Math))
+		}
+		return imp, nil
+	}
+	if err := imp.Math.VerifyContentHash(); err != nil {
+		return nil, fmt.Errorf("math: bundle %w", err)
+	}
+	return imp, nil
+}
+
+// convertBundle converts a bundle document to GameMath, without an integrity hash.
+func convertBundle(doc *importDoc) (*Imported, error) {
+	imp := &Imported{Format: FormatBundle, FromSchemaVersion: CurrentSchemaVersion, TargetRTP: doc.BundleRTP}
+	g := &GameMath{
+		SchemaVersion: CurrentSchemaVersion,
+		ModelVersion:  doc.ModelVersion,
+		Mechanic:      Mechanic{Type: doc.Mechanic.Type, MatchCount: doc.Mechanic.MatchCountCamel, AllowWildcards: doc.Mechanic.AllowWildcardsCamel},
+		MathMode:      strings.ToUpper(doc.BundleMathMode),
+		WinLogic:      doc.BundleWinLogic,
+		TotalTickets:  doc.BundleTotalTickets,
+	}
+	if g.ModelVersion == "" {
+		g.ModelVersion = "1.0"
+	}
+	if g.Mechanic.MatchCount == 0 && doc.BundleMatch != nil {
+		g.Mechanic.MatchCount = doc.BundleMatch.MatchCount
+	}
+	if doc.BundleMatch != nil && doc.BundleMatch.AllowWildcards {
+		g.Mechanic.AllowWildcards = true
+	}
+	if g.MathMode == "" {
+		g.MathMode = MathModeUnlimited
+	}
+	if g.WinLogic == "" {
+		g.WinLogic = WinLogicSingle
+	}
+	for _, t := range doc.BundlePrizeTable {
+		mult := 0.0
+		comps, sum := t.prizeComponents()
+		switch {
+		case t.IsWin != nil && !*t.IsWin:
+		case t.Value != nil:
+			mult = *t.Value
+		case len(comps) > 0:
+			mult = sum
+		}
+		g.PrizeTable = append(g.PrizeTable, PrizeTier{Tier: t.ID, Multiplier: mult, Components: comps, Boost: t.Boost})
+	}
+	if err := fillWeights(g, doc.BundlePrizeTable, imp); err != nil {
+		return nil, err
+	}
+	imp.Math = g
+	return imp, nil
+}
+
+func importRGS(doc *importDoc) (*Imported, error) {
+	imp := &Imported{Format: FormatGameMath}
+	if doc.SchemaVersion != nil {
+		imp.FromSchemaVersion = *doc.SchemaVersion
+	}
+	if imp.FromSchemaVersion > CurrentSchemaVersion {
+		return nil, fmt.Errorf("math: schema_version %d is newer than supported version %d", imp.FromSchemaVersion, CurrentSchemaVersion)
+	}
+	if imp.FromSchemaVersion < 0 {
+		return nil, fmt.Errorf("math: invalid schema_version %d", imp.FromSchemaVersion)
+	}
+	g := &GameMath{
+		SchemaVersion: imp.FromSchemaVersion,
+		ModelID:       doc.ModelID,
+		ModelVersion:  doc.ModelVersion,
+		Mechanic:      Mechanic{Type: doc.Mechanic.Type, MatchCount: doc.Mechanic.MatchCount, AllowWildcards: doc.Mechanic.AllowWildcards},
+		MathMode:      doc.MathMode,
+		WinLogic:      doc.WinLogic,
+		TotalTickets:  doc.TotalTickets,
+		Stats:         doc.Stats,
+		Integrity:     doc.Integrity,
+	}
+	for _, t := range doc.PrizeTable {
+		mult := 0.0
+		comps, sum := t.prizeComponents()
+		if t.Multiplier != nil {
+			mult = *t.Multiplier
+		} else if len(comps) > 0 {
+			mult = sum
+		}
+		g.PrizeTable = append(g.PrizeTable, PrizeTier{Tier: t.Tier, Multiplier: mult, Components: comps, Boost: t.Boost})
+		if _, err := t.Weight.Int64(); t.Probability != "" || (t.Weight != "" && err != nil) {
+			imp.Format = FormatRGS
+		}
+	}
+	if err := fillWeights(g, doc.PrizeTable, imp); err != nil {
+		return nil, err
+	}
+	if imp.FromSchemaVersion < CurrentSchemaVersion {
+		// The declared hash covers the document as written; check it before migrating.
+		hashed := g.Integrity != nil && g.Integrity.ContentHash != ""
+		if hashed {
+			if err := g.VerifyContentHash(); err != nil {
+				return nil, err
+			}
+		}
+		for v := imp.FromSchemaVersion; v < CurrentSchemaVersion; v++ {
+			if m, ok := migrations[v]; ok {
+				m(g)
+			}
+			g.SchemaVersion = v + 1
+			imp.Notes = append(imp.Notes, fmt.Sprintf("migrated schema_version %d to %d", v, v+1))
+		}
+		if hashed {
+			if err := g.Seal(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	imp.Math = g
+	return imp, nil
+}
+
+// fillWeights sets g.PrizeTable weights from the source tiers. Integer weights are used as
+// they are (and checked against probabilities when both are given); otherwise fractional
+// weights or probabilities are converted.
+func fillWeights(g *GameMath, src []importTier, imp *Imported) error {
+	n := len(src)
+	weights := make([]int64, n)
+	masses := make([]float64, n)
+	var haveProb, haveWeight, intWeights int
+	for i, t := range src {
+		if t.Probability != "" {
+			p, err := t.Probability.Float64()
+			if err != nil || p < 0 || p > 1 || math.IsNaN(p) {
+				return fmt.Errorf("math: tier %q: invalid probability %q", g.PrizeTable[i].Tier, t.Probability)
+			}
+			haveProb++
+			masses[i] = p
+		}
+		if t.Weight != "" {
+			haveWeight++
+			if w, err := t.Weight.Int64(); err == nil && w >= 0 {
+				weights[i] = w
+				intWeights++
+			} else if f, err := t.Weight.Float64(); err != nil || f < 0 || math.IsNaN(f) || math.IsInf(f, 0) {
+				return fmt.Errorf("math: tier %q: invalid weight %q", g.PrizeTable[i].Tier, t.Weight)
+			}
+		}
+	}
+
+	if intWeights == n {
+		var total int64
+		for _, w := range weights {
+			if total += w; total > maxTotalWeight || total < 0 {
+				return errors.New("math: total weight too large")
+			}
+		}
+		if total == 0 {
+			return errors.New("math: prize table has no positive weights")
+		}
+		if haveProb == n {
+			if err := checkWeightsMatchProbabilities(g, weights, masses, total); err != nil {
+				return err
+			}
+		}
+		for i := range g.PrizeTable {
+			g.PrizeTable[i].Weight = weights[i]
+		}
+		imp.Exact = true
+		return nil
+	}
+
+	probabilities := true
+	switch {
+	case haveWeight == n:
+		// Fractional weights: relative masses, no implied remainder.
+		probabilities = false
+		for i, t := range src {
+			masses[i], _ = t.Weight.Float64()
+		}
+	case haveProb == n:
+	default:
+		return errors.New("math: every tier needs a weight or every tier needs a probability")
+	}
+
+	var sum float64
+	for _, m := range masses {
+		sum += m
+	}
+	if sum <= 0 {
+		return errors.New("math: prize table has no positive weights")
+	}
+	if probabilities && sum > 1+probabilityTolerance {
+		return fmt.Errorf("math: probabilities sum to %v (> 1)", sum)
+	}
+	impliedLose := probabilities && sum < 1-probabilityTolerance
+
+	ws, remainder, exact := exactWeights(masses, probabilities)
+	if !exact {
+		ws, remainder = approxWeights(masses, impliedLose)
+		imp.Notes = append(imp.Notes, fmt.Sprintf("probabilities have no exact fraction; scaled to total weight %d", int64(approxTotalWeight)))
+	}
+	for i := range g.PrizeTable {
+		g.PrizeTable[i].Weight = ws[i]
+	}
+	imp.Exact = exact
+	if impliedLose && remainder > 0 {
+		addLoseWeight(g, remainder)
+		imp.Notes = append(imp.Notes, fmt.Sprintf("probabilities sum to %v; remainder assigned to LOSE", sum))
+	}
+	return nil
+}
+
+func checkWeightsMatchProbabilities(g *GameMath, weights []int64, probs []float64, total int64) error {
+	var psum float64
+	for _, p := range probs {
+		psum += p
+	}
+	if psum <= 0 {
+		return nil
+	}
+	for i, w := range weights {
+		if d := float64(w)/float64(total) - probs[i]/psum; math.Abs(d) > probabilityTolerance {
+			return fmt.Errorf("math: tier %q: weight %d disagrees with probability %v", g.PrizeTable[i].Tier, w, probs[i])
+		}
+	}
+	return nil
+}
+
+// addLoseWeight adds w to the LOSE tier, creating it if needed.
+func addLoseWeight(g *GameMath, w int64) {
+	for i := range g.PrizeTable {
+		if g.PrizeTable[i].Tier == "LOSE" {
+			g.PrizeTable[i].Weight += w
+			return
+		}
+	}
+	g.PrizeTable = append(g.PrizeTable, PrizeTier{Tier: "LOSE", Multiplier: 0, Weight: w})
+}
+
+// exactWeights writes each mass as its simplest exact fraction and scales all of them to the
+// least common denominator L. For probabilities the remainder is L minus the weights (the
+// implied LOSE share). ok is false when a mass has no fraction with a denominator up to
+// maxDenominator or L exceeds maxTotalWeight.
+func exactWeights(masses []float64, probabilities bool) (weights []int64, remainder int64, ok bool) {
+	nums := make([]*big.Int, len(masses))
+	dens := make([]*big.Int, len(masses))
+	lcm := big.NewInt(1)
+	limit := big.NewInt(maxTotalWeight)
+	for i, m := range masses {
+		n, d, found := simplestFraction(m)
+		if !found {
+			return nil, 0, false
+		}
+		nums[i], dens[i] = n, d
+		gcd := new(big.Int).GCD(nil, nil, lcm, d)
+		lcm.Mul(lcm, new(big.Int).Quo(d, gcd))
+		if lcm.Cmp(limit) > 0 {
+			return nil, 0, false
+		}
+	}
+	weights = make([]int64, len(masses))
+	total := new(big.Int)
+	for i := range masses {
+		w := new(big.Int).Mul(nums[i], new(big.Int).Quo(lcm, dens[i]))
+		total.Add(total, w)
+		weights[i] = w.Int64()
+	}
+	if total.Cmp(limit) > 0 {
+		return nil, 0, false
+	}
+	if probabilities && total.Cmp(lcm) < 0 {
+		rem := new(big.Int).Sub(lcm, total)
+		// A remainder below the tolerance is float noise in the source, not a LOSE share.
+		if new(big.Rat).SetFrac(rem, lcm).Cmp(new(big.Rat).SetFloat64(probabilityTolerance)) > 0 {
+			remainder = rem.Int64()
+		}
+	}
+	return weights, remainder, true
+}
+
+// simplestFraction returns the first continued-fraction convergent n/d of x (x >= 0) that
+// converts back to exactly x, or found = false if d would exceed maxDenominator.
+func simplestFraction(x float64) (n, d *big.Int, found bool) {
+	if x == 0 {
+		return big.NewInt(0), big.NewInt(1), true
+	}
+	r := new(big.Rat).SetFloat64(x)
+	if r == nil {
+		return nil, nil, false
+	}
+	h0, h1 := big.NewInt(0), big.NewInt(1)
+	k0, k1 := big.NewInt(1), big.NewInt(0)
+	limit := big.NewInt(maxDenominator)
+	num, den := new(big.Int).Set(r.Num()), new(big.Int).Set(r.Denom())
+	for den.Sign() != 0 {
+		a, rem := new(big.Int).QuoRem(num, den, new(big.Int))
+		h := new(big.Int).Add(new(big.Int).Mul(a, h1), h0)
+		k := new(big.Int).Add(new(big.Int).Mul(a, k1), k0)
+		if k.Cmp(limit) > 0 {
+			return nil, nil, false
+		}
+		if f, _ := new(big.Rat).SetFrac(h, k).Float64(); f == x {
+			return h, k, true
+		}
+		h0, h1, k0, k1 = h1, h, k1, k
+		num, den = den, rem
+	}
+	return nil, nil, false
+}
+
+// approxWeights scales masses to approxTotalWeight (plus the implied remainder for
+// probabilities) with largest-remainder rounding, the same apportionment NewPool uses.
+func approxWeights(masses []float64, impliedLose bool) ([]int64, int64) {
+	type share struct {
+		idx  int
+		frac float64
+	}
+	var sum float64
+	for _, m := range masses {
+		sum += m
+	}
+	scale := float64(approxTotalWeight) / sum
+	target := int64(approxTotalWeight)
+	if impliedLose {
+		scale = float64(approxTotalWeight)
+		target = int64(math.Round(sum * approxTotalWeight))
+	}
+	weights := make([]int64, len(masses))
+	shares := make([]share, len(masses))
+	var allocated int64
+	for i, m := range masses {
+		exact := m * scale
+		weights[i] = int64(math.Floor(exact))
+		allocated += weights[i]
+		shares[i] = share{idx: i, frac: exact - math.Floor(exact)}
+	}
+	sort.SliceStable(shares, func(a, b int) bool { return shares[a].frac > shares[b].frac })
+	for i := 0; allocated < target && i < len(shares); i++ {
+		weights[shares[i].idx]++
+		allocated++
+	}
+	var remainder int64
+	if impliedLose {
+		remainder = approxTotalWeight - allocated
+	}
+	return weights, remainder
+}
@@ -0,0 +1,256 @@
+package gamemath
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func weightsOf(g *GameMath) map[string]int64 {
+	out := make(map[string]int64, len(g.PrizeTable))
+	for _, t := range g.PrizeTable {
+		out[t.Tier] = t.Weight
+	}
+	return out
+}
+
+// withBundleHash adds the content hash of the model a bundle document converts to.
+func withBundleHash(t *testing.T, doc string) string {
+	t.Helper()
+	var d importDoc
+	if err := json.Unmarshal([]byte(doc), &d); err != nil {
+		t.Fatal(err)
+	}
+	imp, err := convertBundle(&d)
+	if err != nil {
+		t.Fatal(err)
+	}
+	h, err := imp.Math.ContentHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return strings.Replace(doc, "{", `{"integrity":{"content_hash":"`+h+`"},`, 1)
+}
+
+func TestImport_BundleFormat(t *testing.T) {
+	unhashed := `{"rtp":0.96,"mathMode":"UNLIMITED","totalTickets":1000,"winLogic":"SINGLE_WIN",
+		"prizeTable":[
+			{"id":"big","value":100,"probability":0.000014532560201130634,"weight":5,"isWin":true},
+			{"id":"small","value":2,"probability":0.9999854674397989,"weight":344050,"isWin":true},
+			{"id":"dud","value":5,"probability":0,"weight":0,"isWin":false}],
+		"mechanic":{"type":"match_2","matchCount":2}}`
+	doc := withBundleHash(t, unhashed)
+	imp, err := Import([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := imp.Math
+	if imp.Format != FormatBundle || !imp.Exact || imp.TargetRTP != 0.96 {
+		t.Errorf("import %+v", imp)
+	}
+	if g.SchemaVersion != CurrentSchemaVersion || g.Mechanic.MatchCount != 2 || g.TotalTickets != 1000 {
+		t.Errorf("math %+v", g)
+	}
+	if w := weightsOf(g); w["big"] != 5 || w["small"] != 344050 {
+		t.Errorf("weights %v", w)
+	}
+	if dud, _ := g.Tier("dud"); dud.Multiplier != 0 {
+		t.Errorf("non-win tier must pay 0, got %v", dud.Multiplier)
+	}
+	if err := g.VerifyContentHash(); err != nil {
+		t.Errorf("bundle import must keep its verified hash: %v", err)
+	}
+
+	bad := strings.Replace(doc, `"weight":5,`, `"weight":6,`, 1)
+	if _, err := Import([]byte(bad)); err == nil {
+		t.Error("weights that disagree with probabilities should be rejected")
+	}
+	if _, err := Import([]byte(unhashed)); !errors.Is(err, ErrContentHashMissing) {
+		t.Errorf("bundle without a hash: got %v", err)
+	}
+	tampered := strings.Replace(doc, `"value":100`, `"value":200`, 1)
+	if _, err := Import([]byte(tampered)); !errors.Is(err, ErrContentHashMismatch) {
+		t.Errorf("bundle edited after hashing: got %v", err)
+	}
+}
+
+func TestImportLegacy_SealsUnhashedMath(t *testing.T) {
+	bundle := `{"prizeTable":[{"id":"win","value":2,"weight":1,"isWin":true},{"id":"lose","weight":3,"isWin":false}]}`
+	rgs := `{"model_id":"m","model_version":"1","schema_version":1,"prize_table":[{"tier":"LOSE","weight":3},{"tier":"WIN","multiplier":2,"weight":1}]}`
+	for _, doc := range []string{bundle, rgs} {
+		imp, err := ImportLegacy([]byte(doc))
+		if err != nil {
+			t.Fatalf("%s: %v", doc, err)
+		}
+		if err := imp.Math.VerifyContentHash(); err != nil || len(imp.Notes) == 0 {
+			t.Errorf("%s: not sealed (%v), notes %v", doc, err, imp.Notes)
+		}
+	}
+	if _, err := Import([]byte(bundle)); !errors.Is(err, ErrContentHashMissing) {
+		t.Errorf("Import of an unhashed bundle: %v", err)
+	}
+	wrong := strings.Replace(bundle, "{", `{"integrity":{"content_hash":"`+strings.Repeat("0", 64)+`"},`, 1)
+	if _, err := ImportLegacy([]byte(wrong)); !errors.Is(err, ErrContentHashMismatch) {
+		t.Errorf("wrong hash: %v", err)
+	}
+}
+
+func TestImport_ProbabilitiesAreExact(t *testing.T) {
+	// Probabilities exported as float64(weight)/total recover the original integer ratios
+	// (in lowest terms: the source weights share a factor of 5).
+	weights := []int64{5, 50, 1000, 53000, 290000}
+	reduced := []int64{1, 10, 200, 10600, 58000}
+	var total int64
+	for _, w := range weights {
+		total += w
+	}
+	var tiers []string
+	for i, w := range weights {
+		tiers = append(tiers, fmt.Sprintf(`{"tier":"T%d","multiplier":%d,"probability":%v}`, i, i+1, float64(w)/float64(total)))
+	}
+	imp, err := Import([]byte(`{"schema_version":1,"prize_table":[` + strings.Join(tiers, ",") + `]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imp.Format != FormatRGS || !imp.Exact {
+		t.Errorf("format %s exact %v", imp.Format, imp.Exact)
+	}
+	for i, tier := range imp.Math.PrizeTable {
+		if tier.Weight != reduced[i] {
+			t.Errorf("tier %s weight %d want %d", tier.Tier, tier.Weight, reduced[i])
+		}
+	}
+	if len(imp.Math.PrizeTable) != len(weights) {
+		t.Errorf("complete probabilities must not add a LOSE tier: %+v", imp.Math.PrizeTable)
+	}
+}
+
+func TestImport_ImpliedLoseAndFractionalWeights(t *testing.T) {
+	imp, err := Import([]byte(`{"schema_version":1,"prize_table":[{"tier":"A","multiplier":2,"probability":0.1},{"tier":"B","multiplier":5,"probability":0.05}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := weightsOf(imp.Math); w["A"] != 2 || w["B"] != 1 || w["LOSE"] != 17 {
+		t.Errorf("weights %v want A=2 B=1 LOSE=17", w)
+	}
+
+	imp, err = Import([]byte(`{"schema_version":1,"prize_table":[{"tier":"A","multiplier":2,"weight":0.5},{"tier":"B","multiplier":0,"weight":0.25}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if w := weightsOf(imp.Math); w["A"] != 2 || w["B"] != 1 || len(w) != 2 {
+		t.Errorf("weights %v want A=2 B=1", w)
+	}
+
+	if _, err := Import([]byte(`{"prize_table":[{"tier":"A","probability":0.7},{"tier":"B","probability":0.7}]}`)); err == nil {
+		t.Error("probabilities above 1 should be rejected")
+	}
+	if _, err := Import([]byte(`{"prize_table":[{"tier":"A","probability":0.7},{"tier":"B","weight":3}]}`)); err == nil {
+		t.Error("mixed weights and probabilities should be rejected")
+	}
+}
+
+func TestImport_InexactProbabilitiesAreScaled(t *testing.T) {
+	imp, err := Import([]byte(`{"schema_version":1,"prize_table":[{"tier":"A","multiplier":2,"probability":0.123456789123456},{"tier":"LOSE","probability":0.876543210876544}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imp.Exact {
+		t.Error("expected an inexact conversion")
+	}
+	var total int64
+	for _, tier := range imp.Math.PrizeTable {
+		total += tier.Weight
+	}
+	if total != approxTotalWeight {
+		t.Errorf("total weight %d want %d", total, int64(approxTotalWeight))
+	}
+}
+
+func TestImport_GameMathPassesThrough(t *testing.T) {
+	g := sealed(t, &GameMath{
+		SchemaVersion: 1, ModelID: "m", ModelVersion: "2", MathMode: MathModeUnlimited,
+		PrizeTable: []PrizeTier{{Tier: "LOSE", Weight: 9}, {Tier: "T1", Multiplier: 2.5, Weight: 1}},
+	})
+	data, _ := json.Marshal(g)
+	imp, err := Import(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imp.Format != FormatGameMath || imp.Math.ModelID != "m" || imp.Math.Integrity.ContentHash != g.Integrity.ContentHash {
+		t.Errorf("import %+v", imp.Math)
+	}
+	if err := imp.Math.VerifyContentHash(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestImport_MigratesSchemaZero(t *testing.T) {
+	old := &GameMath{ModelID: "m", PrizeTable: []PrizeTier{{Tier: "LOSE", Weight: 3}, {Tier: "T1", Multiplier: 2, Weight: 1}}}
+	old = sealed(t, old)
+	data, _ := json.Marshal(old)
+	imp, err := Import(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := imp.Math
+	if imp.FromSchemaVersion != 0 || g.SchemaVersion != CurrentSchemaVersion || g.MathMode != MathModeUnlimited || g.WinLogic != "SINGLE_WIN" {
+		t.Errorf("migrated %+v", g)
+	}
+	if err := g.VerifyContentHash(); err != nil {
+		t.Errorf("migrated model must be resealed: %v", err)
+	}
+
+	old.Integrity.ContentHash = strings.Repeat("0", 64)
+	data, _ = json.Marshal(old)
+	if _, err := Import(data); !errors.Is(err, ErrContentHashMismatch) {
+		t.Errorf("tampered v0 document: got %v", err)
+	}
+	if _, err := Import([]byte(`{"schema_version":99,"prize_table":[{"tier":"A","weight":1}]}`)); err == nil {
+		t.Error("future schema versions should be rejected")
+	}
+}
+
+func TestImport_MultiWinComponents(t *testing.T) {
+	doc := `{"winLogic":"MULTI_WIN","prizeTable":[
+			{"id":"T2","value":2,"weight":30,"isWin":true},
+			{"id":"T2+T5","weight":5,"isWin":true,"components":[{"id":"T2","value":2},{"id":"T5","value":5}]},
+			{"id":"LOSE","value":0,"weight":65,"isWin":false}]}`
+	imp, err := Import([]byte(withBundleHash(t, doc)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	combo, ok := imp.Math.Tier("T2+T5")
+	if !ok || combo.Multiplier != 7 || len(combo.Components) != 2 || combo.Components[1] != (PrizeComponent{Tier: "T5", Multiplier: 5}) {
+		t.Fatalf("combination %+v", combo)
+	}
+	if _, err := Validate(imp.Math, StatsTolerance); err != nil {
+		t.Errorf("imported MULTI_WIN model invalid: %v", err)
+	}
+}
+
+func TestImport_WildcardsAndBoost(t *testing.T) {
+	doc := `{"match":{"matchCount":3,"allowWildcards":true},
+		"prizeTable":[
+			{"id":"T10","value":10,"weight":1,"boost":5},
+			{"id":"LOSE","value":0,"weight":9,"isWin":false}]}`
+	imp, err := Import([]byte(withBundleHash(t, doc)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	g := imp.Math
+	if !g.Mechanic.AllowWildcards || g.Mechanic.MatchCount != 3 {
+		t.Errorf("mechanic %+v", g.Mechanic)
+	}
+	if tier, _ := g.Tier("T10"); tier.Boost != 5 || tier.Wins()[0].Base() != 2 {
+		t.Errorf("tier %+v", tier)
+	}
+
+	plain := `{"model_id":"m","mechanic":{"type":"match_n","allow_wildcards":true},
+		"prize_table":[{"tier":"LOSE","multiplier":0,"weight":1}]}`
+	if imp, err := Import([]byte(plain)); err != nil || !imp.Math.Mechanic.AllowWildcards {
+		t.Errorf("gamemath format: %v %+v", err, imp)
+	}
+}
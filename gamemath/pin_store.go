@@ -0,0 +1,48 @@
+package gamemath
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// DBPinBackend persists session pins in the game_math_pins table (scripts/004_game_math_pins.sql),
+// shared by every RGS instance. The first instance to pin a session wins; the others read its pin.
+type DBPinBackend struct {
+	db *sql.DB
+}
+
+func NewDBPinBackend(db *sql.DB) *DBPinBackend {
+	return &DBPinBackend{db: db}
+}
+
+func (b *DBPinBackend) Pin(sessionID, modelID, version string, now time.Time) (string, time.Time, error) {
+	ctx := context.Background()
+	// Insert the pin, or replace it if it expired; a live pin is kept and read back below.
+	_, err := b.db.ExecContext(ctx, `
+		INSERT INTO game_math_pins (session_id, model_id, model_version, pinned_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (session_id, model_id) DO UPDATE
+		SET model_version = EXCLUDED.model_version, pinned_at = EXCLUDED.pinned_at
+		WHERE game_math_pins.pinned_at <= $5
+	`, sessionID, modelID, version, now, now.Add(-PinTTL))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	var pinned string
+	var at time.Time
+	err = b.db.QueryRowContext(ctx, `
+		SELECT model_version, pinned_at FROM game_math_pins WHERE session_id = $1 AND model_id = $2
+	`, sessionID, modelID).Scan(&pinned, &at)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	return pinned, at, nil
+}
+
+func (b *DBPinBackend) Prune(cutoff time.Time) error {
+	_, err := b.db.ExecContext(context.Background(), `
+		DELETE FROM game_math_pins WHERE pinned_at < $1
+	`, cutoff)
+	return err
+}
@@ -0,0 +1,257 @@
+package gamemath
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+	"sort"
+	"time"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
+)
+
+var (
+	// ErrPoolExhausted is returned when every ticket of the current series has been sold.
+	ErrPoolExhausted = errors.New("ticket pool exhausted")
+	// ErrNoPool is returned when a LIMITED model has no open series and none can be opened.
+	ErrNoPool = errors.New("no ticket pool for model")
+)
+
+// Pool is one finite print run (series) of a LIMITED model. Tiers hold the exact number of
+// tickets printed per prize tier; tickets are drawn without replacement until Remaining is 0.
+type Pool struct {
+	ModelID      string     `json:"model_id"`
+	ModelVersion string     `json:"model_version,omitempty"`
+	SeriesID     string     `json:"series_id"`
+	TotalTickets int64      `json:"total_tickets"`
+	Tiers        []PoolTier `json:"tiers"`
+	OpenedAt     time.Time  `json:"opened_at"`
+	ExhaustedAt  *time.Time `json:"exhausted_at,omitempty"`
+}
+
+// PoolTier is the printed and remaining ticket count for one prize tier of a series.
+type PoolTier struct {
+	Tier       string  `json:"tier"`
+	Multiplier float64 `json:"multiplier"`
+	Printed    int64   `json:"printed"`
+	Remaining  int64   `json:"remaining"`
+}
+
+// NewPool prints a series of totalTickets for g. Tickets are allocated to tiers in proportion to
+// their weights (largest remainder), so the series holds exactly totalTickets tickets.
+func NewPool(g *GameMath, seriesID string, totalTickets int64) (*Pool, error) {
+	if g == nil || len(g.PrizeTable) == 0 {
+		return nil, fmt.Errorf("pool: prize table required")
+	}
+	if totalTickets <= 0 {
+		return nil, fmt.Errorf("pool: total tickets must be positive")
+	}
+	if seriesID == "" {
+		return nil, fmt.Errorf("pool: series id required")
+	}
+	var totalWeight int64
+	for _, t := range g.PrizeTable {
+		if t.Weight > 0 {
+			totalWeight += t.Weight
+		}
+	}
+	if totalWeight <= 0 {
+		return nil, fmt.Errorf("pool: prize table has no positive weights")
+	}
+	type share struct {
+		idx int
+		rem *big.Int
+	}
+	p := &Pool{
+		ModelID:      g.ModelID,
+		ModelVersion: g.ModelVersion,
+		SeriesID:     seriesID,
+		TotalTickets: totalTickets,
+		Tiers:        make([]PoolTier, len(g.PrizeTable)),
+		OpenedAt:     time.Now().UTC(),
+	}
+	bigTotal := big.NewInt(totalTickets)
+	bigWeight := big.NewInt(totalWeight)
+	var allocated int64
+	shares := make([]share, 0, len(g.PrizeTable))
+	for i, t := range g.PrizeTable {
+		p.Tiers[i] = PoolTier{Tier: t.Tier, Multiplier: t.Multiplier}
+		if t.Weight <= 0 {
+			continue
+		}
+		q, r := new(big.Int).QuoRem(new(big.Int).Mul(big.NewInt(t.Weight), bigTotal), bigWeight, new(big.Int))
+		p.Tiers[i].Printed = q.Int64()
+		allocated += q.Int64()
+		shares = append(shares, share{idx: i, rem: r})
+	}
+	// Hand the leftover tickets to the tiers with the largest remainders.
+	sort.SliceStable(shares, func(a, b int) bool { return shares[a].rem.Cmp(shares[b].rem) > 0 })
+	for i := 0; allocated < totalTickets && len(shares) > 0; i++ {
+		p.Tiers[shares[i%len(shares)].idx].Printed++
+		allocated++
+	}
+	for i := range p.Tiers {
+		p.Tiers[i].Remaining = p.Tiers[i].Printed
+	}
+	return p, nil
+}
+
+// Remaining returns the number of unsold tickets in the series.
+func (p *Pool) Remaining() int64 {
+	if p == nil {
+		return 0
+	}
+	var n int64
+	for _, t := range p.Tiers {
+		n += t.Remaining
+	}
+	return n
+}
+
+// Exhausted reports whether every ticket of the series has been sold.
+func (p *Pool) Exhausted() bool {
+	return p.Remaining() <= 0
+}
+
+// Draw sells one ticket using rng.Default. See DrawFrom.
+func (p *Pool) Draw() (string, error) {
+	return p.DrawFrom(rng.Default)
+}
+
+// DrawFrom sells one ticket: picks uniformly among the remaining tickets, removes it from its tier
+// and returns the tier id. The pool only counts tickets; the tier's prizes are in the model.
+func (p *Pool) DrawFrom(src rng.Source) (string, error) {
+	left := p.Remaining()
+	if left <= 0 {
+		return "", ErrPoolExhausted
+	}
+	idx := src.Int63n(left)
+	var cum int64
+	for i := range p.Tiers {
+		t := &p.Tiers[i]
+		cum += t.Remaining
+		if idx < cum {
+			t.Remaining--
+			if p.Remaining() == 0 {
+				now := time.Now().UTC()
+				p.ExhaustedAt = &now
+			}
+			return t.Tier, nil
+		}
+	}
+	return "", ErrPoolExhausted
+}
+
+// Return puts a drawn ticket back into the series (e.g. the wallet debit failed after the draw).
+func (p *Pool) Return(tier string) error {
+	for i := range p.Tiers {
+		t := &p.Tiers[i]
+		if t.Tier != tier {
+			continue
+		}
+		if t.Remaining >= t.Printed {
+			return fmt.Errorf("pool: tier %s has no sold tickets to return", tier)
+		}
+		t.Remaining++
+		p.ExhaustedAt = nil
+		return nil
+	}
+	return fmt.Errorf("pool: unknown tier %s", tier)
+}
+
+// PoolBackend persists ticket pools, one per model version: a series is printed from one
+// version's prize table, so activating a new version starts its own series instead of drawing
+// the new tiers from the old print run. Update must run fn under a lock that is exclusive across
+// every RGS instance sharing the backend, and persist the pool fn returns (nil keeps the current one).
+type PoolBackend interface {
+	Get(modelID, version string) (*Pool, error)
+	Update(modelID, version string, fn func(cur *Pool) (*Pool, error)) error
+}
+
+// PoolTicket identifies a ticket sold from a series, so it can be returned if the purchase fails.
+type PoolTicket struct {
+	ModelID      string `json:"model_id"`
+	ModelVersion string `json:"model_version,omitempty"`
+	SeriesID     string `json:"series_id"`
+	Tier         string `json:"tier"`
+}
+
+// Pools draws LIMITED tickets through a PoolBackend.
+type Pools struct {
+	backend PoolBackend
+}
+
+func NewPools(backend PoolBackend) *Pools {
+	return &Pools{backend: backend}
+}
+
+// Open prints a new series for g's version, replacing its current one. totalTickets <= 0 uses
+// g.TotalTickets.
+func (ps *Pools) Open(g *GameMath, seriesID string, totalTickets int64) (*Pool, error) {
+	if totalTickets <= 0 {
+		totalTickets = g.TotalTickets
+	}
+	p, err := NewPool(g, seriesID, totalTickets)
+	if err != nil {
+		return nil, err
+	}
+	err = ps.backend.Update(g.ModelID, g.ModelVersion, func(*Pool) (*Pool, error) { return p, nil })
+	if err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// Get returns the current series of a model version, or nil if none was opened.
+func (ps *Pools) Get(modelID, version string) (*Pool, error) {
+	return ps.backend.Get(modelID, version)
+}
+
+// Draw sells one ticket of the current series of g's version and returns its tier as g defines
+// it, components and boost included. The first series of a version is opened automatically from
+// g.TotalTickets; once a series is exhausted, an admin has to open the next one. A ticket whose
+// tier g does not define is not sold.
+func (ps *Pools) Draw(g *GameMath, src rng.Source) (PrizeTier, PoolTicket, error) {
+	var tier PrizeTier
+	var ticket PoolTicket
+	err := ps.backend.Update(g.ModelID, g.ModelVersion, func(cur *Pool) (*Pool, error) {
+		if cur == nil {
+			if g.TotalTickets <= 0 {
+				return nil, ErrNoPool
+			}
+			p, err := NewPool(g, "1", g.TotalTickets)
+			if err != nil {
+				return nil, err
+			}
+			cur = p
+		}
+		id, err := cur.DrawFrom(src)
+		if err != nil {
+			return nil, err
+		}
+		t, ok := g.Tier(id)
+		if !ok {
+			return nil, fmt.Errorf("pool %s@%s series %s: tier %s is not in the model", cur.ModelID, cur.ModelVersion, cur.SeriesID, id)
+		}
+		tier = t
+		ticket = PoolTicket{ModelID: cur.ModelID, ModelVersion: cur.ModelVersion, SeriesID: cur.SeriesID, Tier: id}
+		return cur, nil
+	})
+	if err != nil {
+		return PrizeTier{}, PoolTicket{}, err
+	}
+	return tier, ticket, nil
+}
+
+// Return puts a sold ticket back, as long as its series is still the current one.
+func (ps *Pools) Return(t PoolTicket) error {
+	return ps.backend.Update(t.ModelID, t.ModelVersion, func(cur *Pool) (*Pool, error) {
+		if cur == nil || cur.SeriesID != t.SeriesID {
+			return nil, nil
+		}
+		if err := cur.Return(t.Tier); err != nil {
+			return nil, err
+		}
+		return cur, nil
+	})
+}
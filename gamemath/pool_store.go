@@ -0,0 +1,187 @@
+package gamemath
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// FilePoolBackend persists ticket pools to data/ticket_pools.json. It is only safe for a single
+// RGS instance; deployments with several instances should use DBPoolBackend.
+type FilePoolBackend struct {
+	mu      sync.Mutex
+	pools   map[string]*Pool // by poolKey
+	dataDir string
+}
+
+// poolKey identifies the pool of one model version.
+func poolKey(modelID, version string) string {
+	return modelID + "@" + version
+}
+
+func NewFilePoolBackend(dataDir string) *FilePoolBackend {
+	if dataDir == "" {
+		dataDir = "data"
+	}
+	b := &FilePoolBackend{
+		pools:   make(map[string]*Pool),
+		dataDir: dataDir,
+	}
+	b.load()
+	return b
+}
+
+func (b *FilePoolBackend) path() string {
+	return filepath.Join(b.dataDir, "ticket_pools.json")
+}
+
+func (b *FilePoolBackend) load() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	data, err := os.ReadFile(b.path())
+	if err != nil {
+		return
+	}
+	var list []*Pool
+	if err := json.Unmarshal(data, &list); err != nil {
+		return
+	}
+	for _, p := range list {
+		if p != nil && p.ModelID != "" {
+			b.pools[poolKey(p.ModelID, p.ModelVersion)] = p
+		}
+	}
+}
+
+// saveLocked writes all pools to disk. Caller must hold b.mu.
+func (b *FilePoolBackend) saveLocked() error {
+	list := make([]*Pool, 0, len(b.pools))
+	for _, p := range b.pools {
+		list = append(list, p)
+	}
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(b.dataDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(b.path(), data, 0644)
+}
+
+func (b *FilePoolBackend) Get(modelID, version string) (*Pool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	p, ok := b.pools[poolKey(modelID, version)]
+	if !ok {
+		return nil, nil
+	}
+	return clonePool(p), nil
+}
+
+func (b *FilePoolBackend) Update(modelID, version string, fn func(cur *Pool) (*Pool, error)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	key := poolKey(modelID, version)
+	var cur *Pool
+	if p, ok := b.pools[key]; ok {
+		cur = clonePool(p)
+	}
+	next, err := fn(cur)
+	if err != nil || next == nil {
+		return err
+	}
+	b.pools[key] = next
+	return b.saveLocked()
+}
+
+func clonePool(p *Pool) *Pool {
+	c := *p
+	c.Tiers = append([]PoolTier(nil), p.Tiers...)
+	return &c
+}
+
+// DBPoolBackend persists ticket pools in the ticket_pools table (scripts/002_ticket_pools.sql).
+// Update locks the model version's row with SELECT ... FOR UPDATE, so draws are serialised across
+// instances.
+type DBPoolBackend struct {
+	db *sql.DB
+}
+
+func NewDBPoolBackend(db *sql.DB) *DBPoolBackend {
+	return &DBPoolBackend{db: db}
+}
+
+func (b *DBPoolBackend) Get(modelID, version string) (*Pool, error) {
+	var data []byte
+	err := b.db.QueryRowContext(context.Background(), `
+		SELECT pool FROM ticket_pools WHERE model_id = $1 AND model_version = $2
+	`, modelID, version).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var p Pool
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+func (b *DBPoolBackend) Update(modelID, version string, fn func(cur *Pool) (*Pool, error)) error {
+	ctx := context.Background()
+	// Make sure the row exists so FOR UPDATE has something to lock, even for the first series.
+	_, err := b.db.ExecContext(ctx, `
+		INSERT INTO ticket_pools (model_id, model_version, series_id, pool)
+		VALUES ($1, $2, '', 'null'::jsonb)
+		ON CONFLICT (model_id, model_version) DO NOTHING
+	`, modelID, version)
+	if err != nil {
+		return err
+	}
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var data []byte
+	err = tx.QueryRowContext(ctx, `
+		SELECT pool FROM ticket_pools WHERE model_id = $1 AND model_version = $2 FOR UPDATE
+	`, modelID, version).Scan(&data)
+	if err != nil {
+		return err
+	}
+	var cur *Pool
+	if len(data) > 0 && string(data) != "null" {
+		var p Pool
+		if err := json.Unmarshal(data, &p); err != nil {
+			return err
+		}
+		cur = &p
+	}
+	next, err := fn(cur)
+	if err != nil || next == nil {
+		return err
+	}
+	out, err := json.Marshal(next)
+	if err != nil {
+		return err
+	}
+	_, err = tx.ExecContext(ctx, `
+		UPDATE ticket_pools
+		SET series_id = $3,
+		    pool = $4::jsonb,
+		    remaining = $5,
+		    updated_at = CURRENT_TIMESTAMP
+		WHERE model_id = $1 AND model_version = $2
+	`, modelID, version, next.SeriesID, string(out), next.Remaining())
+	if err != nil {
+		return err
+	}
+	return tx.Commit()
+}
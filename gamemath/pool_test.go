@@ -0,0 +1,247 @@
+package gamemath
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
+)
+
+func testLimitedMath() *GameMath {
+	return &GameMath{
+		ModelID:      "limited_test",
+		MathMode:     MathModeLimited,
+		TotalTickets: 1000,
+		PrizeTable: []PrizeTier{
+			{Tier: "LOSE", Multiplier: 0, Weight: 700},
+			{Tier: "T1", Multiplier: 2, Weight: 250},
+			{Tier: "T2", Multiplier: 10, Weight: 49},
+			{Tier: "T3", Multiplier: 100, Weight: 1},
+		},
+	}
+}
+
+func TestNewPool_ExactCounts(t *testing.T) {
+	p, err := NewPool(testLimitedMath(), "S1", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]int64{"LOSE": 700, "T1": 250, "T2": 49, "T3": 1}
+	for _, tier := range p.Tiers {
+		if tier.Printed != want[tier.Tier] || tier.Remaining != tier.Printed {
+			t.Errorf("tier %s: printed %d remaining %d, want %d", tier.Tier, tier.Printed, tier.Remaining, want[tier.Tier])
+		}
+	}
+	if p.Remaining() != 1000 {
+		t.Errorf("remaining %d want 1000", p.Remaining())
+	}
+}
+
+func TestNewPool_LargestRemainder(t *testing.T) {
+	g := &GameMath{PrizeTable: []PrizeTier{
+		{Tier: "A", Weight: 1},
+		{Tier: "B", Weight: 1},
+		{Tier: "C", Weight: 1},
+	}}
+	p, err := NewPool(g, "S1", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p.Remaining() != 10 {
+		t.Fatalf("series must hold exactly 10 tickets, got %d", p.Remaining())
+	}
+	for _, tier := range p.Tiers {
+		if tier.Printed < 3 || tier.Printed > 4 {
+			t.Errorf("tier %s printed %d, want 3 or 4", tier.Tier, tier.Printed)
+		}
+	}
+}
+
+func TestPool_DrawWithoutReplacement(t *testing.T) {
+	p, err := NewPool(testLimitedMath(), "S1", 1000)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := map[string]int64{}
+	for i := 0; i < 1000; i++ {
+		tier, err := p.Draw()
+		if err != nil {
+			t.Fatalf("draw %d: %v", i, err)
+		}
+		count[tier]++
+	}
+	want := map[string]int64{"LOSE": 700, "T1": 250, "T2": 49, "T3": 1}
+	for tier, n := range want {
+		if count[tier] != n {
+			t.Errorf("tier %s drawn %d times, want exactly %d", tier, count[tier], n)
+		}
+	}
+	if !p.Exhausted() || p.ExhaustedAt == nil {
+		t.Error("pool should be exhausted after selling every ticket")
+	}
+	if _, err := p.Draw(); !errors.Is(err, ErrPoolExhausted) {
+		t.Errorf("draw on exhausted pool: got %v want ErrPoolExhausted", err)
+	}
+}
+
+func TestPool_Return(t *testing.T) {
+	p, _ := NewPool(testLimitedMath(), "S1", 1000)
+	tier, err := p.Draw()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Return(tier); err != nil {
+		t.Fatal(err)
+	}
+	if p.Remaining() != 1000 {
+		t.Errorf("remaining %d want 1000 after return", p.Remaining())
+	}
+	if err := p.Return(tier); err == nil {
+		t.Error("returning more tickets than were sold should fail")
+	}
+}
+
+func TestPools_FileBackendPersistence(t *testing.T) {
+	dir := t.TempDir()
+	g := testLimitedMath()
+	ps := NewPools(NewFilePoolBackend(dir))
+
+	// First draw opens series "1" from total_tickets.
+	_, ticket, err := ps.Draw(g, rng.Default)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticket.SeriesID != "1" {
+		t.Errorf("auto-opened series %q want 1", ticket.SeriesID)
+	}
+
+	reloaded := NewPools(NewFilePoolBackend(dir))
+	p, err := reloaded.Get(g.ModelID, g.ModelVersion)
+	if err != nil || p == nil {
+		t.Fatalf("reload: %v %v", p, err)
+	}
+	if p.Remaining() != 999 {
+		t.Errorf("remaining after reload %d want 999", p.Remaining())
+	}
+
+	if err := reloaded.Return(ticket); err != nil {
+		t.Fatal(err)
+	}
+	p, _ = reloaded.Get(g.ModelID, g.ModelVersion)
+	if p.Remaining() != 1000 {
+		t.Errorf("remaining after return %d want 1000", p.Remaining())
+	}
+}
+
+func TestPools_ExhaustionAndNewSeries(t *testing.T) {
+	g := testLimitedMath()
+	ps := NewPools(NewFilePoolBackend(t.TempDir()))
+	if _, err := ps.Open(g, "S1", 3); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 3; i++ {
+		if _, _, err := ps.Draw(g, rng.Default); err != nil {
+			t.Fatalf("draw %d: %v", i, err)
+		}
+	}
+	if _, _, err := ps.Draw(g, rng.Default); !errors.Is(err, ErrPoolExhausted) {
+		t.Fatalf("got %v want ErrPoolExhausted", err)
+	}
+	if _, err := ps.Open(g, "S2", 0); err != nil {
+		t.Fatal(err)
+	}
+	_, ticket, err := ps.Draw(g, rng.Default)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticket.SeriesID != "S2" {
+		t.Errorf("ticket from series %q want S2", ticket.SeriesID)
+	}
+}
+
+func TestPools_NoTotalTickets(t *testing.T) {
+	g := testLimitedMath()
+	g.TotalTickets = 0
+	ps := NewPools(NewFilePoolBackend(t.TempDir()))
+	if _, _, err := ps.Draw(g, rng.Default); !errors.Is(err, ErrNoPool) {
+		t.Errorf("got %v want ErrNoPool", err)
+	}
+}
+
+func TestPools_SeriesPerVersion(t *testing.T) {
+	v1 := testLimitedMath()
+	v1.ModelVersion = "v1"
+	ps := NewPools(NewFilePoolBackend(t.TempDir()))
+	if _, err := ps.Open(v1, "S1", 3); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := ps.Draw(v1, rng.Default); err != nil {
+		t.Fatal(err)
+	}
+
+	// v2 pays T1 at 3x: its draws come from its own series, printed from its own prize table.
+	v2 := testLimitedMath()
+	v2.ModelVersion = "v2"
+	v2.PrizeTable[1].Multiplier = 3
+	_, ticket, err := ps.Draw(v2, rng.Default)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticket.ModelVersion != "v2" || ticket.SeriesID != "1" {
+		t.Errorf("v2 ticket %+v, want auto-opened series 1 of v2", ticket)
+	}
+	p2, _ := ps.Get(v2.ModelID, "v2")
+	if p2 == nil || p2.TotalTickets != 1000 || p2.Tiers[1].Multiplier != 3 {
+		t.Fatalf("v2 pool %+v", p2)
+	}
+	p1, _ := ps.Get(v1.ModelID, "v1")
+	if p1 == nil || p1.SeriesID != "S1" || p1.Remaining() != 2 {
+		t.Fatalf("v1 pool %+v", p1)
+	}
+
+	if err := ps.Return(ticket); err != nil {
+		t.Fatal(err)
+	}
+	if p2, _ = ps.Get(v2.ModelID, "v2"); p2.Remaining() != 1000 {
+		t.Errorf("v2 remaining after return %d want 1000", p2.Remaining())
+	}
+	if p1, _ = ps.Get(v1.ModelID, "v1"); p1.Remaining() != 2 {
+		t.Errorf("returning a v2 ticket changed v1: remaining %d", p1.Remaining())
+	}
+}
+
+func TestPools_DrawReturnsTheModelTier(t *testing.T) {
+	g := &GameMath{
+		ModelID:      "combo",
+		ModelVersion: "1",
+		MathMode:     MathModeLimited,
+		WinLogic:     WinLogicMulti,
+		TotalTickets: 1,
+		PrizeTable: []PrizeTier{{Tier: "T1+T2", Multiplier: 7, Weight: 1, Components: []PrizeComponent{
+			{Tier: "T1", Multiplier: 2, Boost: 2},
+			{Tier: "T2", Multiplier: 5},
+		}}},
+	}
+	ps := NewPools(NewFilePoolBackend(t.TempDir()))
+	tier, ticket, err := ps.Draw(g, rng.NewSeeded([]byte("combo")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ticket.Tier != "T1+T2" || len(tier.Components) != 2 || tier.Components[0].Boost != 2 {
+		t.Errorf("drew %+v (ticket %+v), want the model's combination tier", tier, ticket)
+	}
+
+	// A series printed from tiers the model no longer has sells nothing.
+	other := *g
+	other.ModelVersion = "2"
+	if _, err := ps.Open(&other, "S2", 1); err != nil {
+		t.Fatal(err)
+	}
+	other.PrizeTable = []PrizeTier{{Tier: "LOSE", Weight: 1}}
+	if _, _, err := ps.Draw(&other, rng.NewSeeded([]byte("combo"))); err == nil {
+		t.Error("a tier missing from the model was sold")
+	}
+	if p, _ := ps.Get("combo", "2"); p.Remaining() != 1 {
+		t.Errorf("%d tickets left, want 1", p.Remaining())
+	}
+}
@@ -0,0 +1,74 @@
+package gamemath
+
+import (
+	"errors"
+	"fmt"
+	"math"
+)
+
+// DefaultTargetRTP is the RTP a win-only prize table is fitted to when no target is configured.
+const DefaultTargetRTP = 0.96
+
+// rtpTolerance is how close FitTargetRTP gets to the target before it stops scaling weights.
+const rtpTolerance = 1e-6
+
+// ErrUnreachableRTP is returned by FitTargetRTP when no LOSE weight gives the target RTP.
+var ErrUnreachableRTP = errors.New("target RTP unreachable")
+
+// FitTargetRTP sets the weight of the LOSE tier (adding one if needed) so the prize table
+// returns target, and returns the RTP actually achieved. The other tiers keep their relative
+// weights; they are scaled by a power of ten when that is needed to land within rtpTolerance.
+//
+// Adding lose weight can only lower the RTP, so targets above the RTP of the table without a
+// LOSE tier are refused with ErrUnreachableRTP, as are targets outside (0, 1].
+func FitTargetRTP(g *GameMath, target float64) (float64, error) {
+	if math.IsNaN(target) || target <= 0 || target > 1 {
+		return 0, fmt.Errorf("%w: %v is not in (0, 1]", ErrUnreachableRTP, target)
+	}
+	lose := -1
+	var fixed int64    // total weight of every tier except LOSE
+	var payout float64 // sum of weight * multiplier
+	for i, t := range g.PrizeTable {
+		if t.Tier == "LOSE" && t.Multiplier == 0 {
+			lose = i
+			continue
+		}
+		fixed += t.Weight
+		payout += float64(t.Weight) * t.Multiplier
+	}
+	if fixed <= 0 || payout <= 0 {
+		return 0, fmt.Errorf("%w: prize table has no winning weight", ErrUnreachableRTP)
+	}
+	if ceiling := payout / float64(fixed); target > ceiling+rtpTolerance {
+		return 0, fmt.Errorf("%w: %.4f%% is above the %.4f%% the prize table pays with no losing tickets", ErrUnreachableRTP, target*100, ceiling*100)
+	}
+
+	var scale, loseWeight int64 = 1, 0
+	achieved := 0.0
+	for k := int64(1); fixed*k <= maxTotalWeight/2; k *= 10 {
+		l := int64(math.Round(payout*float64(k)/target)) - fixed*k
+		if l < 0 {
+			l = 0
+		}
+		scale, loseWeight = k, l
+		achieved = payout * float64(k) / float64(fixed*k+l)
+		if math.Abs(achieved-target) <= rtpTolerance {
+			break
+		}
+	}
+	if achieved == 0 {
+		return 0, fmt.Errorf("%w: total weight too large", ErrUnreachableRTP)
+	}
+
+	for i := range g.PrizeTable {
+		if i != lose {
+			g.PrizeTable[i].Weight *= scale
+		}
+	}
+	if lose >= 0 {
+		g.PrizeTable[lose].Weight = loseWeight
+	} else if loseWeight > 0 {
+		g.PrizeTable = append(g.PrizeTable, PrizeTier{Tier: "LOSE", Multiplier: 0, Weight: loseWeight})
+	}
+	return achieved, nil
+}
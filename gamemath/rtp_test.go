@@ -0,0 +1,66 @@
+package gamemath
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func winOnly() *GameMath {
+	return &GameMath{
+		ModelID: "fit",
+		PrizeTable: []PrizeTier{
+			{Tier: "T1", Multiplier: 1, Weight: 290},
+			{Tier: "T2", Multiplier: 10, Weight: 53},
+			{Tier: "T3", Multiplier: 100, Weight: 1},
+		},
+	}
+}
+
+func TestFitTargetRTP(t *testing.T) {
+	for _, target := range []float64{0.94, 0.96, 0.97} {
+		g := winOnly()
+		got, err := FitTargetRTP(g, target)
+		if err != nil {
+			t.Fatalf("target %v: %v", target, err)
+		}
+		r, err := Analyze(g)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if math.Abs(r.RTP-target) > rtpTolerance || math.Abs(got-r.RTP) > 1e-12 {
+			t.Errorf("target %v: analyzer RTP %v, reported %v", target, r.RTP, got)
+		}
+		if g.PrizeTable[len(g.PrizeTable)-1].Tier != "LOSE" {
+			t.Errorf("target %v: no LOSE tier added: %+v", target, g.PrizeTable)
+		}
+		// Winning tiers keep their relative odds.
+		if g.PrizeTable[0].Weight != 290*g.PrizeTable[2].Weight {
+			t.Errorf("target %v: tier ratios changed: %+v", target, g.PrizeTable)
+		}
+	}
+}
+
+func TestFitTargetRTP_ReusesLoseTier(t *testing.T) {
+	g := winOnly()
+	g.PrizeTable = append(g.PrizeTable, PrizeTier{Tier: "LOSE", Weight: 5})
+	if _, err := FitTargetRTP(g, 0.9); err != nil {
+		t.Fatal(err)
+	}
+	if len(g.PrizeTable) != 4 {
+		t.Errorf("expected the existing LOSE tier to be resized, got %+v", g.PrizeTable)
+	}
+}
+
+func TestFitTargetRTP_Unreachable(t *testing.T) {
+	// Paying at most 1x with every ticket a winner returns 100%; with a 0.5x tier, less.
+	g := &GameMath{PrizeTable: []PrizeTier{{Tier: "A", Multiplier: 0.5, Weight: 1}, {Tier: "B", Multiplier: 1, Weight: 1}}}
+	for _, target := range []float64{0.8, 0, -1, 1.2, math.NaN()} {
+		if _, err := FitTargetRTP(g, target); !errors.Is(err, ErrUnreachableRTP) {
+			t.Errorf("target %v: err = %v, want ErrUnreachableRTP", target, err)
+		}
+	}
+	if len(g.PrizeTable) != 2 || g.PrizeTable[0].Weight != 1 {
+		t.Errorf("refused fit modified the table: %+v", g.PrizeTable)
+	}
+}
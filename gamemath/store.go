@@ -2,16 +2,73 @@ package gamemath
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"sort"
 	"sync"
+	"time"
 )
 
-// Store persists game math by model_id.
+// Version statuses, matching the game_math.status column.
+const (
+	StatusDraft    = "DRAFT"
+	StatusActive   = "ACTIVE"
+	StatusArchived = "ARCHIVED"
+)
+
+var (
+	ErrModelNotFound   = errors.New("game math model not found")
+	ErrVersionNotFound = errors.New("game math version not found")
+	ErrNoRollback      = errors.New("no previous version to roll back to")
+	ErrActiveVersion   = errors.New("the active version cannot be archived or drafted; activate another version first")
+)
+
+// PinTTL is how long a session keeps the version it first played with.
+const PinTTL = 24 * time.Hour
+
+// PinBackend persists session pins, so a session keeps its version across restarts and on every
+// RGS instance sharing the backend.
+type PinBackend interface {
+	// Pin returns the version pinned for sessionID and modelID and when it was pinned, if that
+	// was less than PinTTL before now; otherwise it pins version at now and returns it.
+	Pin(sessionID, modelID, version string, now time.Time) (string, time.Time, error)
+	// Prune drops pins made before cutoff.
+	Prune(cutoff time.Time) error
+}
+
+// Store persists game math by model_id. Every model_version registered for a model is kept;
+// one of them is active and is what Get returns.
 type Store struct {
-	mu      sync.RWMutex
-	math    map[string]*GameMath
-	dataDir string
+	mu         sync.RWMutex
+	models     map[string]*modelVersions
+	pins       map[string]pin // by pinKey; a cache of pinBackend
+	pinBackend PinBackend
+	dataDir    string
+}
+
+type modelVersions struct {
+	versions map[string]*GameMath // by ModelVersion
+	active   string
+	// history lists previously active versions, oldest first (rollback targets).
+	history []string
+	// sources maps each version put by an external source (see PutFrom and Retain) to it;
+	// versions registered otherwise have no entry.
+	sources map[string]string
+}
+
+type pin struct {
+	version string
+	at      time.Time
+}
+
+// VersionInfo describes one stored version of a model.
+type VersionInfo struct {
+	ModelVersion string `json:"model_version"`
+	Status       string `json:"status"`
+	ContentHash  string `json:"content_hash,omitempty"`
 }
 
 func NewStore(dataDir string) *Store {
@@ -19,7 +76,8 @@ func NewStore(dataDir string) *Store {
 		dataDir = "data"
 	}
 	s := &Store{
-		math:    make(map[string]*GameMath),
+		models:  make(map[string]*modelVersions),
+		pins:    make(map[string]pin),
 		dataDir: dataDir,
 	}
 	s.load()
@@ -34,9 +92,18 @@ func (s *Store) ensureDir() error {
 	return os.MkdirAll(s.dataDir, 0755)
 }
 
+// storedEntry is one model in game_math.json. Math is the active version, kept so files
+// written before versioning still load.
 type storedEntry struct {
-	ModelID string    `json:"model_id"`
-	Math    *GameMath `json:"math"`
+	ModelID       string      `json:"model_id"`
+	Math          *GameMath   `json:"math"`
+	ActiveVersion string      `json:"active_version"`
+	History       []string    `json:"history,omitempty"`
+	Versions      []*GameMath `json:"versions,omitempty"`
+	// Sources maps versions to the external source that put them. Source is what files written
+	// before sources were tracked per version hold: one source for every version of the model.
+	Sources map[string]string `json:"sources,omitempty"`
+	Source  string            `json:"source,omitempty"`
 }
 
 func (s *Store) load() {
@@ -48,49 +115,404 @@ func (s *Store) load() {
 	}
 	var list []storedEntry
 	if err := json.Unmarshal(data, &list); err != nil {
+		log.Printf("game_math: %s: %v", s.path(), err)
 		return
 	}
+	migrated := false
+	defer func() {
+		if !migrated {
+			return
+		}
+		if err := s.saveLocked(); err != nil {
+			log.Printf("game_math: %s: save sealed versions: %v", s.path(), err)
+		}
+	}()
 	for _, e := range list {
-		if e.ModelID != "" && e.Math != nil {
-			s.math[e.ModelID] = e.Math
+		if e.ModelID == "" {
+			continue
+		}
+		versions := e.Versions
+		active := e.ActiveVersion
+		if len(versions) == 0 && e.Math != nil {
+			versions = []*GameMath{e.Math}
+			active = e.Math.ModelVersion
+		}
+		mv := &modelVersions{versions: make(map[string]*GameMath), sources: make(map[string]string)}
+		for _, m := range versions {
+			if m == nil {
+				continue
+			}
+			// Versions saved before hashes were required are sealed now; versions edited on
+			// disk since they were registered are dropped.
+			sealed, err := m.SealLegacy()
+			if err != nil {
+				log.Printf("game_math: %s: DROPPED model_id=%s version=%s: %v; re-register it to play it again", s.path(), e.ModelID, m.ModelVersion, err)
+				continue
+			}
+			if sealed {
+				log.Printf("game_math: %s: model_id=%s version=%s had no content hash; sealed it as %s", s.path(), e.ModelID, m.ModelVersion, m.Integrity.ContentHash)
+				migrated = true
+			}
+			mv.versions[m.ModelVersion] = m
+			if src := e.Sources[m.ModelVersion]; src != "" {
+				mv.sources[m.ModelVersion] = src
+			} else if e.Sources == nil && e.Source != "" {
+				mv.sources[m.ModelVersion] = e.Source
+			}
 		}
+		if len(mv.versions) == 0 {
+			log.Printf("game_math: %s: DROPPED model_id=%s: no valid version; its games cannot be played", s.path(), e.ModelID)
+			continue
+		}
+		if active != "" && mv.versions[active] == nil {
+			log.Printf("game_math: %s: model_id=%s active version %s was rejected; the model has no active version", s.path(), e.ModelID, active)
+		}
+		if _, ok := mv.versions[active]; ok {
+			mv.active = active
+		}
+		for _, v := range e.History {
+			if _, ok := mv.versions[v]; ok {
+				mv.history = append(mv.history, v)
+			}
+		}
+		s.models[e.ModelID] = mv
 	}
 }
 
 // saveLocked writes the store to disk. Caller must hold s.mu.
 func (s *Store) saveLocked() error {
-	list := make([]storedEntry, 0, len(s.math))
-	for id, m := range s.math {
-		list = append(list, storedEntry{ModelID: id, Math: m})
+	list := make([]storedEntry, 0, len(s.models))
+	for id, mv := range s.models {
+		e := storedEntry{
+			ModelID:       id,
+			Math:          mv.versions[mv.active],
+			ActiveVersion: mv.active,
+			History:       mv.history,
+		}
+		if len(mv.sources) > 0 {
+			e.Sources = mv.sources
+		}
+		for _, v := range mv.sortedVersions() {
+			e.Versions = append(e.Versions, mv.versions[v])
+		}
+		list = append(list, e)
 	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ModelID < list[j].ModelID })
 	data, err := json.MarshalIndent(list, "", "  ")
 	if err != nil {
 		return err
 	}
-	if err := os.MkdirAll(s.dataDir, 0755); err != nil {
+	if err := s.ensureDir(); err != nil {
 		return err
 	}
 	return os.WriteFile(s.path(), data, 0644)
 }
 
-// Register stores game math by its model_id. Overwrites if exists.
+func (mv *modelVersions) sortedVersions() []string {
+	out := make([]string, 0, len(mv.versions))
+	for v := range mv.versions {
+		out = append(out, v)
+	}
+	sort.Strings(out)
+	return out
+}
+
+// activate makes version the active one, remembering the previous active version.
+func (mv *modelVersions) activate(version string) {
+	if mv.active == version {
+		return
+	}
+	if mv.active != "" {
+		mv.archive(mv.active)
+	}
+	mv.active = version
+}
+
+// archive records version as previously active (most recent last, no duplicates).
+func (mv *modelVersions) archive(version string) {
+	mv.unarchive(version)
+	mv.history = append(mv.history, version)
+}
+
+// unarchive drops version from the rollback targets.
+func (mv *modelVersions) unarchive(version string) {
+	for i, v := range mv.history {
+		if v == version {
+			mv.history = append(mv.history[:i], mv.history[i+1:]...)
+			return
+		}
+	}
+}
+
+// status returns the store status of version: ACTIVE, ARCHIVED (a rollback target) or DRAFT.
+func (mv *modelVersions) status(version string) string {
+	if version == mv.active {
+		return StatusActive
+	}
+	for _, v := range mv.history {
+		if v == version {
+			return StatusArchived
+		}
+	}
+	return StatusDraft
+}
+
+// Register stores game math by its model_id and model_version and makes that version active.
+// Re-registering an existing version overwrites it.
+// Models that fail Validate are rejected with a *ValidationError; models whose
+// Integrity.ContentHash is missing or wrong are rejected too (see VerifyContentHash).
 func (s *Store) Register(math *GameMath) error {
+	return s.Put(math, StatusActive)
+}
+
+// Put stores a version of a model with the given status: ACTIVE activates it, ARCHIVED
+// keeps it as a rollback target, DRAFT just stores it. Validation is the same as Register.
+// The active version cannot be put as ARCHIVED or DRAFT (ErrActiveVersion): that would leave
+// the model without math to play. Putting a version again with the same content hash and
+// status is a no-op. Otherwise the stored *GameMath is replaced, never mutated, so rounds
+// already holding the old one finish on it.
+func (s *Store) Put(math *GameMath, status string) error {
+	return s.PutFrom("", math, status)
+}
+
+// PutFrom is Put for a version that external source (e.g. the game_math table) holds: Retain
+// with that source removes it once source no longer has it. Versions put with Put or Register,
+// or by another source, are not affected by source's Retain.
+func (s *Store) PutFrom(source string, math *GameMath, status string) error {
 	if math == nil || math.ModelID == "" {
 		return nil
 	}
+	if _, err := Validate(math, StatsTolerance); err != nil {
+		return err
+	}
+	if err := math.VerifyContentHash(); err != nil {
+		return fmt.Errorf("game math %q: %w", math.ModelID, err)
+	}
+	want := status
+	if want != StatusActive && want != StatusArchived {
+		want = StatusDraft
+	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.math[math.ModelID] = math
+	mv, ok := s.models[math.ModelID]
+	if ok && want != StatusActive && mv.active == math.ModelVersion {
+		return fmt.Errorf("%w: %s@%s", ErrActiveVersion, math.ModelID, math.ModelVersion)
+	}
+	if !ok {
+		mv = &modelVersions{versions: make(map[string]*GameMath), sources: make(map[string]string)}
+		s.models[math.ModelID] = mv
+	}
+	if cur := mv.versions[math.ModelVersion]; cur != nil && cur.Integrity.ContentHash == math.Integrity.ContentHash &&
+		mv.status(math.ModelVersion) == want && mv.sources[math.ModelVersion] == source {
+		return nil
+	}
+	mv.versions[math.ModelVersion] = math
+	if source != "" {
+		mv.sources[math.ModelVersion] = source
+	} else {
+		delete(mv.sources, math.ModelVersion)
+	}
+	switch want {
+	case StatusActive:
+		mv.activate(math.ModelVersion)
+	case StatusArchived:
+		mv.archive(math.ModelVersion)
+	default:
+		mv.unarchive(math.ModelVersion)
+	}
 	return s.saveLocked()
 }
 
-// Get returns game math for the given model_id, or nil.
+// Retain removes every version source put (see PutFrom) that is not in keep, which maps each
+// model id to the versions source still has; a model left without versions is removed. This is
+// how a reload drops versions whose source rows were deleted. Versions registered otherwise, or
+// put by another source, are kept even when their model id is in keep. Removing the active
+// version leaves the model with no active version, so its games cannot be played until another
+// one is activated. Retain returns the removed versions as model_id@version.
+func (s *Store) Retain(source string, keep map[string]map[string]bool) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var removed []string
+	for id, mv := range s.models {
+		for _, v := range mv.sortedVersions() {
+			if mv.sources[v] != source || keep[id][v] {
+				continue
+			}
+			delete(mv.versions, v)
+			delete(mv.sources, v)
+			mv.unarchive(v)
+			if mv.active == v {
+				mv.active = ""
+			}
+			removed = append(removed, id+"@"+v)
+		}
+		if len(mv.versions) == 0 {
+			delete(s.models, id)
+		}
+	}
+	if len(removed) == 0 {
+		return nil, nil
+	}
+	return removed, s.saveLocked()
+}
+
+// Get returns the active game math for the given model_id, or nil.
 func (s *Store) Get(modelID string) *GameMath {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	m, ok := s.math[modelID]
+	mv, ok := s.models[modelID]
 	if !ok {
 		return nil
 	}
+	return mv.versions[mv.active]
+}
+
+// GetVersion returns a specific version of a model, or nil.
+func (s *Store) GetVersion(modelID, version string) *GameMath {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	mv, ok := s.models[modelID]
+	if !ok {
+		return nil
+	}
+	return mv.versions[version]
+}
+
+// Versions lists every stored version of a model with its status.
+func (s *Store) Versions(modelID string) ([]VersionInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	mv, ok := s.models[modelID]
+	if !ok {
+		return nil, ErrModelNotFound
+	}
+	out := make([]VersionInfo, 0, len(mv.versions))
+	for _, v := range mv.sortedVersions() {
+		info := VersionInfo{ModelVersion: v, Status: mv.status(v)}
+		if m := mv.versions[v]; m.Integrity != nil {
+			info.ContentHash = m.Integrity.ContentHash
+		}
+		out = append(out, info)
+	}
+	return out, nil
+}
+
+// Activate makes a stored version the active one. Sessions already pinned keep their version.
+func (s *Store) Activate(modelID, version string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mv, ok := s.models[modelID]
+	if !ok {
+		return ErrModelNotFound
+	}
+	if _, ok := mv.versions[version]; !ok {
+		return fmt.Errorf("%w: %s@%s", ErrVersionNotFound, modelID, version)
+	}
+	mv.activate(version)
+	return s.saveLocked()
+}
+
+// Rollback re-activates the most recently active version before the current one and
+// returns it. The rolled-back version is kept as a draft.
+func (s *Store) Rollback(modelID string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mv, ok := s.models[modelID]
+	if !ok {
+		return "", ErrModelNotFound
+	}
+	for len(mv.history) > 0 {
+		prev := mv.history[len(mv.history)-1]
+		mv.history = mv.history[:len(mv.history)-1]
+		if _, ok := mv.versions[prev]; ok && prev != mv.active {
+			mv.active = prev
+			return prev, s.saveLocked()
+		}
+	}
+	return "", ErrNoRollback
+}
+
+// SetPinBackend makes the store persist session pins in b (see PinBackend). Without one, pins
+// live in memory only.
+func (s *Store) SetPinBackend(b PinBackend) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pinBackend = b
+}
+
+func pinKey(sessionID, modelID string) string {
+	return sessionID + "\x00" + modelID
+}
+
+// Pinned returns the version of a model a session plays with. The first call for a session
+// pins the active version; later calls return that version even after another one is
+// activated, until the pin is PinTTL old or the version is deleted. Pins are cached in memory
+// and persisted through the PinBackend, if one is set; a lookup of a cached pin only takes the
+// read lock.
+func (s *Store) Pinned(sessionID, modelID string) *GameMath {
+	if sessionID == "" {
+		return s.Get(modelID)
+	}
+	now := time.Now()
+	key := pinKey(sessionID, modelID)
+	s.mu.RLock()
+	mv, ok := s.models[modelID]
+	if !ok {
+		s.mu.RUnlock()
+		return nil
+	}
+	if p, ok := s.pins[key]; ok && now.Sub(p.at) < PinTTL {
+		if m, ok := mv.versions[p.version]; ok {
+			s.mu.RUnlock()
+			return m
+		}
+	}
+	active, backend := mv.active, s.pinBackend
+	s.mu.RUnlock()
+	if active == "" {
+		return nil
+	}
+
+	p := pin{version: active, at: now}
+	if backend != nil {
+		// Outside the lock: the backend may be a database.
+		v, at, err := backend.Pin(sessionID, modelID, active, now)
+		if err != nil {
+			log.Printf("game_math: pin session for model_id=%s: %v", modelID, err)
+		} else {
+			p = pin{version: v, at: at}
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	mv, ok = s.models[modelID]
+	if !ok {
+		return nil
+	}
+	m := mv.versions[p.version]
+	if m == nil {
+		// The pinned version was deleted: the session moves to the active version.
+		p = pin{version: mv.active, at: now}
+		if m = mv.versions[mv.active]; m == nil {
+			return nil
+		}
+	}
+	s.pins[key] = p
 	return m
 }
+
+// PrunePins drops pins older than PinTTL at now, from memory and from the PinBackend.
+func (s *Store) PrunePins(now time.Time) error {
+	s.mu.Lock()
+	for k, p := range s.pins {
+		if now.Sub(p.at) >= PinTTL {
+			delete(s.pins, k)
+		}
+	}
+	backend := s.pinBackend
+	s.mu.Unlock()
+	if backend == nil {
+		return nil
+	}
+	return backend.Prune(now.Add(-PinTTL))
+}
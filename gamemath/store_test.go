@@ -1,11 +1,23 @@
 package gamemath
 
 import (
+	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 )
 
+// sealed stamps g with its content hash so Register accepts it.
+func sealed(t *testing.T, g *GameMath) *GameMath {
+	t.Helper()
+	if err := g.Seal(); err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
 func TestStore_RegisterGet(t *testing.T) {
 	dir := t.TempDir()
 	s := NewStore(dir)
@@ -14,7 +26,7 @@ func TestStore_RegisterGet(t *testing.T) {
 		ModelID:   "scratch_match3",
 		PrizeTable: []PrizeTier{{Tier: "LOSE", Multiplier: 0, Weight: 100}},
 	}
-	if err := s.Register(math); err != nil {
+	if err := s.Register(sealed(t, math)); err != nil {
 		t.Fatal(err)
 	}
 	got := s.Get("scratch_match3")
@@ -33,8 +45,8 @@ func TestStore_RegisterOverwrite(t *testing.T) {
 	dir := t.TempDir()
 	s := NewStore(dir)
 
-	s.Register(&GameMath{ModelID: "m1", PrizeTable: []PrizeTier{{Tier: "A", Weight: 1}}})
-	s.Register(&GameMath{ModelID: "m1", PrizeTable: []PrizeTier{{Tier: "B", Weight: 2}}})
+	s.Register(sealed(t, &GameMath{ModelID: "m1", PrizeTable: []PrizeTier{{Tier: "A", Weight: 1}}}))
+	s.Register(sealed(t, &GameMath{ModelID: "m1", PrizeTable: []PrizeTier{{Tier: "B", Weight: 2}}}))
 	got := s.Get("m1")
 	if got == nil || got.PrizeTable[0].Tier != "B" {
 		t.Errorf("expected overwrite: %+v", got)
@@ -52,7 +64,7 @@ func TestStore_Persistence(t *testing.T) {
 		},
 	}
 	s1 := NewStore(dir)
-	if err := s1.Register(math); err != nil {
+	if err := s1.Register(sealed(t, math)); err != nil {
 		t.Fatal(err)
 	}
 
@@ -89,7 +101,7 @@ func TestStore_LoadFromFile(t *testing.T) {
 		ModelID: "from_file",
 		PrizeTable: []PrizeTier{{Tier: "X", Multiplier: 1, Weight: 10}},
 	}
-	if err := s1.Register(math); err != nil {
+	if err := s1.Register(sealed(t, math)); err != nil {
 		t.Fatal(err)
 	}
 	data, err := os.ReadFile(filepath.Join(dir, "game_math.json"))
@@ -109,3 +121,306 @@ func TestStore_LoadFromFile(t *testing.T) {
 		t.Errorf("loaded: %+v", got)
 	}
 }
+
+func TestStore_RegisterRequiresContentHash(t *testing.T) {
+	s := NewStore(t.TempDir())
+	g := &GameMath{ModelID: "unsealed", PrizeTable: []PrizeTier{{Tier: "LOSE", Weight: 1}}}
+	if err := s.Register(g); !errors.Is(err, ErrContentHashMissing) {
+		t.Errorf("got %v want ErrContentHashMissing", err)
+	}
+	sealed(t, g)
+	g.PrizeTable[0].Weight = 2 // tamper after sealing
+	if err := s.Register(g); !errors.Is(err, ErrContentHashMismatch) {
+		t.Errorf("got %v want ErrContentHashMismatch", err)
+	}
+	if s.Get("unsealed") != nil {
+		t.Error("rejected model must not be stored")
+	}
+}
+
+func versioned(t *testing.T, modelID, version string, mult float64) *GameMath {
+	return sealed(t, &GameMath{
+		ModelID:      modelID,
+		ModelVersion: version,
+		PrizeTable:   []PrizeTier{{Tier: "LOSE", Weight: 50}, {Tier: "WIN", Multiplier: mult, Weight: 50}},
+	})
+}
+
+func TestStore_VersionsActivateRollback(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(dir)
+	if err := s.Register(versioned(t, "m", "1.0", 1.8)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Register(versioned(t, "m", "2.0", 1.9)); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Put(versioned(t, "m", "3.0", 1.95), StatusDraft); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Get("m"); got == nil || got.ModelVersion != "2.0" {
+		t.Fatalf("active = %+v, want 2.0", got)
+	}
+	if s.GetVersion("m", "1.0") == nil {
+		t.Fatal("older version should be kept")
+	}
+	want := map[string]string{"1.0": StatusArchived, "2.0": StatusActive, "3.0": StatusDraft}
+	versions, err := s.Versions("m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range versions {
+		if v.Status != want[v.ModelVersion] {
+			t.Errorf("version %s status %s want %s", v.ModelVersion, v.Status, want[v.ModelVersion])
+		}
+	}
+
+	if err := s.Activate("m", "3.0"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Activate("m", "9.9"); !errors.Is(err, ErrVersionNotFound) {
+		t.Errorf("activate unknown version: got %v", err)
+	}
+
+	// Survives a restart.
+	s = NewStore(dir)
+	if got := s.Get("m"); got == nil || got.ModelVersion != "3.0" {
+		t.Fatalf("after reload active = %+v, want 3.0", got)
+	}
+	if v, err := s.Rollback("m"); err != nil || v != "2.0" {
+		t.Fatalf("rollback = %q, %v; want 2.0", v, err)
+	}
+	if v, err := s.Rollback("m"); err != nil || v != "1.0" {
+		t.Fatalf("second rollback = %q, %v; want 1.0", v, err)
+	}
+	if _, err := s.Rollback("m"); !errors.Is(err, ErrNoRollback) {
+		t.Errorf("rollback past history: got %v", err)
+	}
+}
+
+func TestStore_PinnedSurvivesActivation(t *testing.T) {
+	s := NewStore(t.TempDir())
+	s.Register(versioned(t, "m", "1.0", 1.8))
+	if got := s.Pinned("sess-a", "m"); got == nil || got.ModelVersion != "1.0" {
+		t.Fatalf("pinned = %+v", got)
+	}
+	s.Register(versioned(t, "m", "2.0", 1.9))
+	if got := s.Pinned("sess-a", "m"); got.ModelVersion != "1.0" {
+		t.Errorf("existing session moved to %s mid-session", got.ModelVersion)
+	}
+	if got := s.Pinned("sess-b", "m"); got.ModelVersion != "2.0" {
+		t.Errorf("new session got %s, want active 2.0", got.ModelVersion)
+	}
+	if got := s.Pinned("", "m"); got.ModelVersion != "2.0" {
+		t.Errorf("no session should get the active version, got %s", got.ModelVersion)
+	}
+}
+
+func TestStore_PutReplacesWithoutMutating(t *testing.T) {
+	s := NewStore(t.TempDir())
+	s.Register(versioned(t, "m", "1.0", 1.8))
+	inFlight := s.Get("m")
+	same := versioned(t, "m", "1.0", 1.8)
+	if err := s.Put(same, StatusActive); err != nil {
+		t.Fatal(err)
+	}
+	if s.Get("m") != inFlight {
+		t.Error("re-putting unchanged math should keep the stored version")
+	}
+	if err := s.Put(versioned(t, "m", "1.0", 1.9), StatusActive); err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Get("m"); got == inFlight || got.PrizeTable[1].Multiplier != 1.9 {
+		t.Errorf("changed math not served: %+v", got.PrizeTable)
+	}
+	if inFlight.PrizeTable[1].Multiplier != 1.8 {
+		t.Error("math held by an in-flight round was mutated")
+	}
+}
+
+func TestStore_LoadsUnversionedFile(t *testing.T) {
+	dir := t.TempDir()
+	g := versioned(t, "legacy", "", 1.5)
+	data, _ := json.Marshal([]map[string]interface{}{{"model_id": "legacy", "math": g}})
+	if err := os.WriteFile(filepath.Join(dir, "game_math.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	if got := NewStore(dir).Get("legacy"); got == nil {
+		t.Fatal("pre-versioning game_math.json should still load")
+	}
+}
+
+func TestStore_LoadSealsUnhashedVersions(t *testing.T) {
+	dir := t.TempDir()
+	legacy := &GameMath{ModelID: "legacy", ModelVersion: "1", PrizeTable: []PrizeTier{{Tier: "LOSE", Weight: 1}}}
+	tampered := versioned(t, "tampered", "1", 2)
+	tampered.PrizeTable[1].Multiplier = 3
+	data, _ := json.Marshal([]storedEntry{
+		{ModelID: "legacy", ActiveVersion: "1", Versions: []*GameMath{legacy}},
+		{ModelID: "tampered", ActiveVersion: "1", Versions: []*GameMath{tampered}},
+	})
+	if err := os.WriteFile(filepath.Join(dir, "game_math.json"), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := NewStore(dir)
+	got := s.Get("legacy")
+	if got == nil || got.VerifyContentHash() != nil {
+		t.Fatalf("unhashed version should be sealed on load, got %+v", got)
+	}
+	if s.Get("tampered") != nil {
+		t.Error("version with a wrong hash must be dropped")
+	}
+	// The hash is saved, so the next start verifies the version instead of sealing it again.
+	if again := NewStore(dir).Get("legacy"); again == nil || again.Integrity == nil || again.Integrity.ContentHash != got.Integrity.ContentHash {
+		t.Errorf("after restart: %+v", again)
+	}
+}
+
+func TestStore_RefusesToArchiveActive(t *testing.T) {
+	s := NewStore(t.TempDir())
+	s.Register(versioned(t, "m", "1.0", 1.8))
+	for _, status := range []string{StatusArchived, StatusDraft} {
+		if err := s.Put(versioned(t, "m", "1.0", 1.8), status); !errors.Is(err, ErrActiveVersion) {
+			t.Errorf("put active version as %s: got %v", status, err)
+		}
+	}
+	if got := s.Get("m"); got == nil || got.ModelVersion != "1.0" {
+		t.Fatalf("model lost its active version: %+v", got)
+	}
+	s.Register(versioned(t, "m", "2.0", 1.9))
+	if err := s.Put(versioned(t, "m", "1.0", 1.8), StatusArchived); err != nil {
+		t.Errorf("archiving an inactive version: %v", err)
+	}
+}
+
+func TestStore_DraftPutMatchesSource(t *testing.T) {
+	s := NewStore(t.TempDir())
+	s.Register(versioned(t, "m", "1.0", 1.8))
+	s.Register(versioned(t, "m", "2.0", 1.9)) // archives 1.0
+	if err := s.Put(versioned(t, "m", "1.0", 1.8), StatusDraft); err != nil {
+		t.Fatal(err)
+	}
+	versions, _ := s.Versions("m")
+	if versions[0].Status != StatusDraft {
+		t.Errorf("1.0 status %s want DRAFT", versions[0].Status)
+	}
+	if _, err := s.Rollback("m"); !errors.Is(err, ErrNoRollback) {
+		t.Errorf("a draft is not a rollback target, got %v", err)
+	}
+}
+
+func TestStore_RetainRemovesDeletedVersions(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(dir)
+	s.PutFrom("game_math", versioned(t, "db", "1.0", 1.8), StatusActive)
+	s.PutFrom("game_math", versioned(t, "db", "2.0", 1.9), StatusActive)
+	s.PutFrom("game_math", versioned(t, "gone", "1.0", 1.8), StatusActive)
+	s.Register(versioned(t, "local", "1.0", 1.8))
+
+	if removed, err := s.Retain("game_math", map[string]map[string]bool{"db": {"1.0": true, "2.0": true}, "gone": {"1.0": true}}); err != nil || len(removed) != 0 {
+		t.Fatalf("removed %v, %v", removed, err)
+	}
+	// The rows of db@2.0 (the active version) and of every gone version are deleted.
+	removed, err := s.Retain("game_math", map[string]map[string]bool{"db": {"1.0": true}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 2 {
+		t.Errorf("removed %v", removed)
+	}
+	s = NewStore(dir)
+	if s.GetVersion("db", "2.0") != nil || s.Get("db") != nil {
+		t.Error("db@2.0 should be gone and db left without an active version")
+	}
+	if s.GetVersion("db", "1.0") == nil {
+		t.Error("db@1.0 still has a row")
+	}
+	if _, err := s.Versions("gone"); !errors.Is(err, ErrModelNotFound) {
+		t.Errorf("gone: %v", err)
+	}
+	if s.Get("local") == nil {
+		t.Error("models from other sources are kept")
+	}
+}
+
+// memPins is a PinBackend shared by several stores, like the game_math_pins table.
+type memPins struct {
+	pins map[string]pin
+}
+
+func (b *memPins) Pin(sessionID, modelID, version string, now time.Time) (string, time.Time, error) {
+	key := pinKey(sessionID, modelID)
+	if p, ok := b.pins[key]; ok && now.Sub(p.at) < PinTTL {
+		return p.version, p.at, nil
+	}
+	b.pins[key] = pin{version: version, at: now}
+	return version, now, nil
+}
+
+func (b *memPins) Prune(cutoff time.Time) error {
+	for k, p := range b.pins {
+		if p.at.Before(cutoff) {
+			delete(b.pins, k)
+		}
+	}
+	return nil
+}
+
+func TestStore_RetainKeepsVersionsOfOtherSources(t *testing.T) {
+	dir := t.TempDir()
+	s := NewStore(dir)
+	if err := s.PutFrom("game_math", versioned(t, "mixed", "1.0", 1.8), StatusArchived); err != nil {
+		t.Fatal(err)
+	}
+	// 2.0 comes from the admin API or a bundle under the same model id.
+	if err := s.Register(versioned(t, "mixed", "2.0", 1.9)); err != nil {
+		t.Fatal(err)
+	}
+	if removed, _ := s.Retain("game_math", map[string]map[string]bool{"mixed": {"1.0": true}}); len(removed) != 0 {
+		t.Fatalf("removed %v", removed)
+	}
+	// The 1.0 row is deleted; after a restart the next reload removes only that version.
+	s = NewStore(dir)
+	removed, err := s.Retain("game_math", map[string]map[string]bool{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(removed) != 1 || removed[0] != "mixed@1.0" {
+		t.Errorf("removed %v", removed)
+	}
+	if got := s.Get("mixed"); got == nil || got.ModelVersion != "2.0" {
+		t.Errorf("active version %+v", got)
+	}
+}
+
+func TestStore_PinsSurviveRestart(t *testing.T) {
+	dir := t.TempDir()
+	backend := &memPins{pins: make(map[string]pin)}
+	s := NewStore(dir)
+	s.SetPinBackend(backend)
+	s.Register(versioned(t, "m", "1.0", 1.8))
+	if got := s.Pinned("sess", "m"); got.ModelVersion != "1.0" {
+		t.Fatalf("pinned %s", got.ModelVersion)
+	}
+	s.Register(versioned(t, "m", "2.0", 1.9))
+
+	// A restarted (or second) instance reads the pin from the backend.
+	restarted := NewStore(dir)
+	restarted.SetPinBackend(backend)
+	if got := restarted.Pinned("sess", "m"); got.ModelVersion != "1.0" {
+		t.Errorf("session moved to %s after a restart", got.ModelVersion)
+	}
+	if got := restarted.Pinned("other", "m"); got.ModelVersion != "2.0" {
+		t.Errorf("new session got %s, want active 2.0", got.ModelVersion)
+	}
+
+	if err := restarted.PrunePins(time.Now().Add(PinTTL)); err != nil {
+		t.Fatal(err)
+	}
+	if len(backend.pins) != 0 || len(restarted.pins) != 0 {
+		t.Errorf("expired pins kept: backend %v, memory %v", backend.pins, restarted.pins)
+	}
+	if got := restarted.Pinned("sess", "m"); got.ModelVersion != "2.0" {
+		t.Errorf("expired pin still served %s", got.ModelVersion)
+	}
+}
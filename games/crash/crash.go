@@ -1,15 +1,15 @@
 package crash
 
 import (
-	"crypto/rand"
-	"math/big"
-)
+	"math"
+	"time"
 
-// StepMultiplier converts step (0, 1, 2, ...) to display multiplier: 1.00 + step*0.01
-const StepSize = 0.01
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
+)
 
-// CrashStepMin/Max: crash happens at step in [Min, Max] (multiplier 1.10 to 5.00 for 10-400)
-const CrashStepMin, CrashStepMax = 10, 400
+// StepSize is the multiplier per step: step 0 is 1.00x, step 150 is 2.50x (gamemath.CrashTick).
+const StepSize = gamemath.CrashTick
 
 // Multiplier returns the multiplier at step (e.g. step 50 -> 1.50)
 func Multiplier(step int) float64 {
@@ -19,13 +19,45 @@ func Multiplier(step int) float64 {
 	return 1.0 + float64(step)*StepSize
 }
 
-// GenerateCrashStep returns a random crash step in [CrashStepMin, CrashStepMax] using CSPRNG.
-func GenerateCrashStep() int {
-	n := CrashStepMax - CrashStepMin + 1
-	max := big.NewInt(int64(n))
-	v, err := rand.Int(rand.Reader, max)
-	if err != nil {
-		return CrashStepMin
+// Step returns the last step at or below mult (0 below 1.00x).
+func Step(mult float64) int {
+	step := int(math.Floor((mult-1)/StepSize + 1e-9))
+	if step < 0 {
+		return 0
 	}
-	return CrashStepMin + int(v.Int64())
+	return step
+}
+
+// TargetStep returns the first step at or above mult, the step a cashout target pays at.
+func TargetStep(mult float64) int {
+	step := int(math.Ceil((mult-1)/StepSize - 1e-9))
+	if step < 0 {
+		return 0
+	}
+	return step
+}
+
+// MaxStep is the highest crash step of m, its capped multiplier.
+func MaxStep(m gamemath.CrashMath) int {
+	return Step(m.Cap())
+}
+
+// GenerateCrashStep returns the crash step of a round of m using CSPRNG.
+func GenerateCrashStep(m gamemath.CrashMath) int {
+	return GenerateCrashStepFrom(m, rng.Default)
+}
+
+// GenerateCrashStepFrom is GenerateCrashStep drawing from src.
+func GenerateCrashStepFrom(m gamemath.CrashMath, src rng.Source) int {
+	return Step(m.CrashPointFrom(src))
+}
+
+// StepAt is the step a round of m has reached elapsed after it started.
+func StepAt(m gamemath.CrashMath, elapsed time.Duration) int {
+	return Step(m.MultiplierAt(elapsed))
+}
+
+// StepTime is how long after the start a round of m reaches step.
+func StepTime(m gamemath.CrashMath, step int) time.Duration {
+	return m.TimeTo(Multiplier(step))
 }
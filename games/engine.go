@@ -0,0 +1,149 @@
+package games
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/round"
+)
+
+// GameEngine is a game type's math and state machine. The server's round orchestrator wraps
+// every engine with the wallet (debit, credit, refund), result persistence and round-id
+// idempotency, so an engine only decides outcomes and keeps its own open-round state.
+//
+// Instant engines (EngineInfo.Instant) resolve a round completely in Start, before any money
+// moves. Other engines are started after the bet is debited and are driven by Act until an
+// action (or the engine itself) decides the round.
+type GameEngine interface {
+	// Describe reports what the engine is and which actions Act accepts.
+	Describe() EngineInfo
+	// Start opens a round for bet. Instant engines return it decided (Settled set).
+	Start(ctx context.Context, bet Bet) (*Round, error)
+	// Act applies a player action to the open round roundID and returns the round. When the
+	// action decides it, the round comes back with Settled set and Win final; the orchestrator
+	// credits Win and then calls Settle.
+	Act(ctx context.Context, roundID string, act Action) (*Round, error)
+	// Settle commits a decided round once its win is credited; until then the engine must keep
+	// the round open, so a failed credit can be retried.
+	Settle(ctx context.Context, rnd *Round) error
+}
+
+// Replayer is implemented by engines that can present a settled round again from its result,
+// for retries that reuse a round id.
+type Replayer interface {
+	Replay(ctx context.Context, res *round.Result) (*Round, error)
+}
+
+// Resumer is implemented by engines whose rounds stay open after Start, so a retried start that
+// reuses the round id of a round still in play gets that round back instead of a new one.
+type Resumer interface {
+	// Resume returns the open round roundID, or false when there is none.
+	Resume(ctx context.Context, roundID string) (*Round, bool)
+}
+
+// BetChecker is implemented by engines that can reject a bet before it is debited (invalid
+// options, no round taking bets). Start must still check: the bet may be placed later.
+type BetChecker interface {
+	CheckBet(ctx context.Context, bet Bet) error
+}
+
+// EngineInfo describes a game engine.
+type EngineInfo struct {
+	ID      string   `json:"id"`   // e.g. "scratch", "crash"
+	Name    string   `json:"name"` // game name sent with wallet transactions, e.g. "Scratch"
+	Instant bool     `json:"instant"`
+	Actions []string `json:"actions,omitempty"` // actions Act accepts, e.g. "cashout"
+}
+
+// Bet is a player's stake on a new round.
+type Bet struct {
+	RoundID    string
+	GameID     string
+	SessionID  string
+	Currency   string
+	Amount     float64
+	DeviceType string
+	// GameCode is the operator game code, when it differs from GameID.
+	GameCode string
+	// BetRef identifies the debit in the wallet (platform bet id or operator transaction id).
+	// It is set before Start for engines that are not instant.
+	BetRef string
+	// Data is the request body, for the engine's own bet options (e.g. a crash auto-cashout).
+	Data json.RawMessage
+}
+
+// Action is a player action on an open round.
+type Action struct {
+	Name string
+	Data json.RawMessage // the request body, for the engine to decode
+}
+
+// Round is a round as the orchestrator sees it.
+type Round struct {
+	Bet
+	// Settled is set once the round is decided; Win is then the amount to credit.
+	Settled bool
+	Win     float64
+	// View is the engine's response payload for the player.
+	View any
+	// State is engine-specific detail for game-specific endpoints.
+	State any
+	// Record holds the engine's part of the round result (outcome detail, RNG, math); the
+	// orchestrator adds the wallet fields when the round settles.
+	Record *round.Result
+	// Parts, when set, are the results of the tickets of a multi-ticket round (a bundle) and are
+	// recorded instead of Record. Each has its own RoundID, Bet and WinAmount; the orchestrator
+	// adds the round's wallet fields and sets BatchID to the round id.
+	Parts []*round.Result
+	// Void, when set, releases what Start reserved (e.g. a LIMITED ticket) if the round is
+	// abandoned because the wallet failed.
+	Void func()
+}
+
+// Error is a round error the client can act on: the HTTP status and code to answer with.
+type Error struct {
+	Status int
+	Code   string
+	Msg    string
+}
+
+func (e *Error) Error() string { return e.Msg }
+
+// Errorf returns an Error with status and code.
+func Errorf(status int, code, msg string) *Error {
+	return &Error{Status: status, Code: code, Msg: msg}
+}
+
+var (
+	ErrRoundNotFound = Errorf(http.StatusNotFound, "ROUND_NOT_FOUND", "round not found")
+	ErrRoundSettled  = Errorf(http.StatusConflict, "ROUND_SETTLED", "round already settled")
+	ErrUnknownAction = Errorf(http.StatusNotFound, "INVALID_PATH", "invalid path")
+)
+
+// RegisterEngine makes e the engine of gameIDs, or of every game without its own engine when
+// gameIDs is empty.
+func (r *Registry) RegisterEngine(e GameEngine, gameIDs ...string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if len(gameIDs) == 0 {
+		r.defaultEngine = e
+		return
+	}
+	if r.engines == nil {
+		r.engines = make(map[string]GameEngine)
+	}
+	for _, id := range gameIDs {
+		r.engines[id] = e
+	}
+}
+
+// Engine returns the engine that plays gameID.
+func (r *Registry) Engine(gameID string) (GameEngine, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if e, ok := r.engines[gameID]; ok {
+		return e, true
+	}
+	return r.defaultEngine, r.defaultEngine != nil
+}
@@ -10,6 +10,9 @@ import (
 type Registry struct {
 	mu        sync.RWMutex
 	providers map[string]*Provider
+	// engines play games by game id; defaultEngine plays the rest (see RegisterEngine).
+	engines       map[string]GameEngine
+	defaultEngine GameEngine
 }
 
 type Provider struct {
@@ -23,6 +26,19 @@ func NewRegistry() *Registry {
 	return r
 }
 
+// Reload replaces the catalog with the current games table, so games that were disabled or
+// removed disappear. On error the current catalog is kept.
+func (r *Registry) Reload() error {
+	fresh := &Registry{providers: make(map[string]*Provider)}
+	if err := loadFromDB(fresh); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.providers = fresh.providers
+	return nil
+}
+
 func (r *Registry) Register(providerID string, gameIDs []string) {
 	r.mu.Lock()
 	defer r.mu.Unlock()
@@ -0,0 +1,90 @@
+package scratch
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
+)
+
+// composeValues splits each winning multiplier into symbol values, largest first, within the
+// grid. It returns the value of each part in bet multipliers and false when the layout has no
+// valued symbols or a multiplier cannot be made from their values.
+func (l Layout) composeValues(multipliers []float64) ([]float64, bool) {
+	if l.Mechanic != MechanicSymbolHunt || len(l.Values) == 0 || len(multipliers) == 0 {
+		return nil, false
+	}
+	seen := make(map[int64]bool, len(l.Values))
+	values := make([]int64, 0, len(l.Values))
+	for _, v := range l.Values {
+		if u := toUnits(v); u > 0 && !seen[u] {
+			seen[u] = true
+			values = append(values, u)
+		}
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] > values[j] })
+	var out []float64
+	for _, m := range multipliers {
+		parts, ok := splitUnits(toUnits(m), values, l.Cells()-len(out))
+		if !ok || len(parts) == 0 {
+			return nil, false
+		}
+		for _, p := range parts {
+			out = append(out, float64(p)/prizeScale)
+		}
+	}
+	return out, true
+}
+
+// CanCompose reports whether a Symbol Hunt win paying multipliers can be shown as valued
+// symbols adding up to the prize. Prizes that cannot are shown with one tier symbol per win.
+func (l Layout) CanCompose(multipliers []float64) bool {
+	_, ok := l.composeValues(multipliers)
+	return ok
+}
+
+// revealValued builds a Symbol Hunt map whose valued symbols add up to o's prize: one symbol
+// per part of the composition, a random one among equally valued symbols, with its value in
+// Prizes. A boosted win composes its base prize and shows the multiplier symbol boost too.
+// ok is false (and nothing is drawn) when the prize cannot be composed, so the caller falls
+// back to one tier symbol per win.
+func (l Layout) revealValued(src rng.Source, o *Outcome, boost string) (m RevealMap, ok bool, err error) {
+	var multipliers []float64
+	for _, win := range o.Wins {
+		multipliers = append(multipliers, win.Base())
+	}
+	parts, ok := l.composeValues(multipliers)
+	if !ok {
+		return RevealMap{}, false, nil
+	}
+	byValue := make(map[int64][]string)
+	for _, sym := range sortedKeys(l.Values) {
+		u := toUnits(l.Values[sym])
+		byValue[u] = append(byValue[u], sym)
+	}
+	wins := make([]string, len(parts))
+	for i, p := range parts {
+		wins[i] = rng.Pick(src, byValue[toUnits(p)])
+	}
+	t := ticket{wins: wins, boost: boost}
+	grid, err := solve(src, l.Cells(), l.bounds(t))
+	if err != nil {
+		return RevealMap{}, true, err
+	}
+	m = RevealMap{Cells: grid, Prizes: make([]float64, len(grid))}
+	for i, sym := range grid {
+		m.Prizes[i] = l.Values[sym]
+	}
+	if err := l.check(m, t); err != nil {
+		return RevealMap{}, true, fmt.Errorf("%w: %v", ErrNoRevealMap, err)
+	}
+	ev, _ := l.Evaluate(m)
+	want := 0.0
+	for _, win := range o.Wins {
+		want += win.Multiplier
+	}
+	if toUnits(ev.Prize) != toUnits(want) {
+		return RevealMap{}, true, fmt.Errorf("%w: grid shows %vx, want %vx", ErrNoRevealMap, ev.Prize, want)
+	}
+	return m, true, nil
+}
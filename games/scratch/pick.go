@@ -0,0 +1,134 @@
+package scratch
+
+import (
+	"fmt"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
+)
+
+// Pick mechanics: the player opens cells one at a time (see DealPicks).
+const (
+	MechanicPickOne = "pick_one"
+	MechanicPickN   = "pick_n"
+)
+
+// IsPick reports whether mechanic is played pick by pick rather than revealed in one go.
+func IsPick(mechanic string) bool {
+	return mechanic == MechanicPickOne || mechanic == MechanicPickN
+}
+
+// PickItem is what a cell of a pick game shows when opened.
+type PickItem struct {
+	Symbol string  `json:"symbol"`
+	Prize  float64 `json:"prize,omitempty"` // bet multiplier, 0 for empty cells
+}
+
+// PickDeal is a pick ticket committed at purchase. The outcome does not depend on which cells
+// the player opens: the k-th pick shows Found[k] wherever it lands, and the cells left unpicked
+// show Rest in cell order when the round ends.
+type PickDeal struct {
+	Found []PickItem `json:"found"`
+	Rest  []PickItem `json:"rest"`
+}
+
+// PickCount is the number of cells a pick player opens (1 for pick_one).
+func (l Layout) PickCount() int {
+	if l.Mechanic == MechanicPickOne || l.Picks <= 0 {
+		return 1
+	}
+	return l.Picks
+}
+
+func (l Layout) validatePick() error {
+	if p := l.PickCount(); p > l.Cells() {
+		return fmt.Errorf("%d picks do not fit a %d-cell grid", p, l.Cells())
+	}
+	if len(l.Duds) == 0 {
+		return fmt.Errorf("%s needs dud symbols for picks that win nothing", l.Mechanic)
+	}
+	if len(l.NearMisses) > 0 {
+		return fmt.Errorf("near_miss is only supported for %s", MechanicMatchN)
+	}
+	for _, v := range l.PrizeValues {
+		if toUnits(v) <= 0 {
+			return fmt.Errorf("prize value %v must be positive", v)
+		}
+	}
+	return nil
+}
+
+// DealPicks commits the contents of a pick ticket for o: one found item per winning component
+// (its tier's symbol and multiplier), duds for the remaining picks, in random order; the cells
+// never picked show teasers (a win symbol and one of PrizeValues) or, without prize values,
+// duds. The found prizes are checked to add up to o's prize.
+func (l Layout) DealPicks(src rng.Source, o *Outcome) (PickDeal, error) {
+	if !IsPick(l.Mechanic) {
+		return PickDeal{}, fmt.Errorf("%w: %q is not a pick mechanic", ErrNoRevealMap, l.Mechanic)
+	}
+	if err := l.validatePick(); err != nil {
+		return PickDeal{}, fmt.Errorf("%w: %v", ErrNoRevealMap, err)
+	}
+	tiers := o.WinTiers()
+	if len(tiers) > 0 && len(o.Wins) == 0 {
+		return PickDeal{}, fmt.Errorf("%w: outcome %q has no prize breakdown", ErrNoRevealMap, o.Tier)
+	}
+	if len(tiers) > l.PickCount() {
+		return PickDeal{}, fmt.Errorf("%w: %d wins need more than %d picks", ErrNoRevealMap, len(tiers), l.PickCount())
+	}
+	syms := l.pickWinSymbols(src, tiers)
+	if len(syms) < len(tiers) {
+		return PickDeal{}, fmt.Errorf("%w: no symbol for every winning tier of %q", ErrNoRevealMap, o.Tier)
+	}
+	d := PickDeal{Found: make([]PickItem, 0, l.PickCount())}
+	want := 0.0
+	for i, sym := range syms {
+		want += o.Wins[i].Multiplier
+		d.Found = append(d.Found, PickItem{Symbol: sym, Prize: o.Wins[i].Multiplier})
+	}
+	for len(d.Found) < l.PickCount() {
+		d.Found = append(d.Found, PickItem{Symbol: rng.Pick(src, l.Duds)})
+	}
+	rng.Shuffle(src, d.Found)
+
+	teasers := append(append([]string(nil), l.Wins...), l.Top...)
+	for i := l.PickCount(); i < l.Cells(); i++ {
+		if len(teasers) == 0 || len(l.PrizeValues) == 0 {
+			d.Rest = append(d.Rest, PickItem{Symbol: rng.Pick(src, l.Duds)})
+			continue
+		}
+		d.Rest = append(d.Rest, PickItem{Symbol: rng.Pick(src, teasers), Prize: rng.Pick(src, l.PrizeValues)})
+	}
+
+	got := 0.0
+	for _, it := range d.Found {
+		got += it.Prize
+	}
+	if toUnits(got) != toUnits(want) {
+		return PickDeal{}, fmt.Errorf("%w: picks show %vx, want %vx", ErrNoRevealMap, got, want)
+	}
+	return d, nil
+}
+
+// Board is the final reveal map once every pick is made: picked (cells in pick order) show their
+// found items, the other cells the rest in cell order.
+func (d PickDeal) Board(picked []int) RevealMap {
+	cells := len(d.Found) + len(d.Rest)
+	m := RevealMap{Cells: make([]string, cells), Prizes: make([]float64, cells)}
+	taken := make([]bool, cells)
+	for k, cell := range picked {
+		if k >= len(d.Found) || cell < 0 || cell >= cells {
+			continue
+		}
+		m.Cells[cell], m.Prizes[cell] = d.Found[k].Symbol, d.Found[k].Prize
+		taken[cell] = true
+	}
+	next := 0
+	for cell := 0; cell < cells && next < len(d.Rest); cell++ {
+		if taken[cell] {
+			continue
+		}
+		m.Cells[cell], m.Prizes[cell] = d.Rest[next].Symbol, d.Rest[next].Prize
+		next++
+	}
+	return m
+}
@@ -0,0 +1,590 @@
+package scratch
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
+)
+
+// Mechanics a scratch game can use (scratch_games.mechanic.type).
+const (
+	MechanicMatchN      = "match_n"
+	MechanicTargetMatch = "target_match"
+	MechanicSymbolHunt  = "symbol_hunt"
+)
+
+// ErrNoRevealMap is returned when a layout cannot show an outcome without breaking the
+// reveal-map rules (.cursor/rules/scratch-math-and-revealmap.mdc).
+var ErrNoRevealMap = errors.New("no valid reveal map")
+
+// Layout is a scratch game's grid and symbols as the reveal-map engine sees them.
+type Layout struct {
+	Mechanic   string // MechanicMatchN, MechanicSymbolHunt or MechanicTargetMatch
+	Rows, Cols int    // default 3x3; the Your Numbers zone for target_match
+	MatchCount int    // match_n: copies of a symbol that win (default 3)
+	// Target Match: WinningNumbers and YourNumbers size the two zones (default 2 and Rows*Cols),
+	// numbers run from 1 to NumberRange (default 30, at least both zones) and every Your Numbers
+	// cell shows one of PrizeValues (bet multipliers).
+	WinningNumbers int
+	YourNumbers    int
+	NumberRange    int
+	PrizeValues    []float64
+	// Picks is how many cells a pick_n player opens (pick_one: 1).
+	Picks int
+	// Top are the top-prize symbols. They never appear on a losing ticket except as a near miss,
+	// nor as filler on a winning one.
+	Top  []string
+	Wins []string // symbols shown for prize tiers without a mapping
+	Duds []string // symbols that never pay
+	// Tiers maps a prize tier to the symbols that show it.
+	Tiers map[string][]string
+	// NearMisses are the teasers losing tickets may show (match_n only).
+	NearMisses []NearMiss
+	// Values is the bet multiplier each valued symbol pays (symbol_hunt): a win shows valued
+	// symbols adding up to the prize.
+	Values map[string]float64
+	// Wilds count toward any match (match_n). WildChance of tickets (default 0.25) show one,
+	// 1 to match_count-1 times, as far as the grid allows without a wrong win.
+	Wilds      []string
+	WildChance float64
+	// Boosts is the factor of each multiplier symbol (match_n, symbol_hunt): a win boosted by a
+	// factor shows its base prize and a multiplier symbol of that factor.
+	Boosts map[string]float64
+}
+
+// NearMiss is a teaser shown on a share of losing tickets: Count copies of Symbol.
+type NearMiss struct {
+	Symbol      string  `json:"symbol,omitempty"` // default: the first top symbol
+	Count       int     `json:"count,omitempty"`  // default: match_count-1
+	Probability float64 `json:"probability"`      // share of losing tickets that show it
+}
+
+// RevealMap is a generated reveal map.
+type RevealMap struct {
+	// Cells holds the symbol ID of each cell, row-major (for target_match, a number: the Winning
+	// Numbers zone first, then Your Numbers).
+	Cells []string
+	// Prizes is the bet multiplier shown under each cell, for mechanics that show one (0 for
+	// cells without a prize).
+	Prizes []float64
+	// Zones splits Cells into the mechanic's areas (target_match).
+	Zones []Zone
+}
+
+// Zone is a run of cells in a reveal map that the frontend renders as one area.
+type Zone struct {
+	ID    string `json:"id"`    // ZoneWinning or ZoneYours
+	Start int    `json:"start"` // index of the zone's first cell in revealMap
+	Rows  int    `json:"rows"`
+	Cols  int    `json:"cols"`
+}
+
+// Evaluation is what a grid shows the player.
+type Evaluation struct {
+	// Wins has one entry per win on the grid: the symbol of each completed match (match_n),
+	// each special symbol found (symbol_hunt) or each matched number (target_match). Sorted.
+	Wins []string
+	// Counts is the number of cells showing each symbol.
+	Counts map[string]int
+	// Prize is the total multiplier of the winning cells, for mechanics that show prizes,
+	// including Boost.
+	Prize float64
+	// Boost is the product of the multiplier symbols shown, 1 without any.
+	Boost float64
+}
+
+// Cells is the number of cells in the reveal map.
+func (l Layout) Cells() int {
+	if l.Mechanic == MechanicTargetMatch {
+		w, y := l.zoneSizes()
+		return w + y
+	}
+	return l.gridSize()
+}
+
+// Dims returns Rows and Cols, default 3x3.
+func (l Layout) Dims() (rows, cols int) {
+	rows, cols = l.Rows, l.Cols
+	if rows <= 0 {
+		rows = 3
+	}
+	if cols <= 0 {
+		cols = 3
+	}
+	return rows, cols
+}
+
+// gridSize is Rows*Cols, default 3x3.
+func (l Layout) gridSize() int {
+	rows, cols := l.Dims()
+	return rows * cols
+}
+
+func (l Layout) matchCount() int {
+	if l.MatchCount <= 0 {
+		return 3
+	}
+	return l.MatchCount
+}
+
+// Validate checks that the layout can show a losing ticket and every near miss, and a single
+// win of every mapped symbol, without breaking the reveal-map rules.
+func (l Layout) Validate() error {
+	if err := l.validateSpecials(); err != nil {
+		return err
+	}
+	switch l.Mechanic {
+	case MechanicMatchN, MechanicSymbolHunt:
+	case MechanicTargetMatch:
+		return l.validateTarget()
+	case MechanicPickOne, MechanicPickN:
+		return l.validatePick()
+	default:
+		return fmt.Errorf("mechanic %q has no reveal-map engine", l.Mechanic)
+	}
+	known := l.symbols()
+	tiers := sortedKeys(l.Tiers)
+	for _, tier := range tiers {
+		for _, sym := range l.Tiers[tier] {
+			if !known[sym] {
+				return fmt.Errorf("tier %q: unknown symbol %q", tier, sym)
+			}
+		}
+	}
+	if l.Mechanic == MechanicMatchN && l.matchCount() > l.Cells() {
+		return fmt.Errorf("match_count %d does not fit a %d-cell grid", l.matchCount(), l.Cells())
+	}
+	if l.Mechanic != MechanicSymbolHunt && len(l.Values) > 0 {
+		return fmt.Errorf("symbol values are only supported for %s", MechanicSymbolHunt)
+	}
+	for _, sym := range l.Duds {
+		if _, ok := l.Values[sym]; ok {
+			return fmt.Errorf("dud symbol %q cannot carry a value", sym)
+		}
+	}
+	for _, sym := range sortedKeys(l.Values) {
+		if v := l.Values[sym]; toUnits(v) <= 0 {
+			return fmt.Errorf("symbol %q: value %v must be positive", sym, v)
+		}
+	}
+	if l.Mechanic != MechanicMatchN && len(l.NearMisses) > 0 {
+		return fmt.Errorf("near_miss is only supported for %s", MechanicMatchN)
+	}
+	total := 0.0
+	for i := range l.NearMisses {
+		m, err := l.nearMiss(i)
+		if err != nil {
+			return err
+		}
+		total += m.Probability
+		if !feasible(l.Cells(), l.bounds(ticket{miss: &m})) {
+			return fmt.Errorf("near_miss %d: not enough symbols to fill the grid without a win", i)
+		}
+	}
+	if total > 1 {
+		return fmt.Errorf("near_miss probabilities add up to %v (max 1)", total)
+	}
+	if !feasible(l.Cells(), l.bounds(ticket{})) {
+		return fmt.Errorf("not enough dud symbols to fill a losing grid without a win")
+	}
+	for _, tier := range tiers {
+		for _, sym := range l.Tiers[tier] {
+			if !feasible(l.Cells(), l.bounds(ticket{wins: []string{sym}})) {
+				return fmt.Errorf("tier %q: not enough symbols to show %q without a second win", tier, sym)
+			}
+		}
+	}
+	return nil
+}
+
+// nearMiss returns near miss i with its defaults filled in.
+func (l Layout) nearMiss(i int) (NearMiss, error) {
+	m := l.NearMisses[i]
+	if m.Symbol == "" && len(l.Top) > 0 {
+		m.Symbol = l.Top[0]
+	}
+	if m.Count == 0 {
+		m.Count = l.matchCount() - 1
+	}
+	switch {
+	case !l.symbols()[m.Symbol]:
+		return m, fmt.Errorf("near_miss %d: unknown symbol %q", i, m.Symbol)
+	case m.Count < 1 || m.Count >= l.matchCount():
+		return m, fmt.Errorf("near_miss %d: count %d must be between 1 and match_count-1", i, m.Count)
+	case m.Probability < 0 || m.Probability > 1:
+		return m, fmt.Errorf("near_miss %d: probability %v is not in [0, 1]", i, m.Probability)
+	}
+	return m, nil
+}
+
+// PresentationSource is the random source a ticket's reveal map is drawn from. It depends on
+// the round's presentation seed alone, so a layout, an outcome and a seed always give the same
+// map and any past ticket can be shown again.
+func PresentationSource(seed int64) rng.Source {
+	var b [8]byte
+	binary.BigEndian.PutUint64(b[:], uint64(seed))
+	return rng.NewSeeded(b[:])
+}
+
+// Reveal builds the reveal map for o, showing exactly o's wins. Every map is checked by
+// Evaluate before it is returned; ErrNoRevealMap means the layout cannot show the outcome.
+func (l Layout) Reveal(src rng.Source, o *Outcome) (RevealMap, error) {
+	switch l.Mechanic {
+	case MechanicMatchN, MechanicSymbolHunt:
+	case MechanicTargetMatch:
+		return l.revealTarget(src, o)
+	default:
+		return RevealMap{}, fmt.Errorf("%w: mechanic %q has no reveal-map engine", ErrNoRevealMap, l.Mechanic)
+	}
+	boost, err := l.pickBoost(src, o)
+	if err != nil {
+		return RevealMap{}, err
+	}
+	if m, ok, err := l.revealValued(src, o, boost); ok {
+		return m, err
+	}
+	t := ticket{wins: l.pickWinSymbols(src, o.WinTiers()), boost: boost}
+	if len(t.wins) < len(o.WinTiers()) {
+		return RevealMap{}, fmt.Errorf("%w: no symbol for every winning tier of %q", ErrNoRevealMap, o.Tier)
+	}
+	if len(t.wins) == 0 {
+		t.miss = l.pickNearMiss(src)
+	}
+	t.wild, t.wilds = l.pickWilds(src, t)
+	grid, err := solve(src, l.Cells(), l.bounds(t))
+	if err != nil {
+		return RevealMap{}, err
+	}
+	m := RevealMap{Cells: grid}
+	if err := l.check(m, t); err != nil {
+		return RevealMap{}, fmt.Errorf("%w: %v", ErrNoRevealMap, err)
+	}
+	return m, nil
+}
+
+// pickWinSymbols returns a symbol for each winning tier: one of the symbols mapped to the tier,
+// or a fallback for unmapped tiers. A symbol already shown for another component is avoided
+// while the tier has alternatives.
+func (l Layout) pickWinSymbols(src rng.Source, tiers []string) []string {
+	var fallback []string
+	for _, sym := range append(append([]string(nil), l.Top...), l.Wins...) {
+		if _, valued := l.Values[sym]; !valued {
+			fallback = append(fallback, sym) // a valued symbol would show the wrong amount
+		}
+	}
+	if len(fallback) == 0 {
+		fallback = append(append([]string(nil), l.Top...), l.Wins...)
+	}
+	if l.Mechanic == MechanicMatchN {
+		switch {
+		case len(l.Wins) > 0:
+			fallback = l.Wins
+		case len(l.Top) > 0:
+			fallback = l.Top
+		case len(l.Duds) > 0:
+			fallback = l.Duds[:1]
+		}
+	}
+	used := make(map[string]bool, len(tiers))
+	out := make([]string, 0, len(tiers))
+	for _, tier := range tiers {
+		set := l.Tiers[tier]
+		if len(set) == 0 {
+			set = fallback
+		}
+		if len(set) == 0 {
+			continue
+		}
+		var free []string
+		for _, sym := range set {
+			if !used[sym] {
+				free = append(free, sym)
+			}
+		}
+		if len(free) == 0 {
+			free = set
+		}
+		sym := rng.Pick(src, free)
+		used[sym] = true
+		out = append(out, sym)
+	}
+	return out
+}
+
+// pickNearMiss picks the near miss a losing ticket shows, or nil. No draw is made when the
+// layout has none.
+func (l Layout) pickNearMiss(src rng.Source) *NearMiss {
+	if len(l.NearMisses) == 0 {
+		return nil
+	}
+	u := rng.Float64(src)
+	for i := range l.NearMisses {
+		m, err := l.nearMiss(i)
+		if err != nil {
+			continue
+		}
+		if u < m.Probability {
+			return &m
+		}
+		u -= m.Probability
+	}
+	return nil
+}
+
+// bounds returns how many cells each symbol may take on a grid showing t (one win symbol per
+// winning component, the near miss, wilds and multiplier symbol, if any):
+//   - a win symbol shows exactly match_count copies per win, less the wilds (match_n), or one
+//     per win (symbol_hunt);
+//   - the near-miss symbol shows exactly its count, the wild symbol t.wilds times and the
+//     multiplier symbol once;
+//   - top, wild and multiplier symbols show nowhere else;
+//   - match_n fillers stop at match_count-1 copies less the wilds. Duds fill first; "win"
+//     symbols are added only when the duds cannot fill the grid alone.
+//   - symbol_hunt fills with duds only, as often as needed.
+func (l Layout) bounds(t ticket) []bound {
+	per := 1
+	if l.Mechanic == MechanicMatchN {
+		per = l.matchCount()
+	}
+	filler := l.matchCount() - 1 - t.wilds
+	if l.Mechanic == MechanicSymbolHunt {
+		filler = l.Cells()
+	}
+	var out []bound
+	fixed := make(map[string]int)
+	for _, sym := range t.wins {
+		if _, ok := fixed[sym]; !ok {
+			out = append(out, bound{symbol: sym, min: -t.wilds, max: -t.wilds})
+			fixed[sym] = len(out) - 1
+		}
+		out[fixed[sym]].min += per
+		out[fixed[sym]].max += per
+	}
+	if t.miss != nil {
+		if _, ok := fixed[t.miss.Symbol]; !ok {
+			// A near miss that wilds would complete cannot be shown (min > max).
+			out = append(out, bound{symbol: t.miss.Symbol, min: t.miss.Count, max: min(t.miss.Count, filler)})
+			fixed[t.miss.Symbol] = len(out) - 1
+		}
+	}
+	if t.wilds > 0 {
+		out = append(out, bound{symbol: t.wild, min: t.wilds, max: t.wilds})
+	}
+	if t.boost != "" {
+		out = append(out, bound{symbol: t.boost, min: 1, max: 1})
+	}
+	special := make(map[string]bool)
+	for _, sym := range l.Top {
+		special[sym] = true
+	}
+	for _, sym := range l.Wilds {
+		special[sym] = true
+	}
+	for sym := range l.Boosts {
+		special[sym] = true
+	}
+	if l.Mechanic == MechanicSymbolHunt {
+		for _, sym := range l.Wins {
+			special[sym] = true
+		}
+		for _, syms := range l.Tiers {
+			for _, sym := range syms {
+				special[sym] = true
+			}
+		}
+		for sym := range l.Values {
+			special[sym] = true
+		}
+	}
+	add := func(syms []string) {
+		for _, sym := range syms {
+			if _, ok := fixed[sym]; ok || special[sym] {
+				continue
+			}
+			out = append(out, bound{symbol: sym, max: filler})
+			fixed[sym] = len(out) - 1
+		}
+	}
+	add(l.Duds)
+	if l.Mechanic == MechanicMatchN && !feasible(l.Cells(), out) {
+		add(l.Wins)
+	}
+	return out
+}
+
+// Evaluate reads a reveal map the way a player would. It fails on maps of the wrong size and
+// on symbols the layout does not define.
+func (l Layout) Evaluate(m RevealMap) (Evaluation, error) {
+	grid := m.Cells
+	if len(grid) != l.Cells() {
+		return Evaluation{}, fmt.Errorf("grid has %d cells, want %d", len(grid), l.Cells())
+	}
+	if m.Prizes != nil && len(m.Prizes) != len(grid) {
+		return Evaluation{}, fmt.Errorf("%d prizes for %d cells", len(m.Prizes), len(grid))
+	}
+	if l.Mechanic == MechanicTargetMatch {
+		return l.evaluateTarget(m)
+	}
+	known := l.symbols()
+	ev := Evaluation{Counts: make(map[string]int), Boost: 1}
+	for i, sym := range grid {
+		if !known[sym] {
+			return Evaluation{}, fmt.Errorf("cell %d: unknown symbol %q", i, sym)
+		}
+		ev.Counts[sym]++
+		if b, ok := l.Boosts[sym]; ok {
+			ev.Boost *= b
+		}
+	}
+	switch l.Mechanic {
+	case MechanicMatchN:
+		wilds := l.wildCount(ev.Counts)
+		for sym, n := range ev.Counts {
+			if l.isSpecial(sym) {
+				continue
+			}
+			for k := 0; k < (n+wilds)/l.matchCount(); k++ {
+				ev.Wins = append(ev.Wins, sym)
+			}
+		}
+	case MechanicSymbolHunt:
+		dud := make(map[string]bool, len(l.Duds))
+		for _, sym := range l.Duds {
+			dud[sym] = true
+		}
+		for i, sym := range grid {
+			if !dud[sym] && !l.isSpecial(sym) {
+				ev.Wins = append(ev.Wins, sym)
+				if m.Prizes != nil {
+					ev.Prize += m.Prizes[i]
+				}
+			}
+		}
+		ev.Prize *= ev.Boost
+	default:
+		return Evaluation{}, fmt.Errorf("mechanic %q has no evaluator", l.Mechanic)
+	}
+	sort.Strings(ev.Wins)
+	return ev, nil
+}
+
+// check evaluates m and confirms it shows exactly t's wins: no accidental or extended matches,
+// no top symbol on a losing ticket beyond the near miss, the near miss as configured and the
+// multiplier symbol's boost.
+func (l Layout) check(m RevealMap, t ticket) error {
+	wins, miss := t.wins, t.miss
+	ev, err := l.Evaluate(m)
+	if err != nil {
+		return err
+	}
+	want := append([]string(nil), wins...)
+	sort.Strings(want)
+	if fmt.Sprint(ev.Wins) != fmt.Sprint(want) {
+		return fmt.Errorf("grid shows wins %v, want %v", ev.Wins, want)
+	}
+	if l.Mechanic == MechanicMatchN {
+		wilds := l.wildCount(ev.Counts)
+		for sym, n := range ev.Counts {
+			if n += wilds; !l.isSpecial(sym) && n > l.matchCount() && n%l.matchCount() != 0 {
+				return fmt.Errorf("symbol %q shows %d times with wilds, not a whole number of matches", sym, n)
+			}
+		}
+	}
+	boost := 1.0
+	if t.boost != "" {
+		boost = l.Boosts[t.boost]
+	}
+	if toUnits(ev.Boost) != toUnits(boost) {
+		return fmt.Errorf("grid shows a %vx boost, want %vx", ev.Boost, boost)
+	}
+	if len(wins) == 0 {
+		for _, sym := range l.Top {
+			allowed := 0
+			if miss != nil && miss.Symbol == sym {
+				allowed = miss.Count
+			}
+			if ev.Counts[sym] != allowed {
+				return fmt.Errorf("top symbol %q shows %d times on a losing ticket", sym, ev.Counts[sym])
+			}
+		}
+	}
+	if miss != nil && ev.Counts[miss.Symbol] != miss.Count {
+		return fmt.Errorf("near miss %q shows %d times, want %d", miss.Symbol, ev.Counts[miss.Symbol], miss.Count)
+	}
+	return nil
+}
+
+// sortedKeys returns the keys of m in order, for deterministic iteration.
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// symbols returns the set of symbols the layout defines.
+func (l Layout) symbols() map[string]bool {
+	known := make(map[string]bool)
+	for _, list := range [][]string{l.Top, l.Wins, l.Duds} {
+		for _, sym := range list {
+			known[sym] = true
+		}
+	}
+	for _, syms := range l.Tiers {
+		for _, sym := range syms {
+			known[sym] = true
+		}
+	}
+	for sym := range l.Values {
+		known[sym] = true
+	}
+	for _, sym := range l.Wilds {
+		known[sym] = true
+	}
+	for sym := range l.Boosts {
+		known[sym] = true
+	}
+	return known
+}
+
+// isSpecial reports whether sym is a wild or multiplier symbol, which never wins on its own.
+func (l Layout) isSpecial(sym string) bool {
+	if _, ok := l.Boosts[sym]; ok {
+		return true
+	}
+	for _, w := range l.Wilds {
+		if w == sym {
+			return true
+		}
+	}
+	return false
+}
+
+// wildCount is the number of wild cells among counts.
+func (l Layout) wildCount(counts map[string]int) int {
+	n := 0
+	for _, sym := range l.Wilds {
+		n += counts[sym]
+	}
+	return n
+}
+
+// WinTiers lists the prize tier of each winning component of the outcome.
+func (o *Outcome) WinTiers() []string {
+	if len(o.Wins) > 0 {
+		tiers := make([]string, len(o.Wins))
+		for i, w := range o.Wins {
+			tiers[i] = w.Tier
+		}
+		return tiers
+	}
+	if o.WinAmount > 0 {
+		return []string{o.Tier}
+	}
+	return nil
+}
@@ -0,0 +1,448 @@
+package scratch
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
+)
+
+func matchLayout() Layout {
+	return Layout{
+		Mechanic:   MechanicMatchN,
+		MatchCount: 3,
+		Top:        []string{"diamond"},
+		Wins:       []string{"bell", "cherry"},
+		Duds:       []string{"lemon", "plum", "grape", "pear", "kiwi"},
+		Tiers:      map[string][]string{"T_TOP": {"diamond"}, "T_10": {"bell"}, "T_2": {"cherry"}},
+	}
+}
+
+func lose() *Outcome { return &Outcome{Tier: "LOSE"} }
+
+func TestReveal_MatchNLossHasNoMatch(t *testing.T) {
+	l := matchLayout()
+	src := rng.NewSeeded([]byte("loss"))
+	for i := 0; i < 500; i++ {
+		m, err := l.Reveal(src, lose())
+		if err != nil {
+			t.Fatal(err)
+		}
+		ev, err := l.Evaluate(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(ev.Wins) != 0 || ev.Counts["diamond"] != 0 {
+			t.Fatalf("losing grid %v: wins %v, %d top symbols", m.Cells, ev.Wins, ev.Counts["diamond"])
+		}
+		for sym, n := range ev.Counts {
+			if n >= 3 {
+				t.Fatalf("losing grid %v shows %q %d times", m.Cells, sym, n)
+			}
+		}
+	}
+}
+
+func TestReveal_MatchNWinsExactly(t *testing.T) {
+	l := matchLayout()
+	src := rng.NewSeeded([]byte("win"))
+	o := &Outcome{Tier: "COMBO", WinAmount: 12, Wins: []Win{{Tier: "T_10"}, {Tier: "T_2"}}}
+	for i := 0; i < 500; i++ {
+		m, err := l.Reveal(src, o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ev, _ := l.Evaluate(m)
+		if ev.Counts["bell"] != 3 || ev.Counts["cherry"] != 3 || len(ev.Wins) != 2 {
+			t.Fatalf("grid %v: wins %v, counts %v", m.Cells, ev.Wins, ev.Counts)
+		}
+		if ev.Counts["diamond"] != 0 {
+			t.Fatalf("grid %v shows the top symbol on a lower win", m.Cells)
+		}
+	}
+}
+
+func TestReveal_NearMiss(t *testing.T) {
+	l := matchLayout()
+	l.NearMisses = []NearMiss{{Probability: 1}}
+	if err := l.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	src := rng.NewSeeded([]byte("tease"))
+	for i := 0; i < 200; i++ {
+		m, err := l.Reveal(src, lose())
+		if err != nil {
+			t.Fatal(err)
+		}
+		ev, _ := l.Evaluate(m)
+		if ev.Counts["diamond"] != 2 || len(ev.Wins) != 0 {
+			t.Fatalf("near-miss grid %v: %d top symbols, wins %v", m.Cells, ev.Counts["diamond"], ev.Wins)
+		}
+	}
+
+	l.NearMisses = []NearMiss{{Symbol: "bell", Count: 3, Probability: 0.5}}
+	if err := l.Validate(); err == nil {
+		t.Error("Validate accepted a near miss that completes a match")
+	}
+	l.NearMisses = []NearMiss{{Probability: 0.6}, {Symbol: "bell", Probability: 0.6}}
+	if err := l.Validate(); err == nil {
+		t.Error("Validate accepted near-miss probabilities above 1")
+	}
+}
+
+func TestReveal_TooFewSymbols(t *testing.T) {
+	// Four duds hold at most 8 cells without a match; the win symbols make up the ninth.
+	l := matchLayout()
+	l.Duds = l.Duds[:4]
+	if err := l.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	m, err := l.Reveal(rng.NewSeeded([]byte("short")), lose())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev, _ := l.Evaluate(m); len(ev.Wins) != 0 {
+		t.Fatalf("grid %v has wins %v", m.Cells, ev.Wins)
+	}
+
+	l.Duds, l.Wins = l.Duds[:2], nil
+	if err := l.Validate(); err == nil {
+		t.Error("Validate accepted a layout that cannot fill a losing grid")
+	}
+	if _, err := l.Reveal(rng.NewSeeded([]byte("short")), lose()); !errors.Is(err, ErrNoRevealMap) {
+		t.Errorf("err = %v, want ErrNoRevealMap", err)
+	}
+}
+
+func TestReveal_SymbolHunt(t *testing.T) {
+	l := matchLayout()
+	l.Mechanic = MechanicSymbolHunt
+	src := rng.NewSeeded([]byte("hunt"))
+	for i := 0; i < 200; i++ {
+		m, err := l.Reveal(src, lose())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev, _ := l.Evaluate(m); len(ev.Wins) != 0 {
+			t.Fatalf("losing hunt grid %v shows %v", m.Cells, ev.Wins)
+		}
+		m, err = l.Reveal(src, &Outcome{Tier: "T_TOP", WinAmount: 100})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev, _ := l.Evaluate(m); len(ev.Wins) != 1 || ev.Wins[0] != "diamond" {
+			t.Fatalf("winning hunt grid %v shows %v", m.Cells, ev.Wins)
+		}
+	}
+}
+
+func TestReveal_SymbolHuntValues(t *testing.T) {
+	l := matchLayout()
+	l.Mechanic = MechanicSymbolHunt
+	l.Values = map[string]float64{"coin_1": 1, "coin_5": 5, "bag_5": 5, "gem": 20}
+	if err := l.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	src := rng.NewSeeded([]byte("values"))
+	combo := &Outcome{Tier: "COMBO", WinAmount: 32, Wins: []Win{{Tier: "T_27", Multiplier: 27}, {Tier: "T_5", Multiplier: 5}}}
+	for i := 0; i < 200; i++ {
+		m, err := l.Reveal(src, combo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ev, err := l.Evaluate(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 27 = 20 + 5 + 1 + 1, then 5.
+		if ev.Prize != 32 || len(ev.Wins) != 5 || ev.Counts["gem"] != 1 || ev.Counts["coin_1"] != 2 {
+			t.Fatalf("map %v prizes %v: wins %v worth %v", m.Cells, m.Prizes, ev.Wins, ev.Prize)
+		}
+	}
+
+	// 0.5x cannot be composed, and 10 coin_1 would not fit the grid: both fall back to one
+	// tier symbol per win.
+	for _, o := range []*Outcome{
+		{Tier: "T_10", WinAmount: 0.5, Wins: []Win{{Tier: "T_10", Multiplier: 0.5}}},
+		{Tier: "T_10", WinAmount: 10, Wins: []Win{{Tier: "T_10", Multiplier: 10}}},
+	} {
+		l.Values = map[string]float64{"coin_1": 1}
+		m, err := l.Reveal(src, o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ev, _ := l.Evaluate(m); m.Prizes != nil || len(ev.Wins) != 1 || ev.Wins[0] != "bell" {
+			t.Errorf("%vx: map %v prizes %v, want the fallback tier symbol", o.WinAmount, m.Cells, m.Prizes)
+		}
+	}
+	if l.CanCompose([]float64{10}) || !l.CanCompose([]float64{3}) {
+		t.Error("CanCompose ignores the grid capacity")
+	}
+
+	l.Values["lemon"] = 1
+	if err := l.Validate(); err == nil {
+		t.Error("Validate accepted a valued dud")
+	}
+}
+
+func TestReveal_Wilds(t *testing.T) {
+	l := matchLayout()
+	l.Wilds, l.WildChance = []string{"wild"}, 1
+	l.NearMisses = []NearMiss{{Probability: 0.5}}
+	if err := l.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	src := rng.NewSeeded([]byte("wilds"))
+	o := &Outcome{Tier: "COMBO", WinAmount: 12, Wins: []Win{{Tier: "T_10"}, {Tier: "T_2"}}}
+	shown := 0
+	for i := 0; i < 300; i++ {
+		for _, out := range []*Outcome{lose(), o} {
+			m, err := l.Reveal(src, out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			ev, _ := l.Evaluate(m)
+			w := ev.Counts["wild"]
+			shown += w
+			if len(ev.Wins) != len(out.WinTiers()) {
+				t.Fatalf("grid %v: wins %v, want %v", m.Cells, ev.Wins, out.WinTiers())
+			}
+			if out == o && (ev.Counts["bell"]+w != 3 || ev.Counts["cherry"]+w != 3) {
+				t.Fatalf("grid %v: wilds must complete each match exactly", m.Cells)
+			}
+		}
+	}
+	if shown == 0 {
+		t.Error("wild_chance 1 never showed a wild")
+	}
+
+	grid := []string{"bell", "wild", "bell", "lemon", "plum", "grape", "pear", "kiwi", "lemon"}
+	if ev, _ := l.Evaluate(RevealMap{Cells: grid}); len(ev.Wins) != 2 {
+		t.Errorf("wins = %v, want bell and the lemon the wild completes", ev.Wins)
+	}
+	l.Mechanic = MechanicSymbolHunt
+	if err := l.Validate(); err == nil {
+		t.Error("Validate accepted wilds on symbol_hunt")
+	}
+}
+
+func TestReveal_Boost(t *testing.T) {
+	l := matchLayout()
+	l.Boosts = map[string]float64{"x2": 2, "x5": 5}
+	if err := l.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	src := rng.NewSeeded([]byte("boost"))
+	o := &Outcome{Tier: "T_50", WinAmount: 50, Wins: []Win{{Tier: "T_10", Multiplier: 50, Boost: 5}}}
+	for i := 0; i < 200; i++ {
+		m, err := l.Reveal(src, o)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ev, _ := l.Evaluate(m)
+		if ev.Boost != 5 || ev.Counts["x5"] != 1 || ev.Counts["x2"] != 0 || len(ev.Wins) != 1 || ev.Wins[0] != "bell" {
+			t.Fatalf("grid %v: wins %v boost %v", m.Cells, ev.Wins, ev.Boost)
+		}
+		m, _ = l.Reveal(src, lose())
+		if ev, _ := l.Evaluate(m); ev.Boost != 1 {
+			t.Fatalf("losing grid %v shows a multiplier", m.Cells)
+		}
+	}
+
+	// Symbol Hunt composes the base prize: 30x = (5 + 1) x5.
+	l.Mechanic = MechanicSymbolHunt
+	l.Values = map[string]float64{"coin_1": 1, "coin_5": 5}
+	hunt := &Outcome{Tier: "T_30", WinAmount: 30, Wins: []Win{{Tier: "T_30", Multiplier: 30, Boost: 5}}}
+	m, err := l.Reveal(src, hunt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ev, _ := l.Evaluate(m); ev.Prize != 30 || len(ev.Wins) != 2 || ev.Counts["x5"] != 1 {
+		t.Errorf("hunt map %v prizes %v: wins %v worth %v", m.Cells, m.Prizes, ev.Wins, ev.Prize)
+	}
+
+	mixed := &Outcome{Tier: "COMBO", WinAmount: 30, Wins: []Win{{Tier: "A", Multiplier: 10, Boost: 2}, {Tier: "B", Multiplier: 20, Boost: 5}}}
+	if _, err := l.Reveal(src, mixed); !errors.Is(err, ErrNoRevealMap) {
+		t.Errorf("mixed boosts: err = %v, want ErrNoRevealMap", err)
+	}
+	if l.CheckBoosts([]float64{0, 3}) == nil || l.CheckBoosts([]float64{2, 2}) != nil {
+		t.Error("CheckBoosts must require one factor the layout has a symbol for")
+	}
+}
+
+func TestPresentationSource(t *testing.T) {
+	l := matchLayout()
+	l.NearMisses = []NearMiss{{Probability: 0.5}}
+	o := &Outcome{Tier: "COMBO", WinAmount: 12, Wins: []Win{{Tier: "T_10"}, {Tier: "T_2"}}}
+	for seed := int64(0); seed < 50; seed++ {
+		for _, out := range []*Outcome{lose(), o} {
+			a, err := l.Reveal(PresentationSource(seed), out)
+			if err != nil {
+				t.Fatal(err)
+			}
+			b, _ := l.Reveal(PresentationSource(seed), out)
+			if fmt.Sprint(a) != fmt.Sprint(b) {
+				t.Fatalf("seed %d: %v then %v", seed, a.Cells, b.Cells)
+			}
+		}
+	}
+	a, _ := l.Reveal(PresentationSource(1), o)
+	b, _ := l.Reveal(PresentationSource(2), o)
+	if fmt.Sprint(a) == fmt.Sprint(b) {
+		t.Error("different seeds gave the same map")
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	l := matchLayout()
+	grid := []string{"bell", "bell", "bell", "lemon", "lemon", "lemon", "plum", "grape", "pear"}
+	ev, err := l.Evaluate(RevealMap{Cells: grid})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ev.Wins) != 2 || ev.Wins[0] != "bell" || ev.Wins[1] != "lemon" {
+		t.Errorf("wins = %v, want [bell lemon] (the dud match counts too)", ev.Wins)
+	}
+	if err := l.check(RevealMap{Cells: grid}, ticket{wins: []string{"bell"}}); err == nil {
+		t.Error("check accepted an accidental dud match")
+	}
+	if _, err := l.Evaluate(RevealMap{Cells: grid[:8]}); err == nil {
+		t.Error("Evaluate accepted a short grid")
+	}
+	if _, err := l.Evaluate(RevealMap{Cells: append(grid[:8:8], "ghost")}); err == nil {
+		t.Error("Evaluate accepted an unknown symbol")
+	}
+}
+
+func targetLayout() Layout {
+	return Layout{
+		Mechanic:       MechanicTargetMatch,
+		Rows:           2,
+		Cols:           4,
+		WinningNumbers: 3,
+		NumberRange:    20,
+		PrizeValues:    []float64{0.5, 1, 2, 5, 10, 100},
+	}
+}
+
+func TestReveal_TargetMatch(t *testing.T) {
+	l := targetLayout()
+	if err := l.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	src := rng.NewSeeded([]byte("target"))
+	cases := []struct {
+		o       *Outcome
+		matches int
+		prize   float64
+	}{
+		{lose(), 0, 0},
+		{&Outcome{Tier: "T5", WinAmount: 5, Wins: []Win{{Tier: "T5", Multiplier: 5}}}, 1, 5},
+		{&Outcome{Tier: "T17", WinAmount: 17.5, Wins: []Win{{Tier: "T17", Multiplier: 17.5}}}, 4, 17.5},
+		{&Outcome{Tier: "COMBO", WinAmount: 102, Wins: []Win{{Tier: "T100", Multiplier: 100}, {Tier: "T2", Multiplier: 2}}}, 2, 102},
+	}
+	for _, c := range cases {
+		for i := 0; i < 100; i++ {
+			m, err := l.Reveal(src, c.o)
+			if err != nil {
+				t.Fatalf("%s: %v", c.o.Tier, err)
+			}
+			if len(m.Cells) != 11 || len(m.Zones) != 2 || m.Zones[1].Start != 3 || m.Zones[1].Rows != 2 {
+				t.Fatalf("%s: cells %v, zones %+v", c.o.Tier, m.Cells, m.Zones)
+			}
+			ev, err := l.Evaluate(m)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(ev.Wins) != c.matches || ev.Prize != c.prize {
+				t.Fatalf("%s: map %v prizes %v shows %d matches worth %v", c.o.Tier, m.Cells, m.Prizes, len(ev.Wins), ev.Prize)
+			}
+		}
+	}
+
+	// 3x needs two cells (2 + 1); 0.25x cannot be made from the prize values.
+	if _, err := l.SplitPrizes([]float64{3}); err != nil {
+		t.Error(err)
+	}
+	if _, err := l.Reveal(src, &Outcome{Tier: "Q", WinAmount: 0.25, Wins: []Win{{Tier: "Q", Multiplier: 0.25}}}); !errors.Is(err, ErrNoRevealMap) {
+		t.Errorf("err = %v, want ErrNoRevealMap", err)
+	}
+	l.NumberRange = 10
+	if err := l.Validate(); err == nil {
+		t.Error("Validate accepted a number range smaller than both zones")
+	}
+}
+
+func TestSolve_Bounds(t *testing.T) {
+	bounds := []bound{{symbol: "a", min: 3, max: 3}, {symbol: "b", min: 1, max: 2}, {symbol: "c", max: 9}}
+	src := rng.NewSeeded([]byte("solve"))
+	for i := 0; i < 500; i++ {
+		grid, err := solve(src, 9, bounds)
+		if err != nil {
+			t.Fatal(err)
+		}
+		counts := map[string]int{}
+		for _, sym := range grid {
+			counts[sym]++
+		}
+		if counts["a"] != 3 || counts["b"] < 1 || counts["b"] > 2 || counts["a"]+counts["b"]+counts["c"] != 9 {
+			t.Fatalf("grid %v breaks the bounds", grid)
+		}
+	}
+	if _, err := solve(src, 9, bounds[:2]); !errors.Is(err, ErrNoRevealMap) {
+		t.Errorf("err = %v, want ErrNoRevealMap", err)
+	}
+}
+
+func TestDealPicks(t *testing.T) {
+	l := matchLayout()
+	l.Mechanic, l.Picks, l.Rows, l.Cols = MechanicPickN, 3, 2, 3
+	l.PrizeValues = []float64{1, 10, 100}
+	if err := l.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	src := rng.NewSeeded([]byte("picks"))
+	combo := &Outcome{Tier: "COMBO", WinAmount: 12, Wins: []Win{{Tier: "T_10", Multiplier: 10}, {Tier: "T_2", Multiplier: 2}}}
+	for i := 0; i < 100; i++ {
+		d, err := l.DealPicks(src, combo)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(d.Found) != 3 || len(d.Rest) != 3 {
+			t.Fatalf("deal %+v: want 3 found and 3 rest", d)
+		}
+		picked := []int{4, 0, 5}
+		m := d.Board(picked)
+		total := 0.0
+		for k, cell := range picked {
+			if m.Cells[cell] != d.Found[k].Symbol {
+				t.Fatalf("pick %d at cell %d shows %q, want %q", k, cell, m.Cells[cell], d.Found[k].Symbol)
+			}
+			total += m.Prizes[cell]
+		}
+		if total != 12 {
+			t.Fatalf("picks %v on %v %v pay %vx, want 12x", picked, m.Cells, m.Prizes, total)
+		}
+		for _, cell := range []int{1, 2, 3} {
+			if m.Cells[cell] == "" {
+				t.Fatalf("unpicked cell %d is empty: %v", cell, m.Cells)
+			}
+		}
+	}
+
+	d, err := l.DealPicks(src, lose())
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, it := range d.Found {
+		if it.Prize != 0 {
+			t.Errorf("losing ticket finds %+v", it)
+		}
+	}
+
+	l.Mechanic = MechanicPickOne
+	if _, err := l.DealPicks(src, combo); !errors.Is(err, ErrNoRevealMap) {
+		t.Errorf("pick_one dealt two wins: err = %v", err)
+	}
+}
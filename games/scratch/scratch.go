@@ -1,10 +1,8 @@
 package scratch
 
 import (
-	"crypto/rand"
-	"math/big"
-
 	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
 )
 
 // Symbol is a scratch card symbol.
@@ -23,29 +21,42 @@ type Outcome struct {
 	WinAmount float64   `json:"winAmount"`
 	Match     bool      `json:"match"` // true if all 3 match
 	Tier      string    `json:"tier,omitempty"`
+	// Wins lists every prize the ticket awards (several for a MULTI_WIN combination);
+	// WinAmount is their total.
+	Wins []Win `json:"wins,omitempty"`
 }
 
-// Multiplier when 3 match (legacy fallback).
-const WinMultiplier = 2.0
+// Win is one prize awarded by a ticket.
+type Win struct {
+	Tier       string  `json:"tier"`
+	Multiplier float64 `json:"multiplier"`
+	Amount     float64 `json:"amount"`
+	// Boost is the multiplier symbol the win is shown with (0 for none): the grid shows a win
+	// worth Multiplier/Boost and a Boost x symbol.
+	Boost float64 `json:"boost,omitempty"`
+}
 
-// secureIntn returns a uniform random int in [0, n) using crypto/rand (CSPRNG).
-func secureIntn(n int) int {
-	if n <= 0 {
-		return 0
-	}
-	max := big.NewInt(int64(n))
-	v, err := rand.Int(rand.Reader, max)
-	if err != nil {
-		return 0
+// Base is the win before its multiplier symbol.
+func (w Win) Base() float64 {
+	if w.Boost > 1 {
+		return w.Multiplier / w.Boost
 	}
-	return int(v.Int64())
+	return w.Multiplier
 }
 
+// Multiplier when 3 match (legacy fallback).
+const WinMultiplier = 2.0
+
 // Generate produces 3 random symbols and win amount using legacy logic (4 symbols, match 3 = 2x).
 func Generate(betAmount float64) Outcome {
+	return GenerateFrom(rng.Default, betAmount)
+}
+
+// GenerateFrom is Generate drawing from src.
+func GenerateFrom(src rng.Source, betAmount float64) Outcome {
 	var s [3]string
 	for i := range s {
-		s[i] = symbols[secureIntn(len(symbols))]
+		s[i] = rng.Pick(src, symbols)
 	}
 	match := s[0] == s[1] && s[1] == s[2]
 	winAmount := 0.0
@@ -62,30 +73,50 @@ func Generate(betAmount float64) Outcome {
 // GenerateWithMath produces outcome from stored game math: weighted tier selection, then multiplier * bet.
 // For display: LOSE = 3 different symbols; WIN tier = 3 same symbol.
 func GenerateWithMath(betAmount float64, math *gamemath.GameMath) (Outcome, bool) {
+	return GenerateWithMathFrom(rng.Default, betAmount, math)
+}
+
+// GenerateWithMathFrom is GenerateWithMath drawing the tier and symbols from src.
+func GenerateWithMathFrom(src rng.Source, betAmount float64, math *gamemath.GameMath) (Outcome, bool) {
 	if math == nil {
 		return Outcome{}, false
 	}
-	tier, ok := math.PickTier()
+	tier, ok := math.PickTierFrom(src)
 	if !ok {
 		return Outcome{}, false
 	}
+	return OutcomeForTierFrom(src, betAmount, tier), true
+}
+
+// OutcomeForTier builds the outcome for an already selected tier (e.g. a ticket drawn from a LIMITED pool).
+func OutcomeForTier(betAmount float64, tier gamemath.PrizeTier) Outcome {
+	return OutcomeForTierFrom(rng.Default, betAmount, tier)
+}
+
+// OutcomeForTierFrom is OutcomeForTier drawing the display symbols from src.
+func OutcomeForTierFrom(src rng.Source, betAmount float64, tier gamemath.PrizeTier) Outcome {
 	winAmount := betAmount * tier.Multiplier
 	var s [3]string
 	if tier.Tier == "LOSE" || tier.Multiplier == 0 {
 		for i := range s {
-			s[i] = symbols[secureIntn(len(symbols))]
+			s[i] = rng.Pick(src, symbols)
 		}
 		for s[0] == s[1] && s[1] == s[2] {
-			s[2] = symbols[secureIntn(len(symbols))]
+			s[2] = rng.Pick(src, symbols)
 		}
 	} else {
-		sym := symbols[secureIntn(len(symbols))]
+		sym := rng.Pick(src, symbols)
 		s[0], s[1], s[2] = sym, sym, sym
 	}
+	var wins []Win
+	for _, c := range tier.Wins() {
+		wins = append(wins, Win{Tier: c.Tier, Multiplier: c.Multiplier, Amount: betAmount * c.Multiplier, Boost: c.Boost})
+	}
 	return Outcome{
 		Symbols:   s,
 		WinAmount: winAmount,
 		Match:     winAmount > 0,
 		Tier:      tier.Tier,
-	}, true
+		Wins:      wins,
+	}
 }
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
 )
 
 func TestGenerate_Legacy(t *testing.T) {
@@ -146,3 +147,48 @@ func TestGenerateWithMath_RTP(t *testing.T) {
 		t.Errorf("RTP %.4f out of expected range [0.35, 0.42] for this prize table", rtp)
 	}
 }
+
+func TestGenerateWithMathFrom_Replay(t *testing.T) {
+	math := testScratchMatch3Math()
+	for i := 0; i < 50; i++ {
+		rec := rng.NewRecorder(rng.NewSeeded([]byte{byte(i)}))
+		want, ok := GenerateWithMathFrom(rec, 1, math)
+		if !ok {
+			t.Fatal("GenerateWithMathFrom failed")
+		}
+		rp := rng.NewReplay(rec.Draws())
+		got, _ := GenerateWithMathFrom(rp, 1, math)
+		if err := rp.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if got.WinAmount != want.WinAmount || got.Symbols != want.Symbols {
+			t.Fatalf("replayed %+v, recorded %+v", got, want)
+		}
+		again, _ := GenerateWithMathFrom(rng.NewSeeded([]byte{byte(i)}), 1, math)
+		if again.WinAmount != want.WinAmount || again.Symbols != want.Symbols {
+			t.Fatalf("same seed gave %+v, want %+v", again, want)
+		}
+	}
+}
+
+func TestOutcomeForTier_MultiWin(t *testing.T) {
+	combo := gamemath.PrizeTier{
+		Tier:       "T2+T5",
+		Multiplier: 7,
+		Components: []gamemath.PrizeComponent{{Tier: "T2", Multiplier: 2}, {Tier: "T5", Multiplier: 5}},
+	}
+	o := OutcomeForTier(10, combo)
+	if o.WinAmount != 70 || o.Tier != "T2+T5" || !o.Match {
+		t.Fatalf("outcome %+v", o)
+	}
+	if len(o.Wins) != 2 || o.Wins[0].Amount != 20 || o.Wins[1].Amount != 50 {
+		t.Errorf("wins %+v, want T2 20 and T5 50", o.Wins)
+	}
+	single := OutcomeForTier(10, gamemath.PrizeTier{Tier: "T2", Multiplier: 2})
+	if len(single.Wins) != 1 || single.Wins[0].Amount != 20 {
+		t.Errorf("single win %+v", single.Wins)
+	}
+	if lose := OutcomeForTier(10, gamemath.PrizeTier{Tier: "LOSE"}); len(lose.Wins) != 0 {
+		t.Errorf("lose wins %+v", lose.Wins)
+	}
+}
@@ -0,0 +1,70 @@
+package scratch
+
+import (
+	"fmt"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
+)
+
+// bound limits how many cells of a grid may show a symbol. Symbols without a bound are not
+// placed at all.
+type bound struct {
+	symbol   string
+	min, max int
+}
+
+// feasible reports whether cells cells can be filled within bounds: the minimums fit and the
+// maximums cover the grid.
+func feasible(cells int, bounds []bound) bool {
+	need, room := 0, 0
+	for _, b := range bounds {
+		if b.min > b.max {
+			return false
+		}
+		need += b.min
+		room += b.max
+	}
+	return need <= cells && room >= cells
+}
+
+// solve fills cells cells so that every symbol's count lies within its bound. Cells are visited
+// in random order and each takes a symbol drawn uniformly from those that leave the rest of the
+// grid satisfiable, so the solver never backtracks and every valid grid can come out. It fails
+// with ErrNoRevealMap when the bounds admit no grid.
+func solve(src rng.Source, cells int, bounds []bound) ([]string, error) {
+	if !feasible(cells, bounds) {
+		return nil, fmt.Errorf("%w: %d cells cannot be filled within the symbol limits", ErrNoRevealMap, cells)
+	}
+	counts := make([]int, len(bounds))
+	need, room := 0, 0 // cells still owed to minimums, cells still allowed by maximums
+	for _, b := range bounds {
+		need += b.min
+		room += b.max
+	}
+	grid := make([]string, cells)
+	candidates := make([]int, 0, len(bounds))
+	for k, cell := range rng.DistinctIndices(src, cells, cells) {
+		left := cells - k - 1 // cells still empty after this one
+		candidates = candidates[:0]
+		for i, b := range bounds {
+			if counts[i] >= b.max {
+				continue
+			}
+			n, r := need, room-1
+			if counts[i] < b.min {
+				n--
+			}
+			if n <= left && r >= left {
+				candidates = append(candidates, i)
+			}
+		}
+		i := rng.Pick(src, candidates)
+		if counts[i] < bounds[i].min {
+			need--
+		}
+		room--
+		counts[i]++
+		grid[cell] = bounds[i].symbol
+	}
+	return grid, nil
+}
@@ -0,0 +1,218 @@
+package scratch
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
+)
+
+// Target Match zones, in reveal-map order.
+const (
+	ZoneWinning = "winning" // Winning Numbers
+	ZoneYours   = "yours"   // Your Numbers, each with a prize
+)
+
+// prizeScale turns bet multipliers into integer units so prizes add up exactly.
+const prizeScale = 10000
+
+// zoneSizes returns the sizes of the Winning Numbers and Your Numbers zones.
+func (l Layout) zoneSizes() (winning, yours int) {
+	winning, yours = l.WinningNumbers, l.YourNumbers
+	if winning <= 0 {
+		winning = 2
+	}
+	if yours <= 0 {
+		yours = l.gridSize()
+	}
+	return winning, yours
+}
+
+func (l Layout) numberRange() int {
+	if l.NumberRange > 0 {
+		return l.NumberRange
+	}
+	w, y := l.zoneSizes()
+	if w+y > 30 {
+		return w + y
+	}
+	return 30
+}
+
+// Zones describes where each Target Match zone sits in the reveal map. Winning Numbers is one
+// row; Your Numbers is Rows x Cols when that matches its size, otherwise one row.
+func (l Layout) Zones() []Zone {
+	if l.Mechanic != MechanicTargetMatch {
+		return nil
+	}
+	w, y := l.zoneSizes()
+	rows, cols := 1, y
+	if l.Rows > 0 && l.Cols > 0 && l.Rows*l.Cols == y {
+		rows, cols = l.Rows, l.Cols
+	}
+	return []Zone{
+		{ID: ZoneWinning, Start: 0, Rows: 1, Cols: w},
+		{ID: ZoneYours, Start: w, Rows: rows, Cols: cols},
+	}
+}
+
+func (l Layout) validateTarget() error {
+	w, y := l.zoneSizes()
+	if y < 1 {
+		return fmt.Errorf("target_match needs at least one Your Numbers cell")
+	}
+	if r := l.numberRange(); r < w+y {
+		return fmt.Errorf("number_range %d is too small for %d winning and %d player numbers", r, w, y)
+	}
+	if len(l.NearMisses) > 0 {
+		return fmt.Errorf("near_miss is only supported for %s", MechanicMatchN)
+	}
+	if len(l.PrizeValues) == 0 {
+		return fmt.Errorf("target_match needs prize_values")
+	}
+	for _, v := range l.PrizeValues {
+		if !(v > 0) || math.IsInf(v, 0) {
+			return fmt.Errorf("prize value %v must be positive", v)
+		}
+	}
+	return nil
+}
+
+// SplitPrizes breaks each winning multiplier into prize values, one per matched Your Numbers
+// cell, so the cells add up to the multiplier. Larger values are tried first, so a prize uses
+// few cells. It fails when a multiplier cannot be made from PrizeValues or the cells run out.
+func (l Layout) SplitPrizes(multipliers []float64) ([]float64, error) {
+	values := make([]int64, 0, len(l.PrizeValues))
+	for _, v := range l.PrizeValues {
+		values = append(values, toUnits(v))
+	}
+	sort.Slice(values, func(i, j int) bool { return values[i] > values[j] })
+	_, cells := l.zoneSizes()
+	var out []float64
+	for _, m := range multipliers {
+		parts, ok := splitUnits(toUnits(m), values, cells-len(out))
+		if !ok {
+			return nil, fmt.Errorf("prize %vx cannot be shown with prize values %v in %d cells", m, l.PrizeValues, cells)
+		}
+		for _, p := range parts {
+			out = append(out, float64(p)/prizeScale)
+		}
+	}
+	return out, nil
+}
+
+func toUnits(m float64) int64 { return int64(math.Round(m * prizeScale)) }
+
+// splitUnits finds at most maxParts values (descending, repeats allowed) that add up to target.
+func splitUnits(target int64, values []int64, maxParts int) ([]int64, bool) {
+	if target == 0 {
+		return nil, true
+	}
+	if maxParts <= 0 {
+		return nil, false
+	}
+	for i, v := range values {
+		if v*int64(maxParts) < target {
+			break // values are descending: the rest are too small as well
+		}
+		if v > target || v <= 0 {
+			continue
+		}
+		if rest, ok := splitUnits(target-v, values[i:], maxParts-1); ok {
+			return append([]int64{v}, rest...), true
+		}
+	}
+	return nil, false
+}
+
+// revealTarget draws distinct Winning Numbers, then Your Numbers: one cell per prize part,
+// showing a winning number and the part as its prize, and the rest numbers that match nothing,
+// showing a random prize value.
+func (l Layout) revealTarget(src rng.Source, o *Outcome) (RevealMap, error) {
+	var multipliers []float64
+	for _, win := range o.Wins {
+		multipliers = append(multipliers, win.Multiplier)
+	}
+	if len(multipliers) == 0 && o.WinAmount > 0 {
+		return RevealMap{}, fmt.Errorf("%w: outcome %q has no prize breakdown", ErrNoRevealMap, o.Tier)
+	}
+	if err := l.validateTarget(); err != nil {
+		return RevealMap{}, fmt.Errorf("%w: %v", ErrNoRevealMap, err)
+	}
+	parts, err := l.SplitPrizes(multipliers)
+	if err != nil {
+		return RevealMap{}, fmt.Errorf("%w: %v", ErrNoRevealMap, err)
+	}
+	w, y := l.zoneSizes()
+	numbers := rng.DistinctIndices(src, l.numberRange(), w+y) // winning numbers, then non-matching
+	m := RevealMap{
+		Cells:  make([]string, w+y),
+		Prizes: make([]float64, w+y),
+		Zones:  l.Zones(),
+	}
+	for i := 0; i < w; i++ {
+		m.Cells[i] = strconv.Itoa(numbers[i] + 1)
+	}
+	matched := rng.DistinctIndices(src, y, len(parts))
+	isMatch := make(map[int]int, len(parts))
+	for k, cell := range matched {
+		isMatch[cell] = k
+	}
+	next := w
+	for cell := 0; cell < y; cell++ {
+		if k, ok := isMatch[cell]; ok {
+			m.Cells[w+cell] = m.Cells[rng.Intn(src, w)]
+			m.Prizes[w+cell] = parts[k]
+			continue
+		}
+		m.Cells[w+cell] = strconv.Itoa(numbers[next] + 1)
+		m.Prizes[w+cell] = rng.Pick(src, l.PrizeValues)
+		next++
+	}
+
+	ev, err := l.Evaluate(m)
+	if err != nil {
+		return RevealMap{}, fmt.Errorf("%w: %v", ErrNoRevealMap, err)
+	}
+	want := 0.0
+	for _, p := range multipliers {
+		want += p
+	}
+	if len(ev.Wins) != len(parts) || toUnits(ev.Prize) != toUnits(want) {
+		return RevealMap{}, fmt.Errorf("%w: grid shows %d matches worth %vx, want %d worth %vx", ErrNoRevealMap, len(ev.Wins), ev.Prize, len(parts), want)
+	}
+	return m, nil
+}
+
+// evaluateTarget counts the Your Numbers cells that show a Winning Number and adds up their
+// prizes.
+func (l Layout) evaluateTarget(m RevealMap) (Evaluation, error) {
+	if len(m.Prizes) != len(m.Cells) {
+		return Evaluation{}, fmt.Errorf("%d prizes for %d cells", len(m.Prizes), len(m.Cells))
+	}
+	w, _ := l.zoneSizes()
+	ev := Evaluation{Counts: make(map[string]int), Boost: 1}
+	winning := make(map[string]bool, w)
+	for i, cell := range m.Cells {
+		n, err := strconv.Atoi(cell)
+		if err != nil || n < 1 || n > l.numberRange() {
+			return Evaluation{}, fmt.Errorf("cell %d: %q is not a number from 1 to %d", i, cell, l.numberRange())
+		}
+		ev.Counts[cell]++
+		if i < w {
+			if winning[cell] {
+				return Evaluation{}, fmt.Errorf("winning number %s appears twice", cell)
+			}
+			winning[cell] = true
+			continue
+		}
+		if winning[cell] {
+			ev.Wins = append(ev.Wins, cell)
+			ev.Prize += m.Prizes[i]
+		}
+	}
+	sort.Strings(ev.Wins)
+	return ev, nil
+}
@@ -0,0 +1,152 @@
+package scratch
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
+)
+
+// defaultWildChance is the share of match_n tickets that show wilds when the layout has some.
+const defaultWildChance = 0.25
+
+// ticket is what a Match-N or Symbol Hunt grid must show.
+type ticket struct {
+	wins  []string  // the symbol of each winning component
+	miss  *NearMiss // the near miss of a losing ticket, or nil
+	wild  string    // the wild symbol shown, wilds times (match_n)
+	wilds int
+	boost string // the multiplier symbol shown, or ""
+}
+
+func (l Layout) wildChance() float64 {
+	if l.WildChance > 0 {
+		return l.WildChance
+	}
+	return defaultWildChance
+}
+
+// validateSpecials checks the wild and multiplier symbols: wilds only for match_n, multipliers
+// for match_n and symbol_hunt, each in no other role.
+func (l Layout) validateSpecials() error {
+	if len(l.Wilds) > 0 && l.Mechanic != MechanicMatchN {
+		return fmt.Errorf("wild symbols are only supported for %s", MechanicMatchN)
+	}
+	if len(l.Boosts) > 0 && l.Mechanic != MechanicMatchN && l.Mechanic != MechanicSymbolHunt {
+		return fmt.Errorf("multiplier symbols are only supported for %s and %s", MechanicMatchN, MechanicSymbolHunt)
+	}
+	if len(l.Wilds) > 0 && l.matchCount() < 2 {
+		return fmt.Errorf("wild symbols need a match_count of at least 2")
+	}
+	if l.WildChance < 0 || l.WildChance > 1 {
+		return fmt.Errorf("wild_chance %v is not in [0, 1]", l.WildChance)
+	}
+	other := make(map[string]bool)
+	for _, list := range [][]string{l.Top, l.Wins, l.Duds} {
+		for _, sym := range list {
+			other[sym] = true
+		}
+	}
+	for _, syms := range l.Tiers {
+		for _, sym := range syms {
+			other[sym] = true
+		}
+	}
+	for sym := range l.Values {
+		other[sym] = true
+	}
+	for _, sym := range l.Wilds {
+		if other[sym] {
+			return fmt.Errorf("wild symbol %q cannot show a prize tier or fill the grid", sym)
+		}
+		if _, ok := l.Boosts[sym]; ok {
+			return fmt.Errorf("symbol %q cannot be both wild and a multiplier", sym)
+		}
+	}
+	for _, sym := range sortedKeys(l.Boosts) {
+		if other[sym] {
+			return fmt.Errorf("multiplier symbol %q cannot show a prize tier or fill the grid", sym)
+		}
+		if b := l.Boosts[sym]; !(b > 1) || math.IsInf(b, 0) {
+			return fmt.Errorf("multiplier symbol %q: factor %v must be above 1", sym, b)
+		}
+	}
+	return nil
+}
+
+// commonBoost returns the multiplier symbol factor a ticket with wins of boosts shows, 1 for
+// none. A multiplier symbol scales every win on the ticket, so the boosts must agree.
+func commonBoost(boosts []float64) (float64, error) {
+	out := 1.0
+	for i, b := range boosts {
+		if b <= 1 {
+			b = 1
+		}
+		if i > 0 && toUnits(b) != toUnits(out) {
+			return 0, fmt.Errorf("wins boosted %vx and %vx cannot share a multiplier symbol", out, b)
+		}
+		out = b
+	}
+	return out, nil
+}
+
+// boostSymbols returns the multiplier symbols with factor b.
+func (l Layout) boostSymbols(b float64) []string {
+	var out []string
+	for _, sym := range sortedKeys(l.Boosts) {
+		if toUnits(l.Boosts[sym]) == toUnits(b) {
+			out = append(out, sym)
+		}
+	}
+	return out
+}
+
+// CheckBoosts checks that a win whose components carry boosts (multiplier symbol factors, 0 for
+// none) can be shown: the boosts agree and the layout has a multiplier symbol of that factor.
+func (l Layout) CheckBoosts(boosts []float64) error {
+	b, err := commonBoost(boosts)
+	if err != nil {
+		return err
+	}
+	if b > 1 && len(l.boostSymbols(b)) == 0 {
+		return fmt.Errorf("no multiplier symbol of %vx", b)
+	}
+	return nil
+}
+
+// pickBoost picks the multiplier symbol o's wins are shown with, or "". No draw is made when
+// the wins have no boost.
+func (l Layout) pickBoost(src rng.Source, o *Outcome) (string, error) {
+	boosts := make([]float64, len(o.Wins))
+	for i, win := range o.Wins {
+		boosts[i] = win.Boost
+	}
+	if err := l.CheckBoosts(boosts); err != nil {
+		return "", fmt.Errorf("%w: %v", ErrNoRevealMap, err)
+	}
+	b, _ := commonBoost(boosts)
+	if b <= 1 {
+		return "", nil
+	}
+	return rng.Pick(src, l.boostSymbols(b)), nil
+}
+
+// pickWilds decides the wilds t shows: on WildChance of match_n tickets, one wild symbol
+// 1 to match_count-1 times, fewer if the grid cannot show t with that many (a wild counts
+// toward every symbol, so each other symbol must stay further from a match). No draw is made
+// when the layout has no wilds.
+func (l Layout) pickWilds(src rng.Source, t ticket) (string, int) {
+	if l.Mechanic != MechanicMatchN || len(l.Wilds) == 0 {
+		return "", 0
+	}
+	if rng.Float64(src) >= l.wildChance() {
+		return "", 0
+	}
+	t.wild = rng.Pick(src, l.Wilds)
+	for t.wilds = 1 + rng.Intn(src, l.matchCount()-1); t.wilds > 0; t.wilds-- {
+		if feasible(l.Cells(), l.bounds(t)) {
+			return t.wild, t.wilds
+		}
+	}
+	return "", 0
+}
@@ -0,0 +1,43 @@
+package rgs
+
+import (
+	"context"
+	"errors"
+	"os"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// ErrNoDatabase is returned by Listen when DATABASE_URL is not set.
+var ErrNoDatabase = errors.New("DATABASE_URL is not set")
+
+// Listen opens a dedicated connection to DATABASE_URL, LISTENs on channel and calls fn with the
+// backend pid of the sender and the payload of every notification. It blocks until ctx is done or the connection fails; callers
+// reconnect by calling it again. Notifications are not delivered through a transaction-mode
+// pooler (PgBouncer, Supabase :6543), so callers should also poll.
+func Listen(ctx context.Context, channel string, fn func(pid uint32, payload string)) error {
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		return ErrNoDatabase
+	}
+	config, err := pgx.ParseConfig(dsn)
+	if err != nil {
+		return err
+	}
+	config.DefaultQueryExecMode = pgx.QueryExecModeSimpleProtocol
+	conn, err := pgx.ConnectConfig(ctx, config)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+	if _, err := conn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+		return err
+	}
+	for {
+		n, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		fn(n.PID, n.Payload)
+	}
+}
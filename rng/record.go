@@ -0,0 +1,84 @@
+package rng
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Draw is one recorded call: Int63n(N) returned V.
+type Draw struct {
+	N int64 `json:"n"`
+	V int64 `json:"v"`
+}
+
+// Recorder wraps a Source and logs every draw, e.g. into a round result for disputes.
+type Recorder struct {
+	src   Source
+	mu    sync.Mutex
+	draws []Draw
+}
+
+func NewRecorder(src Source) *Recorder {
+	if src == nil {
+		src = Default
+	}
+	return &Recorder{src: src}
+}
+
+func (r *Recorder) Int63n(n int64) int64 {
+	v := r.src.Int63n(n)
+	r.mu.Lock()
+	r.draws = append(r.draws, Draw{N: n, V: v})
+	r.mu.Unlock()
+	return v
+}
+
+// Draws returns a copy of the draw log.
+func (r *Recorder) Draws() []Draw {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Draw(nil), r.draws...)
+}
+
+// Replay feeds a recorded draw log back in order. A call whose bound differs from the
+// recording, or a call past the end of the log, is a divergence: it returns 0 and is
+// reported by Err.
+type Replay struct {
+	mu    sync.Mutex
+	draws []Draw
+	pos   int
+	err   error
+}
+
+func NewReplay(draws []Draw) *Replay {
+	return &Replay{draws: draws}
+}
+
+func (r *Replay) Int63n(n int64) int64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return 0
+	}
+	if r.pos >= len(r.draws) {
+		r.err = fmt.Errorf("rng replay: draw %d past end of log", r.pos)
+		return 0
+	}
+	d := r.draws[r.pos]
+	if d.N != n {
+		r.err = fmt.Errorf("rng replay: draw %d asked for n=%d, log has n=%d", r.pos, n, d.N)
+		return 0
+	}
+	r.pos++
+	return d.V
+}
+
+// Err reports the first divergence, or an error if draws were left unused.
+func (r *Replay) Err() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err == nil && r.pos != len(r.draws) {
+		return fmt.Errorf("rng replay: %d of %d draws unused", len(r.draws)-r.pos, len(r.draws))
+	}
+	return r.err
+}
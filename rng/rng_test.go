@@ -0,0 +1,108 @@
+package rng
+
+import (
+	"crypto/rand"
+	"errors"
+	"testing"
+)
+
+func TestSeeded_Deterministic(t *testing.T) {
+	a, b := NewSeeded([]byte("seed")), NewSeeded([]byte("seed"))
+	other := NewSeeded([]byte("seed2"))
+	same := true
+	for i := 0; i < 1000; i++ {
+		n := int64(i%97 + 1)
+		va, vb := a.Int63n(n), b.Int63n(n)
+		if va != vb {
+			t.Fatalf("draw %d: %d != %d", i, va, vb)
+		}
+		if va < 0 || va >= n {
+			t.Fatalf("draw %d: %d out of [0,%d)", i, va, n)
+		}
+		if other.Int63n(n) != va {
+			same = false
+		}
+	}
+	if same {
+		t.Error("different seeds produced the same stream")
+	}
+}
+
+func TestNewSeededHex(t *testing.T) {
+	if _, err := NewSeededHex("zz"); err == nil {
+		t.Error("bad hex should fail")
+	}
+	if _, err := NewSeededHex(""); err == nil {
+		t.Error("empty seed should fail")
+	}
+	seed := NewSeed(NewSeeded([]byte("x")))
+	if len(seed) != 2*SeedSize {
+		t.Fatalf("seed length %d", len(seed))
+	}
+	if _, err := NewSeededHex(seed); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("no entropy") }
+
+func TestCrypto_PanicsWithoutEntropy(t *testing.T) {
+	saved := rand.Reader
+	rand.Reader = failingReader{}
+	defer func() {
+		rand.Reader = saved
+		if recover() == nil {
+			t.Error("Int63n must panic when crypto/rand fails, not return a fixed value")
+		}
+	}()
+	Crypto().Int63n(10)
+}
+
+func TestIntn_Uniform(t *testing.T) {
+	src := NewSeeded([]byte("uniform"))
+	const n, draws = 10, 100_000
+	var counts [n]int
+	for i := 0; i < draws; i++ {
+		counts[Intn(src, n)]++
+	}
+	for v, c := range counts {
+		// expected 10000, sd ~95: allow 6 sd
+		if c < draws/n-600 || c > draws/n+600 {
+			t.Errorf("value %d drawn %d times", v, c)
+		}
+	}
+}
+
+func TestRecorderReplay(t *testing.T) {
+	rec := NewRecorder(NewSeeded([]byte("round")))
+	want := DistinctIndices(rec, 9, 3)
+	wantF := Float64(rec)
+
+	rp := NewReplay(rec.Draws())
+	got := DistinctIndices(rp, 9, 3)
+	gotF := Float64(rp)
+	if err := rp.Err(); err != nil {
+		t.Fatal(err)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("replayed %v, recorded %v", got, want)
+		}
+	}
+	if gotF != wantF {
+		t.Errorf("replayed %v, recorded %v", gotF, wantF)
+	}
+
+	short := NewReplay(rec.Draws())
+	DistinctIndices(short, 9, 3)
+	if short.Err() == nil {
+		t.Error("unused draws should be reported")
+	}
+	wrong := NewReplay(rec.Draws())
+	DistinctIndices(wrong, 8, 3)
+	if wrong.Err() == nil {
+		t.Error("a different bound should be reported as divergence")
+	}
+}
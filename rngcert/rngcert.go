@@ -0,0 +1,121 @@
+// Package rngcert runs statistical randomness tests (chi-square uniformity, runs, serial
+// correlation, gap and poker) against the RGS random source and the functions that map it to
+// game outcomes, and renders the results as a JSON and a text report for certification labs.
+package rngcert
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
+)
+
+// DefaultAlpha is the significance level below which a test fails.
+const DefaultAlpha = 0.001
+
+// Options control a certification run.
+type Options struct {
+	Samples int     // draws per subject
+	Alpha   float64 // 0 = DefaultAlpha
+	// Source describes the source for the report, e.g. "crypto/rand".
+	Source string
+}
+
+// SubjectReport holds the test results for one subject.
+type SubjectReport struct {
+	Name        string       `json:"name"`
+	Description string       `json:"description"`
+	Categories  int          `json:"categories"`
+	Samples     int          `json:"samples"`
+	Tests       []TestResult `json:"tests"`
+	Passed      bool         `json:"passed"`
+}
+
+// Report is a full certification run.
+type Report struct {
+	GeneratedAt time.Time       `json:"generated_at"`
+	Source      string          `json:"source"`
+	Samples     int             `json:"samples_per_subject"`
+	Alpha       float64         `json:"alpha"`
+	Subjects    []SubjectReport `json:"subjects"`
+	Passed      bool            `json:"passed"`
+}
+
+// Run draws opts.Samples values for each subject from src and applies every test.
+func Run(src rng.Source, subjects []Subject, opts Options) (*Report, error) {
+	if opts.Samples < 1000 {
+		return nil, fmt.Errorf("rngcert: need at least 1000 samples, got %d", opts.Samples)
+	}
+	alpha := opts.Alpha
+	if alpha <= 0 {
+		alpha = DefaultAlpha
+	}
+	rep := &Report{
+		GeneratedAt: time.Now().UTC(),
+		Source:      opts.Source,
+		Samples:     opts.Samples,
+		Alpha:       alpha,
+		Passed:      true,
+	}
+	for _, sub := range subjects {
+		s := sample{values: make([]int, opts.Samples), probs: sub.Probs}
+		for i := range s.values {
+			v := sub.Draw(src)
+			if v < 0 || v >= len(sub.Probs) {
+				return nil, fmt.Errorf("rngcert: %s drew %d outside [0, %d)", sub.Name, v, len(sub.Probs))
+			}
+			s.values[i] = v
+		}
+		sr := SubjectReport{
+			Name:        sub.Name,
+			Description: sub.Description,
+			Categories:  len(sub.Probs),
+			Samples:     opts.Samples,
+			Passed:      true,
+		}
+		for _, t := range []TestResult{s.chiSquareUniformity(), s.runs(), s.serialCorrelation(), s.gap(), s.poker()} {
+			if !t.Skipped {
+				t.Passed = t.PValue >= alpha
+			}
+			sr.Passed = sr.Passed && t.Passed
+			sr.Tests = append(sr.Tests, t)
+		}
+		rep.Passed = rep.Passed && sr.Passed
+		rep.Subjects = append(rep.Subjects, sr)
+	}
+	return rep, nil
+}
+
+// String renders the report for humans.
+func (r *Report) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "RNG certification report\n")
+	fmt.Fprintf(&b, "generated:  %s\n", r.GeneratedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "source:     %s\n", r.Source)
+	fmt.Fprintf(&b, "samples:    %d per subject\n", r.Samples)
+	fmt.Fprintf(&b, "alpha:      %g (a test fails when p < alpha)\n", r.Alpha)
+	fmt.Fprintf(&b, "verdict:    %s\n", verdict(r.Passed))
+	for _, s := range r.Subjects {
+		fmt.Fprintf(&b, "\n%s — %s\n", s.Name, s.Description)
+		for _, t := range s.Tests {
+			stat := fmt.Sprintf("%.4f", t.Statistic)
+			if t.DF > 0 {
+				stat += fmt.Sprintf(" (df %d)", t.DF)
+			}
+			v := verdict(t.Passed)
+			if t.Skipped {
+				v = "N/A"
+			}
+			fmt.Fprintf(&b, "  %-22s %-20s p=%-10.6f %-4s %s\n", t.Name, stat, t.PValue, v, t.Detail)
+		}
+	}
+	return b.String()
+}
+
+func verdict(ok bool) string {
+	if ok {
+		return "PASS"
+	}
+	return "FAIL"
+}
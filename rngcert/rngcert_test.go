@@ -0,0 +1,129 @@
+package rngcert
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
+)
+
+func TestChiSquareSF_KnownQuantiles(t *testing.T) {
+	for _, c := range []struct {
+		x  float64
+		df int
+		p  float64
+	}{
+		{3.841459, 1, 0.05},
+		{18.307038, 10, 0.05},
+		{6.634897, 1, 0.01},
+		{124.342113, 100, 0.05},
+	} {
+		if got := chiSquareSF(c.x, c.df); math.Abs(got-c.p) > 1e-5 {
+			t.Errorf("chiSquareSF(%v, %d) = %v want %v", c.x, c.df, got, c.p)
+		}
+	}
+}
+
+func TestDistinctDistribution_Poker(t *testing.T) {
+	// Classic poker test over decimal digits, hands of 5.
+	want := []float64{0, 0.0001, 0.0135, 0.18, 0.504, 0.3024}
+	got := distinctDistribution(uniform(10), 5)
+	for r := range want {
+		if math.Abs(got[r]-want[r]) > 1e-12 {
+			t.Errorf("P(%d distinct) = %v want %v", r, got[r], want[r])
+		}
+	}
+}
+
+func testSubjects(t *testing.T) []Subject {
+	t.Helper()
+	pick, err := PickTierSubject(&gamemath.GameMath{ModelID: "cert", PrizeTable: []gamemath.PrizeTier{
+		{Tier: "LOSE", Weight: 700}, {Tier: "T1", Multiplier: 2, Weight: 250},
+		{Tier: "T2", Multiplier: 5, Weight: 45}, {Tier: "T3", Multiplier: 20, Weight: 5},
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cells, err := DistinctIndicesSubject(9, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return []Subject{RawSubject(256), pick, CrashStepSubject(gamemath.DefaultCrashMath()), NextNumberSubject(), cells}
+}
+
+func TestRun_SeededSourcePasses(t *testing.T) {
+	rep, err := Run(rng.NewSeeded([]byte("certification")), testSubjects(t), Options{Samples: 200_000, Source: "test"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !rep.Passed {
+		t.Errorf("seeded source failed:\n%s", rep)
+	}
+	if len(rep.Subjects) != 5 || len(rep.Subjects[0].Tests) != 5 {
+		t.Fatalf("report shape: %d subjects", len(rep.Subjects))
+	}
+}
+
+// counter is a perfectly uniform but predictable source.
+type counter struct{ i int64 }
+
+func (c *counter) Int63n(n int64) int64 {
+	c.i++
+	return c.i % n
+}
+
+// biased favours low values.
+type biased struct{ src rng.Source }
+
+func (b biased) Int63n(n int64) int64 {
+	v := b.src.Int63n(n)
+	if v > n/2 && b.src.Int63n(10) == 0 {
+		return v - n/2
+	}
+	return v
+}
+
+func TestRun_BadSourcesFail(t *testing.T) {
+	sub := []Subject{NextNumberSubject()}
+	rep, err := Run(&counter{}, sub, Options{Samples: 50_000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	failed := map[string]bool{}
+	for _, tr := range rep.Subjects[0].Tests {
+		failed[tr.Name] = !tr.Passed
+	}
+	if rep.Passed || !failed[TestRuns] || !failed[TestSerialCorrelation] || !failed[TestGap] || !failed[TestPoker] {
+		t.Errorf("counter source should fail runs, serial, gap and poker:\n%s", rep)
+	}
+	if failed[TestChiSquare] {
+		t.Error("counter source is uniform and should pass chi-square")
+	}
+
+	rep, err = Run(biased{rng.NewSeeded([]byte("bias"))}, []Subject{CrashStepSubject(gamemath.DefaultCrashMath())}, Options{Samples: 200_000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rep.Subjects[0].Tests[0].Passed {
+		t.Errorf("biased source should fail chi-square:\n%s", rep)
+	}
+}
+
+func TestDistinctIndicesSubject_Rank(t *testing.T) {
+	sub, err := DistinctIndicesSubject(4, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[int]bool{}
+	src := rng.NewSeeded([]byte("rank"))
+	for i := 0; i < 2000; i++ {
+		seen[sub.Draw(src)] = true
+	}
+	if len(seen) != 12 {
+		t.Errorf("saw %d ranks, want 12", len(seen))
+	}
+	if _, err := DistinctIndicesSubject(100, 10); err == nil {
+		t.Error("oversized selection should be rejected")
+	}
+}
@@ -0,0 +1,95 @@
+package rngcert
+
+import "math"
+
+// minExpected is the smallest expected count per chi-square cell; sparser cells are pooled.
+const minExpected = 5
+
+// chiSquare compares observed counts with expected counts after pooling adjacent cells
+// until each has an expected count of at least minExpected. It returns the statistic, the
+// degrees of freedom (pooled cells - 1) and the upper-tail p-value. df is 0 when fewer than
+// two cells remain, in which case the test is not applicable.
+func chiSquare(observed []int64, expected []float64) (stat float64, df int, p float64) {
+	var obs []float64
+	var exp []float64
+	var o, e float64
+	for i := range expected {
+		o += float64(observed[i])
+		e += expected[i]
+		if e >= minExpected {
+			obs, exp = append(obs, o), append(exp, e)
+			o, e = 0, 0
+		}
+	}
+	if len(exp) == 0 {
+		return 0, 0, 1
+	}
+	obs[len(obs)-1] += o
+	exp[len(exp)-1] += e
+	for i := range exp {
+		d := obs[i] - exp[i]
+		stat += d * d / exp[i]
+	}
+	df = len(exp) - 1
+	if df < 1 {
+		return stat, 0, 1
+	}
+	return stat, df, chiSquareSF(stat, df)
+}
+
+// chiSquareSF is the upper tail P(X >= x) of the chi-square distribution with df degrees of freedom.
+func chiSquareSF(x float64, df int) float64 {
+	if x <= 0 {
+		return 1
+	}
+	return gammaQ(float64(df)/2, x/2)
+}
+
+// normalTwoSided is the two-sided p-value of a standard normal z score.
+func normalTwoSided(z float64) float64 {
+	return math.Erfc(math.Abs(z) / math.Sqrt2)
+}
+
+// gammaQ is the regularized upper incomplete gamma function Q(a, x), computed by series
+// for x < a+1 and by continued fraction otherwise (Numerical Recipes, 6.2).
+func gammaQ(a, x float64) float64 {
+	if x <= 0 {
+		return 1
+	}
+	lg, _ := math.Lgamma(a)
+	if x < a+1 {
+		sum, term := 1/a, 1/a
+		for n := 1; n < 1000; n++ {
+			term *= x / (a + float64(n))
+			sum += term
+			if math.Abs(term) < math.Abs(sum)*1e-15 {
+				break
+			}
+		}
+		return 1 - sum*math.Exp(-x+a*math.Log(x)-lg)
+	}
+	const tiny = 1e-300
+	b := x + 1 - a
+	c := 1 / tiny
+	d := 1 / b
+	h := d
+	for i := 1; i < 1000; i++ {
+		an := -float64(i) * (float64(i) - a)
+		b += 2
+		d = an*d + b
+		if math.Abs(d) < tiny {
+			d = tiny
+		}
+		c = b + an/c
+		if math.Abs(c) < tiny {
+			c = tiny
+		}
+		d = 1 / d
+		del := d * c
+		h *= del
+		if math.Abs(del-1) < 1e-15 {
+			break
+		}
+	}
+	return math.Exp(-x+a*math.Log(x)-lg) * h
+}
@@ -0,0 +1,144 @@
+package rngcert
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games/crash"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/round"
+)
+
+// Subject is one RNG mapping under test: Draw maps the source to a category in
+// [0, len(Probs)), and Probs is the distribution the mapping is supposed to produce.
+type Subject struct {
+	Name        string
+	Description string
+	Probs       []float64
+	Draw        func(src rng.Source) int
+}
+
+func uniform(k int) []float64 {
+	probs := make([]float64, k)
+	for i := range probs {
+		probs[i] = 1 / float64(k)
+	}
+	return probs
+}
+
+// RawSubject tests the source itself through rng.Intn over [0, k).
+func RawSubject(k int) Subject {
+	return Subject{
+		Name:        "rng.Intn",
+		Description: fmt.Sprintf("raw source, uniform integers in [0, %d)", k),
+		Probs:       uniform(k),
+		Draw:        func(src rng.Source) int { return rng.Intn(src, k) },
+	}
+}
+
+// PickTierSubject tests gamemath.PickTier on g; categories are prize table rows and the
+// expected distribution is weight / total weight.
+func PickTierSubject(g *gamemath.GameMath) (Subject, error) {
+	var total int64
+	for _, t := range g.PrizeTable {
+		if t.Weight > 0 {
+			total += t.Weight
+		}
+	}
+	if total <= 0 {
+		return Subject{}, fmt.Errorf("rngcert: model %q has no positive weights", g.ModelID)
+	}
+	probs := make([]float64, len(g.PrizeTable))
+	index := make(map[string]int, len(g.PrizeTable))
+	for i, t := range g.PrizeTable {
+		if t.Weight > 0 {
+			probs[i] = float64(t.Weight) / float64(total)
+		}
+		index[t.Tier] = i
+	}
+	return Subject{
+		Name:        "gamemath.PickTier",
+		Description: fmt.Sprintf("prize tier of model %s (version %s), %d tiers, total weight %d", g.ModelID, g.ModelVersion, len(g.PrizeTable), total),
+		Probs:       probs,
+		Draw: func(src rng.Source) int {
+			t, _ := g.PickTierFrom(src)
+			return index[t.Tier]
+		},
+	}, nil
+}
+
+// crashBands are the lower edges of the crash point bands CrashStepSubject counts, above the
+// instant crash band [1.00, 1.01). Edges at or above the model's cap are dropped.
+var crashBands = []float64{1.01, 1.2, 1.5, 2, 3, 5, 10, 100}
+
+// CrashStepSubject tests crash.GenerateCrashStep on m. Crash points are counted in bands
+// (crashBands, then the cap on its own) whose expected probabilities follow from
+// P(crash point >= x) = m.Reach(x).
+func CrashStepSubject(m gamemath.CrashMath) Subject {
+	top := m.Cap()
+	edges := []float64{1}
+	for _, e := range crashBands {
+		if e < top {
+			edges = append(edges, e)
+		}
+	}
+	edges = append(edges, top)
+	probs := make([]float64, len(edges))
+	for i, e := range edges {
+		probs[i] = m.Reach(e)
+		if i+1 < len(edges) {
+			probs[i] -= m.Reach(edges[i+1])
+		}
+	}
+	return Subject{
+		Name:        "crash.GenerateCrashStep",
+		Description: fmt.Sprintf("crash point, P(x >= m) = %.4f/m up to %.2f, in %d bands", 1-m.HouseEdge, top, len(edges)),
+		Probs:       probs,
+		Draw: func(src rng.Source) int {
+			x := crash.Multiplier(crash.GenerateCrashStepFrom(m, src))
+			return sort.Search(len(edges), func(i int) bool { return edges[i] > x+1e-9 }) - 1
+		},
+	}
+}
+
+// NextNumberSubject tests round.NextNumber, uniform over [MinNumber, MaxNumber].
+func NextNumberSubject() Subject {
+	return Subject{
+		Name:        "round.NextNumber",
+		Description: fmt.Sprintf("Hi/Lo number, uniform in [%d, %d]", round.MinNumber, round.MaxNumber),
+		Probs:       uniform(round.MaxNumber - round.MinNumber + 1),
+		Draw:        func(src rng.Source) int { return round.NextNumberFrom(src) - round.MinNumber },
+	}
+}
+
+// DistinctIndicesSubject tests rng.DistinctIndices(total, count), the reveal-map cell picker.
+// Each ordered selection is ranked into [0, total!/(total-count)!), which must be uniform.
+func DistinctIndicesSubject(total, count int) (Subject, error) {
+	k := 1
+	for i := 0; i < count; i++ {
+		k *= total - i
+		if k > 1_000_000 || k <= 0 {
+			return Subject{}, fmt.Errorf("rngcert: DistinctIndices(%d, %d) has too many outcomes to test", total, count)
+		}
+	}
+	return Subject{
+		Name:        "rng.DistinctIndices",
+		Description: fmt.Sprintf("ordered choice of %d of %d reveal cells, %d outcomes", count, total, k),
+		Probs:       uniform(k),
+		Draw: func(src rng.Source) int {
+			idxs := rng.DistinctIndices(src, total, count)
+			rank := 0
+			for p, v := range idxs {
+				r := v
+				for _, earlier := range idxs[:p] {
+					if earlier < v {
+						r--
+					}
+				}
+				rank = rank*(total-p) + r
+			}
+			return rank
+		},
+	}, nil
+}
@@ -0,0 +1,225 @@
+package rngcert
+
+import (
+	"fmt"
+	"math"
+)
+
+// Test names, as they appear in reports.
+const (
+	TestChiSquare         = "chi_square_uniformity"
+	TestRuns              = "runs"
+	TestSerialCorrelation = "serial_correlation"
+	TestGap               = "gap"
+	TestPoker             = "poker"
+)
+
+// pokerHand is the number of consecutive values per poker-test hand.
+const pokerHand = 5
+
+// TestResult is the outcome of one statistical test on one subject.
+type TestResult struct {
+	Name      string  `json:"name"`
+	Statistic float64 `json:"statistic"`
+	DF        int     `json:"df,omitempty"` // chi-square degrees of freedom
+	PValue    float64 `json:"p_value"`
+	Passed    bool    `json:"passed"`
+	Skipped   bool    `json:"skipped,omitempty"` // not applicable to this subject
+	Detail    string  `json:"detail,omitempty"`
+}
+
+// sample is a drawn sequence of category indices in [0, len(probs)) with their expected
+// probabilities.
+type sample struct {
+	values []int
+	probs  []float64
+}
+
+// splitPrefix returns the category count m whose prefix [0, m) has cumulative probability
+// closest to target (1 <= m < k) and that probability. The prefix is the "hit" event of the
+// runs and gap tests, which lets them work on non-uniform mappings such as PickTier.
+func (s sample) splitPrefix(target float64) (int, float64) {
+	best, bestP := 1, s.probs[0]
+	cum := 0.0
+	for m := 1; m < len(s.probs); m++ {
+		cum += s.probs[m-1]
+		if math.Abs(cum-target) < math.Abs(bestP-target) {
+			best, bestP = m, cum
+		}
+	}
+	return best, bestP
+}
+
+// chiSquareUniformity checks category frequencies against the expected distribution (for
+// uniform mappings this is the classic uniformity test).
+func (s sample) chiSquareUniformity() TestResult {
+	observed := make([]int64, len(s.probs))
+	for _, v := range s.values {
+		observed[v]++
+	}
+	expected := make([]float64, len(s.probs))
+	n := float64(len(s.values))
+	for i, p := range s.probs {
+		expected[i] = p * n
+	}
+	stat, df, p := chiSquare(observed, expected)
+	return TestResult{Name: TestChiSquare, Statistic: stat, DF: df, PValue: p,
+		Detail: fmt.Sprintf("%d categories", len(s.probs))}
+}
+
+// runs is the Wald–Wolfowitz runs test on the indicator value < m, where [0, m) carries
+// about half the probability mass.
+func (s sample) runs() TestResult {
+	m, _ := s.splitPrefix(0.5)
+	var n1, n2, runs float64
+	prev := -1
+	for _, v := range s.values {
+		hit := 0
+		if v < m {
+			hit = 1
+			n1++
+		} else {
+			n2++
+		}
+		if hit != prev {
+			runs++
+			prev = hit
+		}
+	}
+	n := n1 + n2
+	if n1 == 0 || n2 == 0 {
+		return TestResult{Name: TestRuns, PValue: 0, Detail: "sequence never crosses the split"}
+	}
+	mu := 2*n1*n2/n + 1
+	variance := (mu - 1) * (mu - 2) / (n - 1)
+	z := (runs - mu) / math.Sqrt(variance)
+	return TestResult{Name: TestRuns, Statistic: z, PValue: normalTwoSided(z),
+		Detail: fmt.Sprintf("%.0f runs, expected %.1f (split below category %d)", runs, mu, m)}
+}
+
+// serialCorrelation tests the lag-1 autocorrelation of the sequence against zero.
+func (s sample) serialCorrelation() TestResult {
+	n := float64(len(s.values))
+	var mean float64
+	for _, v := range s.values {
+		mean += float64(v)
+	}
+	mean /= n
+	var num, den float64
+	for i, v := range s.values {
+		d := float64(v) - mean
+		den += d * d
+		if i+1 < len(s.values) {
+			num += d * (float64(s.values[i+1]) - mean)
+		}
+	}
+	if den == 0 {
+		return TestResult{Name: TestSerialCorrelation, PValue: 1, Passed: true, Skipped: true, Detail: "constant sequence"}
+	}
+	r := num / den
+	z := (r + 1/(n-1)) * math.Sqrt(n)
+	return TestResult{Name: TestSerialCorrelation, Statistic: r, PValue: normalTwoSided(z),
+		Detail: fmt.Sprintf("lag-1 r = %.6f, z = %.3f", r, z)}
+}
+
+// gap counts the non-hits between successive hits, where a hit is value < m and [0, m)
+// carries about a quarter of the mass, and compares the gap lengths with the geometric
+// distribution.
+func (s sample) gap() TestResult {
+	m, p := s.splitPrefix(0.25)
+	if p <= 0 || p >= 1 {
+		return TestResult{Name: TestGap, PValue: 1, Passed: true, Skipped: true, Detail: "no proper split"}
+	}
+	// Cells 0..t-1 plus a ">= t" tail; t covers 99% of the mass.
+	t := int(math.Ceil(math.Log(0.01) / math.Log(1-p)))
+	if t > 200 {
+		t = 200
+	}
+	observed := make([]int64, t+1)
+	gaps := int64(0)
+	length, started := 0, false
+	for _, v := range s.values {
+		if v >= m {
+			length++
+			continue
+		}
+		if started {
+			if length > t {
+				length = t
+			}
+			observed[length]++
+			gaps++
+		}
+		started, length = true, 0
+	}
+	expected := make([]float64, t+1)
+	q := 1.0
+	for g := 0; g < t; g++ {
+		expected[g] = float64(gaps) * p * q
+		q *= 1 - p
+	}
+	expected[t] = float64(gaps) * q
+	stat, df, pv := chiSquare(observed, expected)
+	return TestResult{Name: TestGap, Statistic: stat, DF: df, PValue: pv,
+		Detail: fmt.Sprintf("%d gaps, hit = category < %d (p = %.4f)", gaps, m, p)}
+}
+
+// poker splits the sequence into hands of pokerHand values and compares the number of
+// distinct values per hand with its exact distribution under the expected probabilities.
+func (s sample) poker() TestResult {
+	hands := len(s.values) / pokerHand
+	observed := make([]int64, pokerHand)
+	seen := make(map[int]struct{}, pokerHand)
+	for h := 0; h < hands; h++ {
+		clear(seen)
+		for _, v := range s.values[h*pokerHand : (h+1)*pokerHand] {
+			seen[v] = struct{}{}
+		}
+		observed[len(seen)-1]++
+	}
+	dist := distinctDistribution(s.probs, pokerHand)
+	expected := make([]float64, pokerHand)
+	for i := range expected {
+		expected[i] = dist[i+1] * float64(hands)
+	}
+	stat, df, p := chiSquare(observed, expected)
+	return TestResult{Name: TestPoker, Statistic: stat, DF: df, PValue: p,
+		Detail: fmt.Sprintf("%d hands of %d", hands, pokerHand)}
+}
+
+// distinctDistribution returns P(r distinct values) for r = 0..h among h independent draws
+// from probs. e[j][m] sums, over ways to use j categories in m draws, the product of
+// q^c/c! for each used category; P(r) = h! * e[r][h].
+func distinctDistribution(probs []float64, h int) []float64 {
+	e := make([][]float64, h+1)
+	for j := range e {
+		e[j] = make([]float64, h+1)
+	}
+	e[0][0] = 1
+	for _, q := range probs {
+		if q <= 0 {
+			continue
+		}
+		for j := h - 1; j >= 0; j-- {
+			for m := h - 1; m >= 0; m-- {
+				if e[j][m] == 0 {
+					continue
+				}
+				term := 1.0
+				for c := 1; m+c <= h; c++ {
+					term *= q / float64(c)
+					e[j+1][m+c] += e[j][m] * term
+				}
+			}
+		}
+	}
+	fact := 1.0
+	for i := 2; i <= h; i++ {
+		fact *= float64(i)
+	}
+	out := make([]float64, h+1)
+	for r := range out {
+		out[r] = fact * e[r][h]
+	}
+	return out
+}
@@ -0,0 +1,81 @@
+package round
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// ConfigStore keeps every game config version rounds were played with, keyed by version, in
+// data/config_versions.json. Results record the version, so a past round can be presented again
+// after the config has changed.
+type ConfigStore struct {
+	mu        sync.Mutex
+	byVersion map[string]json.RawMessage
+	dataDir   string
+}
+
+func NewConfigStore(dataDir string) *ConfigStore {
+	if dataDir == "" {
+		dataDir = "data"
+	}
+	s := &ConfigStore{
+		byVersion: make(map[string]json.RawMessage),
+		dataDir:   dataDir,
+	}
+	s.load()
+	return s
+}
+
+func (s *ConfigStore) path() string {
+	return filepath.Join(s.dataDir, "config_versions.json")
+}
+
+func (s *ConfigStore) load() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := os.ReadFile(s.path())
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal(data, &s.byVersion)
+	if s.byVersion == nil {
+		s.byVersion = make(map[string]json.RawMessage)
+	}
+}
+
+func (s *ConfigStore) save() error {
+	data, err := json.MarshalIndent(s.byVersion, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dataDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path(), data, 0644)
+}
+
+// Put stores config under version unless that version is already stored.
+func (s *ConfigStore) Put(version string, config any) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.byVersion[version]; ok {
+		return nil
+	}
+	data, err := json.Marshal(config)
+	if err != nil {
+		return err
+	}
+	s.byVersion[version] = data
+	return s.save()
+}
+
+// Get decodes the config stored under version into config. It reports false when the version
+// is unknown or does not decode.
+func (s *ConfigStore) Get(version string, config any) bool {
+	s.mu.Lock()
+	data, ok := s.byVersion[version]
+	s.mu.Unlock()
+	return ok && json.Unmarshal(data, config) == nil
+}
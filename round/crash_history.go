@@ -0,0 +1,102 @@
+package round
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/fair"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
+)
+
+// crashHistoryLimit is how many shared crash rounds CrashHistoryStore keeps.
+const crashHistoryLimit = 500
+
+// CrashPoint is a finished shared crash round.
+type CrashPoint struct {
+	RoundID         string    `json:"roundId"`
+	CrashStep       int       `json:"crashStep"`
+	CrashMultiplier float64   `json:"crashMultiplier"`
+	Bets            int       `json:"bets"`
+	StartedAt       time.Time `json:"startedAt"`
+	CrashedAt       time.Time `json:"crashedAt"`
+	// Seed is the round's RNG seed, revealed once it crashed; SeedHash (SHA-256 of Seed) was
+	// published when its betting window opened.
+	Seed     string `json:"seed"`
+	SeedHash string `json:"seedHash"`
+	// Chain places Seed in the crash seed chain and names the salt it was mixed with (nil for
+	// rounds drawn from server seeds alone, before the chain).
+	Chain *fair.ChainProof `json:"chain,omitempty"`
+	// Math is the crash math the crash point was drawn with (nil for rounds recorded before it
+	// was kept).
+	Math *gamemath.CrashMath `json:"math,omitempty"`
+}
+
+// CrashHistoryStore keeps the most recent shared crash rounds, newest last, in
+// data/crash_history.json.
+type CrashHistoryStore struct {
+	mu      sync.Mutex
+	points  []CrashPoint
+	dataDir string
+}
+
+func NewCrashHistoryStore(dataDir string) *CrashHistoryStore {
+	if dataDir == "" {
+		dataDir = "data"
+	}
+	s := &CrashHistoryStore{dataDir: dataDir}
+	s.load()
+	return s
+}
+
+func (s *CrashHistoryStore) path() string {
+	return filepath.Join(s.dataDir, "crash_history.json")
+}
+
+func (s *CrashHistoryStore) load() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := os.ReadFile(s.path())
+	if err != nil {
+		return
+	}
+	_ = json.Unmarshal(data, &s.points)
+}
+
+func (s *CrashHistoryStore) save() error {
+	data, err := json.MarshalIndent(s.points, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dataDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path(), data, 0644)
+}
+
+// Append records a finished round, dropping the oldest beyond the store's limit.
+func (s *CrashHistoryStore) Append(p CrashPoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.points = append(s.points, p)
+	if n := len(s.points) - crashHistoryLimit; n > 0 {
+		s.points = append([]CrashPoint(nil), s.points[n:]...)
+	}
+	return s.save()
+}
+
+// Recent returns up to limit finished rounds, newest first.
+func (s *CrashHistoryStore) Recent(limit int) []CrashPoint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if limit <= 0 || limit > len(s.points) {
+		limit = len(s.points)
+	}
+	out := make([]CrashPoint, 0, limit)
+	for i := len(s.points) - 1; i >= 0 && len(out) < limit; i-- {
+		out = append(out, s.points[i])
+	}
+	return out
+}
@@ -6,17 +6,30 @@ import (
 	"path/filepath"
 	"sync"
 	"time"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
 )
 
-// CrashRound holds state for one crash game round.
+// CrashRound holds state for one crash bet. Bets on the same shared round (GameRoundID) share
+// its crash step and start time.
 type CrashRound struct {
-	RoundID   string    `json:"roundId"`
-	BetID     string    `json:"betId"`
-	Currency  string    `json:"currency"`
-	Amount    float64   `json:"amount"`
-	CrashStep int       `json:"crashStep"`
-	StartedAt time.Time `json:"startedAt"`
-	Settled   bool      `json:"settled"`
+	RoundID     string    `json:"roundId"`
+	GameRoundID string    `json:"gameRoundId,omitempty"`
+	SessionID   string    `json:"sessionId,omitempty"`
+	BetID       string    `json:"betId"`
+	Currency    string    `json:"currency"`
+	Amount      float64   `json:"amount"`
+	CrashStep   int       `json:"crashStep"`
+	StartedAt   time.Time `json:"startedAt"`
+	Settled     bool      `json:"settled"`
+	// AutoCashoutStep, when set, is the step the server cashes the bet out at on its own.
+	AutoCashoutStep int `json:"autoCashoutStep,omitempty"`
+	// RNGSeed is the seed the crash step was drawn from (replay). Its hash is published when the
+	// shared round opens and the seed revealed when it crashes.
+	RNGSeed string `json:"rngSeed,omitempty"`
+	// Math is the crash math of the shared round: its growth curve times the bet and bounds its
+	// auto-cashout step (nil for bets placed before it was kept).
+	Math *gamemath.CrashMath `json:"math,omitempty"`
 }
 
 // CrashStore persists active crash rounds.
@@ -79,19 +92,11 @@ func (s *CrashStore) save() error {
 	return os.WriteFile(s.path(), data, 0644)
 }
 
-func (s *CrashStore) Create(roundID, betID, currency string, amount float64, crashStep int) *CrashRound {
-	r := &CrashRound{
-		RoundID:   roundID,
-		BetID:     betID,
-		Currency:  currency,
-		Amount:    amount,
-		CrashStep: crashStep,
-		StartedAt: time.Now(),
-		Settled:   false,
-	}
+// Create stores a new open bet.
+func (s *CrashStore) Create(r *CrashRound) *CrashRound {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.rounds[roundID] = r
+	s.rounds[r.RoundID] = r
 	_ = s.save()
 	return r
 }
@@ -112,6 +117,34 @@ func (s *CrashStore) Settle(roundID string) {
 	}
 }
 
+// Open returns the bets that are not settled yet.
+func (s *CrashStore) Open() []*CrashRound {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*CrashRound
+	for _, r := range s.rounds {
+		if !r.Settled {
+			out = append(out, r)
+		}
+	}
+	return out
+}
+
+// PruneSettled drops settled bets that started before cutoff.
+func (s *CrashStore) PruneSettled(cutoff time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.rounds)
+	for id, r := range s.rounds {
+		if r.Settled && r.StartedAt.Before(cutoff) {
+			delete(s.rounds, id)
+		}
+	}
+	if len(s.rounds) != n {
+		_ = s.save()
+	}
+}
+
 func (s *CrashStore) Delete(roundID string) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -0,0 +1,270 @@
+package round
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/fair"
+)
+
+// Pick round errors returned by PickStore.Pick.
+var (
+	ErrPickRoundNotFound = errors.New("pick round not found")
+	ErrPickRoundComplete = errors.New("pick round already complete")
+	ErrPickCell          = errors.New("cell is out of range or already picked")
+)
+
+// PickRound is an interactive Pick-One / Pick-N ticket. The outcome and the deal are fixed at
+// purchase, when the bet is debited; the player then opens cells one at a time until Picks cells
+// are open, and the win is credited once the round is complete.
+type PickRound struct {
+	RoundID    string  `json:"roundId"`
+	SessionID  string  `json:"sessionId"`
+	GameID     string  `json:"gameId"`
+	Currency   string  `json:"currency"`
+	DeviceType string  `json:"deviceType,omitempty"`
+	Bet        float64 `json:"bet"`
+	BetID      string  `json:"betId,omitempty"` // the debit's wallet reference
+	Rows       int     `json:"rows"`
+	Cols       int     `json:"cols"`
+	Picks      int     `json:"picks"`
+	// Deal and Outcome are the scratch layer's scratch.PickDeal and scratch.Outcome, encoded:
+	// the round store keeps them without depending on the game.
+	Deal    json.RawMessage `json:"deal"`
+	Outcome json.RawMessage `json:"outcome"`
+	// Picked lists the opened cells in pick order.
+	Picked    []int     `json:"picked"`
+	CreatedAt time.Time `json:"createdAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	// Completed is set once every pick is made, by the player or by auto-complete.
+	Completed    bool      `json:"completed"`
+	AutoComplete bool      `json:"autoComplete,omitempty"`
+	CompletedAt  time.Time `json:"completedAt,omitempty"`
+	// Fair is set in provably fair mode: the seeds and nonce the round was drawn from.
+	Fair *fair.Proof `json:"fair,omitempty"`
+	// PresentationSeed is the seed Deal was drawn from (scratch.PresentationSource).
+	PresentationSeed int64 `json:"presentationSeed,omitempty"`
+	// Record is the round result recorded once the round is complete and paid.
+	Record *Result `json:"record,omitempty"`
+	// Paid is set once the win of the complete round is credited (see SetPaid).
+	Paid bool `json:"paid,omitempty"`
+}
+
+// Cells is the number of cells on the board.
+func (r *PickRound) Cells() int { return r.Rows * r.Cols }
+
+// complete opens the lowest unpicked cells until every pick is made.
+func (r *PickRound) complete(now time.Time, auto bool) {
+	taken := make(map[int]bool, len(r.Picked))
+	for _, c := range r.Picked {
+		taken[c] = true
+	}
+	for cell := 0; len(r.Picked) < r.Picks && cell < r.Cells(); cell++ {
+		if !taken[cell] {
+			r.Picked = append(r.Picked, cell)
+		}
+	}
+	r.Completed, r.AutoComplete, r.CompletedAt = true, auto, now
+}
+
+func (r *PickRound) clone() *PickRound {
+	c := *r
+	c.Picked = append([]int(nil), r.Picked...)
+	return &c
+}
+
+// PickStore persists pick rounds to data/pick_rounds.json so they survive restarts and players
+// can resume after a reconnect. Completed, paid rounds are kept until Prune drops them.
+type PickStore struct {
+	mu      sync.Mutex
+	rounds  map[string]*PickRound
+	dataDir string
+}
+
+func NewPickStore(dataDir string) *PickStore {
+	if dataDir == "" {
+		dataDir = "data"
+	}
+	s := &PickStore{
+		rounds:  make(map[string]*PickRound),
+		dataDir: dataDir,
+	}
+	s.load()
+	return s
+}
+
+func (s *PickStore) path() string {
+	return filepath.Join(s.dataDir, "pick_rounds.json")
+}
+
+func (s *PickStore) load() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := os.ReadFile(s.path())
+	if err != nil {
+		return
+	}
+	var list []*PickRound
+	if err := json.Unmarshal(data, &list); err != nil {
+		return
+	}
+	for _, r := range list {
+		if r != nil && r.RoundID != "" {
+			// Rounds without a record were bought when the win was credited at purchase.
+			if r.Record == nil {
+				r.Paid = true
+			}
+			s.rounds[r.RoundID] = r
+		}
+	}
+}
+
+func (s *PickStore) save() error {
+	list := make([]*PickRound, 0, len(s.rounds))
+	for _, r := range s.rounds {
+		list = append(list, r)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	data, err := json.MarshalIndent(list, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dataDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path(), data, 0644)
+}
+
+// Create stores a new round. A round that cannot be saved is not kept.
+func (s *PickStore) Create(r *PickRound) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rounds[r.RoundID] = r.clone()
+	if err := s.save(); err != nil {
+		delete(s.rounds, r.RoundID)
+		return err
+	}
+	return nil
+}
+
+// Get returns a copy of a round.
+func (s *PickStore) Get(roundID string) (*PickRound, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.rounds[roundID]
+	if !ok {
+		return nil, false
+	}
+	return r.clone(), true
+}
+
+// Open returns the unfinished rounds of a session, oldest first (resume after a reconnect).
+func (s *PickStore) Open(sessionID string) []*PickRound {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*PickRound
+	for _, r := range s.rounds {
+		if r.SessionID == sessionID && !r.Completed {
+			out = append(out, r.clone())
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+// Pick opens cell in round roundID, completing the round with its last pick. A round past its
+// ExpiresAt is auto-completed instead and ErrPickRoundComplete returned with it.
+func (s *PickStore) Pick(roundID string, cell int, now time.Time) (*PickRound, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.rounds[roundID]
+	if !ok {
+		return nil, ErrPickRoundNotFound
+	}
+	if !r.Completed && now.After(r.ExpiresAt) {
+		r.complete(now, true)
+		_ = s.save()
+	}
+	if r.Completed {
+		return r.clone(), ErrPickRoundComplete
+	}
+	if cell < 0 || cell >= r.Cells() {
+		return r.clone(), ErrPickCell
+	}
+	for _, c := range r.Picked {
+		if c == cell {
+			return r.clone(), ErrPickCell
+		}
+	}
+	r.Picked = append(r.Picked, cell)
+	if len(r.Picked) >= r.Picks {
+		r.complete(now, false)
+	}
+	return r.clone(), s.save()
+}
+
+// CompleteExpired auto-completes every unfinished round past its ExpiresAt and returns them.
+func (s *PickStore) CompleteExpired(now time.Time) []*PickRound {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*PickRound
+	for _, r := range s.rounds {
+		if !r.Completed && now.After(r.ExpiresAt) {
+			r.complete(now, true)
+			out = append(out, r.clone())
+		}
+	}
+	if len(out) > 0 {
+		_ = s.save()
+	}
+	return out
+}
+
+// Unpaid returns the complete rounds whose win is not credited yet, oldest first.
+func (s *PickStore) Unpaid() []*PickRound {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var out []*PickRound
+	for _, r := range s.rounds {
+		if r.Completed && !r.Paid {
+			out = append(out, r.clone())
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out
+}
+
+// SetPaid records that the win of the complete round roundID was credited.
+func (s *PickStore) SetPaid(roundID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.rounds[roundID]
+	if !ok {
+		return ErrPickRoundNotFound
+	}
+	if !r.Completed {
+		return errors.New("pick round is not complete")
+	}
+	r.Paid = true
+	return s.save()
+}
+
+// Prune drops paid rounds completed before cutoff.
+func (s *PickStore) Prune(cutoff time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.rounds)
+	for id, r := range s.rounds {
+		if r.Completed && r.Paid && r.CompletedAt.Before(cutoff) {
+			delete(s.rounds, id)
+		}
+	}
+	if len(s.rounds) != n {
+		_ = s.save()
+	}
+}
@@ -0,0 +1,125 @@
+package round
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// newPickRound is a 2x2 round with two picks, created at now and expiring a minute later.
+func newPickRound(id string, now time.Time) *PickRound {
+	return &PickRound{
+		RoundID:   id,
+		SessionID: "sess",
+		GameID:    "PICK",
+		Bet:       1,
+		Rows:      2,
+		Cols:      2,
+		Picks:     2,
+		Picked:    []int{},
+		CreatedAt: now,
+		ExpiresAt: now.Add(time.Minute),
+		Record:    &Result{Tier: "T_5"},
+	}
+}
+
+func TestPickStore_Pick(t *testing.T) {
+	dir := t.TempDir()
+	st := NewPickStore(dir)
+	now := time.Now()
+	if err := st.Create(newPickRound("r1", now)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := st.Pick("nope", 0, now); !errors.Is(err, ErrPickRoundNotFound) {
+		t.Errorf("unknown round: %v", err)
+	}
+	for _, cell := range []int{-1, 4} {
+		if _, err := st.Pick("r1", cell, now); !errors.Is(err, ErrPickCell) {
+			t.Errorf("cell %d: %v", cell, err)
+		}
+	}
+	pr, err := st.Pick("r1", 3, now)
+	if err != nil || pr.Completed || len(pr.Picked) != 1 {
+		t.Fatalf("first pick: %+v, %v", pr, err)
+	}
+	if _, err := st.Pick("r1", 3, now); !errors.Is(err, ErrPickCell) {
+		t.Errorf("picking a cell twice: %v", err)
+	}
+	if pr, err = st.Pick("r1", 0, now); err != nil || !pr.Completed || pr.AutoComplete {
+		t.Fatalf("last pick: %+v, %v", pr, err)
+	}
+	if _, err := st.Pick("r1", 1, now); !errors.Is(err, ErrPickRoundComplete) {
+		t.Errorf("pick after completion: %v", err)
+	}
+
+	// The picks survive a restart.
+	pr, ok := NewPickStore(dir).Get("r1")
+	if !ok || !pr.Completed || len(pr.Picked) != 2 || pr.Picked[0] != 3 || pr.Picked[1] != 0 {
+		t.Errorf("after reload: %+v", pr)
+	}
+}
+
+func TestPickStore_PickAfterExpiryAutoCompletes(t *testing.T) {
+	st := NewPickStore(t.TempDir())
+	now := time.Now()
+	st.Create(newPickRound("r1", now))
+	st.Pick("r1", 2, now)
+
+	pr, err := st.Pick("r1", 3, now.Add(2*time.Minute))
+	if !errors.Is(err, ErrPickRoundComplete) {
+		t.Fatalf("pick after expiry: %v", err)
+	}
+	// The player's pick is kept; the lowest free cell makes up the rest.
+	if !pr.Completed || !pr.AutoComplete || len(pr.Picked) != 2 || pr.Picked[0] != 2 || pr.Picked[1] != 0 {
+		t.Errorf("auto-completed round %+v", pr)
+	}
+	if len(st.Open("sess")) != 0 {
+		t.Error("completed round still listed as open")
+	}
+}
+
+func TestPickStore_CompleteExpired(t *testing.T) {
+	st := NewPickStore(t.TempDir())
+	now := time.Now()
+	st.Create(newPickRound("old", now.Add(-2*time.Minute)))
+	st.Create(newPickRound("new", now))
+
+	done := st.CompleteExpired(now)
+	if len(done) != 1 || done[0].RoundID != "old" || !done[0].AutoComplete || len(done[0].Picked) != 2 {
+		t.Fatalf("completed %+v", done)
+	}
+	if open := st.Open("sess"); len(open) != 1 || open[0].RoundID != "new" {
+		t.Errorf("open rounds %+v", open)
+	}
+	if again := st.CompleteExpired(now); len(again) != 0 {
+		t.Errorf("completed twice: %+v", again)
+	}
+	if unpaid := st.Unpaid(); len(unpaid) != 1 || unpaid[0].RoundID != "old" {
+		t.Errorf("unpaid %+v", unpaid)
+	}
+}
+
+func TestPickStore_PruneKeepsUnpaidRounds(t *testing.T) {
+	st := NewPickStore(t.TempDir())
+	now := time.Now()
+	st.Create(newPickRound("paid", now.Add(-2*time.Minute)))
+	st.Create(newPickRound("unpaid", now.Add(-2*time.Minute)))
+	st.Create(newPickRound("open", now))
+	st.CompleteExpired(now.Add(-30 * time.Second))
+	if err := st.SetPaid("paid"); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SetPaid("open"); err == nil {
+		t.Error("an open round cannot be paid")
+	}
+
+	st.Prune(now)
+	if _, ok := st.Get("paid"); ok {
+		t.Error("paid round completed before the cutoff was kept")
+	}
+	for _, id := range []string{"unpaid", "open"} {
+		if _, ok := st.Get(id); !ok {
+			t.Errorf("%s round was pruned", id)
+		}
+	}
+}
@@ -1,11 +1,17 @@
 package round
 
 import (
+	"bufio"
+	"bytes"
 	"encoding/json"
+	"log"
 	"os"
 	"path/filepath"
 	"sync"
 	"time"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/fair"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
 )
 
 // Result records a settled round for audit (same style as platform transactions.json).
@@ -20,22 +26,88 @@ type Result struct {
 	// Scratch (optional): for idempotent round/start replay
 	Symbols   []string  `json:"symbols,omitempty"`
 	WinAmount float64  `json:"winAmount,omitempty"`
+	// Tier is the prize tier drawn; Wins lists every prize it awarded (MULTI_WIN combinations
+	// award several), as the scratch layer's encoded []scratch.Win.
+	Tier string          `json:"tier,omitempty"`
+	Wins json.RawMessage `json:"wins,omitempty"`
+	// MathHash is the Integrity.ContentHash of the math model that produced the outcome (audit).
+	MathHash     string `json:"mathHash,omitempty"`
+	ModelVersion string `json:"modelVersion,omitempty"`
+	// RNGSeed and RNGDraws replay the round: rng.NewSeededHex(RNGSeed) reproduces every draw,
+	// and RNGDraws is the exact log (rng.NewReplay) for disputes.
+	RNGSeed  string     `json:"rngSeed,omitempty"`
+	RNGDraws []rng.Draw `json:"rngDraws,omitempty"`
+	// Fair is set for provably fair rounds (server seed hash, client seed, nonce).
+	Fair *fair.Proof `json:"fair,omitempty"`
+	// Scratch presentation: the reveal map is a pure function of the game's config version, the
+	// outcome (Tier, Wins) and PresentationSeed, so it can be rebuilt for disputes.
+	GameID           string  `json:"gameId,omitempty"`
+	SessionID        string  `json:"sessionId,omitempty"`
+	Bet              float64 `json:"bet,omitempty"`
+	ConfigVersion    string  `json:"configVersion,omitempty"`
+	PresentationSeed int64   `json:"presentationSeed,omitempty"`
+	// BatchID is set for tickets bought together (POST /api/scratch/play/batch): the bundle was
+	// debited and credited once, as wallet round BatchID.
+	BatchID string `json:"batchId,omitempty"`
+	// Picked is the order a pick round's cells were opened in (row-major indexes); AutoComplete
+	// is set when the timeout made the remaining picks. With the presentation fields they
+	// rebuild the pick board.
+	Picked       []int `json:"picked,omitempty"`
+	AutoComplete bool  `json:"autoComplete,omitempty"`
 }
 
-// ResultsStore appends settled round results to data/round_results.json.
+// ResultsStore appends settled round results to data/round_results.jsonl, one JSON result per
+// line. The file is only ever appended to; an in-memory index of line offsets by round id,
+// built when the store opens, serves lookups, so neither Append nor GetByRoundID reads the
+// whole history. Results written by earlier versions to data/round_results.json (one JSON
+// array) are moved to the log on first open.
 type ResultsStore struct {
 	mu      sync.Mutex
 	dataDir string
+	byRound map[string][]resultRef // oldest first
+	// versions is the math version of each session's latest round of a game, by
+	// sessionID + "\x00" + gameID.
+	versions map[string]playedVersion
+}
+
+// resultRef locates one result in the log.
+type resultRef struct {
+	off int64
+	n   int
+}
+
+type playedVersion struct {
+	version string
+	at      time.Time
+}
+
+// resultHead is what the index keeps of a result.
+type resultHead struct {
+	RoundID      string    `json:"roundId"`
+	SessionID    string    `json:"sessionId"`
+	GameID       string    `json:"gameId"`
+	ModelVersion string    `json:"modelVersion"`
+	SettledAt    time.Time `json:"settledAt"`
 }
 
 func NewResultsStore(dataDir string) *ResultsStore {
 	if dataDir == "" {
 		dataDir = "data"
 	}
-	return &ResultsStore{dataDir: dataDir}
+	rs := &ResultsStore{
+		dataDir:  dataDir,
+		byRound:  make(map[string][]resultRef),
+		versions: make(map[string]playedVersion),
+	}
+	rs.load()
+	return rs
 }
 
 func (rs *ResultsStore) path() string {
+	return filepath.Join(rs.dataDir, "round_results.jsonl")
+}
+
+func (rs *ResultsStore) legacyPath() string {
 	return filepath.Join(rs.dataDir, "round_results.json")
 }
 
@@ -43,50 +115,153 @@ func (rs *ResultsStore) ensureDir() error {
 	return os.MkdirAll(rs.dataDir, 0755)
 }
 
-// Append adds a settled round result to the JSON file (append to array, same as platform ledger).
-func (rs *ResultsStore) Append(r *Result) error {
+// load indexes the log, migrating the legacy array file first if there is no log yet.
+func (rs *ResultsStore) load() {
 	rs.mu.Lock()
 	defer rs.mu.Unlock()
-	if err := rs.ensureDir(); err != nil {
+	if _, err := os.Stat(rs.path()); os.IsNotExist(err) {
+		if err := rs.migrateLocked(); err != nil {
+			log.Printf("round results: migrate %s: %v", rs.legacyPath(), err)
+		}
+	}
+	f, err := os.Open(rs.path())
+	if err != nil {
+		return
+	}
+	defer f.Close()
+	r := bufio.NewReader(f)
+	var off int64
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 && line[len(line)-1] == '\n' {
+			var head resultHead
+			if jerr := json.Unmarshal(line, &head); jerr == nil {
+				rs.indexLocked(head, resultRef{off: off, n: len(line)})
+			} else {
+				log.Printf("round results: %s: skipping bad line at offset %d: %v", rs.path(), off, jerr)
+			}
+			off += int64(len(line))
+		}
+		if err != nil {
+			if len(line) > 0 {
+				// A final line without a newline is a write cut short by a crash; drop it.
+				log.Printf("round results: %s: truncating partial line at offset %d", rs.path(), off)
+				if terr := os.Truncate(rs.path(), off); terr != nil {
+					log.Printf("round results: %s: %v", rs.path(), terr)
+				}
+			}
+			break
+		}
+	}
+}
+
+// migrateLocked copies the legacy array file into the log and renames it. Caller must hold rs.mu.
+func (rs *ResultsStore) migrateLocked() error {
+	data, err := os.ReadFile(rs.legacyPath())
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
 		return err
 	}
-	path := rs.path()
 	var list []*Result
-	data, err := os.ReadFile(path)
-	if err == nil {
-		_ = json.Unmarshal(data, &list)
+	if err := json.Unmarshal(data, &list); err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	for _, r := range list {
+		line, err := json.Marshal(r)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	if err := os.WriteFile(rs.path(), buf.Bytes(), 0644); err != nil {
+		return err
+	}
+	return os.Rename(rs.legacyPath(), rs.legacyPath()+".migrated")
+}
+
+// Append adds a settled round result to the end of the log.
+func (rs *ResultsStore) Append(r *Result) error {
+	line, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	line = append(line, '\n')
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	if err := rs.ensureDir(); err != nil {
+		return err
 	}
-	if list == nil {
-		list = []*Result{}
+	f, err := os.OpenFile(rs.path(), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
 	}
-	list = append(list, r)
-	data, err = json.MarshalIndent(list, "", "  ")
+	defer f.Close()
+	st, err := f.Stat()
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(path, data, 0644)
+	if _, err := f.Write(line); err != nil {
+		// Drop a partial line so the next result starts on a line of its own.
+		_ = f.Truncate(st.Size())
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	head := resultHead{RoundID: r.RoundID, SessionID: r.SessionID, GameID: r.GameID, ModelVersion: r.ModelVersion, SettledAt: r.SettledAt}
+	rs.indexLocked(head, resultRef{off: st.Size(), n: len(line)})
+	return nil
 }
 
-// GetByRoundID returns a settled result by round ID if found (for idempotent replay).
+// indexLocked adds the result at ref to the index. Caller must hold rs.mu.
+func (rs *ResultsStore) indexLocked(h resultHead, ref resultRef) {
+	rs.byRound[h.RoundID] = append(rs.byRound[h.RoundID], ref)
+	if h.SessionID != "" && h.ModelVersion != "" {
+		key := h.SessionID + "\x00" + h.GameID
+		if cur, ok := rs.versions[key]; !ok || !h.SettledAt.Before(cur.at) {
+			rs.versions[key] = playedVersion{version: h.ModelVersion, at: h.SettledAt}
+		}
+	}
+}
+
+// LatestModelVersion returns the math version of the latest round sessionID played of gameID,
+// and when it settled.
+func (rs *ResultsStore) LatestModelVersion(sessionID, gameID string) (string, time.Time, bool) {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+	v, ok := rs.versions[sessionID+"\x00"+gameID]
+	return v.version, v.at, ok
+}
+
+// GetByRoundID returns the latest settled result with round ID roundID, or nil if there is none.
 func (rs *ResultsStore) GetByRoundID(roundID string) (*Result, error) {
 	rs.mu.Lock()
 	defer rs.mu.Unlock()
-	if err := rs.ensureDir(); err != nil {
-		return nil, err
+	refs := rs.byRound[roundID]
+	if len(refs) == 0 {
+		return nil, nil
 	}
-	path := rs.path()
-	data, err := os.ReadFile(path)
+	return rs.readLocked(refs[len(refs)-1])
+}
+
+// readLocked reads the result at ref. Caller must hold rs.mu.
+func (rs *ResultsStore) readLocked(ref resultRef) (*Result, error) {
+	f, err := os.Open(rs.path())
 	if err != nil {
 		return nil, err
 	}
-	var list []*Result
-	if err := json.Unmarshal(data, &list); err != nil {
+	defer f.Close()
+	buf := make([]byte, ref.n)
+	if _, err := f.ReadAt(buf, ref.off); err != nil {
 		return nil, err
 	}
-	for i := len(list) - 1; i >= 0; i-- {
-		if list[i].RoundID == roundID {
-			return list[i], nil
-		}
+	var r Result
+	if err := json.Unmarshal(buf, &r); err != nil {
+		return nil, err
 	}
-	return nil, nil
+	return &r, nil
 }
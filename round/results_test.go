@@ -0,0 +1,98 @@
+package round
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestResultsStore_AppendGet(t *testing.T) {
+	dir := t.TempDir()
+	rs := NewResultsStore(dir)
+	if r, err := rs.GetByRoundID("r1"); err != nil || r != nil {
+		t.Fatalf("empty store: %v %v", r, err)
+	}
+	for _, r := range []*Result{
+		{RoundID: "r1", SessionID: "s1", WinAmount: 1},
+		{RoundID: "r2", SessionID: "s1", WinAmount: 2},
+		{RoundID: "r1", SessionID: "s2", WinAmount: 3},
+	} {
+		if err := rs.Append(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if r, _ := rs.GetByRoundID("r1"); r == nil || r.WinAmount != 3 {
+		t.Errorf("latest r1 = %+v", r)
+	}
+
+	// Reopening indexes the log again.
+	reopened := NewResultsStore(dir)
+	if r, _ := reopened.GetByRoundID("r2"); r == nil || r.WinAmount != 2 {
+		t.Errorf("r2 after reopen = %+v", r)
+	}
+	if err := reopened.Append(&Result{RoundID: "r3"}); err != nil {
+		t.Fatal(err)
+	}
+	if r, _ := reopened.GetByRoundID("r3"); r == nil {
+		t.Error("r3 not found after append")
+	}
+}
+
+func TestResultsStore_MigratesLegacyFile(t *testing.T) {
+	dir := t.TempDir()
+	legacy, _ := json.Marshal([]*Result{{RoundID: "old", BetID: "b1"}})
+	if err := os.WriteFile(filepath.Join(dir, "round_results.json"), legacy, 0644); err != nil {
+		t.Fatal(err)
+	}
+	rs := NewResultsStore(dir)
+	if r, _ := rs.GetByRoundID("old"); r == nil || r.BetID != "b1" {
+		t.Fatalf("migrated result = %+v", r)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "round_results.json")); !os.IsNotExist(err) {
+		t.Error("legacy file should be renamed after migration")
+	}
+}
+
+func TestResultsStore_DropsPartialLine(t *testing.T) {
+	dir := t.TempDir()
+	rs := NewResultsStore(dir)
+	if err := rs.Append(&Result{RoundID: "r1"}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(filepath.Join(dir, "round_results.jsonl"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString(`{"roundId":"torn","bet`)
+	f.Close()
+
+	rs = NewResultsStore(dir)
+	if err := rs.Append(&Result{RoundID: "r2"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []string{"r1", "r2"} {
+		if r, err := NewResultsStore(dir).GetByRoundID(id); err != nil || r == nil {
+			t.Errorf("%s after a torn write: %v %v", id, r, err)
+		}
+	}
+}
+
+func TestResultsStore_LatestModelVersion(t *testing.T) {
+	dir := t.TempDir()
+	rs := NewResultsStore(dir)
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	rs.Append(&Result{RoundID: "a", SessionID: "s", GameID: "g", ModelVersion: "1.0", SettledAt: t0})
+	rs.Append(&Result{RoundID: "b", SessionID: "s", GameID: "g", ModelVersion: "2.0", SettledAt: t0.Add(time.Minute)})
+	rs.Append(&Result{RoundID: "c", SessionID: "s", GameID: "crash", SettledAt: t0.Add(2 * time.Minute)})
+	for _, store := range []*ResultsStore{rs, NewResultsStore(dir)} {
+		v, at, ok := store.LatestModelVersion("s", "g")
+		if !ok || v != "2.0" || !at.Equal(t0.Add(time.Minute)) {
+			t.Errorf("latest version %q %v %v", v, at, ok)
+		}
+		if _, _, ok := store.LatestModelVersion("s", "crash"); ok {
+			t.Error("rounds without a model version should not pin")
+		}
+	}
+}
@@ -1,29 +1,19 @@
 package round
 
 import (
-	"crypto/rand"
 	"encoding/json"
-	"math/big"
 	"os"
 	"path/filepath"
 	"sync"
 	"time"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
 )
 
 const minNum, maxNum = 1, 10
 
-// secureIntn returns a uniform random int in [0, n) using crypto/rand (CSPRNG).
-func secureIntn(n int) int {
-	if n <= 0 {
-		return 0
-	}
-	max := big.NewInt(int64(n))
-	v, err := rand.Int(rand.Reader, max)
-	if err != nil {
-		return 0
-	}
-	return int(v.Int64())
-}
+// MinNumber and MaxNumber bound the Hi/Lo numbers drawn by NextNumber.
+const MinNumber, MaxNumber = minNum, maxNum
 
 // Round holds state for one game round (e.g. Hi/Lo). Persisted to JSON.
 type Round struct {
@@ -33,13 +23,51 @@ type Round struct {
 	Amount        float64   `json:"amount"`
 	CurrentNumber int       `json:"currentNumber"`
 	CreatedAt     time.Time `json:"createdAt"`
+	// RNGSeed seeds every draw of the round (opening and next number) for replay.
+	RNGSeed string `json:"rngSeed,omitempty"`
+}
+
+// Source returns the round's random source positioned after the opening number, so the next
+// draw is the Hi/Lo next number. Rounds without a seed (created before seeding) use rng.Default.
+func (r *Round) Source() rng.Source {
+	src, err := rng.NewSeededHex(r.RNGSeed)
+	if err != nil {
+		return rng.Default
+	}
+	NextNumberFrom(src) // opening number
+	return src
 }
 
 // NextNumber returns a random number in [minNum, maxNum] for Hi/Lo.
 // Uses cryptographically secure RNG (crypto/rand) for fair, unpredictable outcomes.
 func NextNumber() int {
+	return NextNumberFrom(rng.Default)
+}
+
+// NextNumberFrom is NextNumber drawing from src.
+func NextNumberFrom(src rng.Source) int {
 	size := maxNum - minNum + 1
-	return minNum + secureIntn(size)
+	return minNum + rng.Intn(src, size)
+}
+
+// Hi/Lo outcomes.
+const (
+	OutcomeWin  = "win"
+	OutcomeLose = "lose"
+	OutcomePush = "push"
+)
+
+// Resolve settles a Hi/Lo guess ("higher" or "lower") of next against current.
+// Equal numbers push (stake returned); any other choice loses.
+func Resolve(current, next int, choice string) string {
+	switch {
+	case next == current:
+		return OutcomePush
+	case choice == "higher" && next > current, choice == "lower" && next < current:
+		return OutcomeWin
+	default:
+		return OutcomeLose
+	}
 }
 
 // Store holds active rounds and persists to rounds.json (same style as platform data/*.json).
@@ -105,8 +133,15 @@ func (s *Store) save() error {
 	return os.WriteFile(s.roundsPath(), data, 0644)
 }
 
-func (s *Store) Create(roundID, betID, currency string, amount float64) *Round {
-	num := NextNumber()
+// Create opens a Hi/Lo round. The opening number is the first draw from seed (see rng.NewSeed).
+func (s *Store) Create(roundID, betID, currency string, amount float64, seed string) *Round {
+	var num int
+	if src, err := rng.NewSeededHex(seed); err == nil {
+		num = NextNumberFrom(src)
+	} else {
+		seed = ""
+		num = NextNumber()
+	}
 	r := &Round{
 		RoundID:       roundID,
 		BetID:         betID,
@@ -114,6 +149,7 @@ func (s *Store) Create(roundID, betID, currency string, amount float64) *Round {
 		Amount:        amount,
 		CurrentNumber: num,
 		CreatedAt:     time.Now(),
+		RNGSeed:       seed,
 	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
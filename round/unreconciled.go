@@ -0,0 +1,131 @@
+package round
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Unreconciled is a round whose money or record could not be settled: the bet was debited but
+// neither the win credit nor the refund went through, or the round was paid but its result could
+// not be recorded. Either way the RGS cannot tell a retry of the round from a new purchase, so
+// retries are refused until an operator has reconciled the round with the wallet and cleared it.
+type Unreconciled struct {
+	RoundID   string    `json:"roundId"`
+	SessionID string    `json:"sessionId"`
+	GameID    string    `json:"gameId"`
+	BetID     string    `json:"betId,omitempty"` // the debit's wallet reference
+	Bet       float64   `json:"bet"`
+	Win       float64   `json:"win"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// UnreconciledStore keeps the unreconciled rounds in data/unreconciled_rounds.json. A round that
+// cannot be saved is still held in memory, so this process keeps refusing its retries.
+type UnreconciledStore struct {
+	mu      sync.Mutex
+	rounds  map[string]Unreconciled
+	dataDir string
+}
+
+func NewUnreconciledStore(dataDir string) *UnreconciledStore {
+	if dataDir == "" {
+		dataDir = "data"
+	}
+	s := &UnreconciledStore{
+		rounds:  make(map[string]Unreconciled),
+		dataDir: dataDir,
+	}
+	s.load()
+	return s
+}
+
+func (s *UnreconciledStore) path() string {
+	return filepath.Join(s.dataDir, "unreconciled_rounds.json")
+}
+
+func (s *UnreconciledStore) load() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, err := os.ReadFile(s.path())
+	if err != nil {
+		return
+	}
+	var list []Unreconciled
+	if err := json.Unmarshal(data, &list); err != nil {
+		return
+	}
+	for _, u := range list {
+		if u.RoundID != "" {
+			s.rounds[u.RoundID] = u
+		}
+	}
+}
+
+func (s *UnreconciledStore) save() error {
+	data, err := json.MarshalIndent(s.listLocked(), "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.dataDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(s.path(), data, 0644)
+}
+
+func (s *UnreconciledStore) listLocked() []Unreconciled {
+	list := make([]Unreconciled, 0, len(s.rounds))
+	for _, u := range s.rounds {
+		list = append(list, u)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].CreatedAt.Before(list[j].CreatedAt) })
+	return list
+}
+
+// Add holds a round for reconciliation. The error reports a failed save; the round is held
+// either way.
+func (s *UnreconciledStore) Add(u Unreconciled) error {
+	if u.CreatedAt.IsZero() {
+		u.CreatedAt = time.Now()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.rounds[u.RoundID] = u
+	return s.save()
+}
+
+// Get returns a held round.
+func (s *UnreconciledStore) Get(roundID string) (Unreconciled, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.rounds[roundID]
+	return u, ok
+}
+
+// List returns the held rounds, oldest first.
+func (s *UnreconciledStore) List() []Unreconciled {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.listLocked()
+}
+
+// Clear releases a reconciled round; it reports false when the round was not held. A round that
+// cannot be cleared on disk stays held.
+func (s *UnreconciledStore) Clear(roundID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.rounds[roundID]
+	if !ok {
+		return false, nil
+	}
+	delete(s.rounds, roundID)
+	if err := s.save(); err != nil {
+		s.rounds[roundID] = u
+		return true, err
+	}
+	return true, nil
+}
@@ -0,0 +1,15 @@
+-- Ticket pools for LIMITED scratch math (finite instant-win series).
+-- One row per model version: the current series with printed/remaining tickets per tier. Each
+-- version prints its own series, so activating a new version never draws from the old print run.
+-- The RGS locks the row (SELECT ... FOR UPDATE) for every draw, so several instances can share it.
+
+CREATE TABLE IF NOT EXISTS ticket_pools (
+  model_id         text NOT NULL,         -- gamemath.GameMath.ModelID
+  model_version    text NOT NULL DEFAULT '', -- gamemath.GameMath.ModelVersion
+  series_id        text NOT NULL,         -- current series (print run)
+  pool             jsonb NOT NULL,        -- gamemath.Pool JSON (tiers with printed/remaining counts)
+  remaining        bigint NOT NULL DEFAULT 0,
+  created_at       timestamptz NOT NULL DEFAULT now(),
+  updated_at       timestamptz NOT NULL DEFAULT now(),
+  PRIMARY KEY (model_id, model_version)
+);
@@ -0,0 +1,28 @@
+-- Runtime reload of game config.
+-- Every insert/update/delete on game_math, scratch_games and games sends a notification on the
+-- rgs_config_changed channel; each RGS instance LISTENs and reloads the table named in the payload.
+-- Payload: {"table": "<table>", "op": "INSERT|UPDATE|DELETE"}.
+-- Instances that cannot LISTEN (e.g. behind a transaction pooler) pick changes up by polling
+-- (RGS_RELOAD_POLL_INTERVAL).
+
+CREATE OR REPLACE FUNCTION rgs_notify_config_changed() RETURNS trigger AS $$
+BEGIN
+  PERFORM pg_notify('rgs_config_changed', json_build_object('table', TG_TABLE_NAME, 'op', TG_OP)::text);
+  RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS game_math_notify_config ON game_math;
+CREATE TRIGGER game_math_notify_config
+  AFTER INSERT OR UPDATE OR DELETE ON game_math
+  FOR EACH STATEMENT EXECUTE FUNCTION rgs_notify_config_changed();
+
+DROP TRIGGER IF EXISTS scratch_games_notify_config ON scratch_games;
+CREATE TRIGGER scratch_games_notify_config
+  AFTER INSERT OR UPDATE OR DELETE ON scratch_games
+  FOR EACH STATEMENT EXECUTE FUNCTION rgs_notify_config_changed();
+
+DROP TRIGGER IF EXISTS games_notify_config ON games;
+CREATE TRIGGER games_notify_config
+  AFTER INSERT OR UPDATE OR DELETE ON games
+  FOR EACH STATEMENT EXECUTE FUNCTION rgs_notify_config_changed();
@@ -0,0 +1,14 @@
+-- Session pins for versioned game math.
+-- A session keeps the math version it first played with for 24h (gamemath.PinTTL), even after
+-- another version is activated. Pins are shared by every RGS instance and survive restarts; the
+-- RGS deletes expired rows periodically.
+
+CREATE TABLE IF NOT EXISTS game_math_pins (
+  session_id       text NOT NULL,
+  model_id         text NOT NULL,         -- gamemath.GameMath.ModelID
+  model_version    text NOT NULL,         -- pinned gamemath.GameMath.ModelVersion
+  pinned_at        timestamptz NOT NULL,
+  PRIMARY KEY (session_id, model_id)
+);
+
+CREATE INDEX IF NOT EXISTS game_math_pins_pinned_at ON game_math_pins (pinned_at);
@@ -0,0 +1,453 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/fair"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games/crash"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/round"
+)
+
+const (
+	// crashTickInterval is how often a running round announces its multiplier and checks whether
+	// it has crashed.
+	crashTickInterval = 100 * time.Millisecond
+	// crashCooldown is how long a crashed round is shown before the next betting window opens.
+	crashCooldown = 3 * time.Second
+	// crashBetRetention is how long settled bets stay in the crash store (duplicate round ids
+	// and late cashouts get ROUND_EXISTS / ROUND_SETTLED instead of being looked up as new).
+	crashBetRetention = 24 * time.Hour
+	// crashSubscriberBuffer is how many events a live stream may fall behind before it is closed.
+	crashSubscriberBuffer = 64
+)
+
+// Phases of a shared crash round.
+const (
+	crashBetting = "betting"
+	crashRunning = "running"
+	crashCrashed = "crashed"
+)
+
+var errBettingClosed = games.Errorf(http.StatusConflict, "BETTING_CLOSED", "betting is closed for this crash round; bet when the next one opens")
+
+// CrashLiveEvent is a message of the crash live stream (GET /rgs/crash/live). Type is the SSE
+// event name: a phase ("betting", "running", "crashed") when the round enters it or a client
+// connects, "tick" for each step while running, "cashout" when a bet is cashed out.
+type CrashLiveEvent struct {
+	Type     string `json:"type"`
+	RoundID  string `json:"roundId"` // the shared round, not a player's bet
+	Phase    string `json:"phase"`
+	SeedHash string `json:"seedHash"` // SHA-256 of the round's seed, committed before betting
+	// Chain places the round's seed in the crash seed chain and names the salt it is mixed with.
+	Chain fair.ChainProof `json:"chain"`
+	// StartsAtMs is when the multiplier starts to grow; bets are taken until then.
+	StartsAtMs   int64   `json:"startsAtMs"`
+	Step         int     `json:"step"`
+	Multiplier   float64 `json:"multiplier"`
+	Bets         int     `json:"bets"`
+	ServerTimeMs int64   `json:"serverTimeMs"`
+	// Crashed only: where the round crashed, and its seed (crash.GenerateCrashStepFrom with the
+	// round's crash math over fair.ChainSource(seed, chain.salt) recomputes the crash step).
+	CrashStep       int     `json:"crashStep,omitempty"`
+	CrashMultiplier float64 `json:"crashMultiplier,omitempty"`
+	Seed            string  `json:"seed,omitempty"`
+	// Cashout only: the multiplier a bet was cashed out at, and its win.
+	CashoutMultiplier float64 `json:"cashoutMultiplier,omitempty"`
+	WinAmount         float64 `json:"winAmount,omitempty"`
+}
+
+// crashTable runs the shared crash game: a betting window, then one multiplier for every bet
+// until the round's single crash step, then the next round. Bets are CrashRounds in the crash
+// store, tied to their shared round by GameRoundID; the crash engine places and cashes them.
+//
+// Every server runs its own table over its own crash store, so the shared round is shared by
+// the players of one instance: a deployment with several instances must send every crash
+// request (bets, cashouts, the live stream) to the same instance.
+type crashTable struct {
+	s      *Server
+	window time.Duration
+	due    chan struct{} // wakes the settler: bets may have reached their auto-cashout step
+
+	mu      sync.Mutex
+	cur     sharedCrashRound
+	subs    map[chan CrashLiveEvent]struct{}
+	waiting bool // open failed: logged once until a round opens
+}
+
+// sharedCrashRound is the table's current round.
+type sharedCrashRound struct {
+	id        string
+	phase     string
+	math      gamemath.CrashMath // the crash step was drawn with it
+	crashStep int
+	startsAt  time.Time
+	seed      string
+	seedHash  string
+	chain     fair.ChainProof // where seed is in the crash seed chain
+	bets      int
+}
+
+func newCrashTable(s *Server, window time.Duration) *crashTable {
+	if window <= 0 {
+		window = 5 * time.Second
+	}
+	return &crashTable{
+		s:      s,
+		window: window,
+		due:    make(chan struct{}, 1),
+		subs:   make(map[chan CrashLiveEvent]struct{}),
+	}
+}
+
+// run plays rounds until ctx is done. Bets are settled by the settler (see settle), starting
+// with those a previous run left open, so the wallet never holds up the rounds.
+func (t *crashTable) run(ctx context.Context) {
+	go t.settle(ctx)
+	t.wakeSettler()
+	for {
+		startsAt, err := t.open(time.Now())
+		if err != nil {
+			if !sleepUntil(ctx, time.Now().Add(crashCooldown)) {
+				return
+			}
+			continue
+		}
+		if !sleepUntil(ctx, startsAt) {
+			return
+		}
+		t.launch()
+		ticker := time.NewTicker(crashTickInterval)
+		for crashed := false; !crashed; {
+			select {
+			case <-ctx.Done():
+				ticker.Stop()
+				return
+			case now := <-ticker.C:
+				crashed = t.tick(now)
+				t.wakeSettler()
+			}
+		}
+		ticker.Stop()
+		t.crash()
+		if !sleepUntil(ctx, time.Now().Add(crashCooldown)) {
+			return
+		}
+	}
+}
+
+// open starts the betting window of a new round and returns when it closes. The round's seed is
+// the next of the crash seed chain, mixed with the chain's salt; open fails, and no round is
+// played, while the chain is not salted or cannot record the seed as used.
+func (t *crashTable) open(now time.Time) (time.Time, error) {
+	proof, seed, err := t.s.crashChain.Next()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if err != nil {
+		if !t.waiting {
+			log.Printf("crash: no rounds until the seed chain can be used: %v", err)
+		}
+		t.waiting = true
+		return time.Time{}, err
+	}
+	t.waiting = false
+	m := t.s.crashMath
+	t.cur = sharedCrashRound{
+		id:        uuid.New().String(),
+		phase:     crashBetting,
+		math:      m,
+		crashStep: crash.GenerateCrashStepFrom(m, fair.ChainSource(seed, proof.Salt)),
+		startsAt:  now.Add(t.window),
+		seed:      seed,
+		seedHash:  fair.HashServerSeed(seed),
+		chain:     proof,
+	}
+	t.broadcastLocked(t.eventLocked(crashBetting, now))
+	return t.cur.startsAt, nil
+}
+
+// launch closes betting: the multiplier starts to grow.
+func (t *crashTable) launch() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.cur.phase = crashRunning
+	t.broadcastLocked(t.eventLocked(crashRunning, time.Now()))
+}
+
+// tick announces the current step; it reports true once the round has reached its crash step.
+func (t *crashTable) tick(now time.Time) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.stepLocked(now) >= t.cur.crashStep {
+		return true
+	}
+	t.broadcastLocked(t.eventLocked("tick", now))
+	return false
+}
+
+// crash ends the running round: it is announced with its seed and added to the crash history,
+// and the settler is woken to settle every bet still open on it: lost, or cashed out at its
+// auto-cashout step.
+func (t *crashTable) crash() {
+	t.mu.Lock()
+	t.cur.phase = crashCrashed
+	cur := t.cur
+	t.broadcastLocked(t.eventLocked(crashCrashed, time.Now()))
+	t.mu.Unlock()
+
+	err := t.s.crashHistory.Append(round.CrashPoint{
+		RoundID:         cur.id,
+		CrashStep:       cur.crashStep,
+		CrashMultiplier: crash.Multiplier(cur.crashStep),
+		Bets:            cur.bets,
+		StartedAt:       cur.startsAt,
+		CrashedAt:       cur.startsAt.Add(crash.StepTime(cur.math, cur.crashStep)),
+		Seed:            cur.seed,
+		SeedHash:        cur.seedHash,
+		Chain:           &cur.chain,
+		Math:            &cur.math,
+	})
+	if err != nil {
+		log.Printf("crash: round %s: record history: %v", cur.id, err)
+	}
+	t.wakeSettler()
+}
+
+// settle runs settleDue whenever the table wakes it, until ctx is done, and drops bets settled
+// more than crashBetRetention ago. Lost bets and auto-cashouts are paid from here, so a slow
+// wallet does not hold up the round's ticks or the next round.
+func (t *crashTable) settle(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.due:
+			t.settleDue(ctx)
+			t.s.crashStore.PruneSettled(time.Now().Add(-crashBetRetention))
+		}
+	}
+}
+
+func (t *crashTable) wakeSettler() {
+	select {
+	case t.due <- struct{}{}:
+	default:
+	}
+}
+
+// settleDue settles the open bets that are decided by now: their round crashed (lost), or they
+// reached their auto-cashout step first (cashed out at it), through the orchestrator like a
+// status request, so the win is credited and the result recorded without any client call. A
+// bet whose settlement fails (wallet down) stays open and is retried on the next wake.
+func (t *crashTable) settleDue(ctx context.Context) {
+	eng, ok := t.s.registry.Engine("crash")
+	if !ok {
+		return
+	}
+	for _, cr := range t.s.crashStore.Open() {
+		current := t.s.currentCrashStep(cr)
+		if current < cr.CrashStep && !autoCashedOut(cr, current) {
+			continue
+		}
+		if _, err := t.s.actRound(ctx, eng, cr.SessionID, cr.RoundID, games.Action{Name: "status"}); err != nil {
+			log.Printf("crash: settle bet %s of round %s: %v", cr.RoundID, cr.GameRoundID, err)
+		}
+	}
+}
+
+// checkBet checks a bet with the round/start body data could be placed now: the current round
+// is in its betting window and the bet's options suit the round's crash math.
+func (t *crashTable) checkBet(data json.RawMessage) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.takingBetsLocked() {
+		return errBettingClosed
+	}
+	_, err := autoCashoutStep(t.cur.math, data)
+	return err
+}
+
+func (t *crashTable) takingBetsLocked() bool {
+	return t.cur.phase == crashBetting && time.Now().Before(t.cur.startsAt)
+}
+
+// placeBet adds bet, already debited, to the round in its betting window. Its auto-cashout step
+// is bounded by the round's crash math, which the bet keeps.
+func (t *crashTable) placeBet(bet games.Bet) (*round.CrashRound, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if !t.takingBetsLocked() {
+		return nil, errBettingClosed
+	}
+	autoStep, err := autoCashoutStep(t.cur.math, bet.Data)
+	if err != nil {
+		return nil, err
+	}
+	m := t.cur.math
+	t.cur.bets++
+	return t.s.crashStore.Create(&round.CrashRound{
+		RoundID:     bet.RoundID,
+		GameRoundID: t.cur.id,
+		SessionID:   bet.SessionID,
+		BetID:       bet.BetRef,
+		Currency:    bet.Currency,
+		Amount:      bet.Amount,
+		CrashStep:   t.cur.crashStep,
+		StartedAt:   t.cur.startsAt,
+		RNGSeed:     t.cur.seed,
+		Math:        &m,
+
+		AutoCashoutStep: autoStep,
+	}), nil
+}
+
+// cashedOut announces a paid cashout of a bet on gameRoundID.
+func (t *crashTable) cashedOut(gameRoundID string, mult, win float64) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if gameRoundID != t.cur.id {
+		return
+	}
+	ev := t.eventLocked("cashout", time.Now())
+	ev.CashoutMultiplier = mult
+	ev.WinAmount = win
+	t.broadcastLocked(ev)
+}
+
+// stepLocked is the current round's step at now (0 until it starts).
+func (t *crashTable) stepLocked(now time.Time) int {
+	if now.Before(t.cur.startsAt) {
+		return 0
+	}
+	return crash.StepAt(t.cur.math, now.Sub(t.cur.startsAt))
+}
+
+// eventLocked describes the current round at now.
+func (t *crashTable) eventLocked(typ string, now time.Time) CrashLiveEvent {
+	ev := CrashLiveEvent{
+		Type:         typ,
+		RoundID:      t.cur.id,
+		Phase:        t.cur.phase,
+		SeedHash:     t.cur.seedHash,
+		Chain:        t.cur.chain,
+		StartsAtMs:   t.cur.startsAt.UnixMilli(),
+		Bets:         t.cur.bets,
+		ServerTimeMs: now.UnixMilli(),
+	}
+	switch t.cur.phase {
+	case crashRunning:
+		ev.Step = t.stepLocked(now)
+	case crashCrashed:
+		ev.Step = t.cur.crashStep
+		ev.CrashStep = t.cur.crashStep
+		ev.CrashMultiplier = crash.Multiplier(t.cur.crashStep)
+		ev.Seed = t.cur.seed
+	}
+	ev.Multiplier = crash.Multiplier(ev.Step)
+	return ev
+}
+
+// broadcastLocked sends ev to every live stream. A stream too far behind is closed; its client
+// reconnects and starts again from the current state.
+func (t *crashTable) broadcastLocked(ev CrashLiveEvent) {
+	for ch := range t.subs {
+		select {
+		case ch <- ev:
+		default:
+			close(ch)
+			delete(t.subs, ch)
+		}
+	}
+}
+
+// subscribe opens a live stream; its first event is the current state (none before the table
+// has opened a round).
+func (t *crashTable) subscribe() chan CrashLiveEvent {
+	ch := make(chan CrashLiveEvent, crashSubscriberBuffer)
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if t.cur.id != "" {
+		ch <- t.eventLocked(t.cur.phase, time.Now())
+	}
+	t.subs[ch] = struct{}{}
+	return ch
+}
+
+func (t *crashTable) unsubscribe(ch chan CrashLiveEvent) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.subs[ch]; ok {
+		close(ch)
+		delete(t.subs, ch)
+	}
+}
+
+// sleepUntil waits until at; it reports false when ctx is done first.
+func sleepUntil(ctx context.Context, at time.Time) bool {
+	timer := time.NewTimer(time.Until(at))
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
+}
+
+// handleCrashLive streams the shared crash game as server-sent events (GET /rgs/crash/live):
+// the current state first, then every CrashLiveEvent until the client disconnects.
+func (s *Server) handleCrashLive(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming unsupported", "TECHNICAL_ERROR")
+		return
+	}
+	ch := s.crashTable.subscribe()
+	defer s.crashTable.unsubscribe(ch)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no") // no proxy buffering (nginx)
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-ch:
+			if !ok {
+				return
+			}
+			data, _ := json.Marshal(ev)
+			if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", ev.Type, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// handleCrashHistory returns finished shared crash rounds, newest first, each with the crash
+// math its crash point was drawn with, and the math and seed chain new rounds are drawn with
+// (GET /rgs/crash/history?limit=50).
+func (s *Server) handleCrashHistory(w http.ResponseWriter, r *http.Request) {
+	limit := 50
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n <= 0 {
+			writeError(w, http.StatusBadRequest, "limit must be a positive integer", "INVALID_REQUEST")
+			return
+		}
+		limit = n
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"rounds": s.crashHistory.Recent(limit), "math": s.crashMath, "chain": s.crashChain.Current()})
+}
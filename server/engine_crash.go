@@ -0,0 +1,274 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/fair"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games/crash"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/round"
+)
+
+// CrashRoundStartResponse is a bet on the shared crash round GameRoundID. RoundID is the bet's
+// own round (cashout, status); StartedAtMs is when the shared multiplier starts to grow.
+type CrashRoundStartResponse struct {
+	RoundID     string `json:"roundId"`
+	GameRoundID string `json:"gameRoundId"`
+	StartedAtMs int64  `json:"startedAtMs"`
+	SeedHash    string `json:"seedHash"` // SHA-256 of the shared round's seed, revealed when it crashes
+	// AutoCashout is the multiplier the server cashes the bet out at, when one was set.
+	AutoCashout float64 `json:"autoCashout,omitempty"`
+	Error       string  `json:"error,omitempty"`
+}
+
+// CrashBetOptions are the crash fields of a round/start body.
+type CrashBetOptions struct {
+	// AutoCashout is a multiplier (e.g. 2.5) the server cashes the bet out at on its own, whether
+	// or not the client is still connected. 0 for none.
+	AutoCashout float64 `json:"auto_cashout"`
+	// Legacy / alternate name (same meaning)
+	AutoCashoutAlt float64 `json:"autoCashout"`
+}
+
+// Crash round/cashout
+type CrashCashoutRequest struct {
+	Token   string `json:"token"`
+	RoundID string `json:"roundId"`
+	// Step the player saw when cashing out; a step the round has not reached yet (or none) cashes
+	// out at the current step.
+	Step int `json:"step"`
+}
+
+type CrashCashoutResponse struct {
+	RoundID      string  `json:"roundId"`
+	CashedOut    bool    `json:"cashedOut"`
+	WinAmount    float64 `json:"winAmount"`
+	BalanceDelta float64 `json:"balanceDelta"`
+	Crashed      bool    `json:"crashed"`
+	CrashStep    int     `json:"crashStep,omitempty"`
+	Multiplier   float64 `json:"multiplier,omitempty"` // cashed out at
+	Error        string  `json:"error,omitempty"`
+}
+
+// crashEngine places bets on the shared crash round (see crashTable): the multiplier grows along
+// the round's crash math from the round's start until its crash step, and a player cashes out
+// before that to win. The bet is debited before it is placed, during the round's betting window.
+type crashEngine struct {
+	s *Server
+}
+
+func (e *crashEngine) Describe() games.EngineInfo {
+	return games.EngineInfo{ID: "crash", Name: "Crash", Actions: []string{"cashout", "status"}}
+}
+
+// CheckBet rejects bets while no round takes them, and invalid bet options, before the debit.
+func (e *crashEngine) CheckBet(ctx context.Context, bet games.Bet) error {
+	return e.s.crashTable.checkBet(bet.Data)
+}
+
+// Start places the bet on the round taking bets.
+func (e *crashEngine) Start(ctx context.Context, bet games.Bet) (*games.Round, error) {
+	if _, ok := e.s.crashStore.Get(bet.RoundID); ok {
+		return nil, games.Errorf(http.StatusConflict, "ROUND_EXISTS", "round already started")
+	}
+	cr, err := e.s.crashTable.placeBet(bet)
+	if err != nil {
+		return nil, err
+	}
+	view := CrashRoundStartResponse{
+		RoundID:     cr.RoundID,
+		GameRoundID: cr.GameRoundID,
+		StartedAtMs: cr.StartedAt.UnixMilli(),
+		SeedHash:    fair.HashServerSeed(cr.RNGSeed),
+	}
+	if cr.AutoCashoutStep > 0 {
+		view.AutoCashout = crash.Multiplier(cr.AutoCashoutStep)
+	}
+	return &games.Round{Bet: bet, View: view}, nil
+}
+
+// autoCashoutStep returns the step of the auto-cashout multiplier in the round/start body data:
+// the first step at or above it, or 0 when none is set. It must not exceed m's cap.
+func autoCashoutStep(m gamemath.CrashMath, data json.RawMessage) (int, error) {
+	var opts CrashBetOptions
+	if len(data) > 0 && json.Unmarshal(data, &opts) != nil {
+		return 0, games.Errorf(http.StatusBadRequest, "INVALID_BODY", "invalid body")
+	}
+	mult := opts.AutoCashout
+	if mult == 0 {
+		mult = opts.AutoCashoutAlt
+	}
+	if mult == 0 {
+		return 0, nil
+	}
+	lo, hi := crash.Multiplier(1), crash.Multiplier(crash.MaxStep(m))
+	if mult < lo || mult > hi {
+		return 0, games.Errorf(http.StatusBadRequest, "INVALID_AUTO_CASHOUT", fmt.Sprintf("auto_cashout must be between %.2f and %.2f", lo, hi))
+	}
+	return crash.TargetStep(mult), nil
+}
+
+// Act handles "cashout" (body: CrashCashoutRequest) and "status". Either decides the bet once
+// its round has crashed, or has reached the bet's auto-cashout step; a cashout before the crash
+// step, or an auto-cashout step at or below it, wins the bet times the step's multiplier.
+func (e *crashEngine) Act(ctx context.Context, roundID string, act games.Action) (*games.Round, error) {
+	switch act.Name {
+	case "cashout":
+		var req CrashCashoutRequest
+		if err := json.Unmarshal(act.Data, &req); err != nil {
+			return nil, games.Errorf(http.StatusBadRequest, "INVALID_BODY", "invalid body")
+		}
+		return e.cashout(roundID, req.Step)
+	case "status":
+		return e.status(roundID), nil
+	}
+	return nil, games.ErrUnknownAction
+}
+
+func (e *crashEngine) cashout(roundID string, step int) (*games.Round, error) {
+	cr, ok := e.s.crashStore.Get(roundID)
+	if !ok {
+		return nil, games.ErrRoundNotFound
+	}
+	if cr.Settled {
+		return nil, games.ErrRoundSettled
+	}
+	if time.Now().Before(cr.StartedAt) {
+		return nil, games.Errorf(http.StatusConflict, "ROUND_NOT_STARTED", "round has not started")
+	}
+	current := e.s.currentCrashStep(cr)
+	switch {
+	case autoCashedOut(cr, current):
+		step = cr.AutoCashoutStep // the server cashed out at the target first
+	case current >= cr.CrashStep:
+		// Crashed before cash out - lose
+		rnd := crashRound(cr)
+		rnd.Settled = true
+		rnd.View = CrashCashoutResponse{
+			RoundID:      roundID,
+			CashedOut:    false,
+			Crashed:      true,
+			CrashStep:    cr.CrashStep,
+			WinAmount:    0,
+			BalanceDelta: -cr.Amount,
+		}
+		return rnd, nil
+	case step <= 0 || step > current:
+		step = current
+	}
+	rnd := cashedOutCrashBet(cr, step)
+	rnd.View = CrashCashoutResponse{
+		RoundID:      roundID,
+		CashedOut:    true,
+		Crashed:      false,
+		WinAmount:    rnd.Win,
+		BalanceDelta: rnd.Win - cr.Amount,
+		Multiplier:   crash.Multiplier(step),
+	}
+	return rnd, nil
+}
+
+// cashedOutCrashBet is cr decided as cashed out at step. State is the step.
+func cashedOutCrashBet(cr *round.CrashRound, step int) *games.Round {
+	rnd := crashRound(cr)
+	rnd.Settled = true
+	rnd.Win = cr.Amount * crash.Multiplier(step)
+	rnd.State = step
+	return rnd
+}
+
+// autoCashedOut reports whether cr reached its auto-cashout step by current. A target equal to
+// the crash point is reached, so it pays (gamemath.CrashMath counts a win when the crash point
+// is at least the target).
+func autoCashedOut(cr *round.CrashRound, current int) bool {
+	return cr.AutoCashoutStep > 0 && cr.AutoCashoutStep <= cr.CrashStep && current >= cr.AutoCashoutStep
+}
+
+// status reports the current step. A bet whose round has crashed is decided (lost) by the first
+// status that sees it, one that reached its auto-cashout step (cashed out) likewise.
+func (e *crashEngine) status(roundID string) *games.Round {
+	cr, ok := e.s.crashStore.Get(roundID)
+	if !ok {
+		return &games.Round{
+			Bet: games.Bet{RoundID: roundID},
+			View: map[string]interface{}{
+				"currentStep": 0,
+				"crashed":     false,
+				"roundId":     roundID,
+			},
+		}
+	}
+	currentStep := e.s.currentCrashStep(cr)
+	crashed := currentStep >= cr.CrashStep
+	view := map[string]interface{}{
+		"roundId":     roundID,
+		"gameRoundId": cr.GameRoundID,
+		"currentStep": currentStep,
+		"multiplier":  crash.Multiplier(currentStep),
+		"crashed":     crashed,
+	}
+	if crashed {
+		view["crashStep"] = cr.CrashStep
+		view["crashMultiplier"] = crash.Multiplier(cr.CrashStep)
+	}
+	if cr.AutoCashoutStep > 0 {
+		view["autoCashout"] = crash.Multiplier(cr.AutoCashoutStep)
+	}
+	if autoCashedOut(cr, currentStep) {
+		rnd := cashedOutCrashBet(cr, cr.AutoCashoutStep)
+		rnd.Settled = !cr.Settled
+		view["cashedOut"] = true
+		view["winAmount"] = rnd.Win
+		rnd.View = view
+		return rnd
+	}
+	rnd := crashRound(cr)
+	rnd.Settled = crashed && !cr.Settled
+	rnd.View = view
+	return rnd
+}
+
+// Settle ends the bet in the crash store; a cashout is announced on the live stream.
+func (e *crashEngine) Settle(ctx context.Context, rnd *games.Round) error {
+	e.s.crashStore.Settle(rnd.RoundID)
+	if step, ok := rnd.State.(int); ok {
+		if cr, ok := e.s.crashStore.Get(rnd.RoundID); ok {
+			e.s.crashTable.cashedOut(cr.GameRoundID, crash.Multiplier(step), rnd.Win)
+		}
+	}
+	return nil
+}
+
+// crashRound is the orchestrator's view of cr.
+func crashRound(cr *round.CrashRound) *games.Round {
+	return &games.Round{
+		Bet: games.Bet{
+			RoundID:   cr.RoundID,
+			GameID:    "crash",
+			SessionID: cr.SessionID,
+			Currency:  cr.Currency,
+			Amount:    cr.Amount,
+			BetRef:    cr.BetID,
+		},
+		Record: &round.Result{RNGSeed: cr.RNGSeed},
+	}
+}
+
+// currentCrashStep is the step cr's round has reached along the growth curve of its crash math.
+func (s *Server) currentCrashStep(cr *round.CrashRound) int {
+	return crash.StepAt(s.crashRoundMath(cr), time.Since(cr.StartedAt))
+}
+
+// crashRoundMath is the crash math of cr's round; bets placed before rounds kept it were placed
+// under the server's.
+func (s *Server) crashRoundMath(cr *round.CrashRound) gamemath.CrashMath {
+	if cr.Math != nil {
+		return *cr.Math
+	}
+	return s.crashMath
+}
@@ -0,0 +1,290 @@
+package server
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/config"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/fair"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games/crash"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/round"
+)
+
+func TestAutoCashoutStep(t *testing.T) {
+	m := gamemath.CrashMath{HouseEdge: 0.01, MaxMultiplier: 100, GrowthRate: gamemath.DefaultCrashGrowthRate}
+	cases := []struct {
+		body string
+		step int
+		code string
+	}{
+		{``, 0, ""},
+		{`{}`, 0, ""},
+		{`{"auto_cashout":2}`, 100, ""},
+		{`{"autoCashout":2.5}`, 150, ""},
+		{`{"auto_cashout":1.234}`, 24, ""}, // rounded up to the next step
+		{`{"auto_cashout":1.01}`, 1, ""},
+		{`{"auto_cashout":100}`, 9900, ""},
+		{`{"auto_cashout":1}`, 0, "INVALID_AUTO_CASHOUT"},
+		{`{"auto_cashout":100.01}`, 0, "INVALID_AUTO_CASHOUT"},
+		{`{"auto_cashout":-2}`, 0, "INVALID_AUTO_CASHOUT"},
+		{`{"auto_cashout":"2"}`, 0, "INVALID_BODY"},
+	}
+	for _, c := range cases {
+		step, err := autoCashoutStep(m, json.RawMessage(c.body))
+		if c.code != "" {
+			if gerr := wantCode(t, err, c.code); gerr.Status != http.StatusBadRequest {
+				t.Errorf("%s: status %d", c.body, gerr.Status)
+			}
+			continue
+		}
+		if err != nil || step != c.step {
+			t.Errorf("%s: step %d, %v; want %d", c.body, step, err, c.step)
+		}
+	}
+}
+
+func TestAutoCashedOut(t *testing.T) {
+	cases := []struct {
+		auto, crashStep, current int
+		want                     bool
+	}{
+		{0, 300, 500, false},   // no target
+		{100, 300, 99, false},  // target not reached yet
+		{100, 300, 100, true},  // target reached before the crash
+		{300, 300, 300, true},  // a target equal to the crash step pays
+		{301, 300, 300, false}, // a target above the crash step loses
+		{301, 300, 500, false},
+	}
+	for _, c := range cases {
+		cr := &round.CrashRound{AutoCashoutStep: c.auto, CrashStep: c.crashStep}
+		if got := autoCashedOut(cr, c.current); got != c.want {
+			t.Errorf("auto %d crash %d at %d: %v want %v", c.auto, c.crashStep, c.current, got, c.want)
+		}
+	}
+}
+
+// startedCrashBet stores an open bet of 2 on a round, drawn with the server's crash math, that
+// passed step a second ago.
+func startedCrashBet(s *Server, id string, crashStep, autoStep, step int) {
+	m := s.crashMath
+	s.crashStore.Create(&round.CrashRound{
+		RoundID:         id,
+		GameRoundID:     "g-" + id,
+		SessionID:       "sess",
+		BetID:           "bet-1",
+		Currency:        "USD",
+		Amount:          2,
+		CrashStep:       crashStep,
+		StartedAt:       time.Now().Add(-crash.StepTime(s.crashMath, step) - time.Second),
+		AutoCashoutStep: autoStep,
+		Math:            &m,
+	})
+}
+
+func TestCrashEngine_StatusSettlesAutoCashoutOnce(t *testing.T) {
+	cases := []struct {
+		auto, crashStep int
+		win             float64
+	}{
+		{100, 300, 4}, // cashed out at 2.00x
+		{300, 300, 8}, // the target equals the crash point: cashed out at 4.00x
+		{301, 300, 0}, // the target is above the crash point: lost
+		{0, 300, 0},   // no target: lost
+	}
+	for i, c := range cases {
+		t.Run(fmt.Sprintf("auto %d crash %d", c.auto, c.crashStep), func(t *testing.T) {
+			plat := &fakePlatform{}
+			s := newTestServer(t, plat)
+			eng := &crashEngine{s: s}
+			id := fmt.Sprintf("b%d", i)
+			startedCrashBet(s, id, c.crashStep, c.auto, 400)
+
+			rnd, err := s.actRound(context.Background(), eng, "sess", id, games.Action{Name: "status"})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !rnd.Settled || rnd.Win != c.win {
+				t.Errorf("first status: settled %v win %v; want win %v", rnd.Settled, rnd.Win, c.win)
+			}
+			if cr, _ := s.crashStore.Get(id); !cr.Settled {
+				t.Error("bet still open")
+			}
+			res, _ := s.results.GetByRoundID(id)
+			if res == nil || res.WinAmount != c.win || res.BetID != "bet-1" {
+				t.Errorf("result %+v", res)
+			}
+
+			// Later status requests (the settler, the client) report the bet without paying it again.
+			rnd, err = s.actRound(context.Background(), eng, "sess", id, games.Action{Name: "status"})
+			if err != nil || rnd.Settled {
+				t.Errorf("second status: settled %v, %v", rnd.Settled, err)
+			}
+			if view := rnd.View.(map[string]interface{}); (c.win > 0) != (view["cashedOut"] == true) {
+				t.Errorf("second status view %v", view)
+			}
+			if _, err := s.actRound(context.Background(), eng, "sess", id, games.Action{Name: "cashout", Data: json.RawMessage(`{}`)}); err != games.ErrRoundSettled {
+				t.Errorf("cashout of a settled bet: %v", err)
+			}
+			var paid float64
+			for _, w := range plat.wins {
+				paid += w
+			}
+			if paid != c.win || len(plat.wins) > 1 {
+				t.Errorf("wins %v, want %v once", plat.wins, c.win)
+			}
+		})
+	}
+}
+
+func TestCrashEngine_StatusBeforeAutoCashoutKeepsBetOpen(t *testing.T) {
+	plat := &fakePlatform{}
+	s := newTestServer(t, plat)
+	startedCrashBet(s, "b1", 300, 200, 150)
+	rnd, err := s.actRound(context.Background(), &crashEngine{s: s}, "sess", "b1", games.Action{Name: "status"})
+	if err != nil || rnd.Settled {
+		t.Fatalf("status at 2.50x for a 3.00x target: settled %v, %v", rnd.Settled, err)
+	}
+	if cr, _ := s.crashStore.Get("b1"); cr.Settled || len(plat.wins) != 0 {
+		t.Errorf("bet settled early: %+v, wins %v", cr, plat.wins)
+	}
+}
+
+func TestCrashTable_SettleDuePaysAutoCashoutOnce(t *testing.T) {
+	plat := &fakePlatform{}
+	s := newTestServer(t, plat)
+	startedCrashBet(s, "due", 300, 100, 150)  // reached its 2.00x target
+	startedCrashBet(s, "open", 300, 200, 150) // 3.00x target not reached yet
+	s.crashTable.settleDue(context.Background())
+	s.crashTable.settleDue(context.Background())
+	if len(plat.wins) != 1 || plat.wins[0] != 4 {
+		t.Errorf("wins %v, want one of 4", plat.wins)
+	}
+	if open := s.crashStore.Open(); len(open) != 1 || open[0].RoundID != "open" {
+		t.Errorf("open bets %+v", open)
+	}
+}
+
+func TestRegisterCrashMath_RefusesChangedVersion(t *testing.T) {
+	cfg := &config.Config{DataDir: t.TempDir(), GamesDir: t.TempDir(), CrashHouseEdge: 0.01}
+	if err := New(cfg).registerCrashMath(); err != nil {
+		t.Fatal(err)
+	}
+	// A restart with another house edge under the same version does not start.
+	cfg.CrashHouseEdge = 0.02
+	if err := New(cfg).registerCrashMath(); !errors.Is(err, gamemath.ErrCrashVersionConflict) {
+		t.Errorf("changed math under version 1: %v", err)
+	}
+	cfg.CrashMathVersion = "2"
+	s := New(cfg)
+	if err := s.registerCrashMath(); err != nil {
+		t.Fatal(err)
+	}
+	if m := s.crashMath; m.ModelID != gamemath.CrashModelID || m.ModelVersion != "2" || m.VerifyContentHash() != nil {
+		t.Errorf("crash math %+v", m)
+	}
+}
+
+func TestFairVerify_RecomputesRecordedCrashRound(t *testing.T) {
+	s := newTestServer(t, &fakePlatform{})
+	if err := s.registerCrashMath(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.crashTable.open(time.Now()); !errors.Is(err, fair.ErrChainUnsalted) {
+		t.Fatalf("round opened on an unsalted chain: %v", err)
+	}
+	if _, err := s.crashChain.SetSalt("00000000000000000002a7c4c1e48d76c5a37902165a270156b7a8d72728a054"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.crashTable.open(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	s.crashTable.crash()
+	p := s.crashHistory.Recent(1)[0]
+	if p.Chain == nil || p.Math == nil {
+		t.Fatalf("recorded round %+v", p)
+	}
+
+	verify := func(req FairVerifyRequest) FairVerifyResponse {
+		t.Helper()
+		body, _ := json.Marshal(req)
+		rec := httptest.NewRecorder()
+		s.handleFairVerify(rec, httptest.NewRequest(http.MethodPost, "/rgs/fair/verify", bytes.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("verify: %d %s", rec.Code, rec.Body)
+		}
+		var resp FairVerifyResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		return resp
+	}
+	req := FairVerifyRequest{
+		Game:            "crash",
+		ServerSeed:      p.Seed,
+		ServerSeedHash:  p.SeedHash,
+		ModelVersion:    p.Math.ModelVersion,
+		Salt:            p.Chain.Salt,
+		ChainCommitment: p.Chain.Commitment,
+		ChainIndex:      p.Chain.Index,
+	}
+	resp := verify(req)
+	if resp.HashMatches == nil || !*resp.HashMatches || resp.ChainMatches == nil || !*resp.ChainMatches {
+		t.Errorf("commitments do not match: %+v", resp)
+	}
+	if resp.CrashStep != p.CrashStep || resp.Multiplier != p.CrashMultiplier {
+		t.Errorf("crash step %d (%.2fx), recorded %d (%.2fx)", resp.CrashStep, resp.Multiplier, p.CrashStep, p.CrashMultiplier)
+	}
+
+	// A seed from outside the chain is caught.
+	req.ChainIndex++
+	if resp := verify(req); resp.ChainMatches == nil || *resp.ChainMatches {
+		t.Errorf("seed verified at the wrong chain index: %+v", resp)
+	}
+}
+
+func TestCrashTable_RoundKeepsItsMathAcrossReload(t *testing.T) {
+	s := newTestServer(t, &fakePlatform{})
+	if _, err := s.crashChain.SetSalt("block"); err != nil {
+		t.Fatal(err)
+	}
+	opened := s.crashMath
+	startsAt, err := s.crashTable.open(time.Now())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The crash math is replaced while the round takes bets: a lower cap, a faster curve.
+	s.crashMath = gamemath.CrashMath{HouseEdge: 0.01, MaxMultiplier: 100, GrowthRate: 1}.WithDefaults()
+
+	bet := testBet("c1")
+	bet.Data = json.RawMessage(`{"auto_cashout":500}`) // above the new cap, within the round's
+	if err := s.crashTable.checkBet(bet.Data); err != nil {
+		t.Fatal(err)
+	}
+	cr, err := s.crashTable.placeBet(bet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cr.AutoCashoutStep != crash.TargetStep(500) || cr.Math == nil || cr.Math.GrowthRate != opened.GrowthRate {
+		t.Fatalf("bet %+v", cr)
+	}
+
+	s.crashTable.mu.Lock()
+	step := s.crashTable.stepLocked(startsAt.Add(10 * time.Second))
+	s.crashTable.mu.Unlock()
+	if want := crash.StepAt(opened, 10*time.Second); step != want {
+		t.Errorf("table at 10s: step %d, want %d on the round's curve", step, want)
+	}
+	cr.StartedAt = time.Now().Add(-10 * time.Second)
+	if got := s.currentCrashStep(cr); got < crash.StepAt(opened, 10*time.Second) || got > crash.StepAt(opened, 11*time.Second) {
+		t.Errorf("bet at 10s: step %d, want %d on the round's curve", got, crash.StepAt(opened, 10*time.Second))
+	}
+}
@@ -0,0 +1,128 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games/scratch"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/round"
+)
+
+// scratchGameName is the game name scratch wallet transactions carry on the platform.
+const scratchGameName = "Scratch"
+
+var errRevealUnavailable = games.Errorf(http.StatusInternalServerError, "TECHNICAL_ERROR", "reveal map unavailable")
+
+// scratchEngine plays instant scratch tickets: the outcome comes from the game's math (see
+// generateScratchOutcome), the reveal map from its scratch_games config. Pick games are
+// interactive and played by pickEngine; bundles by scratchBatchEngine.
+//
+// Round views: View is the provider round/start response (ScratchRoundStartResponse), State
+// the /api/scratch/play response (*ScratchResolvedOutcome).
+type scratchEngine struct {
+	s *Server
+}
+
+func (e *scratchEngine) Describe() games.EngineInfo {
+	return games.EngineInfo{ID: "scratch", Name: scratchGameName, Instant: true}
+}
+
+// Start draws the ticket and builds its reveal map, before any money moves: a grid that cannot
+// be shown fails the purchase.
+func (e *scratchEngine) Start(ctx context.Context, bet games.Bet) (*games.Round, error) {
+	cfg, err := e.s.scratchConfigs.get(bet.GameID)
+	if err != nil {
+		return nil, games.Errorf(http.StatusBadGateway, "TECHNICAL_ERROR", "database error")
+	}
+	if cfg != nil && scratch.IsPick(cfg.Mechanic.Type) {
+		return nil, games.Errorf(http.StatusBadRequest, "INVALID_GAME", "gameId is a pick game; play it with POST /api/scratch/pick")
+	}
+	draw, err := e.s.generateScratchOutcome(bet.SessionID, bet.GameID, bet.Amount)
+	if err != nil {
+		return nil, err
+	}
+	reveal, err := buildRevealMapFromOutcome(scratch.PresentationSource(draw.presentationSeed), cfg, &draw.outcome)
+	if err != nil {
+		log.Printf("scratch play: game %s tier %s: %v", bet.GameID, draw.outcome.Tier, err)
+		e.s.returnPoolTicket(draw.ticket)
+		return nil, errRevealUnavailable
+	}
+	outcome := resolvedScratchOutcome(bet.RoundID, bet.Amount, &draw, reveal)
+	return &games.Round{
+		Bet:     bet,
+		Settled: true,
+		Win:     draw.outcome.WinAmount,
+		View: ScratchRoundStartResponse{
+			RoundID:      bet.RoundID,
+			Symbols:      draw.outcome.Symbols,
+			WinAmount:    draw.outcome.WinAmount,
+			BalanceDelta: draw.outcome.WinAmount - bet.Amount,
+			Tier:         draw.outcome.Tier,
+			Wins:         draw.outcome.Wins,
+			Fair:         draw.proof,
+		},
+		State:  &outcome,
+		Record: e.s.scratchResult(bet.GameID, cfg, &draw),
+		Void:   func() { e.s.returnPoolTicket(draw.ticket) },
+	}, nil
+}
+
+// Act rejects every action: scratch tickets are decided when they are bought.
+func (e *scratchEngine) Act(ctx context.Context, roundID string, act games.Action) (*games.Round, error) {
+	return nil, games.ErrUnknownAction
+}
+
+// Settle has nothing to commit: the round result is the ticket's only record.
+func (e *scratchEngine) Settle(ctx context.Context, rnd *games.Round) error {
+	return nil
+}
+
+// Replay presents a bought ticket again from its result, reveal map included when it can still
+// be rebuilt (see rebuildScratchReveal); otherwise the outcome is returned with an empty
+// revealMap, so a retry always gets the ticket it paid for.
+func (e *scratchEngine) Replay(ctx context.Context, res *round.Result) (*games.Round, error) {
+	var syms [3]string
+	copy(syms[:], res.Symbols)
+	outcome := &ScratchResolvedOutcome{
+		RoundID:          res.RoundID,
+		IsWin:            res.WinAmount > 0,
+		TierID:           res.Tier,
+		FinalPrize:       res.WinAmount,
+		Wins:             resultWins(res),
+		PresentationSeed: res.PresentationSeed,
+		RevealMap:        []string{},
+		Fair:             res.Fair,
+	}
+	if reveal, err := e.s.rebuildScratchReveal(res); err == nil {
+		outcome.RevealMap = reveal.Cells
+		outcome.Prizes = cellPrizes(reveal, res.Bet)
+		outcome.Zones = reveal.Zones
+	} else if !errors.Is(err, errNoPresentationSeed) {
+		log.Printf("scratch play: replay round %s: %v", res.RoundID, err)
+	}
+	return &games.Round{
+		Bet: games.Bet{
+			RoundID:   res.RoundID,
+			GameID:    res.GameID,
+			SessionID: res.SessionID,
+			Amount:    res.Bet,
+			BetRef:    res.BetID,
+		},
+		Settled: true,
+		Win:     res.WinAmount,
+		View: ScratchRoundStartResponse{
+			RoundID:      res.RoundID,
+			Symbols:      syms,
+			WinAmount:    res.WinAmount,
+			BalanceDelta: res.BalanceDelta,
+			Tier:         res.Tier,
+			Wins:         resultWins(res),
+			Fair:         res.Fair,
+		},
+		State:  outcome,
+		Record: res,
+	}, nil
+}
@@ -0,0 +1,236 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/fair"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games/crash"
+)
+
+// RotateFairSeedsRequest is the body for POST /rgs/fair/sessions/{sessionId}/rotate.
+type RotateFairSeedsRequest struct {
+	ClientSeed string `json:"clientSeed"` // optional; empty keeps the current client seed
+}
+
+// RotateFairSeedsResponse reveals the previous server seed and commits to the next one.
+type RotateFairSeedsResponse struct {
+	Revealed fair.SeedPair `json:"revealed"`
+	Active   fair.SeedPair `json:"active"`
+}
+
+// FairVerifyRequest is the body for POST /rgs/fair/verify.
+type FairVerifyRequest struct {
+	Game           string `json:"game"` // scratch game id (the model id when modelId is empty)
+	ServerSeed     string `json:"serverSeed"`
+	ServerSeedHash string `json:"serverSeedHash,omitempty"` // optional: checked against serverSeed
+	ClientSeed     string `json:"clientSeed"`
+	Nonce          uint64 `json:"nonce"`
+	// The model and version the round was played with: a scratch model, or for crash the crash
+	// math version (the current one when empty).
+	ModelID      string `json:"modelId,omitempty"`
+	ModelVersion string `json:"modelVersion,omitempty"`
+	// Crash only, instead of the client seed and nonce: the round's chain proof (chain in the
+	// crash history), which places serverSeed in the crash seed chain.
+	Salt            string `json:"salt,omitempty"`
+	ChainCommitment string `json:"chainCommitment,omitempty"`
+	ChainIndex      int    `json:"chainIndex,omitempty"`
+}
+
+// FairVerifyResponse is the outcome recomputed from the revealed seeds.
+type FairVerifyResponse struct {
+	Game           string  `json:"game"`
+	ServerSeedHash string  `json:"serverSeedHash"`
+	HashMatches    *bool   `json:"hashMatches,omitempty"`
+	ClientSeed     string  `json:"clientSeed"`
+	Nonce          uint64  `json:"nonce"`
+	Tier           string  `json:"tier,omitempty"`
+	Multiplier     float64 `json:"multiplier,omitempty"` // the tier's, or the crash point
+	// Crash only: whether serverSeed is seed chainIndex of the chain, and the crash step.
+	ChainMatches *bool `json:"chainMatches,omitempty"`
+	CrashStep    int   `json:"crashStep,omitempty"`
+}
+
+// SaltCrashChainRequest is the body for POST /rgs/admin/crash/salt.
+type SaltCrashChainRequest struct {
+	Salt string `json:"salt"`
+}
+
+// fairSession returns the authenticated session id of a /rgs/fair/sessions/{sessionId} request,
+// or writes the error and returns "". The request must carry the session's token
+// (Authorization: Bearer <sessionId>) and the session must be live.
+func (s *Server) fairSession(w http.ResponseWriter, r *http.Request) string {
+	if s.fair == nil {
+		writeError(w, http.StatusNotFound, "provably fair mode is disabled", "FAIR_DISABLED")
+		return ""
+	}
+	sessionID := strings.TrimSpace(r.PathValue("sessionId"))
+	if sessionID == "" || len(sessionID) > 512 {
+		writeError(w, http.StatusBadRequest, "invalid session id", "INVALID_REQUEST")
+		return ""
+	}
+	if bearerToken(r) != sessionID {
+		writeError(w, http.StatusUnauthorized, "session token required", "INVALID_SESSION")
+		return ""
+	}
+	if err := s.checkSession(r.Context(), sessionID); err != nil {
+		status, code := roundErrorStatus(err)
+		writeError(w, status, err.Error(), code)
+		return ""
+	}
+	return sessionID
+}
+
+// handleGetFairSession returns the session's active commitment (server seed hash, client seed,
+// next nonce), the hash of the next server seed and the revealed seed history
+// (GET /rgs/fair/sessions/{sessionId}).
+func (s *Server) handleGetFairSession(w http.ResponseWriter, r *http.Request) {
+	sessionID := s.fairSession(w, r)
+	if sessionID == "" {
+		return
+	}
+	sess, err := s.fair.Open(sessionID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "TECHNICAL_ERROR")
+		return
+	}
+	writeJSON(w, http.StatusOK, sess)
+}
+
+// handleRotateFairSeeds reveals the active server seed and starts a new pair
+// (POST /rgs/fair/sessions/{sessionId}/rotate).
+func (s *Server) handleRotateFairSeeds(w http.ResponseWriter, r *http.Request) {
+	sessionID := s.fairSession(w, r)
+	if sessionID == "" {
+		return
+	}
+	var req RotateFairSeedsRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid body", "INVALID_BODY")
+			return
+		}
+	}
+	revealed, next, err := s.fair.Rotate(sessionID, req.ClientSeed)
+	if err != nil {
+		if errors.Is(err, fair.ErrInvalidClientSeed) {
+			writeError(w, http.StatusBadRequest, err.Error(), "INVALID_CLIENT_SEED")
+			return
+		}
+		writeError(w, http.StatusInternalServerError, err.Error(), "TECHNICAL_ERROR")
+		return
+	}
+	writeJSON(w, http.StatusOK, RotateFairSeedsResponse{Revealed: revealed, Active: next})
+}
+
+// handleFairVerify recomputes a round from revealed seeds (POST /rgs/fair/verify): the prize
+// tier of a scratch round of an UNLIMITED model (LIMITED tiers also depend on the remaining
+// ticket pool, so they cannot be recomputed), or the crash point of a shared crash round (see
+// verifyCrashRound).
+func (s *Server) handleFairVerify(w http.ResponseWriter, r *http.Request) {
+	var req FairVerifyRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body", "INVALID_BODY")
+		return
+	}
+	if req.Game == "crash" {
+		s.verifyCrashRound(w, req)
+		return
+	}
+	if req.ServerSeed == "" || req.ClientSeed == "" {
+		writeError(w, http.StatusBadRequest, "serverSeed and clientSeed required", "INVALID_REQUEST")
+		return
+	}
+	resp := FairVerifyResponse{
+		Game:           req.Game,
+		ServerSeedHash: fair.HashServerSeed(req.ServerSeed),
+		ClientSeed:     req.ClientSeed,
+		Nonce:          req.Nonce,
+	}
+	if req.ServerSeedHash != "" {
+		ok := strings.EqualFold(req.ServerSeedHash, resp.ServerSeedHash)
+		resp.HashMatches = &ok
+	}
+	modelID := strings.TrimSpace(req.ModelID)
+	if modelID == "" {
+		modelID = req.Game
+	}
+	math := s.gameMath.Get(modelID)
+	if req.ModelVersion != "" {
+		math = s.gameMath.GetVersion(modelID, req.ModelVersion)
+	}
+	if math == nil {
+		writeError(w, http.StatusNotFound, "game math not found", "MATH_NOT_FOUND")
+		return
+	}
+	if math.IsLimited() {
+		writeError(w, http.StatusUnprocessableEntity, "LIMITED models draw from a ticket pool and cannot be recomputed from seeds", "FAIR_UNSUPPORTED")
+		return
+	}
+	tier, ok := math.PickTierFrom(fair.Source(req.ServerSeed, req.ClientSeed, req.Nonce))
+	if !ok {
+		writeError(w, http.StatusUnprocessableEntity, "model has no prize table", "MATH_INVALID")
+		return
+	}
+	resp.Tier, resp.Multiplier = tier.Tier, tier.Multiplier
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// verifyCrashRound recomputes a shared crash round. Its seed must be seed chainIndex of the
+// crash seed chain published as chainCommitment, and the crash step is drawn from the seed mixed
+// with the chain's salt (fair.ChainSource) under the round's crash math.
+func (s *Server) verifyCrashRound(w http.ResponseWriter, req FairVerifyRequest) {
+	if req.ServerSeed == "" || req.Salt == "" || req.ChainCommitment == "" || req.ChainIndex < 1 {
+		writeError(w, http.StatusBadRequest, "serverSeed, salt, chainCommitment and chainIndex required", "INVALID_REQUEST")
+		return
+	}
+	math := s.crashMath
+	if req.ModelVersion != "" {
+		m, ok := s.crashModels.Get(gamemath.CrashModelID, req.ModelVersion)
+		if !ok {
+			writeError(w, http.StatusNotFound, "crash math version not found", "MATH_NOT_FOUND")
+			return
+		}
+		math = m
+	}
+	resp := FairVerifyResponse{
+		Game:           req.Game,
+		ServerSeedHash: fair.HashServerSeed(req.ServerSeed),
+	}
+	if req.ServerSeedHash != "" {
+		ok := strings.EqualFold(req.ServerSeedHash, resp.ServerSeedHash)
+		resp.HashMatches = &ok
+	}
+	chained := fair.VerifyChainSeed(req.ServerSeed, req.ChainCommitment, req.ChainIndex)
+	resp.ChainMatches = &chained
+	resp.CrashStep = crash.GenerateCrashStepFrom(math, fair.ChainSource(req.ServerSeed, req.Salt))
+	resp.Multiplier = crash.Multiplier(resp.CrashStep)
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleSaltCrashChain salts the crash seed chain, which no round is played from until then
+// (POST /rgs/admin/crash/salt). The salt must be a public value nobody knew when the chain's
+// commitment was published.
+func (s *Server) handleSaltCrashChain(w http.ResponseWriter, r *http.Request) {
+	var req SaltCrashChainRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body", "INVALID_BODY")
+		return
+	}
+	chain, err := s.crashChain.SetSalt(req.Salt)
+	switch {
+	case errors.Is(err, fair.ErrInvalidSalt):
+		writeError(w, http.StatusBadRequest, err.Error(), "INVALID_SALT")
+		return
+	case errors.Is(err, fair.ErrChainSalted):
+		writeError(w, http.StatusConflict, err.Error(), "CHAIN_SALTED")
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error(), "TECHNICAL_ERROR")
+		return
+	}
+	writeJSON(w, http.StatusOK, chain)
+}
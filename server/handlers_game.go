@@ -98,22 +98,18 @@ func (s *Server) handleGamePage(w http.ResponseWriter, r *http.Request) {
 		currency = "USD"
 	}
 	embedOrigin := originFromReferer(referer) // Allow embedding from the validated referer (partner site).
-	switch gameID {
-	case "scratch":
-		s.serveScratchGame(w, r, token, lang, currency, providerID, embedOrigin)
-	case "crash":
-		s.serveCrashGame(w, r, token, lang, currency, providerID, embedOrigin)
-	case "lucky_star":
-		s.serveBundleGame(w, r, "lucky_star", token, lang, currency, providerID, embedOrigin)
-	default:
-		if s.bundleGameExists(gameID) {
-			s.serveBundleGame(w, r, gameID, token, lang, currency, providerID, embedOrigin)
-		} else {
-			writeError(w, http.StatusNotFound, "game not found", "GAME_NOT_FOUND")
-		}
+	if page, ok := s.pages[gameID]; ok {
+		page(w, r, token, lang, currency, providerID, embedOrigin)
+	} else if s.bundleGameExists(gameID) {
+		s.serveBundleGame(w, r, gameID, token, lang, currency, providerID, embedOrigin)
+	} else {
+		writeError(w, http.StatusNotFound, "game not found", "GAME_NOT_FOUND")
 	}
 }
 
+// gamePage serves the built-in page of a game (see registerEngines).
+type gamePage func(w http.ResponseWriter, r *http.Request, token, lang, currency, providerID, embedOrigin string)
+
 // validateGamePageRequest validates session, URL (game_id match), and operator iframe allowance.
 // Embed policy is read from operators.allowed_embed_domains and operators.embed_referer_required.
 func (s *Server) validateGamePageRequest(ctx context.Context, sessionID, urlGameID, referer string) (string, error) {
@@ -558,11 +554,15 @@ func getCrashLabels(lang string) map[string]string {
 			"title": "Crash", "bet": "Apostar", "amount": "Monto", "cashout": "Cobrar",
 			"loading": "Cargando…", "crashed": "¡Cayó!", "cashed": "Cobrado",
 			"error": "Error", "playAgain": "Jugar de nuevo", "multiplier": "Multiplicador",
+			"nextRound": "Próxima ronda en", "betPlaced": "Apuesta realizada", "waiting": "Esperando la próxima ronda",
+			"autoCashout": "Cobro automático (opcional)",
 		},
 		"en": {
 			"title": "Crash", "bet": "Bet", "amount": "Amount", "cashout": "Cash out",
 			"loading": "Loading…", "crashed": "Crashed!", "cashed": "Cashed out",
 			"error": "Error", "playAgain": "Play again", "multiplier": "Multiplier",
+			"nextRound": "Next round in", "betPlaced": "Bet placed", "waiting": "Waiting for the next round",
+			"autoCashout": "Auto cash out (optional)",
 		},
 	}
 	if l, ok := labels[lang]; ok {
@@ -614,22 +614,29 @@ func crashGameHTML(langSafe, baseURLJS, providerIDJS, tokenJS, langJS, currencyJ
     label { display: block; text-align: left; font-size: 0.875rem; color: #a8a29e; margin-top: 12px; }
     .error { color: #ef4444; font-size: 0.875rem; margin-top: 8px; }
     .result { margin-top: 12px; font-size: 1.1rem; }
+    .phase { font-size: 0.875rem; color: #a8a29e; min-height: 1.2em; }
+    .history { display: flex; gap: 6px; justify-content: center; flex-wrap: wrap; margin-bottom: 12px; min-height: 1.5em; }
+    .history span { font-size: 0.75rem; padding: 2px 8px; border-radius: 999px; background: #0c0f17; color: #22c55e; }
+    .history span.low { color: #ef4444; }
   </style>
 </head>
 <body>
   <div class="card">
     <h1>Crash</h1>
+    <div class="history" id="history"></div>
+    <div class="mult" id="mult">1.00x</div>
+    <div class="phase" id="phase"></div>
     <div id="play-area">
       <label id="lbl-amount">Amount</label>
       <input type="number" id="amount" min="1" step="0.01" value="10" placeholder="0.00">
-      <button id="btn-bet" type="button">Bet</button>
+      <label id="lbl-auto">Auto cash out (optional)</label>
+      <input type="number" id="auto-cashout" min="1.01" step="0.01" placeholder="2.00x">
+      <button id="btn-bet" type="button" disabled>Bet</button>
     </div>
     <div id="game-area" style="display:none;">
-      <div class="mult" id="mult">1.00x</div>
-      <button id="btn-cashout" type="button" class="danger">Cash out</button>
+      <button id="btn-cashout" type="button" class="danger" disabled>Cash out</button>
     </div>
     <div id="result-area" style="display:none;">
-      <div class="mult" id="result-mult">1.00x</div>
       <div class="result" id="result-text"></div>
       <p id="win-amount" style="margin-top:8px;color:#a8a29e;"></p>
       <button id="btn-again" type="button">Play again</button>
@@ -644,25 +651,28 @@ func crashGameHTML(langSafe, baseURLJS, providerIDJS, tokenJS, langJS, currencyJ
       var currency = ` + currencyJS + `;
       var lang = ` + langJS + `;
       var labels = ` + labelsJSON + `;
+      document.getElementById("lbl-auto").textContent = labels.autoCashout;
 
       var playArea = document.getElementById("play-area");
       var gameArea = document.getElementById("game-area");
       var resultArea = document.getElementById("result-area");
       var multEl = document.getElementById("mult");
+      var phaseEl = document.getElementById("phase");
+      var historyEl = document.getElementById("history");
       var btnBet = document.getElementById("btn-bet");
       var btnCashout = document.getElementById("btn-cashout");
       var btnAgain = document.getElementById("btn-again");
-      var resultMultEl = document.getElementById("result-mult");
       var resultText = document.getElementById("result-text");
       var winAmountEl = document.getElementById("win-amount");
       var amountInput = document.getElementById("amount");
+      var autoInput = document.getElementById("auto-cashout");
       var errorEl = document.getElementById("error");
 
-      var roundId = null;
-      var startedAtMs = 0;
-      var stepInterval = null;
-      var statusInterval = null;
-      var currentStep = 0;
+      // The shared round, as last seen on the live stream, and the player's bet on it.
+      var live = null;
+      var clockOffset = 0;
+      var countdown = null;
+      var bet = null; // { roundId, gameRoundId }
 
       function showError(msg) {
         errorEl.textContent = msg;
@@ -673,97 +683,143 @@ func crashGameHTML(langSafe, baseURLJS, providerIDJS, tokenJS, langJS, currencyJ
       }
       function multFromStep(s) { return (1 + s * 0.01).toFixed(2); }
 
-      function startRound() {
+      function addHistory(mult, prepend) {
+        var el = document.createElement("span");
+        el.textContent = Number(mult).toFixed(2) + "x";
+        if (mult < 2) el.className = "low";
+        if (prepend) historyEl.insertBefore(el, historyEl.firstChild); else historyEl.appendChild(el);
+        while (historyEl.children.length > 10) historyEl.removeChild(historyEl.lastChild);
+      }
+
+      function showResult(won, data) {
+        bet = null;
+        gameArea.style.display = "none";
+        playArea.style.display = "none";
+        resultArea.style.display = "block";
+        if (won) {
+          resultText.textContent = labels.cashed + " " + Number(data.multiplier).toFixed(2) + "x";
+          resultText.style.color = "#22c55e";
+          winAmountEl.textContent = "+" + (data.winAmount || 0) + " " + currency;
+        } else {
+          resultText.textContent = labels.crashed;
+          resultText.style.color = "#ef4444";
+          winAmountEl.textContent = "";
+        }
+      }
+
+      function render() {
+        if (countdown) { clearInterval(countdown); countdown = null; }
+        if (!live) return;
+        var betting = live.phase === "betting";
+        btnBet.disabled = !betting || bet !== null;
+        multEl.classList.toggle("crashed", live.phase === "crashed");
+        if (betting) {
+          multEl.textContent = "1.00x";
+          var tickCountdown = function() {
+            var left = Math.max(0, live.startsAtMs - (Date.now() + clockOffset));
+            phaseEl.textContent = (bet ? labels.betPlaced + " · " : "") + labels.nextRound + " " + (left / 1000).toFixed(1) + "s";
+          };
+          tickCountdown();
+          countdown = setInterval(tickCountdown, 100);
+        } else if (live.phase === "running") {
+          multEl.textContent = multFromStep(live.step) + "x";
+          phaseEl.textContent = bet ? "" : labels.waiting;
+        } else {
+          multEl.textContent = multFromStep(live.crashStep) + "x";
+          phaseEl.textContent = labels.crashed;
+        }
+        btnCashout.disabled = !(bet && live.phase === "running" && live.roundId === bet.gameRoundId);
+      }
+
+      function connect() {
+        var es = new EventSource(baseURL + "/rgs/crash/live");
+        var onEvent = function(e) {
+          var ev = JSON.parse(e.data);
+          clockOffset = ev.serverTimeMs - Date.now();
+          if (ev.type === "cashout") return;
+          var prev = live;
+          live = ev;
+          if (bet && bet.autoCashout && ev.roundId === bet.gameRoundId && ev.phase === "running" && ev.multiplier >= bet.autoCashout) {
+            checkAutoCashout(bet);
+          }
+          if (ev.type === "crashed" && (!prev || prev.phase !== "crashed")) {
+            addHistory(ev.crashMultiplier, true);
+            if (bet && bet.gameRoundId === ev.roundId) {
+              // A target equal to the crash point still pays.
+              if (bet.autoCashout && bet.autoCashout <= ev.crashMultiplier) checkAutoCashout(bet);
+              else showResult(false, ev);
+            }
+          }
+          render();
+        };
+        ["betting", "running", "tick", "crashed", "cashout"].forEach(function(t) { es.addEventListener(t, onEvent); });
+      }
+
+      // The server cashes the bet out at its auto-cashout target; fetch the result once reached.
+      function checkAutoCashout(b) {
+        if (b.checking) return;
+        b.checking = true;
+        fetch(baseURL + "/rgs/providers/" + providerId + "/games/crash/round/status?roundId=" + encodeURIComponent(b.roundId) + "&token=" + encodeURIComponent(token))
+        .then(function(res) { return res.json(); })
+        .then(function(data) {
+          if (bet === b && data.cashedOut) showResult(true, { multiplier: data.autoCashout, winAmount: data.winAmount });
+          else b.checking = false;
+        })
+        .catch(function() { b.checking = false; });
+      }
+
+      function placeBet() {
         hideError();
         var amount = parseFloat(amountInput.value);
         if (isNaN(amount) || amount <= 0) {
           showError("Invalid amount");
           return;
         }
-        roundId = (typeof crypto !== "undefined" && crypto.randomUUID) ? crypto.randomUUID() : (Date.now().toString(36) + Math.random().toString(36).slice(2));
+        var roundId = (typeof crypto !== "undefined" && crypto.randomUUID) ? crypto.randomUUID() : (Date.now().toString(36) + Math.random().toString(36).slice(2));
         btnBet.disabled = true;
         btnBet.textContent = labels.loading;
         fetch(baseURL + "/rgs/providers/" + providerId + "/games/crash/round/start", {
           method: "POST",
           headers: { "Content-Type": "application/json" },
-          body: JSON.stringify({ token: token, currency: currency, amount: amount, roundId: roundId })
+          body: JSON.stringify({ token: token, currency: currency, amount: amount, roundId: roundId, autoCashout: parseFloat(autoInput.value) || 0 })
         })
         .then(function(res) { return res.json(); })
         .then(function(data) {
-          btnBet.disabled = false;
           btnBet.textContent = labels.bet;
           if (data.error) {
-            showError(data.error || "Request failed");
+            showError(data.message || data.error || "Request failed");
+            render();
             return;
           }
-          startedAtMs = data.startedAtMs || Date.now();
+          bet = { roundId: data.roundId, gameRoundId: data.gameRoundId, autoCashout: data.autoCashout || 0 };
           playArea.style.display = "none";
           gameArea.style.display = "block";
-          currentStep = 0;
-          multEl.textContent = multFromStep(0) + "x";
-          multEl.classList.remove("crashed");
-          btnCashout.disabled = false;
           btnCashout.textContent = labels.cashout;
-          if (stepInterval) clearInterval(stepInterval);
-          if (statusInterval) clearInterval(statusInterval);
-          stepInterval = setInterval(function() {
-            var elapsed = Date.now() - startedAtMs;
-            currentStep = Math.floor(elapsed / 100);
-            multEl.textContent = multFromStep(currentStep) + "x";
-          }, 50);
-          statusInterval = setInterval(function() {
-            fetch(baseURL + "/rgs/providers/" + providerId + "/games/crash/round/status?roundId=" + encodeURIComponent(roundId) + "&token=" + encodeURIComponent(token))
-            .then(function(r) { return r.json(); })
-            .then(function(data) {
-              if (data.crashed) {
-                clearInterval(stepInterval);
-                clearInterval(statusInterval);
-                multEl.textContent = (data.crashMultiplier || multFromStep(data.crashStep || 0)) + "x";
-                multEl.classList.add("crashed");
-                gameArea.style.display = "none";
-                resultArea.style.display = "block";
-                resultMultEl.textContent = (data.crashMultiplier || multFromStep(data.crashStep || 0)) + "x";
-                resultMultEl.className = "mult crashed";
-                resultText.textContent = labels.crashed;
-                winAmountEl.textContent = "";
-              }
-            });
-          }, 500);
+          render();
         })
         .catch(function(err) {
-          btnBet.disabled = false;
           btnBet.textContent = labels.bet;
+          render();
           showError(labels.error + ": " + (err.message || "Network error"));
         });
       }
 
       function cashout() {
-        if (!roundId) return;
+        if (!bet || !live) return;
         btnCashout.disabled = true;
         fetch(baseURL + "/rgs/providers/" + providerId + "/games/crash/round/cashout", {
           method: "POST",
           headers: { "Content-Type": "application/json" },
-          body: JSON.stringify({ token: token, roundId: roundId, step: currentStep })
+          body: JSON.stringify({ token: token, roundId: bet.roundId, step: live.step })
         })
         .then(function(res) { return res.json(); })
         .then(function(data) {
-          if (stepInterval) clearInterval(stepInterval);
-          if (statusInterval) clearInterval(statusInterval);
-          gameArea.style.display = "none";
-          resultArea.style.display = "block";
-          if (data.cashedOut) {
-            resultMultEl.textContent = multFromStep(currentStep) + "x";
-            resultMultEl.className = "mult";
-            resultText.textContent = labels.cashed;
-            resultText.style.color = "#22c55e";
-            winAmountEl.textContent = "+" + (data.winAmount || 0) + " " + currency;
-          } else {
-            resultMultEl.textContent = (data.crashStep != null ? multFromStep(data.crashStep) : multFromStep(currentStep)) + "x";
-            resultMultEl.className = "mult crashed";
-            resultText.textContent = labels.crashed;
-            resultText.style.color = "#ef4444";
-            winAmountEl.textContent = "";
+          if (data.error) {
+            showError(data.message || data.error);
+            render();
+            return;
           }
+          showResult(data.cashedOut, data);
         })
         .catch(function(err) {
           btnCashout.disabled = false;
@@ -771,14 +827,20 @@ func crashGameHTML(langSafe, baseURLJS, providerIDJS, tokenJS, langJS, currencyJ
         });
       }
 
-      btnBet.addEventListener("click", startRound);
+      btnBet.addEventListener("click", placeBet);
       btnCashout.addEventListener("click", cashout);
       btnAgain.addEventListener("click", function() {
         resultArea.style.display = "none";
         playArea.style.display = "block";
-        roundId = null;
         hideError();
+        render();
       });
+
+      fetch(baseURL + "/rgs/crash/history?limit=10")
+        .then(function(res) { return res.json(); })
+        .then(function(data) { (data.rounds || []).forEach(function(r) { addHistory(r.crashMultiplier, false); }); })
+        .catch(function() {});
+      connect();
     })();
   </script>
 </body>
@@ -6,6 +6,7 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -24,6 +25,17 @@ type importZipResponse struct {
 	OK      bool   `json:"ok"`
 	GameID  string `json:"game_id,omitempty"`
 	Message string `json:"message,omitempty"`
+	// RTP is the return to player of the imported math (after fitting to the target RTP).
+	RTP float64 `json:"rtp,omitempty"`
+}
+
+// rgsConfigFile is the shape of rgs_config.json in the bundle: mechanic, symbols and the
+// symbol (set) shown for each prize tier.
+type rgsConfigFile struct {
+	GameID   string               `json:"gameId"`
+	Mechanic ScratchMechanic      `json:"mechanic"`
+	Symbols  []ScratchSymbol      `json:"symbols"`
+	Tiers    []ScratchTierSymbols `json:"tiers"`
 }
 
 // projectScratchJSON is the shape of project_scratch.json in the bundle (displayName, gameId).
@@ -89,6 +101,18 @@ func (s *Server) handleImportZip(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Refuse math that cannot be converted (e.g. a target RTP the prize table can't reach) and
+	// an rgs_config.json that leaves a prize tier without a symbol, before anything is written.
+	rtp, err := s.checkBundleZip(body, gameID)
+	if err != nil {
+		writeJSON(w, http.StatusUnprocessableEntity, importZipResponse{
+			OK:      false,
+			GameID:  gameID,
+			Message: err.Error(),
+		})
+		return
+	}
+
 	if err := s.extractGameBundleZip(gameID, body); err != nil {
 		writeJSON(w, http.StatusBadRequest, importZipResponse{
 			OK:      false,
@@ -129,11 +153,56 @@ func (s *Server) handleImportZip(w http.ResponseWriter, r *http.Request) {
 		OK:      true,
 		GameID:  gameID,
 		Message: "bundle imported",
+		RTP:     rtp,
 	})
 }
 
+// checkBundleZip converts the bundle's math.json and checks that rgs_config.json (when present)
+// maps every prize tier to a symbol and has enough symbols for grids without accidental wins. It returns the RTP of the converted math, 0 without math.json.
+func (s *Server) checkBundleZip(zipBytes []byte, gameID string) (float64, error) {
+	mathJSON, err := readZipFile(zipBytes, "math.json")
+	if err != nil {
+		return 0, nil
+	}
+	gm, err := s.importBundleMath(mathJSON, gameID, gameID+"_default")
+	if err != nil {
+		return 0, fmt.Errorf("math.json: %w", err)
+	}
+	if data, err := readZipFile(zipBytes, "rgs_config.json"); err == nil {
+		var cfg rgsConfigFile
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return 0, fmt.Errorf("rgs_config.json: %w", err)
+		}
+		sc := &ScratchConfig{Mechanic: cfg.Mechanic, Symbols: cfg.Symbols, Tiers: cfg.Tiers}
+		if err := validateTierSymbols(sc, gm); err != nil {
+			return 0, fmt.Errorf("rgs_config.json: %w", err)
+		}
+		if err := validateLayout(sc, gm); err != nil {
+			return 0, fmt.Errorf("rgs_config.json: %w", err)
+		}
+	}
+	report, err := gamemath.Analyze(gm)
+	if err != nil {
+		return 0, err
+	}
+	return report.RTP, nil
+}
+
 // parseProjectScratchFromZip finds project_scratch.json in the ZIP and returns displayName and gameId.
 func parseProjectScratchFromZip(zipBytes []byte) (*projectScratchJSON, error) {
+	data, err := readZipFile(zipBytes, "project_scratch.json")
+	if err != nil {
+		return nil, err
+	}
+	var proj projectScratchJSON
+	if err := json.Unmarshal(data, &proj); err != nil {
+		return nil, err
+	}
+	return &proj, nil
+}
+
+// readZipFile returns the contents of the first file in the ZIP whose base name is name.
+func readZipFile(zipBytes []byte, name string) ([]byte, error) {
 	zr, err := zip.NewReader(bytes.NewReader(zipBytes), int64(len(zipBytes)))
 	if err != nil {
 		return nil, err
@@ -144,28 +213,20 @@ func parseProjectScratchFromZip(zipBytes []byte) (*projectScratchJSON, error) {
 			continue
 		}
 		base := filepath.Base(strings.ReplaceAll(f.Name, "\\", "/"))
-		if base == "project_scratch.json" {
+		if base == name {
 			found = f
 			break
 		}
 	}
 	if found == nil {
-		return nil, fmt.Errorf("project_scratch.json not found in zip")
+		return nil, fmt.Errorf("%s not found in zip", name)
 	}
 	rc, err := found.Open()
 	if err != nil {
 		return nil, err
 	}
 	defer rc.Close()
-	data, err := io.ReadAll(rc)
-	if err != nil {
-		return nil, err
-	}
-	var proj projectScratchJSON
-	if err := json.Unmarshal(data, &proj); err != nil {
-		return nil, err
-	}
-	return &proj, nil
+	return io.ReadAll(rc)
 }
 
 // nextNumericGameID returns the next value from game_numeric_id_seq (DB-generated unique numeric game_id).
@@ -236,7 +297,13 @@ func (s *Server) extractGameBundleZip(gameID string, zipBytes []byte) error {
 	}
 
 	// Parse math.json and register game math keyed by game_id for round APIs.
+	// A math model that fails analysis or its content hash check rejects the whole bundle.
 	if err := s.registerBundleMath(gameID, targetRoot); err != nil {
+		var verr *gamemath.ValidationError
+		if errors.As(err, &verr) || isContentHashError(err) {
+			_ = os.RemoveAll(targetRoot)
+			return fmt.Errorf("math.json rejected: %w", err)
+		}
 		log.Printf("import: game_id=%s register math: %v (bundle still imported)", gameID, err)
 	}
 	return nil
@@ -249,12 +316,6 @@ func (s *Server) upsertScratchConfigFromBundle(ctx context.Context, gameID, bund
 	}
 
 	// Prefer explicit RGS scratch config from rgs_config.json if present.
-	type rgsConfigFile struct {
-		GameID   string          `json:"gameId"`
-		Mechanic ScratchMechanic `json:"mechanic"`
-		Symbols  []ScratchSymbol `json:"symbols"`
-	}
-
 	var mechJSON, symJSON []byte
 	configPath := filepath.Join(bundleRoot, "rgs_config.json")
 	if data, err := os.ReadFile(configPath); err == nil {
@@ -272,9 +333,11 @@ func (s *Server) upsertScratchConfigFromBundle(ctx context.Context, gameID, bund
 				log.Printf("import: game_id=%s marshal mechanic from rgs_config.json failed: %v", gameID, err)
 			}
 			wrapper := struct {
-				Symbols []ScratchSymbol `json:"symbols"`
+				Symbols []ScratchSymbol      `json:"symbols"`
+				Tiers   []ScratchTierSymbols `json:"tiers,omitempty"`
 			}{
 				Symbols: cfg.Symbols,
+				Tiers:   cfg.Tiers,
 			}
 			if b, err := json.Marshal(wrapper); err == nil {
 				symJSON = b
@@ -343,39 +406,65 @@ func (s *Server) upsertGameMathFromBundle(ctx context.Context, gameID, bundleRoo
 		return nil
 	}
 
-	// Validate that it at least parses as gamemath.GameMath, then store raw JSON.
-	var gm gamemath.GameMath
-	if err := json.Unmarshal(data, &gm); err != nil {
-		return fmt.Errorf("invalid math.json: %w", err)
+	// Store the normalized model, so every row in game_math is in the current GameMath schema.
+	var declared struct {
+		ModelID string `json:"model_id"`
 	}
-	modelID := gm.ModelID
+	_ = json.Unmarshal(data, &declared)
+	modelID := declared.ModelID
 	if modelID == "" {
 		modelID = gameID + "_default"
 	}
+	gm, err := s.importBundleMath(data, gameID, modelID)
+	if err != nil {
+		return fmt.Errorf("invalid math.json: %w", err)
+	}
+	data, err = json.Marshal(gm)
+	if err != nil {
+		return err
+	}
 
+	// One row per (game_id, model_id, model_version); the imported version becomes ACTIVE and
+	// the previously active version of the model is ARCHIVED.
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err := tx.ExecContext(ctx, `
+    UPDATE game_math
+    SET status = 'ARCHIVED', updated_at = CURRENT_TIMESTAMP
+    WHERE game_id = $1 AND model_id = $2 AND status = 'ACTIVE'
+      AND COALESCE(math->>'model_version', '') <> $3
+  `, gameID, modelID, gm.ModelVersion); err != nil {
+		return err
+	}
 	var existingID int64
-	err = db.QueryRowContext(ctx, `
-    SELECT id FROM game_math WHERE game_id = $1 AND model_id = $2
-  `, gameID, modelID).Scan(&existingID)
+	err = tx.QueryRowContext(ctx, `
+    SELECT id FROM game_math
+    WHERE game_id = $1 AND model_id = $2 AND COALESCE(math->>'model_version', '') = $3
+  `, gameID, modelID, gm.ModelVersion).Scan(&existingID)
 	switch {
 	case err == sql.ErrNoRows:
-		_, err = db.ExecContext(ctx, `
+		_, err = tx.ExecContext(ctx, `
       INSERT INTO game_math (game_id, model_id, status, math)
       VALUES ($1, $2, 'ACTIVE', $3::jsonb)
     `, gameID, modelID, string(data))
-		return err
 	case err != nil:
 		return err
 	default:
-		_, err = db.ExecContext(ctx, `
+		_, err = tx.ExecContext(ctx, `
       UPDATE game_math
-      SET math = $3::jsonb,
+      SET math = $2::jsonb,
           status = 'ACTIVE',
           updated_at = CURRENT_TIMESTAMP
-      WHERE game_id = $1 AND model_id = $2
-    `, gameID, modelID, string(data))
+      WHERE id = $1
+    `, existingID, string(data))
+	}
+	if err != nil {
 		return err
 	}
+	return tx.Commit()
 }
 
 // upsertGameInDB inserts or updates the game in the game_crafter games table (container DB).
@@ -420,95 +509,18 @@ func sanitizeBundlePath(name string) string {
 	return clean
 }
 
-// rgsMathFile is the JSON shape of math.json produced by GameCrafter (RGS schema).
-type rgsMathFile struct {
-	SchemaVersion int                  `json:"schema_version"`
-	ModelID       string               `json:"model_id"`
-	ModelVersion  string               `json:"model_version"`
-	Mechanic      rgsBundleMechanic    `json:"mechanic"`
-	MathMode      string               `json:"math_mode"`
-	WinLogic      string               `json:"win_logic"`
-	PrizeTable    []rgsBundlePrizeTier `json:"prize_table"`
-	Stats         *rgsBundleStats      `json:"stats,omitempty"`
-	Integrity     *rgsBundleIntegrity  `json:"integrity,omitempty"`
-}
-
-type rgsBundleMechanic struct {
-	Type            string      `json:"type"`
-	MatchCount      int         `json:"match_count,omitempty"`
-	GridSize        interface{} `json:"grid_size,omitempty"`
-	FailProbability float64     `json:"fail_probability,omitempty"`
-}
-
-type rgsBundlePrizeTier struct {
-	Tier        string  `json:"tier"`
-	Multiplier  float64 `json:"multiplier"`
-	Weight      float64 `json:"weight"` // may be float in JSON; we convert to int64
-	Probability float64 `json:"probability,omitempty"`
-}
-
-type rgsBundleStats struct {
-	ComputedRTP float64 `json:"computed_rtp"`
-	HitRate     float64 `json:"hit_rate"`
-	Variance    float64 `json:"variance"`
-	MaxWin      float64 `json:"max_win"`
-}
-
-type rgsBundleIntegrity struct {
-	ContentHash string `json:"content_hash"`
-}
-
-// registerBundleMath reads math.json from the extracted bundle dir, parses it as RGS schema,
-// converts to gamemath.GameMath keyed by gameID, and registers with s.gameMath.
+// registerBundleMath reads math.json from the extracted bundle dir in any supported format
+// (gamemath.Import) and registers it with s.gameMath keyed by gameID.
 func (s *Server) registerBundleMath(gameID, targetRoot string) error {
 	mathPath := filepath.Join(targetRoot, "math.json")
 	data, err := os.ReadFile(mathPath)
 	if err != nil {
 		return fmt.Errorf("read math.json: %w", err)
 	}
-	var raw rgsMathFile
-	if err := json.Unmarshal(data, &raw); err != nil {
-		return fmt.Errorf("parse math.json: %w", err)
-	}
-	if len(raw.PrizeTable) == 0 {
-		return fmt.Errorf("math.json: prize_table is empty")
-	}
-	// Convert to gamemath.GameMath; use gameID as model ID so round APIs resolve by game_id.
-	math := &gamemath.GameMath{
-		SchemaVersion: raw.SchemaVersion,
-		ModelID:       gameID,
-		ModelVersion:  raw.ModelVersion,
-		Mechanic: gamemath.Mechanic{
-			Type:       raw.Mechanic.Type,
-			MatchCount: raw.Mechanic.MatchCount,
-		},
-		MathMode:   raw.MathMode,
-		WinLogic:   raw.WinLogic,
-		PrizeTable: make([]gamemath.PrizeTier, 0, len(raw.PrizeTable)),
-	}
-	for _, p := range raw.PrizeTable {
-		w := int64(p.Weight)
-		if w <= 0 && p.Probability > 0 {
-			w = int64(p.Probability * 1e9)
-		}
-		if w <= 0 {
-			w = 1
-		}
-		math.PrizeTable = append(math.PrizeTable, gamemath.PrizeTier{
-			Tier:       p.Tier,
-			Multiplier: p.Multiplier,
-			Weight:     w,
-		})
-	}
-	if raw.Stats != nil {
-		math.Stats = &gamemath.GameStats{
-			ComputedRTP: raw.Stats.ComputedRTP,
-			HitRate:     raw.Stats.HitRate,
-			Variance:    raw.Stats.Variance,
-		}
-	}
-	if raw.Integrity != nil {
-		math.Integrity = &gamemath.Integrity{ContentHash: raw.Integrity.ContentHash}
+	// Use gameID as model ID so round APIs resolve by game_id.
+	math, err := s.importBundleMath(data, gameID, gameID)
+	if err != nil {
+		return fmt.Errorf("math.json: %w", err)
 	}
 	return s.gameMath.Register(math)
 }
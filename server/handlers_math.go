@@ -0,0 +1,99 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
+)
+
+// handleGetMathReport returns the analyzer report for a registered model
+// (GET /rgs/admin/math/{modelId}/report).
+func (s *Server) handleGetMathReport(w http.ResponseWriter, r *http.Request) {
+	modelID := strings.TrimSpace(r.PathValue("modelId"))
+	math := s.gameMath.Get(modelID)
+	if math == nil {
+		writeError(w, http.StatusNotFound, "game math not found", "MATH_NOT_FOUND")
+		return
+	}
+	report, err := gamemath.Analyze(math)
+	if err != nil {
+		writeError(w, http.StatusUnprocessableEntity, err.Error(), "MATH_INVALID")
+		return
+	}
+	writeJSON(w, http.StatusOK, report)
+}
+
+// MathVersionsResponse lists the stored versions of a model.
+type MathVersionsResponse struct {
+	ModelID       string                 `json:"model_id"`
+	ActiveVersion string                 `json:"active_version"`
+	Versions      []gamemath.VersionInfo `json:"versions"`
+}
+
+// ActivateMathRequest is the body for POST /rgs/admin/math/{modelId}/activate.
+type ActivateMathRequest struct {
+	ModelVersion string `json:"model_version"`
+}
+
+func (s *Server) writeMathVersions(w http.ResponseWriter, modelID string) {
+	versions, err := s.gameMath.Versions(modelID)
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error(), "MATH_NOT_FOUND")
+		return
+	}
+	resp := MathVersionsResponse{ModelID: modelID, Versions: versions}
+	for _, v := range versions {
+		if v.Status == gamemath.StatusActive {
+			resp.ActiveVersion = v.ModelVersion
+		}
+	}
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// handleListMathVersions lists every version of a model (GET /rgs/admin/math/{modelId}/versions).
+func (s *Server) handleListMathVersions(w http.ResponseWriter, r *http.Request) {
+	s.writeMathVersions(w, strings.TrimSpace(r.PathValue("modelId")))
+}
+
+// handleActivateMath activates a stored version (POST /rgs/admin/math/{modelId}/activate).
+// Sessions that already played keep their pinned version.
+func (s *Server) handleActivateMath(w http.ResponseWriter, r *http.Request) {
+	modelID := strings.TrimSpace(r.PathValue("modelId"))
+	var req ActivateMathRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body", "INVALID_BODY")
+		return
+	}
+	if err := s.gameMath.Activate(modelID, strings.TrimSpace(req.ModelVersion)); err != nil {
+		writeError(w, http.StatusNotFound, err.Error(), "MATH_NOT_FOUND")
+		return
+	}
+	if err := s.syncMathStatusToDB(r.Context(), modelID); err != nil {
+		log.Printf("game_math: sync status model_id=%s: %v", modelID, err)
+	}
+	log.Printf("game_math: activated model_id=%s version=%s", modelID, req.ModelVersion)
+	s.writeMathVersions(w, modelID)
+}
+
+// handleRollbackMath re-activates the previously active version (POST /rgs/admin/math/{modelId}/rollback).
+func (s *Server) handleRollbackMath(w http.ResponseWriter, r *http.Request) {
+	modelID := strings.TrimSpace(r.PathValue("modelId"))
+	version, err := s.gameMath.Rollback(modelID)
+	if err != nil {
+		code, errCode := http.StatusNotFound, "MATH_NOT_FOUND"
+		if errors.Is(err, gamemath.ErrNoRollback) {
+			code, errCode = http.StatusConflict, "NO_ROLLBACK"
+		}
+		writeError(w, code, err.Error(), errCode)
+		return
+	}
+	if err := s.syncMathStatusToDB(r.Context(), modelID); err != nil {
+		log.Printf("game_math: sync status model_id=%s: %v", modelID, err)
+	}
+	log.Printf("game_math: rolled back model_id=%s to version=%s", modelID, version)
+	s.writeMathVersions(w, modelID)
+}
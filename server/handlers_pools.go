@@ -0,0 +1,140 @@
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	rgsdb "github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
+)
+
+// newTicketPools picks the ticket pool backend: the ticket_pools table when a DB is configured
+// (shared by every RGS instance), otherwise data/ticket_pools.json.
+func newTicketPools(dataDir string) *gamemath.Pools {
+	if db, err := rgsdb.GetDB(); err == nil && db != nil {
+		return gamemath.NewPools(gamemath.NewDBPoolBackend(db))
+	}
+	return gamemath.NewPools(gamemath.NewFilePoolBackend(dataDir))
+}
+
+// returnPoolTicket puts a LIMITED ticket back into its series after a failed purchase.
+func (s *Server) returnPoolTicket(t *gamemath.PoolTicket) {
+	if t == nil {
+		return
+	}
+	if err := s.pools.Return(*t); err != nil {
+		log.Printf("ticket pool: return model_id=%s version=%s series=%s tier=%s: %v", t.ModelID, t.ModelVersion, t.SeriesID, t.Tier, err)
+	}
+}
+
+// returnPoolTickets returns every LIMITED ticket of a failed purchase; nil entries are skipped.
+func (s *Server) returnPoolTickets(tickets []*gamemath.PoolTicket) {
+	for _, t := range tickets {
+		s.returnPoolTicket(t)
+	}
+}
+
+// OpenTicketPoolRequest is the body for POST /rgs/admin/math/{modelId}/pool.
+type OpenTicketPoolRequest struct {
+	SeriesID     string `json:"series_id"`
+	TotalTickets int64  `json:"total_tickets"` // defaults to the model's total_tickets
+}
+
+// TicketPoolResponse reports a series and its remaining prizes.
+type TicketPoolResponse struct {
+	ModelID      string              `json:"model_id"`
+	ModelVersion string              `json:"model_version,omitempty"`
+	SeriesID     string              `json:"series_id"`
+	TotalTickets int64               `json:"total_tickets"`
+	Remaining    int64               `json:"remaining"`
+	Exhausted    bool                `json:"exhausted"`
+	Tiers        []gamemath.PoolTier `json:"tiers"`
+}
+
+func newTicketPoolResponse(p *gamemath.Pool) TicketPoolResponse {
+	return TicketPoolResponse{
+		ModelID:      p.ModelID,
+		ModelVersion: p.ModelVersion,
+		SeriesID:     p.SeriesID,
+		TotalTickets: p.TotalTickets,
+		Remaining:    p.Remaining(),
+		Exhausted:    p.Exhausted(),
+		Tiers:        p.Tiers,
+	}
+}
+
+// handleGetTicketPool reports the current series of a LIMITED model's active version, or of the
+// version in ?version= (GET /rgs/admin/math/{modelId}/pool).
+func (s *Server) handleGetTicketPool(w http.ResponseWriter, r *http.Request) {
+	modelID := strings.TrimSpace(r.PathValue("modelId"))
+	version := strings.TrimSpace(r.URL.Query().Get("version"))
+	if version == "" {
+		math := s.gameMath.Get(modelID)
+		if math == nil {
+			writeError(w, http.StatusNotFound, "game math not found", "MATH_NOT_FOUND")
+			return
+		}
+		version = math.ModelVersion
+	}
+	p, err := s.pools.Get(modelID, version)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "TECHNICAL_ERROR")
+		return
+	}
+	if p == nil {
+		writeError(w, http.StatusNotFound, "no ticket pool for model", "POOL_NOT_FOUND")
+		return
+	}
+	writeJSON(w, http.StatusOK, newTicketPoolResponse(p))
+}
+
+// handleOpenTicketPool prints a new series for a LIMITED model's active version, replacing its
+// current one (POST /rgs/admin/math/{modelId}/pool).
+func (s *Server) handleOpenTicketPool(w http.ResponseWriter, r *http.Request) {
+	modelID := strings.TrimSpace(r.PathValue("modelId"))
+	math := s.gameMath.Get(modelID)
+	if math == nil {
+		writeError(w, http.StatusNotFound, "game math not found", "MATH_NOT_FOUND")
+		return
+	}
+	if !math.IsLimited() {
+		writeError(w, http.StatusBadRequest, "math_mode is not LIMITED", "MATH_NOT_LIMITED")
+		return
+	}
+	var req OpenTicketPoolRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body", "INVALID_BODY")
+		return
+	}
+	req.SeriesID = strings.TrimSpace(req.SeriesID)
+	if req.SeriesID == "" {
+		writeError(w, http.StatusBadRequest, "series_id required", "INVALID_BODY")
+		return
+	}
+	if cur, err := s.pools.Get(modelID, math.ModelVersion); err == nil && cur != nil && cur.SeriesID == req.SeriesID {
+		writeError(w, http.StatusConflict, "series already open", "SERIES_EXISTS")
+		return
+	}
+	p, err := s.pools.Open(math, req.SeriesID, req.TotalTickets)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error(), "POOL_OPEN_FAILED")
+		return
+	}
+	log.Printf("ticket pool: opened series=%s for model_id=%s version=%s (%d tickets)", p.SeriesID, modelID, p.ModelVersion, p.TotalTickets)
+	writeJSON(w, http.StatusOK, newTicketPoolResponse(p))
+}
+
+// poolErrorCode maps ticket pool errors to API error codes.
+func poolErrorCode(err error) (int, string) {
+	switch {
+	case errors.Is(err, gamemath.ErrPoolExhausted):
+		return http.StatusConflict, "SERIES_EXHAUSTED"
+	case errors.Is(err, gamemath.ErrNoPool):
+		return http.StatusConflict, "SERIES_NOT_OPEN"
+	default:
+		return http.StatusInternalServerError, "TECHNICAL_ERROR"
+	}
+}
@@ -2,17 +2,15 @@ package server
 
 import (
 	"encoding/json"
+	"errors"
+	"io"
 	"net/http"
 	"strings"
-	"time"
 
-	rgsdb "github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/fair"
 	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
-	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games/crash"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games"
 	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games/scratch"
-	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/round"
-
-	"github.com/google/uuid"
 )
 
 // LaunchRequest is the body for POST .../launch.
@@ -63,38 +61,27 @@ func (s *Server) handleProviderRoute(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if len(parts) == 5 && parts[3] == "round" {
-		switch parts[4] {
-		case "start":
-			if r.Method != http.MethodPost {
-				writeError(w, http.StatusMethodNotAllowed, "method not allowed", "METHOD_NOT_ALLOWED")
-				return
-			}
-			if gameID == "crash" {
-				s.handleCrashRoundStart(w, r, providerID)
-				return
-			}
-			// Any other game: use generic scratch round (math from gamemath.Store keyed by gameId, or legacy default).
-			s.handleScratchRoundStart(w, r, providerID, gameID)
+		eng, ok := s.registry.Engine(gameID)
+		if !ok {
+			writeError(w, http.StatusNotFound, "game not found", "GAME_NOT_FOUND")
+			return
+		}
+		// round/start opens a round; round/status (GET) and every other round/<action> (POST) go
+		// to the game's engine.
+		method := http.MethodPost
+		if parts[4] == "status" {
+			method = http.MethodGet
+		}
+		if r.Method != method {
+			writeError(w, http.StatusMethodNotAllowed, "method not allowed", "METHOD_NOT_ALLOWED")
 			return
-		case "cashout":
-			if r.Method != http.MethodPost {
-				writeError(w, http.StatusMethodNotAllowed, "method not allowed", "METHOD_NOT_ALLOWED")
-				return
-			}
-			if gameID == "crash" {
-				s.handleCrashCashout(w, r, providerID)
-				return
-			}
-		case "status":
-			if r.Method != http.MethodGet {
-				writeError(w, http.StatusMethodNotAllowed, "method not allowed", "METHOD_NOT_ALLOWED")
-				return
-			}
-			if gameID == "crash" {
-				s.handleCrashStatus(w, r)
-				return
-			}
 		}
+		if parts[4] == "start" {
+			s.handleRoundStart(w, r, eng, gameID)
+		} else {
+			s.handleRoundAction(w, r, eng, parts[4])
+		}
+		return
 	}
 	writeError(w, http.StatusNotFound, "invalid path", "INVALID_PATH")
 }
@@ -142,9 +129,9 @@ func (s *Server) handleLaunch(w http.ResponseWriter, r *http.Request, providerID
 	})
 }
 
-// ScratchRoundStartRequest is the body for scratch round/start.
+// RoundStartRequest is the body for round/start of every game.
 // Accepts API-doc names (session_id, bet_amount, round_id) and legacy (token, amount, roundId).
-type ScratchRoundStartRequest struct {
+type RoundStartRequest struct {
 	// API-doc (Operator_API_Documentation.md) parameter names
 	SessionID  string  `json:"session_id"`
 	BetAmount  float64 `json:"bet_amount"`
@@ -160,17 +147,28 @@ type ScratchRoundStartRequest struct {
 
 // ScratchRoundStartResponse is the response for scratch round start.
 type ScratchRoundStartResponse struct {
-	RoundID      string    `json:"roundId"`
-	Symbols      [3]string `json:"symbols"`
-	WinAmount    float64   `json:"winAmount"`
-	BalanceDelta float64   `json:"balanceDelta"`   // winAmount - bet (positive if win, negative if lose)
-	Tier         string    `json:"tier,omitempty"` // prize tier id (e.g. for lucky_star: prize_1770242082499_ro6ph9pnk)
-	Error        string    `json:"error,omitempty"`
+	RoundID      string        `json:"roundId"`
+	Symbols      [3]string     `json:"symbols"`
+	WinAmount    float64       `json:"winAmount"`
+	BalanceDelta float64       `json:"balanceDelta"`   // winAmount - bet (positive if win, negative if lose)
+	Tier         string        `json:"tier,omitempty"` // prize tier id (e.g. for lucky_star: prize_1770242082499_ro6ph9pnk)
+	Wins         []scratch.Win `json:"wins,omitempty"` // every prize awarded (several for MULTI_WIN combinations)
+	Fair         *fair.Proof   `json:"fair,omitempty"` // provably fair mode: seeds and nonce of the round
+	Error        string        `json:"error,omitempty"`
 }
 
-func (s *Server) handleScratchRoundStart(w http.ResponseWriter, r *http.Request, providerID, gameID string) {
-	var req ScratchRoundStartRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+// handleRoundStart implements POST .../games/<gameId>/round/start: it places a bet on a new
+// round of the game's engine and answers with the engine's view of the round. The round
+// orchestrator (startRound) moves the money and makes a repeated round_id a replay. The engine
+// reads its own bet options (e.g. crash auto_cashout) from the body.
+func (s *Server) handleRoundStart(w http.ResponseWriter, r *http.Request, eng games.GameEngine, gameID string) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid body", "INVALID_BODY")
+		return
+	}
+	var req RoundStartRequest
+	if err := json.Unmarshal(body, &req); err != nil {
 		writeError(w, http.StatusBadRequest, "invalid body", "INVALID_BODY")
 		return
 	}
@@ -212,420 +210,138 @@ func (s *Server) handleScratchRoundStart(w http.ResponseWriter, r *http.Request,
 	if roundID == "" {
 		roundID = strings.TrimSpace(req.RoundId)
 	}
-	if roundID == "" {
-		roundID = uuid.New().String()
-	}
 	deviceType := strings.TrimSpace(req.DeviceType)
-	if deviceType == "" {
-		deviceType = "desktop"
-	}
 	if deviceType != "desktop" && deviceType != "mobile" {
 		deviceType = "desktop"
 	}
-	gameCode := strings.TrimSpace(req.GameCode)
-	if gameCode == "" {
-		gameCode = gameID
-	}
-
-	if existing, err := s.results.GetByRoundID(roundID); err == nil && existing != nil {
-		var syms [3]string
-		for i := 0; i < 3 && i < len(existing.Symbols); i++ {
-			syms[i] = existing.Symbols[i]
-		}
-		writeJSON(w, http.StatusOK, ScratchRoundStartResponse{
-			RoundID:      existing.RoundID,
-			Symbols:      syms,
-			WinAmount:    existing.WinAmount,
-			BalanceDelta: existing.BalanceDelta,
-		})
-		return
-	}
-
-	var outcome scratch.Outcome
-	// Resolve math by game_id from URL so any imported bundle (with registered math) works.
-	modelID := gameID
-	if math := s.gameMath.Get(modelID); math != nil {
-		if o, ok := scratch.GenerateWithMath(betAmount, math); ok {
-			outcome = o
-		} else {
-			outcome = scratch.Generate(betAmount)
-		}
-	} else {
-		outcome = scratch.Generate(betAmount)
-	}
-	var balanceDelta float64
-	if s.operator != nil {
-		db, err := rgsdb.GetDB()
-		if err != nil || db == nil {
-			writeError(w, http.StatusBadGateway, "database unavailable", "TECHNICAL_ERROR")
-			return
-		}
-		ctx := r.Context()
-		var userID, accountID string
-		var dbGameCode string
-		var operatorID int
-		err = db.QueryRowContext(ctx, `
-        SELECT gs.user_id, u.username, gs.game_id, gs.operator_id
-        FROM game_sessions gs
-        JOIN users u ON gs.user_id = u.id
-        WHERE gs.session_id = $1
-      `, sessionID).Scan(&userID, &accountID, &dbGameCode, &operatorID)
-		if err != nil {
-			writeJSON(w, http.StatusOK, ScratchRoundStartResponse{
-				RoundID:      roundID,
-				Symbols:      outcome.Symbols,
-				WinAmount:    outcome.WinAmount,
-				BalanceDelta: 0,
-				Tier:         outcome.Tier,
-				Error:        "invalid session",
-			})
-			return
-		}
-		if gameCode == "scratch" && dbGameCode != "" {
-			gameCode = dbGameCode
-		}
-		winAmount := outcome.WinAmount
-		// API doc flow: Debit (place bet) then Credit (settle with win amount). Each has its own tx_id.
-		txIDDebit := uuid.New().String()
-		txIDCredit := uuid.New().String()
-		respDebit, err := s.operator.Debit(userID, sessionID, roundID, txIDDebit, gameCode, deviceType, "1.0", betAmount, "")
-		if err != nil || respDebit.Code != 0 {
-			errMsg := "debit failed"
-			if respDebit != nil && respDebit.Message != "" {
-				errMsg = respDebit.Message
-			} else if err != nil {
-				errMsg = err.Error()
-			}
-			writeJSON(w, http.StatusOK, ScratchRoundStartResponse{
-				RoundID:      roundID,
-				Symbols:      outcome.Symbols,
-				WinAmount:    0,
-				BalanceDelta: 0,
-				Tier:         outcome.Tier,
-				Error:        errMsg,
-			})
-			return
-		}
-		respCredit, err := s.operator.Credit(userID, sessionID, roundID, txIDCredit, gameCode, deviceType, "1.0", "completed", "", winAmount)
-		if err != nil || respCredit.Code != 0 {
-			errMsg := "credit failed"
-			if respCredit != nil && respCredit.Message != "" {
-				errMsg = respCredit.Message
-			} else if err != nil {
-				errMsg = err.Error()
-			}
-			writeJSON(w, http.StatusOK, ScratchRoundStartResponse{
-				RoundID:      roundID,
-				Symbols:      outcome.Symbols,
-				WinAmount:    0,
-				BalanceDelta: 0,
-				Tier:         outcome.Tier,
-				Error:        errMsg,
-			})
-			return
-		}
-		netResult := winAmount - betAmount
-		if netResult == 0 {
-			netResult = -betAmount
-		}
-		// Use rgs_wallet_transactions (game_crafter wallet_transactions is for crypto only)
-		_, _ = db.ExecContext(ctx, `
-        INSERT INTO rgs_wallet_transactions (
-          transaction_id,
-          account_id,
-          session_id,
-          round_id,
-          game_id,
-          type,
-          status,
-          amount,
-          currency,
-          bet_amount,
-          win_amount,
-          net_result,
-          user_id,
-          operator_id
-        ) VALUES (
-          $1, $2, $3, $4, $5, 'debit_and_credit', 'completed',
-          $6, $7, $8, $9, $10, $11, $12
-        )
-      `,
-			txIDDebit,
-			accountID,
-			sessionID,
-			roundID,
-			gameCode,
-			betAmount,
-			currency,
-			betAmount,
-			winAmount,
-			netResult,
-			userID,
-			operatorID,
-		)
-		if winAmount > 0 {
-			balanceDelta = winAmount - betAmount
-		} else {
-			balanceDelta = -betAmount
-		}
-	} else {
-		betID, status, err := s.client.Bet(sessionID, currency, betAmount, "Scratch", "")
-		if err != nil {
-			code := status
-			if code == 0 {
-				code = http.StatusBadGateway
-			}
-			writeError(w, code, err.Error(), "BET_FAILED")
-			return
-		}
-		if outcome.Match {
-			_, err = s.client.Win(sessionID, currency, outcome.WinAmount, "Scratch", "")
-			if err != nil {
-				_, _ = s.client.Rollback(sessionID, betID)
-				writeError(w, http.StatusBadGateway, err.Error(), "WIN_FAILED")
-				return
-			}
-			balanceDelta = outcome.WinAmount - betAmount
-		} else {
-			balanceDelta = -betAmount
-		}
-	}
-
-	outcomeStr := "lose"
-	if outcome.Match {
-		outcomeStr = "win"
-	}
-	symbolsSlice := outcome.Symbols[:]
-	_ = s.results.Append(&round.Result{
-		RoundID:      roundID,
-		BetID:        "",
-		Outcome:      outcomeStr,
-		NextNumber:   0,
-		BalanceDelta: balanceDelta,
-		SettledAt:    time.Now(),
-		Symbols:      symbolsSlice,
-		WinAmount:    outcome.WinAmount,
-	})
 
-	writeJSON(w, http.StatusOK, ScratchRoundStartResponse{
-		RoundID:      roundID,
-		Symbols:      outcome.Symbols,
-		WinAmount:    outcome.WinAmount,
-		BalanceDelta: balanceDelta,
-		Tier:         outcome.Tier,
+	rnd, _, err := s.startRound(r.Context(), eng, games.Bet{
+		RoundID:    roundID,
+		GameID:     gameID,
+		SessionID:  sessionID,
+		Currency:   currency,
+		Amount:     betAmount,
+		DeviceType: deviceType,
+		GameCode:   strings.TrimSpace(req.GameCode),
+		Data:       body,
 	})
-}
-
-// Crash round/start
-type CrashRoundStartRequest struct {
-	Token    string  `json:"token"`
-	Currency string  `json:"currency"`
-	Amount   float64 `json:"amount"`
-	RoundID  string  `json:"roundId"`
-}
-
-type CrashRoundStartResponse struct {
-	RoundID     string `json:"roundId"`
-	StartedAtMs int64  `json:"startedAtMs"`
-	Error       string `json:"error,omitempty"`
-}
-
-func (s *Server) handleCrashRoundStart(w http.ResponseWriter, r *http.Request, providerID string) {
-	var req CrashRoundStartRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		writeError(w, http.StatusBadRequest, "invalid body", "INVALID_BODY")
-		return
-	}
-	req.Token = strings.TrimSpace(req.Token)
-	if req.Token == "" {
-		writeError(w, http.StatusUnauthorized, "token required", "TOKEN_REQUIRED")
-		return
-	}
-	if req.Currency == "" {
-		req.Currency = "USD"
-	}
-	if req.Amount <= 0 {
-		writeError(w, http.StatusBadRequest, "amount must be positive", "INVALID_AMOUNT")
-		return
-	}
-	roundID := strings.TrimSpace(req.RoundID)
-	if roundID == "" {
-		roundID = uuid.New().String()
-	}
-
-	betID, status, err := s.client.Bet(req.Token, req.Currency, req.Amount, "Crash", "")
 	if err != nil {
-		code := status
-		if code == 0 {
-			code = http.StatusBadGateway
-		}
-		writeError(w, code, err.Error(), "BET_FAILED")
+		code, errCode := roundErrorStatus(err)
+		writeError(w, code, err.Error(), errCode)
 		return
 	}
-
-	crashStep := crash.GenerateCrashStep()
-	cr := s.crashStore.Create(roundID, betID, req.Currency, req.Amount, crashStep)
-	writeJSON(w, http.StatusOK, CrashRoundStartResponse{
-		RoundID:     cr.RoundID,
-		StartedAtMs: cr.StartedAt.UnixMilli(),
-	})
+	writeJSON(w, http.StatusOK, rnd.View)
 }
 
-// Crash round/cashout
-type CrashCashoutRequest struct {
+// RoundActionRequest identifies the round of a round/<action> request. It is read from the JSON
+// body, or from the query string (session_id or token, roundId) for GET round/status; the
+// engine reads the rest of the body.
+type RoundActionRequest struct {
+	SessionID string `json:"session_id"`
+	RoundID   string `json:"round_id"`
+	// Legacy / alternate names (same meaning)
 	Token   string `json:"token"`
-	RoundID string `json:"roundId"`
-	Step    int    `json:"step"`
-}
-
-type CrashCashoutResponse struct {
-	RoundID      string  `json:"roundId"`
-	CashedOut    bool    `json:"cashedOut"`
-	WinAmount    float64 `json:"winAmount"`
-	BalanceDelta float64 `json:"balanceDelta"`
-	Crashed      bool    `json:"crashed"`
-	CrashStep    int     `json:"crashStep,omitempty"`
-	Error        string  `json:"error,omitempty"`
+	RoundId string `json:"roundId"`
 }
 
-func (s *Server) handleCrashCashout(w http.ResponseWriter, r *http.Request, providerID string) {
-	var req CrashCashoutRequest
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+// handleRoundAction implements .../games/<gameId>/round/<action> (e.g. crash cashout and
+// status): the action goes to the game's engine through the round orchestrator (actRound),
+// which pays the round when the action decides it.
+func (s *Server) handleRoundAction(w http.ResponseWriter, r *http.Request, eng games.GameEngine, action string) {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 1<<20))
+	if err != nil {
 		writeError(w, http.StatusBadRequest, "invalid body", "INVALID_BODY")
 		return
 	}
-	req.Token = strings.TrimSpace(req.Token)
-	if req.Token == "" {
-		writeError(w, http.StatusUnauthorized, "token required", "TOKEN_REQUIRED")
-		return
-	}
-	req.RoundID = strings.TrimSpace(req.RoundID)
-	if req.RoundID == "" {
-		writeError(w, http.StatusBadRequest, "roundId required", "INVALID_ROUND")
-		return
-	}
-
-	cr, ok := s.crashStore.Get(req.RoundID)
-	if !ok {
-		writeError(w, http.StatusNotFound, "round not found", "ROUND_NOT_FOUND")
-		return
-	}
-	if cr.Settled {
-		writeError(w, http.StatusConflict, "round already settled", "ROUND_SETTLED")
+	var req RoundActionRequest
+	if len(body) > 0 && json.Unmarshal(body, &req) != nil {
+		writeError(w, http.StatusBadRequest, "invalid body", "INVALID_BODY")
 		return
 	}
-
-	// Current step from elapsed time (100ms per step)
-	elapsed := time.Since(cr.StartedAt).Milliseconds()
-	currentStep := int(elapsed / 100)
-	if currentStep < 0 {
-		currentStep = 0
-	}
-
-	if req.Step > currentStep {
-		writeError(w, http.StatusBadRequest, "cannot cash out in the future", "INVALID_STEP")
+	q := r.URL.Query()
+	sessionID := firstNonEmpty(req.SessionID, req.Token, q.Get("session_id"), q.Get("token"))
+	if sessionID == "" {
+		writeError(w, http.StatusUnauthorized, "token required", "TOKEN_REQUIRED")
 		return
 	}
-	if req.Step < 0 {
-		req.Step = 0
-	}
-
-	if req.Step >= cr.CrashStep {
-		// Crashed before cash out - lose
-		s.crashStore.Settle(req.RoundID)
-		writeJSON(w, http.StatusOK, CrashCashoutResponse{
-			RoundID:      req.RoundID,
-			CashedOut:    false,
-			Crashed:      true,
-			CrashStep:    cr.CrashStep,
-			WinAmount:    0,
-			BalanceDelta: -cr.Amount,
-		})
+	roundID := firstNonEmpty(req.RoundID, req.RoundId, q.Get("roundId"), q.Get("round_id"))
+	if roundID == "" {
+		writeError(w, http.StatusBadRequest, "roundId required", "INVALID_ROUND")
 		return
 	}
-
-	// Cash out successful
-	mult := crash.Multiplier(req.Step)
-	winAmount := cr.Amount * mult
-	_, err := s.client.Win(req.Token, cr.Currency, winAmount, "Crash", "")
+	rnd, err := s.actRound(r.Context(), eng, sessionID, roundID, games.Action{Name: action, Data: body})
 	if err != nil {
-		writeError(w, http.StatusBadGateway, err.Error(), "WIN_FAILED")
+		code, errCode := roundErrorStatus(err)
+		writeError(w, code, err.Error(), errCode)
 		return
 	}
-	s.crashStore.Settle(req.RoundID)
-
-	writeJSON(w, http.StatusOK, CrashCashoutResponse{
-		RoundID:      req.RoundID,
-		CashedOut:    true,
-		Crashed:      false,
-		WinAmount:    winAmount,
-		BalanceDelta: winAmount - cr.Amount,
-	})
+	writeJSON(w, http.StatusOK, rnd.View)
 }
 
-// Crash round/status (GET)
-func (s *Server) handleCrashStatus(w http.ResponseWriter, r *http.Request) {
-	roundID := strings.TrimSpace(r.URL.Query().Get("roundId"))
-	token := strings.TrimSpace(r.URL.Query().Get("token"))
-	if roundID == "" || token == "" {
-		writeError(w, http.StatusBadRequest, "roundId and token required", "INVALID_REQUEST")
-		return
-	}
-
-	cr, ok := s.crashStore.Get(roundID)
-	if !ok {
-		writeJSON(w, http.StatusOK, map[string]interface{}{
-			"currentStep": 0,
-			"crashed":     false,
-			"roundId":     roundID,
-		})
-		return
-	}
-
-	elapsed := time.Since(cr.StartedAt).Milliseconds()
-	currentStep := int(elapsed / 100)
-	if currentStep < 0 {
-		currentStep = 0
-	}
-
-	crashed := currentStep >= cr.CrashStep
-	if crashed {
-		s.crashStore.Settle(roundID)
-	}
-	resp := map[string]interface{}{
-		"roundId":     roundID,
-		"currentStep": currentStep,
-		"multiplier":  crash.Multiplier(currentStep),
-		"crashed":     crashed,
-	}
-	if crashed {
-		resp["crashStep"] = cr.CrashStep
-		resp["crashMultiplier"] = crash.Multiplier(cr.CrashStep)
+// firstNonEmpty returns the first of values that is not blank, trimmed.
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v = strings.TrimSpace(v); v != "" {
+			return v
+		}
 	}
-	writeJSON(w, http.StatusOK, resp)
+	return ""
 }
 
 // handleRegisterGameMath stores game math for a game (POST .../games/:gameId/math). Body = full game math JSON.
 func (s *Server) handleRegisterGameMath(w http.ResponseWriter, r *http.Request, gameID string) {
-	var math gamemath.GameMath
-	if err := json.NewDecoder(r.Body).Decode(&math); err != nil {
+	body, err := io.ReadAll(io.LimitReader(r.Body, 10<<20))
+	if err != nil {
 		writeError(w, http.StatusBadRequest, "invalid body", "INVALID_BODY")
 		return
 	}
+	// Accepts any format gamemath.Import reads; older schema versions are migrated.
+	imp, err := gamemath.Import(body)
+	if err != nil {
+		if isContentHashError(err) {
+			writeError(w, http.StatusUnprocessableEntity, err.Error(), "HASH_MISMATCH")
+			return
+		}
+		writeError(w, http.StatusBadRequest, err.Error(), "INVALID_BODY")
+		return
+	}
+	math := imp.Math
 	if math.ModelID == "" {
 		writeError(w, http.StatusBadRequest, "model_id required", "INVALID_BODY")
 		return
 	}
-	if len(math.PrizeTable) == 0 {
-		writeError(w, http.StatusBadRequest, "prize_table required", "INVALID_BODY")
+	report, err := gamemath.Validate(math, gamemath.StatsTolerance)
+	if err != nil {
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":   err.Error(),
+			"code":    "MATH_INVALID",
+			"message": err.Error(),
+			"report":  report,
+		})
+		return
+	}
+	if err := math.VerifyContentHash(); err != nil {
+		code := "HASH_MISMATCH"
+		if errors.Is(err, gamemath.ErrContentHashMissing) {
+			code = "HASH_REQUIRED"
+		}
+		computed, _ := math.ContentHash()
+		writeJSON(w, http.StatusUnprocessableEntity, map[string]interface{}{
+			"error":                 err.Error(),
+			"code":                  code,
+			"message":               err.Error(),
+			"computed_content_hash": computed,
+		})
 		return
 	}
-	if err := s.gameMath.Register(&math); err != nil {
+	if err := s.gameMath.Register(math); err != nil {
 		writeError(w, http.StatusInternalServerError, err.Error(), "REGISTER_FAILED")
 		return
 	}
 	writeJSON(w, http.StatusOK, map[string]interface{}{
-		"model_id": math.ModelID,
-		"message":  "game math registered",
+		"model_id":     math.ModelID,
+		"message":      "game math registered",
+		"content_hash": math.Integrity.ContentHash,
+		"report":       report,
 	})
 }
@@ -0,0 +1,145 @@
+package server
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"strings"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games/scratch"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/round"
+)
+
+// ScratchRevealReplay is the reveal map of a past scratch round, rebuilt from its result.
+type ScratchRevealReplay struct {
+	RoundID          string         `json:"round_id"`
+	GameID           string         `json:"game_id"`
+	ConfigVersion    string         `json:"config_version,omitempty"` // empty for games without a scratch config
+	PresentationSeed int64          `json:"presentation_seed"`
+	Tier             string         `json:"tier"`
+	FinalPrize       float64        `json:"final_prize"`
+	Wins             []scratch.Win  `json:"wins,omitempty"`
+	RevealMap        []string       `json:"reveal_map"`
+	Prizes           []float64      `json:"prizes,omitempty"`
+	Zones            []scratch.Zone `json:"zones,omitempty"`
+	// Picked and AutoCompleted are set for pick rounds: the cells opened, in pick order, and
+	// whether the timeout opened the remaining ones.
+	Picked        []int `json:"picked,omitempty"`
+	AutoCompleted bool  `json:"autoCompleted,omitempty"`
+}
+
+// handleGetRoundReveal rebuilds the reveal map of a settled scratch round from the config
+// version, outcome and presentation seed it recorded (GET /rgs/admin/rounds/{roundId}/reveal).
+func (s *Server) handleGetRoundReveal(w http.ResponseWriter, r *http.Request) {
+	roundID := strings.TrimSpace(r.PathValue("roundId"))
+	res, err := s.results.GetByRoundID(roundID)
+	if err != nil && !errors.Is(err, fs.ErrNotExist) {
+		writeError(w, http.StatusInternalServerError, err.Error(), "INTERNAL_ERROR")
+		return
+	}
+	if res == nil {
+		writeError(w, http.StatusNotFound, "round not found", "ROUND_NOT_FOUND")
+		return
+	}
+	m, err := s.rebuildScratchReveal(res)
+	switch {
+	case errors.Is(err, errNoPresentationSeed):
+		writeError(w, http.StatusConflict, err.Error(), "NO_PRESENTATION_SEED")
+		return
+	case errors.Is(err, errConfigVersionNotFound):
+		writeError(w, http.StatusConflict, err.Error(), "CONFIG_VERSION_NOT_FOUND")
+		return
+	case errors.Is(err, errPicksNotAvailable):
+		writeError(w, http.StatusConflict, err.Error(), "PICKS_NOT_AVAILABLE")
+		return
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error(), "REVEAL_FAILED")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, ScratchRevealReplay{
+		RoundID:          roundID,
+		GameID:           res.GameID,
+		ConfigVersion:    res.ConfigVersion,
+		PresentationSeed: res.PresentationSeed,
+		Tier:             res.Tier,
+		FinalPrize:       res.WinAmount,
+		Wins:             resultWins(res),
+		RevealMap:        m.Cells,
+		Prizes:           cellPrizes(m, res.Bet),
+		Zones:            m.Zones,
+		Picked:           res.Picked,
+		AutoCompleted:    res.AutoComplete,
+	})
+}
+
+var (
+	errNoPresentationSeed    = errors.New("round has no presentation seed (not a scratch round, or played before seeds were recorded)")
+	errConfigVersionNotFound = errors.New("config version of the round is not stored")
+	errPicksNotAvailable     = errors.New("pick order of the round is not recorded")
+)
+
+// rebuildScratchReveal regenerates the reveal map of a recorded scratch round from its config
+// version, outcome and presentation seed. Pick rounds also need their pick order, recorded in the
+// result when the round settles; results recorded before that fall back to the pick store, which
+// keeps completed rounds for pickRoundRetention.
+func (s *Server) rebuildScratchReveal(res *round.Result) (scratch.RevealMap, error) {
+	if res.GameID == "" {
+		return scratch.RevealMap{}, errNoPresentationSeed
+	}
+	var cfg *ScratchConfig
+	if res.ConfigVersion != "" {
+		cfg = &ScratchConfig{}
+		if !s.configVersions.Get(res.ConfigVersion, cfg) {
+			return scratch.RevealMap{}, fmt.Errorf("%w: %s", errConfigVersionNotFound, res.ConfigVersion)
+		}
+	}
+	outcome := scratch.Outcome{
+		Match:     res.WinAmount > 0,
+		Tier:      res.Tier,
+		WinAmount: res.WinAmount,
+		Wins:      resultWins(res),
+	}
+	copy(outcome.Symbols[:], res.Symbols)
+	src := scratch.PresentationSource(res.PresentationSeed)
+	if cfg == nil || !scratch.IsPick(cfg.Mechanic.Type) {
+		return buildRevealMapFromOutcome(src, cfg, &outcome)
+	}
+	picked := res.Picked
+	if len(picked) == 0 {
+		pr, ok := s.picks.Get(res.RoundID)
+		if !ok || !pr.Completed {
+			return scratch.RevealMap{}, errPicksNotAvailable
+		}
+		picked = pr.Picked
+	}
+	deal, err := cfg.layout().DealPicks(src, &outcome)
+	if err != nil {
+		return scratch.RevealMap{}, err
+	}
+	return deal.Board(picked), nil
+}
+
+// handleListUnreconciledRounds lists the rounds held for reconciliation (GET
+// /rgs/admin/rounds/unreconciled).
+func (s *Server) handleListUnreconciledRounds(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{"rounds": s.unreconciled.List()})
+}
+
+// handleReconcileRound releases a round an operator has reconciled with the wallet, so retries
+// of its key are answered again (POST /rgs/admin/rounds/{roundId}/reconcile). A retry then replays
+// the recorded result, or plays the round anew when none was recorded.
+func (s *Server) handleReconcileRound(w http.ResponseWriter, r *http.Request) {
+	roundID := strings.TrimSpace(r.PathValue("roundId"))
+	ok, err := s.unreconciled.Clear(roundID)
+	if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error(), "INTERNAL_ERROR")
+		return
+	}
+	if !ok {
+		writeError(w, http.StatusNotFound, "round is not held for reconciliation", "ROUND_NOT_FOUND")
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"roundId": roundID, "reconciled": true})
+}
@@ -2,54 +2,169 @@ package server
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"log"
+	"time"
 
 	rgsdb "github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server"
 	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/round"
 )
 
-// loadGameMathFromDB loads ACTIVE game math definitions from the game_math table (if it exists)
-// and registers them into the in-memory gamemath.Store.
-func (s *Server) loadGameMathFromDB() {
+// mathSource is the gamemath.Store source of models loaded from the game_math table.
+const mathSource = tableGameMath
+
+// loadGameMathFromDB loads game math versions from the game_math table (if it exists) into the
+// in-memory gamemath.Store. The table is authoritative and the row status drives the store:
+// ACTIVE rows become the active version (they are loaded first, so the version they replace can
+// then be archived), ARCHIVED rows are rollback targets (most recently updated last), DRAFT rows
+// are stored but not served. Versions whose rows were deleted are removed from the store.
+// Rows that fail to import are logged and skipped; only a failed query is returned.
+func (s *Server) loadGameMathFromDB() error {
 	db, err := rgsdb.GetDB()
 	if err != nil || db == nil {
-		return
+		return err
 	}
 	ctx := context.Background()
 	rows, err := db.QueryContext(ctx, `
-		SELECT model_id, math
+		SELECT game_id, model_id, status, math
 		FROM game_math
-		WHERE status = 'ACTIVE'
+		WHERE status IN ('ACTIVE', 'DRAFT', 'ARCHIVED')
+		ORDER BY (status <> 'ACTIVE'), updated_at
 	`)
 	if err != nil {
 		// Table might not exist yet; fail silently in that case.
 		log.Printf("game_math: query failed (may be missing table): %v", err)
-		return
+		return err
 	}
 	defer rows.Close()
 
+	keep := make(map[string]map[string]bool)
 	for rows.Next() {
-		var modelID string
+		var gameID, modelID, status string
 		var mathJSON []byte
-		if err := rows.Scan(&modelID, &mathJSON); err != nil {
+		if err := rows.Scan(&gameID, &modelID, &status, &mathJSON); err != nil {
 			log.Printf("game_math: scan row: %v", err)
 			continue
 		}
 		if len(mathJSON) == 0 || modelID == "" {
 			continue
 		}
-		var gm gamemath.GameMath
-		if err := json.Unmarshal(mathJSON, &gm); err != nil {
-			log.Printf("game_math: unmarshal math for model_id=%s: %v", modelID, err)
+		// Rows written before the current schema (or as raw bundle JSON) are upgraded on load,
+		// and rows written before content hashes were required are sealed.
+		gm, err := s.importStoredMath(mathJSON, gameID, modelID)
+		if err != nil {
+			log.Printf("game_math: DROPPED model_id=%s (game_id=%s): %v", modelID, gameID, err)
 			continue
 		}
-		// Ensure ModelID is set consistently.
-		if gm.ModelID == "" {
-			gm.ModelID = modelID
+		if keep[modelID] == nil {
+			keep[modelID] = make(map[string]bool)
+		}
+		keep[modelID][gm.ModelVersion] = true
+		if err := s.gameMath.PutFrom(mathSource, gm, status); err != nil {
+			var verr *gamemath.ValidationError
+			if errors.As(err, &verr) || isContentHashError(err) {
+				log.Printf("game_math: DROPPED model_id=%s version=%s: %v", modelID, gm.ModelVersion, err)
+			} else {
+				log.Printf("game_math: register model_id=%s: %v", modelID, err)
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	removed, err := s.gameMath.Retain(mathSource, keep)
+	for _, v := range removed {
+		log.Printf("game_math: removed %s (row deleted from game_math)", v)
+	}
+	return err
+}
+
+// syncMathStatusToDB writes the store's version statuses for modelID back to game_math so other
+// instances (and the next restart) serve the same active version. The notification the update
+// sends is skipped by this instance, whose store already has the new statuses. No-op without a
+// DB.
+func (s *Server) syncMathStatusToDB(ctx context.Context, modelID string) error {
+	db, err := rgsdb.GetDB()
+	if err != nil || db == nil {
+		return err
+	}
+	versions, err := s.gameMath.Versions(modelID)
+	if err != nil {
+		return err
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	var pid uint32
+	if err := tx.QueryRowContext(ctx, `SELECT pg_backend_pid()`).Scan(&pid); err != nil {
+		return err
+	}
+	for _, v := range versions {
+		if _, err := tx.ExecContext(ctx, `
+      UPDATE game_math
+      SET status = $3, updated_at = CURRENT_TIMESTAMP
+      WHERE model_id = $1 AND COALESCE(math->>'model_version', '') = $2 AND status <> $3
+    `, modelID, v.ModelVersion, v.Status); err != nil {
+			return err
 		}
-		if err := s.gameMath.Register(&gm); err != nil {
-			log.Printf("game_math: register model_id=%s: %v", modelID, err)
+	}
+	s.ownNotify.expect(pid)
+	if err := tx.Commit(); err != nil {
+		s.ownNotify.forget(pid)
+		return err
+	}
+	return nil
+}
+
+// mathPinSweepInterval is how often expired session pins are dropped.
+const mathPinSweepInterval = 10 * time.Minute
+
+// newMathPins picks where session pins are kept: the game_math_pins table when a DB is configured
+// (shared by every RGS instance), otherwise the round results, whose modelVersion is the version
+// each session last played.
+func newMathPins(results *round.ResultsStore) gamemath.PinBackend {
+	if db, err := rgsdb.GetDB(); err == nil && db != nil {
+		return gamemath.NewDBPinBackend(db)
+	}
+	return resultPins{results: results}
+}
+
+// resultPins pins a session to the math version of its latest round of the game (scratch math
+// is keyed by game id), as long as that round settled less than gamemath.PinTTL ago. A session
+// that has not played yet is pinned by its first round's result.
+type resultPins struct {
+	results *round.ResultsStore
+}
+
+func (p resultPins) Pin(sessionID, modelID, version string, now time.Time) (string, time.Time, error) {
+	if v, at, ok := p.results.LatestModelVersion(sessionID, modelID); ok && now.Sub(at) < gamemath.PinTTL {
+		return v, at, nil
+	}
+	return version, now, nil
+}
+
+func (resultPins) Prune(time.Time) error { return nil }
+
+// pruneMathPins drops expired session pins until ctx is done.
+func (s *Server) pruneMathPins(ctx context.Context) {
+	ticker := time.NewTicker(mathPinSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := s.gameMath.PrunePins(now); err != nil {
+				log.Printf("game_math: prune pins: %v", err)
+			}
 		}
 	}
 }
+
+// isContentHashError reports whether err is a missing or mismatched Integrity.ContentHash.
+func isContentHashError(err error) bool {
+	return errors.Is(err, gamemath.ErrContentHashMissing) || errors.Is(err, gamemath.ErrContentHashMismatch)
+}
@@ -0,0 +1,316 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/round"
+)
+
+// maxClientRoundIDLen bounds client-chosen round ids.
+const maxClientRoundIDLen = 128
+
+// clientRoundNamespace is the UUID namespace of round ids derived from client round ids.
+var clientRoundNamespace = uuid.MustParse("8ac13a40-bc56-491a-ab19-a332c207e130")
+
+// clientRoundID is the round id of the purchase a session made with a client-chosen round id
+// (idempotency key). Keys are scoped by session: the same key in another session is another
+// round.
+func clientRoundID(sessionID, key string) string {
+	return uuid.NewSHA1(clientRoundNamespace, []byte(sessionID+"\x00"+key)).String()
+}
+
+// registerEngines registers the built-in game engines, and the pages of the games that have a
+// built-in page (other games are served from their bundle, see handleGamePage).
+func (s *Server) registerEngines() {
+	s.registry.RegisterEngine(&scratchEngine{s: s})
+	s.registry.RegisterEngine(&crashEngine{s: s}, "crash")
+	s.pages = map[string]gamePage{
+		"scratch": s.serveScratchGame,
+		"crash":   s.serveCrashGame,
+		luckyStarModelID: func(w http.ResponseWriter, r *http.Request, token, lang, currency, providerID, embedOrigin string) {
+			s.serveBundleGame(w, r, luckyStarModelID, token, lang, currency, providerID, embedOrigin)
+		},
+	}
+}
+
+var (
+	errRoundIDTooLong    = games.Errorf(http.StatusBadRequest, "INVALID_REQUEST", "roundId is too long")
+	errRoundInProgress   = games.Errorf(http.StatusConflict, "ROUND_IN_PROGRESS", "a request for this roundId is in progress")
+	errRoundIDReused     = games.Errorf(http.StatusConflict, "ROUND_ID_REUSED", "roundId was already used for a different purchase")
+	errReplayUnsupported = games.Errorf(http.StatusConflict, "ROUND_SETTLED", "round already settled")
+	errRoundUnreconciled = games.Errorf(http.StatusConflict, "ROUND_UNRECONCILED", "round is held for reconciliation")
+	errRoundNotRecorded  = games.Errorf(http.StatusInternalServerError, "ROUND_NOT_RECORDED", "round was paid but its result could not be recorded; it is held for reconciliation")
+)
+
+// roundErrorStatus returns the HTTP status and error code to answer a round error with.
+func roundErrorStatus(err error) (int, string) {
+	var gerr *games.Error
+	if errors.As(err, &gerr) {
+		return gerr.Status, gerr.Code
+	}
+	return poolErrorCode(err)
+}
+
+// startRound plays bet on eng. It is the one place a round's money moves:
+//
+//   - Engines implementing games.BetChecker may reject the bet first.
+//   - A bet with a RoundID is a client key: the round id is derived from the session and the
+//     key (clientRoundID). A bet whose key the session already used with the same game and
+//     amount is a retry: the round still in play (engines implementing games.Resumer) or the
+//     settled round (engines implementing games.Replayer) is returned and nothing is charged.
+//     Reusing a key for a different game or amount fails with 409.
+//   - Instant engines decide the round first; then the bet is debited and the win credited. A
+//     failed debit voids the round, a failed credit refunds the debit too.
+//   - Other engines start once the bet is debited; a failed start refunds it.
+//
+// Settled rounds are recorded in the round results. The returned bool reports a replay.
+//
+// A round whose refund fails too, or whose result cannot be recorded, leaves nothing a retry
+// could be replayed from, so it fails closed: it is held in s.unreconciled and retries of its key
+// fail with 409 ROUND_UNRECONCILED until an operator clears it.
+//
+// Retries are detected with an in-process lock on the round id and the instance's own stores
+// (round results, open rounds), so the guarantee holds for one RGS instance; deployments with
+// several instances must route a session's requests to the same instance.
+func (s *Server) startRound(ctx context.Context, eng games.GameEngine, bet games.Bet) (*games.Round, bool, error) {
+	bet.RoundID = strings.TrimSpace(bet.RoundID)
+	if bet.RoundID == "" {
+		bet.RoundID = uuid.New().String()
+	} else {
+		if len(bet.RoundID) > maxClientRoundIDLen {
+			return nil, false, errRoundIDTooLong
+		}
+		bet.RoundID = clientRoundID(bet.SessionID, bet.RoundID)
+		if !s.inflight.tryLock(bet.RoundID) {
+			return nil, false, errRoundInProgress
+		}
+		defer s.inflight.unlock(bet.RoundID)
+		if _, ok := s.unreconciled.Get(bet.RoundID); ok {
+			return nil, false, errRoundUnreconciled
+		}
+		if r, ok := eng.(games.Resumer); ok {
+			if rnd, ok := r.Resume(ctx, bet.RoundID); ok {
+				if rnd.SessionID != bet.SessionID || rnd.GameID != bet.GameID || rnd.Amount != bet.Amount {
+					return nil, false, errRoundIDReused
+				}
+				return rnd, true, nil
+			}
+		}
+		if res, err := s.results.GetByRoundID(bet.RoundID); err == nil && res != nil {
+			rnd, err := s.replayRound(ctx, eng, &bet, res)
+			return rnd, err == nil, err
+		}
+	}
+	if c, ok := eng.(games.BetChecker); ok {
+		if err := c.CheckBet(ctx, bet); err != nil {
+			return nil, false, err
+		}
+	}
+	info := eng.Describe()
+	if info.Instant {
+		rnd, err := eng.Start(ctx, bet)
+		if err != nil {
+			return nil, false, err
+		}
+		w, err := s.openWallet(ctx, &rnd.Bet, info.Name)
+		if err == nil {
+			err = w.debit()
+		}
+		if err != nil {
+			voidRound(rnd)
+			return nil, false, err
+		}
+		if undone, err := w.creditOrRefund(ctx, rnd.Win); err != nil {
+			if undone {
+				voidRound(rnd)
+			} else {
+				s.holdRound(&rnd.Bet, rnd.Win, "win credit and bet refund failed: "+err.Error())
+			}
+			return nil, false, err
+		}
+		if err := s.settleRound(ctx, eng, rnd); err != nil {
+			return nil, false, err
+		}
+		return rnd, false, nil
+	}
+	w, err := s.openWallet(ctx, &bet, info.Name)
+	if err == nil {
+		err = w.debit()
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	rnd, err := eng.Start(ctx, bet)
+	if err != nil {
+		if rerr := w.refund(); rerr != nil {
+			s.holdRound(&bet, 0, fmt.Sprintf("start failed (%v) and bet refund failed: %v", err, rerr))
+		}
+		return nil, false, err
+	}
+	return rnd, false, nil
+}
+
+// replayRound answers a retried bet with the round res recorded.
+func (s *Server) replayRound(ctx context.Context, eng games.GameEngine, bet *games.Bet, res *round.Result) (*games.Round, error) {
+	if res.SessionID != bet.SessionID || res.GameID != bet.GameID || res.Bet != bet.Amount {
+		return nil, errRoundIDReused
+	}
+	r, ok := eng.(games.Replayer)
+	if !ok {
+		return nil, errReplayUnsupported
+	}
+	return r.Replay(ctx, res)
+}
+
+// actRound applies a player action of sessionID to the open round roundID. When the action
+// decides the round, its win is credited and the round settled and recorded; if the credit
+// fails the round stays open, so the action can be retried.
+func (s *Server) actRound(ctx context.Context, eng games.GameEngine, sessionID, roundID string, act games.Action) (*games.Round, error) {
+	s.inflight.lock(roundID)
+	defer s.inflight.unlock(roundID)
+	rnd, err := eng.Act(ctx, roundID, act)
+	if err != nil {
+		return nil, err
+	}
+	if rnd.SessionID != "" && rnd.SessionID != sessionID {
+		return nil, games.ErrRoundNotFound
+	}
+	if !rnd.Settled {
+		return rnd, nil
+	}
+	w, err := s.openWallet(ctx, &rnd.Bet, eng.Describe().Name)
+	if err == nil {
+		err = w.credit(ctx, rnd.Win)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := s.settleRound(ctx, eng, rnd); err != nil {
+		return nil, err
+	}
+	return rnd, nil
+}
+
+// settleRound commits a decided, paid round with its engine and records its result, or the
+// results of its tickets (rnd.Parts). A round whose results cannot all be recorded is held for
+// reconciliation and errRoundNotRecorded returned.
+func (s *Server) settleRound(ctx context.Context, eng games.GameEngine, rnd *games.Round) error {
+	if err := eng.Settle(ctx, rnd); err != nil {
+		log.Printf("round %s: settle: %v", rnd.RoundID, err)
+	}
+	var err error
+	if len(rnd.Parts) > 0 {
+		for _, res := range rnd.Parts {
+			res.BatchID = rnd.RoundID
+			if rerr := s.recordResult(rnd, res, res.Bet, res.WinAmount); rerr != nil && err == nil {
+				err = rerr
+			}
+		}
+	} else {
+		res := rnd.Record
+		if res == nil {
+			res = &round.Result{}
+		}
+		res.RoundID = rnd.RoundID
+		err = s.recordResult(rnd, res, rnd.Amount, rnd.Win)
+	}
+	if err != nil {
+		s.holdRound(&rnd.Bet, rnd.Win, "record result: "+err.Error())
+		return errRoundNotRecorded
+	}
+	return nil
+}
+
+// holdRound holds the round of bet, which won win, for reconciliation (see round.Unreconciled).
+func (s *Server) holdRound(bet *games.Bet, win float64, reason string) {
+	log.Printf("round %s: %s: held for reconciliation", bet.RoundID, reason)
+	err := s.unreconciled.Add(round.Unreconciled{
+		RoundID:   bet.RoundID,
+		SessionID: bet.SessionID,
+		GameID:    bet.GameID,
+		BetID:     bet.BetRef,
+		Bet:       bet.Amount,
+		Win:       win,
+		Reason:    reason,
+	})
+	if err != nil {
+		log.Printf("round %s: save unreconciled round: %v (held until restart)", bet.RoundID, err)
+	}
+}
+
+// recordResult adds the wallet fields of rnd to res, a result staking bet and winning win, and
+// appends it to the round results.
+func (s *Server) recordResult(rnd *games.Round, res *round.Result, bet, win float64) error {
+	res.BetID = rnd.BetRef
+	res.Outcome = "lose"
+	if win > 0 {
+		res.Outcome = "win"
+	}
+	res.WinAmount = win
+	res.BalanceDelta = win - bet
+	res.SettledAt = time.Now()
+	res.GameID = rnd.GameID
+	res.SessionID = rnd.SessionID
+	res.Bet = bet
+	return s.results.Append(res)
+}
+
+// voidRound releases what the engine reserved for a round that will not be played.
+func voidRound(rnd *games.Round) {
+	if rnd.Void != nil {
+		rnd.Void()
+	}
+}
+
+// roundLocks serializes requests on the same round id: a retried purchase must not buy a
+// second ticket, and concurrent actions must not settle a round twice.
+type roundLocks struct {
+	mu   sync.Mutex
+	held map[string]chan struct{} // closed on unlock
+}
+
+// tryLock claims roundID; it reports false when another request holds it.
+func (l *roundLocks) tryLock(roundID string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if _, ok := l.held[roundID]; ok {
+		return false
+	}
+	if l.held == nil {
+		l.held = make(map[string]chan struct{})
+	}
+	l.held[roundID] = make(chan struct{})
+	return true
+}
+
+// lock claims roundID, waiting for the request that holds it.
+func (l *roundLocks) lock(roundID string) {
+	for !l.tryLock(roundID) {
+		l.mu.Lock()
+		ch, ok := l.held[roundID]
+		l.mu.Unlock()
+		if ok {
+			<-ch
+		}
+	}
+}
+
+// unlock releases roundID.
+func (l *roundLocks) unlock(roundID string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if ch, ok := l.held[roundID]; ok {
+		close(ch)
+		delete(l.held, roundID)
+	}
+}
@@ -0,0 +1,373 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/config"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/round"
+)
+
+// fakePlatform is the platform wallet API: it counts calls and fails the ones set to fail.
+type fakePlatform struct {
+	mu           sync.Mutex
+	bets         int
+	wins         []float64
+	rollbacks    int
+	failBet      int // HTTP status to fail bets with, 0 to accept them
+	failWin      int
+	failRollback int
+}
+
+func (p *fakePlatform) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var body struct {
+		Amount float64 `json:"amount"`
+	}
+	_ = json.NewDecoder(r.Body).Decode(&body)
+	fail := 0
+	switch r.URL.Path {
+	case "/api/balance/bet":
+		if fail = p.failBet; fail == 0 {
+			p.bets++
+			writeJSON(w, http.StatusOK, map[string]string{"betId": "bet-1"})
+			return
+		}
+	case "/api/balance/win":
+		if fail = p.failWin; fail == 0 {
+			p.wins = append(p.wins, body.Amount)
+			writeJSON(w, http.StatusOK, map[string]string{})
+			return
+		}
+	case "/api/balance/rollback":
+		if fail = p.failRollback; fail == 0 {
+			p.rollbacks++
+			writeJSON(w, http.StatusOK, map[string]string{})
+			return
+		}
+	case "/api/balance":
+		writeJSON(w, http.StatusOK, map[string]any{"balances": map[string]float64{"USD": 100}})
+		return
+	default:
+		fail = http.StatusNotFound
+	}
+	writeJSON(w, fail, map[string]string{"error": "failed"})
+}
+
+func (p *fakePlatform) set(fn func(*fakePlatform)) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	fn(p)
+}
+
+// newTestServer returns a server whose wallet is plat and whose data lives in a temp dir.
+func newTestServer(t *testing.T, plat *fakePlatform) *Server {
+	t.Helper()
+	ts := httptest.NewServer(plat)
+	t.Cleanup(ts.Close)
+	return New(&config.Config{
+		PlatformURL: ts.URL,
+		DataDir:     t.TempDir(),
+		GamesDir:    t.TempDir(),
+		PickTimeout: time.Minute,
+	})
+}
+
+// testEngine decides every round with a fixed win. Instant rounds are decided in Start, others
+// by any action.
+type testEngine struct {
+	instant  bool
+	win      float64
+	startErr error
+	started  int
+	voided   int
+	settled  int
+	open     map[string]*games.Round
+}
+
+func (e *testEngine) Describe() games.EngineInfo {
+	return games.EngineInfo{ID: "test", Name: "Test", Instant: e.instant}
+}
+
+func (e *testEngine) Start(ctx context.Context, bet games.Bet) (*games.Round, error) {
+	if e.startErr != nil {
+		return nil, e.startErr
+	}
+	e.started++
+	rnd := &games.Round{
+		Bet:    bet,
+		Record: &round.Result{Tier: "T1"},
+		Void:   func() { e.voided++ },
+	}
+	if e.instant {
+		rnd.Settled, rnd.Win = true, e.win
+		return rnd, nil
+	}
+	if e.open == nil {
+		e.open = make(map[string]*games.Round)
+	}
+	e.open[bet.RoundID] = rnd
+	return rnd, nil
+}
+
+func (e *testEngine) Act(ctx context.Context, roundID string, act games.Action) (*games.Round, error) {
+	rnd, ok := e.open[roundID]
+	if !ok {
+		return nil, games.ErrRoundNotFound
+	}
+	rnd.Settled, rnd.Win = true, e.win
+	return rnd, nil
+}
+
+func (e *testEngine) Settle(ctx context.Context, rnd *games.Round) error {
+	e.settled++
+	delete(e.open, rnd.RoundID)
+	return nil
+}
+
+func (e *testEngine) Replay(ctx context.Context, res *round.Result) (*games.Round, error) {
+	return &games.Round{
+		Bet:     games.Bet{RoundID: res.RoundID, GameID: res.GameID, SessionID: res.SessionID, Amount: res.Bet, BetRef: res.BetID},
+		Settled: true,
+		Win:     res.WinAmount,
+		Record:  res,
+	}, nil
+}
+
+func testBet(roundID string) games.Bet {
+	return games.Bet{RoundID: roundID, GameID: "g1", SessionID: "sess", Currency: "USD", Amount: 1}
+}
+
+func wantCode(t *testing.T, err error, code string) *games.Error {
+	t.Helper()
+	var gerr *games.Error
+	if !errors.As(err, &gerr) || gerr.Code != code {
+		t.Fatalf("error %v, want %s", err, code)
+	}
+	return gerr
+}
+
+func TestStartRound_InstantDebitsCreditsAndRecords(t *testing.T) {
+	plat := &fakePlatform{}
+	s := newTestServer(t, plat)
+	eng := &testEngine{instant: true, win: 2.5}
+	rnd, replay, err := s.startRound(context.Background(), eng, testBet("r1"))
+	if err != nil || replay {
+		t.Fatalf("startRound: %v (replay %v)", err, replay)
+	}
+	if plat.bets != 1 || len(plat.wins) != 1 || plat.wins[0] != 2.5 || eng.settled != 1 {
+		t.Errorf("bets %d wins %v settled %d", plat.bets, plat.wins, eng.settled)
+	}
+	if rnd.RoundID != clientRoundID("sess", "r1") {
+		t.Errorf("round id %q", rnd.RoundID)
+	}
+	res, err := s.results.GetByRoundID(rnd.RoundID)
+	if err != nil || res == nil {
+		t.Fatalf("result not recorded: %v", err)
+	}
+	if res.BetID != "bet-1" || res.WinAmount != 2.5 || res.BalanceDelta != 1.5 || res.Tier != "T1" || res.Outcome != "win" {
+		t.Errorf("result %+v", res)
+	}
+	if rnd.BetRef != "bet-1" {
+		t.Errorf("round bet ref %q", rnd.BetRef)
+	}
+}
+
+func TestStartRound_DebitFailureVoidsRound(t *testing.T) {
+	plat := &fakePlatform{failBet: http.StatusPaymentRequired}
+	s := newTestServer(t, plat)
+	eng := &testEngine{instant: true, win: 2}
+	_, _, err := s.startRound(context.Background(), eng, testBet("r1"))
+	wantCode(t, err, "BET_FAILED")
+	if eng.voided != 1 || eng.settled != 0 || len(plat.wins) != 0 {
+		t.Errorf("voided %d settled %d wins %v", eng.voided, eng.settled, plat.wins)
+	}
+	if res, _ := s.results.GetByRoundID(clientRoundID("sess", "r1")); res != nil {
+		t.Errorf("failed round recorded: %+v", res)
+	}
+}
+
+func TestStartRound_CreditFailureRefunds(t *testing.T) {
+	plat := &fakePlatform{failWin: http.StatusInternalServerError}
+	s := newTestServer(t, plat)
+	eng := &testEngine{instant: true, win: 2}
+	_, _, err := s.startRound(context.Background(), eng, testBet("r1"))
+	wantCode(t, err, "WIN_FAILED")
+	if plat.rollbacks != 1 || eng.voided != 1 || eng.settled != 0 {
+		t.Errorf("rollbacks %d voided %d settled %d", plat.rollbacks, eng.voided, eng.settled)
+	}
+	if res, _ := s.results.GetByRoundID(clientRoundID("sess", "r1")); res != nil {
+		t.Errorf("refunded round recorded: %+v", res)
+	}
+}
+
+func TestStartRound_CreditAndRefundFailureHoldsRound(t *testing.T) {
+	plat := &fakePlatform{failWin: http.StatusInternalServerError, failRollback: http.StatusInternalServerError}
+	s := newTestServer(t, plat)
+	eng := &testEngine{instant: true, win: 2}
+	_, _, err := s.startRound(context.Background(), eng, testBet("r1"))
+	wantCode(t, err, "WIN_FAILED")
+	roundID := clientRoundID("sess", "r1")
+	if u, ok := round.NewUnreconciledStore(s.cfg.DataDir).Get(roundID); !ok || u.BetID != "bet-1" || u.Win != 2 {
+		t.Fatalf("held round %+v, %v", u, ok)
+	}
+
+	// The player's money is in limbo: a retry must not debit them again.
+	plat.set(func(p *fakePlatform) { p.failWin, p.failRollback = 0, 0 })
+	_, _, err = s.startRound(context.Background(), eng, testBet("r1"))
+	wantCode(t, err, "ROUND_UNRECONCILED")
+	if plat.bets != 1 || eng.started != 1 {
+		t.Errorf("bets %d started %d", plat.bets, eng.started)
+	}
+
+	// Once an operator releases it, the key plays again.
+	req := httptest.NewRequest(http.MethodPost, "/rgs/admin/rounds/"+roundID+"/reconcile", nil)
+	req.SetPathValue("roundId", roundID)
+	rec := httptest.NewRecorder()
+	s.handleReconcileRound(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("reconcile: %d %s", rec.Code, rec.Body)
+	}
+	if _, _, err := s.startRound(context.Background(), eng, testBet("r1")); err != nil {
+		t.Fatal(err)
+	}
+	if plat.bets != 2 || len(plat.wins) != 1 {
+		t.Errorf("bets %d wins %v", plat.bets, plat.wins)
+	}
+}
+
+func TestStartRound_RecordFailureHoldsRound(t *testing.T) {
+	plat := &fakePlatform{}
+	s := newTestServer(t, plat)
+	// A directory in place of the results log makes every append fail.
+	if err := os.Mkdir(filepath.Join(s.cfg.DataDir, "round_results.jsonl"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	eng := &testEngine{instant: true, win: 2}
+	_, _, err := s.startRound(context.Background(), eng, testBet("r1"))
+	wantCode(t, err, "ROUND_NOT_RECORDED")
+	if plat.bets != 1 || len(plat.wins) != 1 {
+		t.Fatalf("bets %d wins %v", plat.bets, plat.wins)
+	}
+
+	_, _, err = s.startRound(context.Background(), eng, testBet("r1"))
+	wantCode(t, err, "ROUND_UNRECONCILED")
+	if plat.bets != 1 || eng.started != 1 {
+		t.Errorf("bets %d started %d", plat.bets, eng.started)
+	}
+}
+
+func TestStartRound_NonInstantRefundsFailedStart(t *testing.T) {
+	plat := &fakePlatform{}
+	s := newTestServer(t, plat)
+	eng := &testEngine{startErr: games.Errorf(http.StatusInternalServerError, "TECHNICAL_ERROR", "boom")}
+	_, _, err := s.startRound(context.Background(), eng, testBet(""))
+	wantCode(t, err, "TECHNICAL_ERROR")
+	if plat.bets != 1 || plat.rollbacks != 1 {
+		t.Errorf("bets %d rollbacks %d", plat.bets, plat.rollbacks)
+	}
+}
+
+func TestStartRound_ReplaysSameBody(t *testing.T) {
+	plat := &fakePlatform{}
+	s := newTestServer(t, plat)
+	eng := &testEngine{instant: true, win: 3}
+	first, _, err := s.startRound(context.Background(), eng, testBet("r1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	rnd, replay, err := s.startRound(context.Background(), eng, testBet("r1"))
+	if err != nil || !replay {
+		t.Fatalf("retry: %v (replay %v)", err, replay)
+	}
+	if rnd.RoundID != first.RoundID || rnd.Win != 3 || rnd.BetRef != "bet-1" {
+		t.Errorf("replayed round %+v", rnd)
+	}
+	if plat.bets != 1 || len(plat.wins) != 1 || eng.started != 1 {
+		t.Errorf("retry charged again: bets %d wins %v started %d", plat.bets, plat.wins, eng.started)
+	}
+}
+
+func TestStartRound_RoundIDReused(t *testing.T) {
+	plat := &fakePlatform{}
+	s := newTestServer(t, plat)
+	eng := &testEngine{instant: true}
+	if _, _, err := s.startRound(context.Background(), eng, testBet("r1")); err != nil {
+		t.Fatal(err)
+	}
+	other := testBet("r1")
+	other.Amount = 5
+	_, _, err := s.startRound(context.Background(), eng, other)
+	if gerr := wantCode(t, err, "ROUND_ID_REUSED"); gerr.Status != http.StatusConflict {
+		t.Errorf("status %d want 409", gerr.Status)
+	}
+	if plat.bets != 1 {
+		t.Errorf("bets %d", plat.bets)
+	}
+}
+
+func TestStartRound_KeysAreScopedBySession(t *testing.T) {
+	plat := &fakePlatform{}
+	s := newTestServer(t, plat)
+	eng := &testEngine{instant: true, win: 1}
+	first, _, err := s.startRound(context.Background(), eng, testBet("r1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	other := testBet("r1")
+	other.SessionID = "other"
+	rnd, replay, err := s.startRound(context.Background(), eng, other)
+	if err != nil || replay {
+		t.Fatalf("same key in another session: %v (replay %v)", err, replay)
+	}
+	if rnd.RoundID == first.RoundID || plat.bets != 2 || eng.started != 2 {
+		t.Errorf("round %s after %s: bets %d started %d", rnd.RoundID, first.RoundID, plat.bets, eng.started)
+	}
+	if res, _ := s.results.GetByRoundID(rnd.RoundID); res == nil || res.SessionID != "other" {
+		t.Errorf("result %+v", res)
+	}
+}
+
+func TestActRound_CreditFailureKeepsRoundOpen(t *testing.T) {
+	plat := &fakePlatform{}
+	s := newTestServer(t, plat)
+	eng := &testEngine{win: 4}
+	rnd, _, err := s.startRound(context.Background(), eng, testBet(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.actRound(context.Background(), eng, "other", rnd.RoundID, games.Action{Name: "go"}); !errors.Is(err, games.ErrRoundNotFound) {
+		t.Errorf("another session's action: %v", err)
+	}
+
+	plat.set(func(p *fakePlatform) { p.failWin = http.StatusBadGateway })
+	_, err = s.actRound(context.Background(), eng, "sess", rnd.RoundID, games.Action{Name: "go"})
+	wantCode(t, err, "WIN_FAILED")
+	if eng.settled != 0 {
+		t.Fatal("round settled although its credit failed")
+	}
+
+	plat.set(func(p *fakePlatform) { p.failWin = 0 })
+	if _, err := s.actRound(context.Background(), eng, "sess", rnd.RoundID, games.Action{Name: "go"}); err != nil {
+		t.Fatal(err)
+	}
+	if eng.settled != 1 || len(plat.wins) != 1 || plat.wins[0] != 4 {
+		t.Errorf("settled %d wins %v", eng.settled, plat.wins)
+	}
+	if res, _ := s.results.GetByRoundID(rnd.RoundID); res == nil || res.WinAmount != 4 || res.BetID != "bet-1" {
+		t.Errorf("result %+v", res)
+	}
+	if _, err := s.actRound(context.Background(), eng, "sess", rnd.RoundID, games.Action{Name: "go"}); !errors.Is(err, games.ErrRoundNotFound) {
+		t.Errorf("settled round acted on again: %v", err)
+	}
+}
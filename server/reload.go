@@ -0,0 +1,221 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	rgsdb "github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server"
+)
+
+// configChannel is the NOTIFY channel written by the triggers in scripts/003_config_notify.sql.
+const configChannel = "rgs_config_changed"
+
+// Config tables the server caches and can reload at runtime.
+const (
+	tableGameMath     = "game_math"
+	tableScratchGames = "scratch_games"
+	tableGames        = "games"
+)
+
+var configTables = []string{tableGameMath, tableScratchGames, tableGames}
+
+// ownNotifyTTL bounds how long a notification this instance caused is waited for; after that the
+// backend pid may belong to another client.
+const ownNotifyTTL = 30 * time.Second
+
+// ownNotifications remembers the DB backends this instance just changed config through, so the
+// listener can skip the notification they send (see syncMathStatusToDB). The triggers notify once
+// per transaction, so each expected pid skips one notification.
+type ownNotifications struct {
+	mu   sync.Mutex
+	pids map[uint32]time.Time
+}
+
+func (n *ownNotifications) expect(pid uint32) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if n.pids == nil {
+		n.pids = make(map[uint32]time.Time)
+	}
+	n.pids[pid] = time.Now()
+}
+
+func (n *ownNotifications) forget(pid uint32) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	delete(n.pids, pid)
+}
+
+// own reports whether a notification from pid was caused by this instance, consuming it.
+func (n *ownNotifications) own(pid uint32) bool {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	at, ok := n.pids[pid]
+	if !ok {
+		return false
+	}
+	delete(n.pids, pid)
+	return time.Since(at) < ownNotifyTTL
+}
+
+// listenRetry is how long the config listener waits before reconnecting after an error.
+const listenRetry = 30 * time.Second
+
+// ReloadResult is the response of POST /rgs/admin/reload.
+type ReloadResult struct {
+	Reloaded []string          `json:"reloaded"`
+	Errors   map[string]string `json:"errors,omitempty"`
+}
+
+// Reload re-reads the given config tables (all of them when none are given):
+//   - game_math: every row is put into the gamemath store again. Changed versions replace the
+//     stored *GameMath rather than mutating it, so rounds already in flight settle on the math
+//     they started with, and pinned sessions keep their version.
+//   - scratch_games: the cached per-game scratch configs are dropped and read again on demand.
+//   - games: the provider/game catalog is replaced, so disabled games stop launching.
+func (s *Server) Reload(tables ...string) ReloadResult {
+	if len(tables) == 0 {
+		tables = configTables
+	}
+	s.reloadMu.Lock()
+	defer s.reloadMu.Unlock()
+	res := ReloadResult{Reloaded: []string{}}
+	for _, table := range tables {
+		var err error
+		switch table {
+		case tableGameMath:
+			err = s.loadGameMathFromDB()
+		case tableScratchGames:
+			s.scratchConfigs.reset()
+		case tableGames:
+			err = s.registry.Reload()
+		default:
+			err = fmt.Errorf("unknown table %q", table)
+		}
+		if err != nil {
+			if res.Errors == nil {
+				res.Errors = make(map[string]string)
+			}
+			res.Errors[table] = err.Error()
+			log.Printf("config reload: %s: %v", table, err)
+			continue
+		}
+		res.Reloaded = append(res.Reloaded, table)
+	}
+	log.Printf("config reload: reloaded %v", res.Reloaded)
+	return res
+}
+
+// handleReload reloads config tables on demand (POST /rgs/admin/reload[?table=game_math]).
+// The table parameter may be repeated; without it every table is reloaded.
+func (s *Server) handleReload(w http.ResponseWriter, r *http.Request) {
+	tables := r.URL.Query()["table"]
+	for _, t := range tables {
+		if !isConfigTable(t) {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("unknown table %q (want game_math, scratch_games or games)", t), "INVALID_TABLE")
+			return
+		}
+	}
+	res := s.Reload(tables...)
+	code := http.StatusOK
+	if len(res.Errors) > 0 {
+		code = http.StatusBadGateway
+	}
+	writeJSON(w, code, res)
+}
+
+func isConfigTable(table string) bool {
+	for _, t := range configTables {
+		if t == table {
+			return true
+		}
+	}
+	return false
+}
+
+// watchConfig keeps the cached config in step with the DB until ctx is done. Changes arrive
+// through LISTEN/NOTIFY; polling every cfg.ReloadPollInterval catches whatever notifications
+// miss (listener reconnects, transaction-mode poolers, DBs without the triggers). No-op
+// without a DB.
+func (s *Server) watchConfig(ctx context.Context) {
+	db, err := rgsdb.GetDB()
+	if err != nil || db == nil {
+		return
+	}
+	go s.listenConfig(ctx)
+	if s.cfg.ReloadPollInterval > 0 {
+		go s.pollConfig(ctx, db, s.cfg.ReloadPollInterval)
+	}
+}
+
+func (s *Server) listenConfig(ctx context.Context) {
+	for {
+		err := rgsdb.Listen(ctx, configChannel, func(pid uint32, payload string) {
+			if s.ownNotify.own(pid) {
+				return
+			}
+			var n struct {
+				Table string `json:"table"`
+			}
+			if json.Unmarshal([]byte(payload), &n) != nil || !isConfigTable(n.Table) {
+				s.Reload()
+				return
+			}
+			s.Reload(n.Table)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("config reload: listen %s: %v (retrying in %s)", configChannel, err, listenRetry)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetry):
+		}
+	}
+}
+
+func (s *Server) pollConfig(ctx context.Context, db *sql.DB, every time.Duration) {
+	seen := make(map[string]string, len(configTables))
+	for _, table := range configTables {
+		seen[table], _ = tableFingerprint(ctx, db, table)
+	}
+	ticker := time.NewTicker(every)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		var changed []string
+		for _, table := range configTables {
+			fp, err := tableFingerprint(ctx, db, table)
+			if err != nil || fp == seen[table] {
+				continue
+			}
+			seen[table] = fp
+			changed = append(changed, table)
+		}
+		if len(changed) > 0 {
+			s.Reload(changed...)
+		}
+	}
+}
+
+// tableFingerprint hashes every row of a config table, so any insert, update or delete changes
+// it. The config tables are small enough to hash in full. table must be one of configTables.
+func tableFingerprint(ctx context.Context, db *sql.DB, table string) (string, error) {
+	var fp string
+	err := db.QueryRowContext(ctx, `
+		SELECT COUNT(*)::text || ':' || COALESCE(md5(string_agg(md5(t::text), '' ORDER BY md5(t::text))), '')
+		FROM `+table+` t
+	`).Scan(&fp)
+	return fp, err
+}
@@ -0,0 +1,57 @@
+package server
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/fair"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
+)
+
+// newServerRNG returns the source round seeds are drawn from: crypto/rand, or a seeded stream
+// when RGS_RNG_SEED is set (tests and replay environments).
+func newServerRNG(seed string) rng.Source {
+	if seed == "" {
+		return rng.Crypto()
+	}
+	src, err := rng.NewSeededHex(seed)
+	if err != nil {
+		log.Printf("rng: ignoring RGS_RNG_SEED: %v", err)
+		return rng.Crypto()
+	}
+	log.Printf("rng: DETERMINISTIC mode (RGS_RNG_SEED set); never use in production")
+	return src
+}
+
+// roundRNG is the random source of one round and what is needed to replay it.
+type roundRNG struct {
+	// seed is the rng seed of the round (rng.NewSeededHex); empty for provably fair rounds,
+	// whose server seed stays secret until the player rotates it.
+	seed string
+	// proof is set for provably fair rounds (fair.Source(serverSeed, proof.ClientSeed, proof.Nonce)).
+	proof *fair.Proof
+	src   *rng.Recorder
+}
+
+// newRoundRNG starts the random source of one round. In provably fair mode the round draws
+// from the session's seeds and next nonce; otherwise from a fresh seed drawn from s.rng. Either
+// way a recorder logs the draws, so the round can be replayed from the seed or the draw log.
+// A fair round fails with errFairUnavailable when its nonce cannot be saved.
+func (s *Server) newRoundRNG(sessionID string) (roundRNG, error) {
+	if s.fair != nil && sessionID != "" {
+		proof, src, err := s.fair.Next(sessionID)
+		if err != nil {
+			log.Printf("fair: session %s: %v", sessionID, err)
+			return roundRNG{}, errFairUnavailable
+		}
+		return roundRNG{proof: &proof, src: rng.NewRecorder(src)}, nil
+	}
+	seed := rng.NewSeed(s.rng)
+	src, _ := rng.NewSeededHex(seed)
+	return roundRNG{seed: seed, src: rng.NewRecorder(src)}, nil
+}
+
+// errFairUnavailable refuses a provably fair round whose nonce could not be saved: playing it
+// would let a restart reuse the nonce.
+var errFairUnavailable = games.Errorf(http.StatusServiceUnavailable, "FAIR_UNAVAILABLE", "provably fair seeds unavailable")
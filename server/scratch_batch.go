@@ -0,0 +1,153 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games/scratch"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/round"
+)
+
+// scratchBatchSizes are the ticket bundles POST /api/scratch/play/batch sells.
+var scratchBatchSizes = map[int]bool{5: true, 10: true, 25: true}
+
+// ScratchBatchRequest is the request body for POST /api/scratch/play/batch: Count tickets of
+// BetAmount each.
+type ScratchBatchRequest struct {
+	ScratchPlayRequest
+	Count int `json:"count"` // 5, 10 or 25
+}
+
+// ScratchBatchResponse is a paid ticket bundle. The wallet saw one debit of TotalBet and one
+// credit of TotalWin, both under round BatchID.
+type ScratchBatchResponse struct {
+	BatchID  string                   `json:"batchId"`
+	TotalBet float64                  `json:"totalBet"`
+	TotalWin float64                  `json:"totalWin"`
+	Rounds   []ScratchResolvedOutcome `json:"rounds"` // one per ticket, in purchase order
+}
+
+// scratchBatchEngine sells a ticket bundle as one instant round: the wallet sees one debit of
+// the bundle price and one credit of the total win under the round id, which is the bundle's
+// batchId. Bet.Data is the ScratchBatchRequest; State is the *ScratchBatchResponse and Parts the
+// result of every ticket.
+type scratchBatchEngine struct {
+	s *Server
+}
+
+func (e *scratchBatchEngine) Describe() games.EngineInfo {
+	return games.EngineInfo{ID: "scratch_batch", Name: scratchGameName, Instant: true}
+}
+
+// Start draws every ticket and builds its reveal map before any money moves. If one fails, the
+// tickets already drawn go back to their series and the bundle is refused.
+func (e *scratchBatchEngine) Start(ctx context.Context, bet games.Bet) (*games.Round, error) {
+	var req ScratchBatchRequest
+	if err := json.Unmarshal(bet.Data, &req); err != nil || !scratchBatchSizes[req.Count] {
+		return nil, games.Errorf(http.StatusBadRequest, "INVALID_REQUEST", "invalid ticket bundle")
+	}
+	cfg, err := e.s.scratchConfigs.get(bet.GameID)
+	if err != nil {
+		return nil, games.Errorf(http.StatusBadGateway, "TECHNICAL_ERROR", "database error")
+	}
+	if cfg != nil && scratch.IsPick(cfg.Mechanic.Type) {
+		return nil, games.Errorf(http.StatusBadRequest, "INVALID_GAME", "gameId is a pick game; play it with POST /api/scratch/pick")
+	}
+
+	resp := &ScratchBatchResponse{
+		BatchID:  bet.RoundID,
+		TotalBet: bet.Amount,
+		Rounds:   make([]ScratchResolvedOutcome, 0, req.Count),
+	}
+	parts := make([]*round.Result, 0, req.Count)
+	var tickets []*gamemath.PoolTicket
+	for len(parts) < req.Count {
+		draw, err := e.s.generateScratchOutcome(bet.SessionID, bet.GameID, req.BetAmount)
+		if err != nil {
+			e.s.returnPoolTickets(tickets)
+			return nil, err
+		}
+		tickets = append(tickets, draw.ticket)
+		reveal, err := buildRevealMapFromOutcome(scratch.PresentationSource(draw.presentationSeed), cfg, &draw.outcome)
+		if err != nil {
+			log.Printf("scratch batch: game %s tier %s: %v", bet.GameID, draw.outcome.Tier, err)
+			e.s.returnPoolTickets(tickets)
+			return nil, errRevealUnavailable
+		}
+		roundID := uuid.New().String()
+		res := e.s.scratchResult(bet.GameID, cfg, &draw)
+		res.RoundID = roundID
+		res.Bet = req.BetAmount
+		parts = append(parts, res)
+		resp.Rounds = append(resp.Rounds, resolvedScratchOutcome(roundID, req.BetAmount, &draw, reveal))
+		resp.TotalWin += draw.outcome.WinAmount
+	}
+	return &games.Round{
+		Bet:     bet,
+		Settled: true,
+		Win:     resp.TotalWin,
+		State:   resp,
+		Parts:   parts,
+		Void:    func() { e.s.returnPoolTickets(tickets) },
+	}, nil
+}
+
+// Act rejects every action: bundles are decided when they are bought.
+func (e *scratchBatchEngine) Act(ctx context.Context, roundID string, act games.Action) (*games.Round, error) {
+	return nil, games.ErrUnknownAction
+}
+
+// Settle has nothing to commit: the ticket results are the bundle's only record.
+func (e *scratchBatchEngine) Settle(ctx context.Context, rnd *games.Round) error {
+	return nil
+}
+
+// handleScratchPlayBatch implements POST /api/scratch/play/batch through the round orchestrator
+// (see scratchBatchEngine). The purchase is all or nothing: if any ticket cannot be drawn or
+// shown, or the wallet fails, no round is recorded, LIMITED tickets go back to their series and
+// a failed credit refunds the debit.
+func (s *Server) handleScratchPlayBatch(w http.ResponseWriter, r *http.Request) {
+	var req ScratchBatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	if !scratchBatchSizes[req.Count] {
+		http.Error(w, fmt.Sprintf("count %d is not a ticket bundle (5, 10 or 25)", req.Count), http.StatusBadRequest)
+		return
+	}
+	if req.RoundID != "" {
+		http.Error(w, "roundId is not supported for ticket bundles", http.StatusBadRequest)
+		return
+	}
+	deviceType, ok := checkScratchPlayRequest(w, &req.ScratchPlayRequest)
+	if !ok {
+		return
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	rnd, _, err := s.startRound(r.Context(), &scratchBatchEngine{s: s}, games.Bet{
+		GameID:     req.GameID,
+		SessionID:  req.SessionID,
+		Currency:   req.Currency,
+		Amount:     req.BetAmount * float64(req.Count),
+		DeviceType: deviceType,
+		Data:       data,
+	})
+	if err != nil {
+		code, _ := roundErrorStatus(err)
+		http.Error(w, err.Error(), code)
+		return
+	}
+	writeJSON(w, http.StatusOK, rnd.State)
+}
@@ -0,0 +1,154 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games"
+)
+
+// addLimitedGame registers gameID, a LIMITED game without a scratch config whose series is ten
+// tickets: five losers and five paying 2x.
+func addLimitedGame(t *testing.T, s *Server, gameID string) *gamemath.GameMath {
+	t.Helper()
+	math := &gamemath.GameMath{
+		ModelID:      gameID,
+		ModelVersion: "1",
+		MathMode:     gamemath.MathModeLimited,
+		TotalTickets: 10,
+		PrizeTable: []gamemath.PrizeTier{
+			{Tier: "LOSE", Weight: 5},
+			{Tier: "T1", Multiplier: 2, Weight: 5},
+		},
+	}
+	if err := math.Seal(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.gameMath.Register(math); err != nil {
+		t.Fatal(err)
+	}
+	s.scratchConfigs.mu.Lock()
+	if s.scratchConfigs.byGame == nil {
+		s.scratchConfigs.byGame = make(map[string]*ScratchConfig)
+	}
+	s.scratchConfigs.byGame[gameID] = nil
+	s.scratchConfigs.mu.Unlock()
+	return math
+}
+
+func batchBet(t *testing.T, count int) games.Bet {
+	t.Helper()
+	req := ScratchBatchRequest{
+		ScratchPlayRequest: ScratchPlayRequest{SessionID: "sess", GameID: "BATCH", BetAmount: 1, Currency: "USD"},
+		Count:              count,
+	}
+	data, err := json.Marshal(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return games.Bet{GameID: "BATCH", SessionID: "sess", Currency: "USD", Amount: float64(count), Data: data}
+}
+
+// remaining is the number of unsold tickets in the game's series.
+func remaining(t *testing.T, s *Server, math *gamemath.GameMath) int64 {
+	t.Helper()
+	p, err := s.pools.Get(math.ModelID, math.ModelVersion)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p == nil {
+		return math.TotalTickets
+	}
+	return p.Remaining()
+}
+
+// noResults fails if any round was recorded.
+func noResults(t *testing.T, s *Server) {
+	t.Helper()
+	if fi, err := os.Stat(filepath.Join(s.cfg.DataDir, "round_results.jsonl")); err == nil && fi.Size() > 0 {
+		t.Errorf("rounds recorded (%d bytes)", fi.Size())
+	}
+}
+
+func TestScratchBatch_RecordsEveryTicket(t *testing.T) {
+	plat := &fakePlatform{}
+	s := newTestServer(t, plat)
+	math := addLimitedGame(t, s, "BATCH")
+	// A bundle of ten sells the whole series.
+	rnd, _, err := s.startRound(context.Background(), &scratchBatchEngine{s: s}, batchBet(t, 10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp := rnd.State.(*ScratchBatchResponse)
+	if resp.BatchID != rnd.RoundID || resp.TotalBet != 10 || resp.TotalWin != 10 || len(resp.Rounds) != 10 {
+		t.Fatalf("bundle %+v", resp)
+	}
+	if plat.bets != 1 || len(plat.wins) != 1 || plat.wins[0] != 10 {
+		t.Errorf("bets %d wins %v: want one debit and one credit", plat.bets, plat.wins)
+	}
+	for _, o := range resp.Rounds {
+		res, err := s.results.GetByRoundID(o.RoundID)
+		if err != nil || res == nil {
+			t.Fatalf("ticket %s not recorded: %v", o.RoundID, err)
+		}
+		if res.BetID != "bet-1" || res.BatchID != resp.BatchID || res.Bet != 1 || res.WinAmount != o.FinalPrize {
+			t.Errorf("ticket result %+v", res)
+		}
+	}
+	if n := remaining(t, s, math); n != 0 {
+		t.Errorf("%d tickets left in the series", n)
+	}
+}
+
+func TestScratchBatch_DebitFailureReturnsTickets(t *testing.T) {
+	plat := &fakePlatform{failBet: http.StatusPaymentRequired}
+	s := newTestServer(t, plat)
+	math := addLimitedGame(t, s, "BATCH")
+	_, _, err := s.startRound(context.Background(), &scratchBatchEngine{s: s}, batchBet(t, 5))
+	wantCode(t, err, "BET_FAILED")
+	if len(plat.wins) != 0 || plat.rollbacks != 0 {
+		t.Errorf("wins %v rollbacks %d", plat.wins, plat.rollbacks)
+	}
+	if n := remaining(t, s, math); n != 10 {
+		t.Errorf("%d tickets left in the series, want all 10", n)
+	}
+	noResults(t, s)
+}
+
+func TestScratchBatch_CreditFailureRefundsAndReturnsTickets(t *testing.T) {
+	plat := &fakePlatform{failWin: http.StatusBadGateway}
+	s := newTestServer(t, plat)
+	math := addLimitedGame(t, s, "BATCH")
+	_, _, err := s.startRound(context.Background(), &scratchBatchEngine{s: s}, batchBet(t, 10))
+	wantCode(t, err, "WIN_FAILED")
+	if plat.bets != 1 || plat.rollbacks != 1 {
+		t.Errorf("bets %d rollbacks %d: the debit was not refunded", plat.bets, plat.rollbacks)
+	}
+	if n := remaining(t, s, math); n != 10 {
+		t.Errorf("%d tickets left in the series, want all 10", n)
+	}
+	noResults(t, s)
+}
+
+func TestScratchBatch_FailedDrawSellsNothing(t *testing.T) {
+	plat := &fakePlatform{}
+	s := newTestServer(t, plat)
+	math := addLimitedGame(t, s, "BATCH")
+	// The 11th draw of a bundle of 25 finds the series sold out.
+	_, _, err := s.startRound(context.Background(), &scratchBatchEngine{s: s}, batchBet(t, 25))
+	if err == nil {
+		t.Fatal("bundle larger than the series was sold")
+	}
+	if plat.bets != 0 {
+		t.Errorf("bets %d", plat.bets)
+	}
+	if n := remaining(t, s, math); n != 10 {
+		t.Errorf("%d tickets left in the series, want all 10", n)
+	}
+	noResults(t, s)
+}
@@ -2,26 +2,60 @@ package server
 
 import (
 	"context"
+	"crypto/sha256"
 	"database/sql"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"strings"
+	"sync"
 
 	rgsdb "github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games/scratch"
 )
 
 // ScratchMechanic defines the mechanic and grid for a scratch game.
 type ScratchMechanic struct {
-	Type       string `json:"type"`        // "match_n", "target_match", "symbol_hunt"
+	Type       string `json:"type"`        // "match_n", "target_match", "symbol_hunt", "pick_one", "pick_n"
 	MatchCount int    `json:"match_count"` // for match_n
 	Rows       int    `json:"rows"`
 	Cols       int    `json:"cols"`
+	// Target Match zones and prizes: winning_numbers and your_numbers size the zones (default 2
+	// and rows*cols), numbers run from 1 to number_range, and each Your Numbers cell shows one
+	// of prize_values (bet multipliers).
+	WinningNumbers int       `json:"winning_numbers,omitempty"`
+	YourNumbers    int       `json:"your_numbers,omitempty"`
+	NumberRange    int       `json:"number_range,omitempty"`
+	PrizeValues    []float64 `json:"prize_values,omitempty"`
+	// Picks is how many cells a pick_n player opens (pick_one: 1).
+	Picks int `json:"picks,omitempty"`
+	// NearMiss lists the teasers losing match_n tickets show, e.g. {"count": 2, "probability": 0.3}
+	// for two top symbols on 30% of losses.
+	NearMiss []scratch.NearMiss `json:"near_miss,omitempty"`
+	// WildChance is the share of match_n tickets that show "wild" symbols (default 0.25).
+	WildChance float64 `json:"wild_chance,omitempty"`
 }
 
 // ScratchSymbol describes a symbol used in a scratch game grid.
 type ScratchSymbol struct {
 	ID       string `json:"id"`
-	Category string `json:"category"` // "win", "dud", "top", etc.
+	Category string `json:"category"` // "win", "dud", "top", "wild", "multiplier"
 	Image    string `json:"image"`    // image path or URL (for symbol API / frontend)
+	// Value is the bet multiplier the symbol pays when found (symbol_hunt); winning tickets show
+	// valued symbols adding up to the prize.
+	Value float64 `json:"value,omitempty"`
+	// Multiplier is the factor a "multiplier" symbol applies to the ticket's wins; it is shown
+	// with the prize tiers whose math declares that boost.
+	Multiplier float64 `json:"multiplier,omitempty"`
+}
+
+// ScratchTierSymbols maps a prize tier to the symbol (or set of symbols) that shows it, with
+// the payout label the UI displays (e.g. "50000x").
+type ScratchTierSymbols struct {
+	Tier    string   `json:"tier"`
+	Symbols []string `json:"symbols"`
+	Label   string   `json:"label,omitempty"`
 }
 
 // ScratchConfig is the full per-game config loaded from the DB.
@@ -29,6 +63,217 @@ type ScratchConfig struct {
 	GameID   string          `json:"game_id"`
 	Mechanic ScratchMechanic `json:"mechanic"`
 	Symbols  []ScratchSymbol `json:"symbols"`
+	// Tiers maps prize tiers to symbols (symbol_config.tiers). Reveal maps show the awarded
+	// tier's symbol; tiers without a mapping fall back to a random "win" symbol.
+	Tiers []ScratchTierSymbols `json:"tiers,omitempty"`
+}
+
+// Version identifies the config's content: the hex SHA-256 of its JSON. Rounds record it, and
+// the config is kept under it, so their reveal maps can be rebuilt after the config changes.
+func (c *ScratchConfig) Version() string {
+	data, _ := json.Marshal(c)
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// tierSymbols returns the symbols mapped to a prize tier, or nil.
+func (c *ScratchConfig) tierSymbols(tier string) []string {
+	for _, t := range c.Tiers {
+		if t.Tier == tier {
+			return t.Symbols
+		}
+	}
+	return nil
+}
+
+// layout returns the config as the reveal-map engine sees it.
+func (c *ScratchConfig) layout() scratch.Layout {
+	l := scratch.Layout{
+		Mechanic:   c.Mechanic.Type,
+		Rows:       c.Mechanic.Rows,
+		Cols:       c.Mechanic.Cols,
+		MatchCount: c.Mechanic.MatchCount,
+		NearMisses: c.Mechanic.NearMiss,
+		WildChance: c.Mechanic.WildChance,
+		Tiers:      make(map[string][]string, len(c.Tiers)),
+
+		WinningNumbers: c.Mechanic.WinningNumbers,
+		YourNumbers:    c.Mechanic.YourNumbers,
+		NumberRange:    c.Mechanic.NumberRange,
+		PrizeValues:    c.Mechanic.PrizeValues,
+		Picks:          c.Mechanic.Picks,
+	}
+	for _, sym := range c.Symbols {
+		if sym.Value != 0 {
+			if l.Values == nil {
+				l.Values = make(map[string]float64)
+			}
+			l.Values[sym.ID] = sym.Value
+		}
+		switch sym.Category {
+		case "top":
+			l.Top = append(l.Top, sym.ID)
+		case "win":
+			l.Wins = append(l.Wins, sym.ID)
+		case "dud":
+			l.Duds = append(l.Duds, sym.ID)
+		case "wild":
+			l.Wilds = append(l.Wilds, sym.ID)
+		case "multiplier":
+			if l.Boosts == nil {
+				l.Boosts = make(map[string]float64)
+			}
+			l.Boosts[sym.ID] = sym.Multiplier
+		}
+	}
+	for _, t := range c.Tiers {
+		l.Tiers[t.Tier] = t.Symbols
+	}
+	return l
+}
+
+// validateLayout checks that the reveal-map engine can show the outcomes of math with cfg:
+// Match-N and Symbol Hunt grids without accidental wins, every Target Match prize as matched
+// cells, and every pick win within the picks. Other mechanics are not checked.
+func validateLayout(cfg *ScratchConfig, math *gamemath.GameMath) error {
+	l := cfg.layout()
+	switch cfg.Mechanic.Type {
+	case scratch.MechanicMatchN, scratch.MechanicSymbolHunt:
+		if err := l.Validate(); err != nil {
+			return err
+		}
+	case scratch.MechanicPickOne, scratch.MechanicPickN:
+		if err := l.Validate(); err != nil {
+			return err
+		}
+		for _, t := range math.PrizeTable {
+			if n := len(t.Wins()); n > l.PickCount() {
+				return fmt.Errorf("tier %q: %d wins need %d picks, the game has %d", t.Tier, n, n, l.PickCount())
+			}
+		}
+	case scratch.MechanicTargetMatch:
+		if err := l.Validate(); err != nil {
+			return err
+		}
+		for _, t := range math.PrizeTable {
+			var multipliers []float64
+			for _, win := range t.Wins() {
+				multipliers = append(multipliers, win.Multiplier)
+			}
+			if _, err := l.SplitPrizes(multipliers); err != nil {
+				return fmt.Errorf("tier %q: %w", t.Tier, err)
+			}
+		}
+	default:
+		return nil
+	}
+	return validateSpecialSymbols(l, math)
+}
+
+// validateSpecialSymbols checks wild and multiplier symbols against math: wilds need math that
+// allows wildcards, and every boosted prize a multiplier symbol of its factor.
+func validateSpecialSymbols(l scratch.Layout, math *gamemath.GameMath) error {
+	if len(l.Wilds) > 0 && !math.Mechanic.AllowWildcards {
+		return fmt.Errorf("wild symbols need math with match.allowWildcards")
+	}
+	for _, t := range math.PrizeTable {
+		var boosts []float64
+		for _, win := range t.Wins() {
+			boosts = append(boosts, win.Boost)
+		}
+		if err := l.CheckBoosts(boosts); err != nil {
+			return fmt.Errorf("tier %q: %w", t.Tier, err)
+		}
+	}
+	return nil
+}
+
+// validateTierSymbols checks that every prize the math can award (each paying tier, or each
+// component of a MULTI_WIN combination) is mapped to at least one symbol the config defines.
+// Target Match shows prizes as numbers, so there only the mapped symbols are checked; Symbol
+// Hunt prizes that valued symbols can add up to need no mapping either.
+func validateTierSymbols(cfg *ScratchConfig, math *gamemath.GameMath) error {
+	defined := make(map[string]bool, len(cfg.Symbols))
+	for _, sym := range cfg.Symbols {
+		defined[sym.ID] = true
+	}
+	for _, t := range cfg.Tiers {
+		for _, sym := range t.Symbols {
+			if !defined[sym] {
+				return fmt.Errorf("tier %q: symbol %q is not in symbols", t.Tier, sym)
+			}
+		}
+	}
+	if cfg.Mechanic.Type == scratch.MechanicTargetMatch {
+		return nil
+	}
+	l := cfg.layout()
+	var missing []string
+	seen := make(map[string]bool)
+	for _, t := range math.PrizeTable {
+		var multipliers []float64
+		for _, win := range t.Wins() {
+			multipliers = append(multipliers, win.Base())
+		}
+		if l.CanCompose(multipliers) {
+			continue
+		}
+		for _, win := range t.Wins() {
+			if seen[win.Tier] {
+				continue
+			}
+			seen[win.Tier] = true
+			if len(cfg.tierSymbols(win.Tier)) == 0 {
+				missing = append(missing, win.Tier)
+			}
+		}
+	}
+	if len(missing) > 0 {
+		return fmt.Errorf("no symbol mapped to prize tier(s) %s", strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// scratchConfigCache keeps scratch_games rows by game_id (nil for games without a row) until the
+// table is reloaded (see Server.Reload).
+type scratchConfigCache struct {
+	mu     sync.RWMutex
+	byGame map[string]*ScratchConfig
+	gen    uint64 // bumped by reset so loads that raced it are not cached
+}
+
+// get returns the cached config for gameID, loading it from the DB on a miss. Errors are not
+// cached. The returned config is shared and must not be modified.
+func (c *scratchConfigCache) get(gameID string) (*ScratchConfig, error) {
+	c.mu.RLock()
+	cfg, ok := c.byGame[gameID]
+	gen := c.gen
+	c.mu.RUnlock()
+	if ok {
+		return cfg, nil
+	}
+	cfg, err := loadScratchConfigFromDB(gameID)
+	if err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.gen != gen {
+		return cfg, nil
+	}
+	if c.byGame == nil {
+		c.byGame = make(map[string]*ScratchConfig)
+	}
+	c.byGame[gameID] = cfg
+	return cfg, nil
+}
+
+// reset drops every cached config; the next request reads scratch_games again.
+func (c *scratchConfigCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.byGame = nil
+	c.gen++
 }
 
 // loadScratchConfigFromDB loads scratch config for a single game_id from scratch_games.
@@ -54,7 +299,8 @@ func loadScratchConfigFromDB(gameID string) (*ScratchConfig, error) {
 		return nil, err
 	}
 	var wrapper struct {
-		Symbols []ScratchSymbol `json:"symbols"`
+		Symbols []ScratchSymbol      `json:"symbols"`
+		Tiers   []ScratchTierSymbols `json:"tiers"`
 	}
 	if err := json.Unmarshal(symJSON, &wrapper); err != nil {
 		return nil, err
@@ -63,5 +309,6 @@ func loadScratchConfigFromDB(gameID string) (*ScratchConfig, error) {
 		GameID:   gameID,
 		Mechanic: mech,
 		Symbols:  wrapper.Symbols,
+		Tiers:    wrapper.Tiers,
 	}, nil
 }
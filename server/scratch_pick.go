@@ -0,0 +1,420 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games/scratch"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/round"
+)
+
+// pickRoundRetention is how long completed pick rounds stay resumable before they are pruned.
+const pickRoundRetention = 24 * time.Hour
+
+// pickSweepInterval is how often expired pick rounds are auto-completed.
+const pickSweepInterval = 30 * time.Second
+
+// ScratchPickRequest is the body of POST /api/scratch/pick/{roundId}.
+type ScratchPickRequest struct {
+	SessionID string `json:"session_id"`
+	Cell      int    `json:"cell"` // row-major index of the cell to open
+}
+
+// ScratchPickedCell is an opened cell of a pick round.
+type ScratchPickedCell struct {
+	Cell   int     `json:"cell"`
+	Symbol string  `json:"symbol"`
+	Prize  float64 `json:"prize"` // currency amount, 0 for empty cells
+}
+
+// ScratchPickRound is the state of a Pick-One / Pick-N round as the player sees it: only the
+// opened cells until the round is complete, then the full outcome.
+type ScratchPickRound struct {
+	RoundID   string              `json:"roundId"`
+	GameID    string              `json:"gameId"`
+	Rows      int                 `json:"rows"`
+	Cols      int                 `json:"cols"`
+	Picks     int                 `json:"picks"`     // cells the player may open
+	Picked    []ScratchPickedCell `json:"picked"`    // opened cells, in pick order
+	ExpiresAt time.Time           `json:"expiresAt"` // remaining cells open automatically after this
+	Completed bool                `json:"completed"`
+	// AutoCompleted is set when the round timed out and the remaining picks were made for the
+	// player (lowest free cells first).
+	AutoCompleted bool `json:"autoCompleted,omitempty"`
+	// Outcome is set once the round is complete; its revealMap includes the unpicked cells.
+	Outcome *ScratchResolvedOutcome `json:"outcome,omitempty"`
+}
+
+// pickEngine plays Pick-One / Pick-N tickets. It is not instant: the bet is debited first, then
+// Start draws the outcome and commits the content of every cell; "pick" actions open cells, and
+// the last pick (or the timeout, see expirePickRounds) decides the round, whose win is then
+// credited. Round state is the *round.PickRound.
+type pickEngine struct {
+	s *Server
+}
+
+var (
+	errNotPickGame         = games.Errorf(http.StatusBadRequest, "INVALID_GAME", "gameId is not a pick game")
+	errPickDealUnavailable = games.Errorf(http.StatusInternalServerError, "TECHNICAL_ERROR", "pick deal unavailable")
+	errPickRoundOpen       = games.Errorf(http.StatusConflict, "ROUND_IN_PROGRESS", "pick round is not complete")
+)
+
+func (e *pickEngine) Describe() games.EngineInfo {
+	return games.EngineInfo{ID: "scratch_pick", Name: scratchGameName, Actions: []string{"pick"}}
+}
+
+// CheckBet rejects games that are not pick games before the debit.
+func (e *pickEngine) CheckBet(ctx context.Context, bet games.Bet) error {
+	_, err := e.config(bet.GameID)
+	return err
+}
+
+func (e *pickEngine) config(gameID string) (*ScratchConfig, error) {
+	cfg, err := e.s.scratchConfigs.get(gameID)
+	if err != nil {
+		return nil, games.Errorf(http.StatusBadGateway, "TECHNICAL_ERROR", "database error")
+	}
+	if cfg == nil || !scratch.IsPick(cfg.Mechanic.Type) {
+		return nil, errNotPickGame
+	}
+	return cfg, nil
+}
+
+// Start draws the outcome, deals the cells and saves the round. On failure nothing is kept
+// (a LIMITED ticket goes back to its series) and the orchestrator refunds the debit.
+func (e *pickEngine) Start(ctx context.Context, bet games.Bet) (*games.Round, error) {
+	cfg, err := e.config(bet.GameID)
+	if err != nil {
+		return nil, err
+	}
+	draw, err := e.s.generateScratchOutcome(bet.SessionID, bet.GameID, bet.Amount)
+	if err != nil {
+		return nil, err
+	}
+	l := cfg.layout()
+	deal, err := l.DealPicks(scratch.PresentationSource(draw.presentationSeed), &draw.outcome)
+	if err != nil {
+		log.Printf("scratch pick: game %s tier %s: %v", bet.GameID, draw.outcome.Tier, err)
+		e.s.returnPoolTicket(draw.ticket)
+		return nil, errPickDealUnavailable
+	}
+	dealJSON, err := json.Marshal(deal)
+	if err != nil {
+		e.s.returnPoolTicket(draw.ticket)
+		return nil, err
+	}
+	outcomeJSON, err := json.Marshal(draw.outcome)
+	if err != nil {
+		e.s.returnPoolTicket(draw.ticket)
+		return nil, err
+	}
+	rows, cols := l.Dims()
+	now := time.Now()
+	pr := &round.PickRound{
+		RoundID:    bet.RoundID,
+		SessionID:  bet.SessionID,
+		GameID:     bet.GameID,
+		Currency:   bet.Currency,
+		DeviceType: bet.DeviceType,
+		Bet:        bet.Amount,
+		BetID:      bet.BetRef,
+		Rows:       rows,
+		Cols:       cols,
+		Picks:      l.PickCount(),
+		Deal:       dealJSON,
+		Outcome:    outcomeJSON,
+		Picked:     []int{},
+		CreatedAt:  now,
+		ExpiresAt:  now.Add(e.s.cfg.PickTimeout),
+		Fair:       draw.proof,
+		Record:     e.s.scratchResult(bet.GameID, cfg, &draw),
+
+		PresentationSeed: draw.presentationSeed,
+	}
+	if err := e.s.picks.Create(pr); err != nil {
+		log.Printf("scratch pick: save round %s: %v", bet.RoundID, err)
+		e.s.returnPoolTicket(draw.ticket)
+		return nil, games.Errorf(http.StatusInternalServerError, "TECHNICAL_ERROR", "pick round could not be saved")
+	}
+	return e.round(pr), nil
+}
+
+// Resume returns a pick round still in the store, for retried purchases.
+func (e *pickEngine) Resume(ctx context.Context, roundID string) (*games.Round, bool) {
+	pr, ok := e.s.picks.Get(roundID)
+	if !ok {
+		return nil, false
+	}
+	return e.round(pr), true
+}
+
+// Act handles "pick" (body: ScratchPickRequest) and "complete", which settles a round the
+// timeout completed. A complete round whose win is not credited yet comes back decided, so a
+// failed credit is retried by the next action.
+func (e *pickEngine) Act(ctx context.Context, roundID string, act games.Action) (*games.Round, error) {
+	var pr *round.PickRound
+	var err error
+	switch act.Name {
+	case "pick":
+		var req ScratchPickRequest
+		if err := json.Unmarshal(act.Data, &req); err != nil {
+			return nil, games.Errorf(http.StatusBadRequest, "INVALID_BODY", "invalid JSON body")
+		}
+		pr, err = e.s.picks.Pick(roundID, req.Cell, time.Now())
+	case "complete":
+		var ok bool
+		if pr, ok = e.s.picks.Get(roundID); !ok {
+			err = round.ErrPickRoundNotFound
+		} else if !pr.Completed {
+			return nil, errPickRoundOpen
+		}
+	default:
+		return nil, games.ErrUnknownAction
+	}
+	switch {
+	case errors.Is(err, round.ErrPickRoundComplete) && !pr.Paid:
+	case errors.Is(err, round.ErrPickRoundComplete):
+		return nil, games.ErrRoundSettled
+	case errors.Is(err, round.ErrPickCell):
+		return nil, games.Errorf(http.StatusBadRequest, "INVALID_CELL", err.Error())
+	case errors.Is(err, round.ErrPickRoundNotFound):
+		return nil, games.ErrRoundNotFound
+	case err != nil && pr == nil:
+		return nil, err
+	case err != nil:
+		log.Printf("scratch pick: save round %s: %v", roundID, err)
+	}
+	return e.round(pr), nil
+}
+
+// Settle marks the round paid.
+func (e *pickEngine) Settle(ctx context.Context, rnd *games.Round) error {
+	return e.s.picks.SetPaid(rnd.RoundID)
+}
+
+// round is pr as the orchestrator sees it: decided once it is complete and not yet paid.
+func (e *pickEngine) round(pr *round.PickRound) *games.Round {
+	rnd := &games.Round{
+		Bet: games.Bet{
+			RoundID:    pr.RoundID,
+			GameID:     pr.GameID,
+			SessionID:  pr.SessionID,
+			Currency:   pr.Currency,
+			Amount:     pr.Bet,
+			DeviceType: pr.DeviceType,
+			BetRef:     pr.BetID,
+		},
+		Settled: pr.Completed && !pr.Paid,
+		State:   pr,
+	}
+	if rnd.Settled {
+		_, outcome := pickDeal(pr)
+		rnd.Win = outcome.WinAmount
+		if pr.Record != nil {
+			res := *pr.Record
+			res.Picked = append([]int(nil), pr.Picked...)
+			res.AutoComplete = pr.AutoComplete
+			rnd.Record = &res
+		}
+	}
+	return rnd
+}
+
+// handleScratchPickStart implements POST /api/scratch/pick: it buys a pick ticket through the
+// round orchestrator (see pickEngine). The player then opens cells with
+// POST /api/scratch/pick/{roundId}. A client round id (roundId, or the Idempotency-Key header)
+// makes the purchase idempotent: a retry gets the round it already bought.
+func (s *Server) handleScratchPickStart(w http.ResponseWriter, r *http.Request) {
+	req, deviceType, ok := decodeScratchPlayRequest(w, r)
+	if !ok {
+		return
+	}
+	roundID := strings.TrimSpace(req.RoundID)
+	if roundID == "" {
+		roundID = strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+	}
+	rnd, _, err := s.startRound(r.Context(), &pickEngine{s: s}, games.Bet{
+		RoundID:    roundID,
+		GameID:     req.GameID,
+		SessionID:  req.SessionID,
+		Currency:   req.Currency,
+		Amount:     req.BetAmount,
+		DeviceType: deviceType,
+	})
+	if err == nil {
+		if _, ok := rnd.State.(*round.PickRound); !ok {
+			// A pick round already settled and pruned: its result remains, but not the round.
+			err = errReplayUnsupported
+		}
+	}
+	if err != nil {
+		code, _ := roundErrorStatus(err)
+		http.Error(w, err.Error(), code)
+		return
+	}
+	writeJSON(w, http.StatusOK, pickRoundResponse(rnd.State.(*round.PickRound)))
+}
+
+// handleScratchPick implements POST /api/scratch/pick/{roundId}: it opens one cell. The last
+// pick completes the round, its win is credited and the response carries the full outcome. A
+// pick on a round that has timed out answers 409 with the auto-completed round.
+func (s *Server) handleScratchPick(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	var req ScratchPickRequest
+	if err != nil || json.Unmarshal(body, &req) != nil {
+		http.Error(w, "invalid JSON body", http.StatusBadRequest)
+		return
+	}
+	pr, ok := s.sessionPickRound(w, r.PathValue("roundId"), req.SessionID)
+	if !ok {
+		return
+	}
+	rnd, err := s.actRound(r.Context(), &pickEngine{s: s}, pr.SessionID, pr.RoundID, games.Action{Name: "pick", Data: body})
+	if errors.Is(err, games.ErrRoundSettled) {
+		if pr, ok := s.picks.Get(pr.RoundID); ok {
+			writeJSON(w, http.StatusConflict, pickRoundResponse(pr))
+			return
+		}
+	}
+	if err != nil {
+		code, _ := roundErrorStatus(err)
+		http.Error(w, err.Error(), code)
+		return
+	}
+	pr = rnd.State.(*round.PickRound)
+	code := http.StatusOK
+	if pr.AutoComplete {
+		code = http.StatusConflict
+	}
+	writeJSON(w, code, pickRoundResponse(pr))
+}
+
+// handleGetScratchPick implements GET /api/scratch/pick/{roundId}?session_id=...: the round's
+// current state, for resuming after a reconnect.
+func (s *Server) handleGetScratchPick(w http.ResponseWriter, r *http.Request) {
+	pr, ok := s.sessionPickRound(w, r.PathValue("roundId"), r.URL.Query().Get("session_id"))
+	if !ok {
+		return
+	}
+	writeJSON(w, http.StatusOK, pickRoundResponse(pr))
+}
+
+// handleListScratchPicks implements GET /api/scratch/pick?session_id=...: the session's
+// unfinished pick rounds, oldest first, so a reconnecting client can resume them.
+func (s *Server) handleListScratchPicks(w http.ResponseWriter, r *http.Request) {
+	sessionID := strings.TrimSpace(r.URL.Query().Get("session_id"))
+	if sessionID == "" {
+		http.Error(w, "session_id is required", http.StatusUnauthorized)
+		return
+	}
+	out := []ScratchPickRound{}
+	for _, pr := range s.picks.Open(sessionID) {
+		out = append(out, pickRoundResponse(pr))
+	}
+	writeJSON(w, http.StatusOK, out)
+}
+
+// sessionPickRound loads a pick round owned by sessionID. Rounds of other sessions are reported
+// as not found.
+func (s *Server) sessionPickRound(w http.ResponseWriter, roundID, sessionID string) (*round.PickRound, bool) {
+	sessionID = strings.TrimSpace(sessionID)
+	if sessionID == "" {
+		http.Error(w, "session_id is required", http.StatusUnauthorized)
+		return nil, false
+	}
+	pr, ok := s.picks.Get(roundID)
+	if !ok || pr.SessionID != sessionID {
+		http.Error(w, round.ErrPickRoundNotFound.Error(), http.StatusNotFound)
+		return nil, false
+	}
+	return pr, true
+}
+
+// pickDeal decodes the deal and outcome pr was bought with. A round that cannot be decoded is
+// logged and shown empty.
+func pickDeal(pr *round.PickRound) (scratch.PickDeal, scratch.Outcome) {
+	var deal scratch.PickDeal
+	var outcome scratch.Outcome
+	if err := json.Unmarshal(pr.Deal, &deal); err != nil {
+		log.Printf("scratch pick: round %s: decode deal: %v", pr.RoundID, err)
+	}
+	if err := json.Unmarshal(pr.Outcome, &outcome); err != nil {
+		log.Printf("scratch pick: round %s: decode outcome: %v", pr.RoundID, err)
+	}
+	return deal, outcome
+}
+
+// pickRoundResponse shows the opened cells of pr, and everything once it is complete.
+func pickRoundResponse(pr *round.PickRound) ScratchPickRound {
+	resp := ScratchPickRound{
+		RoundID:       pr.RoundID,
+		GameID:        pr.GameID,
+		Rows:          pr.Rows,
+		Cols:          pr.Cols,
+		Picks:         pr.Picks,
+		Picked:        make([]ScratchPickedCell, 0, len(pr.Picked)),
+		ExpiresAt:     pr.ExpiresAt,
+		Completed:     pr.Completed,
+		AutoCompleted: pr.AutoComplete,
+	}
+	deal, outcome := pickDeal(pr)
+	for k, cell := range pr.Picked {
+		if k >= len(deal.Found) {
+			break
+		}
+		it := deal.Found[k]
+		resp.Picked = append(resp.Picked, ScratchPickedCell{Cell: cell, Symbol: it.Symbol, Prize: it.Prize * pr.Bet})
+	}
+	if pr.Completed {
+		board := deal.Board(pr.Picked)
+		resp.Outcome = &ScratchResolvedOutcome{
+			RoundID:          pr.RoundID,
+			IsWin:            outcome.WinAmount > 0,
+			TierID:           outcome.Tier,
+			FinalPrize:       outcome.WinAmount,
+			Wins:             outcome.Wins,
+			PresentationSeed: pr.PresentationSeed,
+			RevealMap:        board.Cells,
+			Prizes:           cellPrizes(board, pr.Bet),
+			Fair:             pr.Fair,
+		}
+	}
+	return resp
+}
+
+// expirePickRounds auto-completes pick rounds nobody finished within cfg.PickTimeout, credits
+// complete rounds whose win is not paid yet (timed out, or a failed credit) and prunes old paid
+// rounds, until ctx is done.
+func (s *Server) expirePickRounds(ctx context.Context) {
+	ticker := time.NewTicker(pickSweepInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			s.settlePickRounds(ctx, now)
+		}
+	}
+}
+
+// settlePickRounds runs one sweep of expirePickRounds.
+func (s *Server) settlePickRounds(ctx context.Context, now time.Time) {
+	for _, pr := range s.picks.CompleteExpired(now) {
+		log.Printf("scratch pick: round %s timed out, auto-completed with picks %v", pr.RoundID, pr.Picked)
+	}
+	eng := &pickEngine{s: s}
+	for _, pr := range s.picks.Unpaid() {
+		if _, err := s.actRound(ctx, eng, pr.SessionID, pr.RoundID, games.Action{Name: "complete"}); err != nil && !errors.Is(err, games.ErrRoundSettled) {
+			log.Printf("scratch pick: settle round %s: %v", pr.RoundID, err)
+		}
+	}
+	s.picks.Prune(now.Add(-pickRoundRetention))
+}
@@ -0,0 +1,164 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/round"
+)
+
+// addPickGame registers a 2x2 Pick-One game "PICK" whose tickets always lose.
+func addPickGame(t *testing.T, s *Server) {
+	t.Helper()
+	math := &gamemath.GameMath{
+		ModelID:      "PICK",
+		ModelVersion: "1",
+		PrizeTable:   []gamemath.PrizeTier{{Tier: "LOSE", Weight: 100}},
+	}
+	if err := math.Seal(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.gameMath.Register(math); err != nil {
+		t.Fatal(err)
+	}
+	s.scratchConfigs.mu.Lock()
+	s.scratchConfigs.byGame = map[string]*ScratchConfig{"PICK": {
+		GameID:   "PICK",
+		Mechanic: ScratchMechanic{Type: "pick_one", Rows: 2, Cols: 2},
+		Symbols: []ScratchSymbol{
+			{ID: "bell", Category: "win"},
+			{ID: "lemon", Category: "dud"},
+			{ID: "plum", Category: "dud"},
+			{ID: "kiwi", Category: "dud"},
+			{ID: "pear", Category: "dud"},
+		},
+	}}
+	s.scratchConfigs.mu.Unlock()
+}
+
+func pickBet(roundID string) games.Bet {
+	return games.Bet{RoundID: roundID, GameID: "PICK", SessionID: "sess", Currency: "USD", Amount: 1}
+}
+
+func TestPickEngine_StartRefundsUnsavedRound(t *testing.T) {
+	plat := &fakePlatform{}
+	s := newTestServer(t, plat)
+	addPickGame(t, s)
+	// A directory in place of the store file makes every save fail.
+	if err := os.Mkdir(filepath.Join(s.cfg.DataDir, "pick_rounds.json"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	_, _, err := s.startRound(context.Background(), &pickEngine{s: s}, pickBet("p1"))
+	wantCode(t, err, "TECHNICAL_ERROR")
+	if plat.bets != 1 || plat.rollbacks != 1 {
+		t.Errorf("bets %d rollbacks %d", plat.bets, plat.rollbacks)
+	}
+	if _, ok := s.picks.Get(clientRoundID("sess", "p1")); ok {
+		t.Error("unsaved round kept")
+	}
+}
+
+func TestPickEngine_RetriedStartResumesRound(t *testing.T) {
+	plat := &fakePlatform{}
+	s := newTestServer(t, plat)
+	addPickGame(t, s)
+	eng := &pickEngine{s: s}
+	first, _, err := s.startRound(context.Background(), eng, pickBet("p1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	id := first.RoundID
+	rnd, replay, err := s.startRound(context.Background(), eng, pickBet("p1"))
+	if err != nil || !replay {
+		t.Fatalf("retry: %v (replay %v)", err, replay)
+	}
+	if pr, ok := rnd.State.(*round.PickRound); !ok || pr.RoundID != id || pr.BetID != "bet-1" {
+		t.Errorf("resumed round %+v", rnd.State)
+	}
+	other := pickBet("p1")
+	other.Amount = 2
+	_, _, err = s.startRound(context.Background(), eng, other)
+	wantCode(t, err, "ROUND_ID_REUSED")
+	if plat.bets != 1 {
+		t.Errorf("bets %d", plat.bets)
+	}
+
+	pick, _ := json.Marshal(ScratchPickRequest{SessionID: "sess", Cell: 2})
+	if _, err = s.actRound(context.Background(), eng, "sess", id, games.Action{Name: "pick", Data: pick}); err != nil {
+		t.Fatal(err)
+	}
+	if pr, ok := s.picks.Get(id); !ok || !pr.Completed || !pr.Paid {
+		t.Errorf("round after the last pick %+v", pr)
+	}
+	if res, _ := s.results.GetByRoundID(id); res == nil || res.BetID != "bet-1" || res.Outcome != "lose" {
+		t.Errorf("result %+v", res)
+	}
+	if _, err := s.actRound(context.Background(), eng, "sess", id, games.Action{Name: "pick", Data: pick}); err != games.ErrRoundSettled {
+		t.Errorf("pick on a paid round: %v", err)
+	}
+	if len(plat.wins) != 0 || plat.rollbacks != 0 {
+		t.Errorf("wins %v rollbacks %d", plat.wins, plat.rollbacks)
+	}
+}
+
+func TestPickEngine_RejectsOtherGames(t *testing.T) {
+	plat := &fakePlatform{}
+	s := newTestServer(t, plat)
+	addPickGame(t, s)
+	s.scratchConfigs.byGame["MATCH"] = &ScratchConfig{GameID: "MATCH", Mechanic: ScratchMechanic{Type: "match_n"}}
+	bet := pickBet("")
+	bet.GameID = "MATCH"
+	_, _, err := s.startRound(context.Background(), &pickEngine{s: s}, bet)
+	if gerr := wantCode(t, err, "INVALID_GAME"); gerr.Status != http.StatusBadRequest {
+		t.Errorf("status %d", gerr.Status)
+	}
+	if plat.bets != 0 {
+		t.Errorf("bets %d", plat.bets)
+	}
+}
+
+func TestRebuildScratchReveal_PickRoundAfterPrune(t *testing.T) {
+	plat := &fakePlatform{}
+	s := newTestServer(t, plat)
+	addPickGame(t, s)
+	eng := &pickEngine{s: s}
+	rnd, _, err := s.startRound(context.Background(), eng, pickBet(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pick, _ := json.Marshal(ScratchPickRequest{SessionID: "sess", Cell: 3})
+	if _, err := s.actRound(context.Background(), eng, "sess", rnd.RoundID, games.Action{Name: "pick", Data: pick}); err != nil {
+		t.Fatal(err)
+	}
+	pr, _ := s.picks.Get(rnd.RoundID)
+	s.picks.Prune(time.Now().Add(pickRoundRetention + time.Hour))
+	if _, ok := s.picks.Get(rnd.RoundID); ok {
+		t.Fatal("round not pruned")
+	}
+
+	res, _ := s.results.GetByRoundID(rnd.RoundID)
+	if res == nil || len(res.Picked) != 1 || res.Picked[0] != 3 || res.AutoComplete {
+		t.Fatalf("result %+v", res)
+	}
+	m, err := s.rebuildScratchReveal(res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	deal, _ := pickDeal(pr)
+	want := deal.Board(pr.Picked)
+	if len(m.Cells) != len(want.Cells) || m.Cells[3] != deal.Found[0].Symbol {
+		t.Fatalf("reveal %v, want %v", m.Cells, want.Cells)
+	}
+	for i := range want.Cells {
+		if m.Cells[i] != want.Cells[i] {
+			t.Fatalf("reveal %v, want %v", m.Cells, want.Cells)
+		}
+	}
+}
@@ -1,17 +1,17 @@
 package server
 
 import (
-	"crypto/rand"
 	"encoding/json"
-	"math/big"
+	"log"
 	"net/http"
 	"strings"
-	"time"
 
-	"github.com/google/uuid"
-
-	rgsdb "github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/fair"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games"
 	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games/scratch"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/round"
 )
 
 // ScratchPlayRequest is the request body for POST /api/scratch/play.
@@ -22,21 +22,35 @@ type ScratchPlayRequest struct {
 	Currency   string  `json:"currency"`
 	OperatorID int     `json:"operatorId,omitempty"`
 	DeviceType string  `json:"deviceType,omitempty"`
+	// RoundID is an optional client-chosen round id (or the Idempotency-Key header): a retry with
+	// the same id returns the original outcome instead of buying another ticket.
+	RoundID string `json:"roundId,omitempty"`
 }
 
 // ScratchResolvedOutcome is the GameCrafter-compatible scratch outcome payload.
 type ScratchResolvedOutcome struct {
-	RoundID          string   `json:"roundId"`
-	IsWin            bool     `json:"isWin"`
-	TierID           string   `json:"tierId"`
-	FinalPrize       float64  `json:"finalPrize"`
-	PresentationSeed int64    `json:"presentationSeed,omitempty"`
-	RevealMap        []string `json:"revealMap"`
+	RoundID    string  `json:"roundId"`
+	IsWin      bool    `json:"isWin"`
+	TierID     string  `json:"tierId"`
+	FinalPrize float64 `json:"finalPrize"`
+	// Wins lists every winning component (one per prize for MULTI_WIN combinations); their
+	// amounts add up to FinalPrize.
+	Wins             []scratch.Win `json:"wins,omitempty"`
+	PresentationSeed int64         `json:"presentationSeed,omitempty"`
+	RevealMap        []string      `json:"revealMap"`
+	// Prizes is the prize amount shown under each revealMap cell, for mechanics that show one
+	// (target_match: the Your Numbers cells; 0 elsewhere).
+	Prizes []float64 `json:"prizes,omitempty"`
+	// Zones describes the areas of revealMap (target_match: Winning Numbers, then Your Numbers).
+	Zones []scratch.Zone `json:"zones,omitempty"`
+	// Fair is set in provably fair mode: the seeds and nonce the outcome was drawn from.
+	Fair *fair.Proof `json:"fair,omitempty"`
 }
 
 type ScratchSymbolsResponse struct {
-	GameID  string          `json:"gameId"`
-	Symbols []ScratchSymbol `json:"symbols"`
+	GameID  string               `json:"gameId"`
+	Symbols []ScratchSymbol      `json:"symbols"`
+	Tiers   []ScratchTierSymbols `json:"tiers,omitempty"` // prize tier -> symbols and payout label
 }
 
 // handleScratchSymbols returns symbol configuration (IDs + images) for a scratch game.
@@ -51,7 +65,7 @@ func (s *Server) handleScratchSymbols(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, "gameId is required", http.StatusBadRequest)
 		return
 	}
-	cfg, err := loadScratchConfigFromDB(gameID)
+	cfg, err := s.scratchConfigs.get(gameID)
 	if err != nil {
 		http.Error(w, "database error", http.StatusBadGateway)
 		return
@@ -63,6 +77,7 @@ func (s *Server) handleScratchSymbols(w http.ResponseWriter, r *http.Request) {
 	resp := ScratchSymbolsResponse{
 		GameID:  cfg.GameID,
 		Symbols: cfg.Symbols,
+		Tiers:   cfg.Tiers,
 	}
 	w.Header().Set("Content-Type", "application/json")
 	_ = json.NewEncoder(w).Encode(resp)
@@ -77,15 +92,77 @@ func (s *Server) handleScratchPlay(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	req, deviceType, ok := decodeScratchPlayRequest(w, r)
+	if !ok {
+		return
+	}
+
+	// A client round id makes the purchase idempotent: a retry gets the round it already bought.
+	roundID := strings.TrimSpace(req.RoundID)
+	if roundID == "" {
+		roundID = strings.TrimSpace(r.Header.Get("Idempotency-Key"))
+	}
+	eng, _ := s.registry.Engine(req.GameID)
+	if _, ok := eng.(*scratchEngine); !ok {
+		http.Error(w, "gameId is not a scratch game", http.StatusBadRequest)
+		return
+	}
+	// The engine builds the reveal map before any money moves, so a grid that cannot be shown
+	// fails the purchase; the orchestrator charges the wallet and records the round.
+	rnd, _, err := s.startRound(r.Context(), eng, games.Bet{
+		RoundID:    roundID,
+		GameID:     req.GameID,
+		SessionID:  req.SessionID,
+		Currency:   req.Currency,
+		Amount:     req.BetAmount,
+		DeviceType: deviceType,
+	})
+	if err != nil {
+		code, _ := roundErrorStatus(err)
+		http.Error(w, err.Error(), code)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(rnd.State)
+}
+
+// resolvedScratchOutcome is the ScratchResolvedOutcome of a paid scratch round.
+func resolvedScratchOutcome(roundID string, bet float64, draw *scratchDraw, reveal scratch.RevealMap) ScratchResolvedOutcome {
+	return ScratchResolvedOutcome{
+		RoundID:          roundID,
+		IsWin:            draw.outcome.WinAmount > 0,
+		TierID:           draw.outcome.Tier,
+		FinalPrize:       draw.outcome.WinAmount,
+		Wins:             draw.outcome.Wins,
+		PresentationSeed: draw.presentationSeed,
+		RevealMap:        reveal.Cells,
+		Prizes:           cellPrizes(reveal, bet),
+		Zones:            reveal.Zones,
+		Fair:             draw.proof,
+	}
+}
+
+// decodeScratchPlayRequest reads and validates a ScratchPlayRequest body, filling in defaults.
+// It answers the request itself and returns false when the body is invalid.
+func decodeScratchPlayRequest(w http.ResponseWriter, r *http.Request) (ScratchPlayRequest, string, bool) {
 	var req ScratchPlayRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		http.Error(w, "invalid JSON body", http.StatusBadRequest)
-		return
+		return req, "", false
 	}
+	deviceType, ok := checkScratchPlayRequest(w, &req)
+	return req, deviceType, ok
+}
+
+// checkScratchPlayRequest validates a decoded ScratchPlayRequest and fills in defaults, returning
+// the device type. It answers the request itself and returns false when req is invalid.
+func checkScratchPlayRequest(w http.ResponseWriter, req *ScratchPlayRequest) (string, bool) {
 	req.SessionID = strings.TrimSpace(req.SessionID)
 	if req.SessionID == "" {
 		http.Error(w, "session_id is required", http.StatusUnauthorized)
-		return
+		return "", false
 	}
 	req.GameID = strings.TrimSpace(req.GameID)
 	if req.GameID == "" {
@@ -93,7 +170,7 @@ func (s *Server) handleScratchPlay(w http.ResponseWriter, r *http.Request) {
 	}
 	if req.BetAmount <= 0 {
 		http.Error(w, "betAmount must be positive", http.StatusBadRequest)
-		return
+		return "", false
 	}
 	if req.Currency == "" {
 		req.Currency = "USD"
@@ -102,358 +179,158 @@ func (s *Server) handleScratchPlay(w http.ResponseWriter, r *http.Request) {
 	if deviceType == "" {
 		deviceType = "desktop"
 	}
+	return deviceType, true
+}
 
-	roundID := uuid.New().String()
-
-	// Generate outcome using existing game math (or legacy scratch fallback).
-	var outcome scratch.Outcome
-	modelID := req.GameID
-	if math := s.gameMath.Get(modelID); math != nil {
-		if o, ok := scratch.GenerateWithMath(req.BetAmount, math); ok {
-			outcome = o
-		} else {
-			outcome = scratch.Generate(req.BetAmount)
-		}
-	} else {
-		outcome = scratch.Generate(req.BetAmount)
-	}
-
-	// Wallet integration: use operator transaction API when configured, otherwise platform client.
-	var finalPrize = outcome.WinAmount
-	if s.operator != nil {
-		db, err := rgsdb.GetDB()
-		if err != nil || db == nil {
-			http.Error(w, "database unavailable", http.StatusBadGateway)
-			return
-		}
-		ctx := r.Context()
-		var userID, accountID string
-		var dbGameCode string
-		var operatorID int
-		err = db.QueryRowContext(ctx, `
-        SELECT gs.user_id, u.username, gs.game_id, gs.operator_id
-        FROM game_sessions gs
-        JOIN users u ON gs.user_id = u.id
-        WHERE gs.session_id = $1
-      `, req.SessionID).Scan(&userID, &accountID, &dbGameCode, &operatorID)
-		if err != nil {
-			http.Error(w, "invalid session", http.StatusUnauthorized)
-			return
-		}
-		gameCode := req.GameID
-		if gameCode == "scratch" && dbGameCode != "" {
-			gameCode = dbGameCode
-		}
-		txIDDebit := uuid.New().String()
-		txIDCredit := uuid.New().String()
-		respDebit, err := s.operator.Debit(userID, req.SessionID, roundID, txIDDebit, gameCode, deviceType, "1.0", req.BetAmount, "")
-		if err != nil || respDebit.Code != 0 {
-			errMsg := "debit failed"
-			if respDebit != nil && respDebit.Message != "" {
-				errMsg = respDebit.Message
-			} else if err != nil {
-				errMsg = err.Error()
-			}
-			http.Error(w, errMsg, http.StatusBadGateway)
-			return
-		}
-		respCredit, err := s.operator.Credit(userID, req.SessionID, roundID, txIDCredit, gameCode, deviceType, "1.0", "completed", "", finalPrize)
-		if err != nil || respCredit.Code != 0 {
-			errMsg := "credit failed"
-			if respCredit != nil && respCredit.Message != "" {
-				errMsg = respCredit.Message
-			} else if err != nil {
-				errMsg = err.Error()
-			}
-			http.Error(w, errMsg, http.StatusBadGateway)
-			return
-		}
-		// Log combined wallet transaction (similar to provider scratch handler).
-		netResult := finalPrize - req.BetAmount
-		if netResult == 0 {
-			netResult = -req.BetAmount
-		}
-		_, _ = db.ExecContext(ctx, `
-        INSERT INTO rgs_wallet_transactions (
-          transaction_id,
-          account_id,
-          session_id,
-          round_id,
-          game_id,
-          type,
-          status,
-          amount,
-          currency,
-          bet_amount,
-          win_amount,
-          net_result,
-          user_id,
-          operator_id
-        ) VALUES (
-          $1, $2, $3, $4, $5, 'debit_and_credit', 'completed',
-          $6, $7, $8, $9, $10, $11, $12
-        )
-      `,
-			txIDDebit,
-			accountID,
-			req.SessionID,
-			roundID,
-			gameCode,
-			req.BetAmount,
-			req.Currency,
-			req.BetAmount,
-			finalPrize,
-			netResult,
-			userID,
-			operatorID,
-		)
-	} else if s.client != nil {
-		// Fallback to platform client if operator transaction API is not configured.
-		betID, status, err := s.client.Bet(req.SessionID, req.Currency, req.BetAmount, "Scratch", "")
-		if err != nil {
-			code := status
-			if code == 0 {
-				code = http.StatusBadGateway
-			}
-			http.Error(w, err.Error(), code)
-			return
-		}
-		if finalPrize > 0 {
-			_, err = s.client.Win(req.SessionID, req.Currency, finalPrize, "Scratch", "")
-			if err != nil {
-				_, _ = s.client.Rollback(req.SessionID, betID)
-				http.Error(w, err.Error(), http.StatusBadGateway)
-				return
-			}
-		}
+// encodeWins is the round.Result form of an outcome's wins (see resultWins).
+func encodeWins(wins []scratch.Win) json.RawMessage {
+	if len(wins) == 0 {
+		return nil
 	}
-
-	// Build revealMap according to mechanic config (Variant A/B/C). Fallback to 1x3 if no config.
-	cfg, err := loadScratchConfigFromDB(req.GameID)
+	data, err := json.Marshal(wins)
 	if err != nil {
-		http.Error(w, "database error", http.StatusBadGateway)
-		return
-	}
-	revealMap := buildRevealMapFromOutcome(cfg, &outcome)
-
-	resp := ScratchResolvedOutcome{
-		RoundID:          roundID,
-		IsWin:            finalPrize > 0,
-		TierID:           outcome.Tier,
-		FinalPrize:       finalPrize,
-		PresentationSeed: time.Now().UnixNano(),
-		RevealMap:        revealMap,
+		log.Printf("scratch: encode wins: %v", err)
+		return nil
 	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	_ = json.NewEncoder(w).Encode(resp)
+	return data
 }
 
-// --- revealMap generation helpers ---
-
-func buildRevealMapFromOutcome(cfg *ScratchConfig, outcome *scratch.Outcome) []string {
-	if cfg == nil {
-		// Fallback: 1x3 grid from simple outcome.
-		return []string{outcome.Symbols[0], outcome.Symbols[1], outcome.Symbols[2]}
+// resultWins decodes the wins recorded in res.
+func resultWins(res *round.Result) []scratch.Win {
+	if len(res.Wins) == 0 {
+		return nil
 	}
-	switch cfg.Mechanic.Type {
-	case "match_n":
-		return generateMatchNRevealMap(cfg, outcome)
-	case "symbol_hunt":
-		return generateSymbolHuntRevealMap(cfg, outcome)
-	case "target_match":
-		return generateTargetMatchRevealMap(cfg, outcome)
-	default:
-		return []string{outcome.Symbols[0], outcome.Symbols[1], outcome.Symbols[2]}
+	var wins []scratch.Win
+	if err := json.Unmarshal(res.Wins, &wins); err != nil {
+		log.Printf("scratch: round %s: decode wins: %v", res.RoundID, err)
+		return nil
 	}
+	return wins
 }
 
-func randomInt(n int) int {
-	if n <= 0 {
-		return 0
+// scratchResult is the scratch part of a round result: the outcome and what produced it, and
+// what rebuilds its reveal map: the config version (cfg is kept under it) and the presentation
+// seed.
+func (s *Server) scratchResult(gameID string, cfg *ScratchConfig, draw *scratchDraw) *round.Result {
+	var version string
+	if cfg != nil {
+		version = cfg.Version()
+		if err := s.configVersions.Put(version, cfg); err != nil {
+			log.Printf("scratch: save config version of %s: %v", gameID, err)
+		}
 	}
-	max := big.NewInt(int64(n))
-	v, err := rand.Int(rand.Reader, max)
-	if err != nil {
-		return 0
+	outcome := draw.outcome
+	return &round.Result{
+		Symbols:      outcome.Symbols[:],
+		WinAmount:    outcome.WinAmount,
+		Tier:         outcome.Tier,
+		Wins:         encodeWins(outcome.Wins),
+		MathHash:     draw.mathHash,
+		ModelVersion: draw.modelVersion,
+		RNGSeed:      draw.seed,
+		RNGDraws:     draw.src.Draws(),
+		Fair:         draw.proof,
+
+		GameID:           gameID,
+		ConfigVersion:    version,
+		PresentationSeed: draw.presentationSeed,
 	}
-	return int(v.Int64())
 }
 
-func randomFrom(list []string) string {
-	if len(list) == 0 {
-		return ""
-	}
-	return list[randomInt(len(list))]
+// scratchDraw is a resolved scratch outcome plus what produced it.
+type scratchDraw struct {
+	outcome scratch.Outcome
+	// ticket is set for LIMITED models; it must go back via returnPoolTicket if the purchase fails.
+	ticket *gamemath.PoolTicket
+	// mathHash and modelVersion identify the math model used.
+	mathHash     string
+	modelVersion string
+	// presentationSeed is the last draw of the round: the reveal map is drawn from
+	// scratch.PresentationSource(presentationSeed) alone, so it can be rebuilt from the result.
+	presentationSeed int64
+	// roundRNG is the round's random source.
+	roundRNG
 }
 
-func randomDistinctIndices(total, count int) []int {
-	if total <= 0 || count <= 0 {
-		return nil
-	}
-	if count > total {
-		count = total
-	}
-	indices := make([]int, total)
-	for i := 0; i < total; i++ {
-		indices[i] = i
-	}
-	// Fisher–Yates shuffle first count elements
-	for i := 0; i < count; i++ {
-		j := i + randomInt(total-i)
-		indices[i], indices[j] = indices[j], indices[i]
+// presentationSeedLimit bounds presentation seeds so JavaScript clients read them exactly.
+const presentationSeedLimit = 1 << 53
+
+// generateScratchOutcome resolves a scratch outcome for modelID using the version pinned to the
+// session, then draws the round's presentation seed.
+func (s *Server) generateScratchOutcome(sessionID, modelID string, betAmount float64) (scratchDraw, error) {
+	d, err := s.resolveScratchOutcome(sessionID, modelID, betAmount)
+	if err != nil {
+		return scratchDraw{}, err
 	}
-	return indices[:count]
+	d.presentationSeed = d.src.Int63n(presentationSeedLimit)
+	return d, nil
 }
 
-// Variant A: Match-N (e.g. 3x3 Match-3/2/4).
-func generateMatchNRevealMap(cfg *ScratchConfig, outcome *scratch.Outcome) []string {
-	rows, cols := cfg.Mechanic.Rows, cfg.Mechanic.Cols
-	if rows <= 0 {
-		rows = 3
-	}
-	if cols <= 0 {
-		cols = 3
-	}
-	total := rows * cols
-	if total <= 0 {
-		total = 9
-	}
+// errMathUnavailable refuses a scratch round for a game without valid (hash-verified, active)
+// math: tickets are only ever drawn from certified math.
+var errMathUnavailable = games.Errorf(http.StatusServiceUnavailable, "MATH_UNAVAILABLE", "game has no valid math")
 
-	var winSyms, dudSyms []string
-	var topSym string
-	for _, s := range cfg.Symbols {
-		switch s.Category {
-		case "win":
-			winSyms = append(winSyms, s.ID)
-		case "dud":
-			dudSyms = append(dudSyms, s.ID)
-		case "top":
-			topSym = s.ID
-		}
-	}
-	if len(dudSyms) == 0 {
-		dudSyms = winSyms
-	}
-	if len(dudSyms) == 0 && topSym != "" {
-		dudSyms = []string{topSym}
+// resolveScratchOutcome draws the outcome of a scratch round. LIMITED models sell a ticket from
+// the model's current series; its tier comes from the model, components and boost included. Games without valid math are refused with errMathUnavailable.
+func (s *Server) resolveScratchOutcome(sessionID, modelID string, betAmount float64) (scratchDraw, error) {
+	math := s.gameMath.Pinned(sessionID, modelID)
+	if math == nil {
+		log.Printf("scratch: no valid game math for %s; refusing play", modelID)
+		return scratchDraw{}, errMathUnavailable
+	}
+	roundRNG, err := s.newRoundRNG(sessionID)
+	if err != nil {
+		return scratchDraw{}, err
 	}
-
-	grid := make([]string, total)
-	isWin := outcome.WinAmount > 0
-	matchCount := cfg.Mechanic.MatchCount
-	if matchCount <= 0 {
-		matchCount = 3
+	d := scratchDraw{roundRNG: roundRNG}
+	d.modelVersion = math.ModelVersion
+	if math.Integrity != nil {
+		d.mathHash = math.Integrity.ContentHash
 	}
-
-	if isWin {
-		// For now, pick any winning symbol; later, link tiers to specific symbols via config/math.
-		winSym := ""
-		if len(winSyms) > 0 {
-			winSym = winSyms[randomInt(len(winSyms))]
-		} else if topSym != "" {
-			winSym = topSym
-		} else if len(dudSyms) > 0 {
-			winSym = dudSyms[0]
-		}
-		idxs := randomDistinctIndices(total, matchCount)
-		for _, i := range idxs {
-			grid[i] = winSym
-		}
-		for i := 0; i < total; i++ {
-			if grid[i] != "" {
-				continue
-			}
-			grid[i] = randomFrom(dudSyms)
-		}
-	} else {
-		// Simple safe loss ticket: all duds, avoid top symbol.
-		for i := 0; i < total; i++ {
-			grid[i] = randomFrom(dudSyms)
+	if math.IsLimited() {
+		tier, ticket, err := s.pools.Draw(math, d.src)
+		if err != nil {
+			return scratchDraw{}, err
 		}
+		d.outcome = scratch.OutcomeForTierFrom(d.src, betAmount, tier)
+		d.ticket = &ticket
+		return d, nil
 	}
-	return grid
+	o, ok := scratch.GenerateWithMathFrom(d.src, betAmount, math)
+	if !ok {
+		log.Printf("scratch: game math %s@%s has no drawable tier; refusing play", modelID, math.ModelVersion)
+		return scratchDraw{}, errMathUnavailable
+	}
+	d.outcome = o
+	return d, nil
 }
 
-// Variant C: Symbol Hunt / Pick One – placeholder implementation:
-// win uses a special symbol, loss uses only duds. Extend with per-tier payout mapping later.
-func generateSymbolHuntRevealMap(cfg *ScratchConfig, outcome *scratch.Outcome) []string {
-	rows, cols := cfg.Mechanic.Rows, cfg.Mechanic.Cols
-	if rows <= 0 {
-		rows = 3
-	}
-	if cols <= 0 {
-		cols = 3
-	}
-	total := rows * cols
-	if total <= 0 {
-		total = 9
-	}
-	var specialSyms, dudSyms []string
-	for _, s := range cfg.Symbols {
-		switch s.Category {
-		case "top", "win":
-			specialSyms = append(specialSyms, s.ID)
-		case "dud":
-			dudSyms = append(dudSyms, s.ID)
-		}
+// --- revealMap generation helpers ---
+
+// buildRevealMapFromOutcome builds the reveal map for the outcome. Match-N, Symbol Hunt and
+// Target Match maps come from the reveal-map engine (scratch.Layout), which checks every map
+// before it is returned; without a config the legacy 1x3 symbols are shown.
+func buildRevealMapFromOutcome(src rng.Source, cfg *ScratchConfig, outcome *scratch.Outcome) (scratch.RevealMap, error) {
+	if cfg == nil {
+		// Fallback: 1x3 grid from simple outcome.
+		return scratch.RevealMap{Cells: outcome.Symbols[:]}, nil
 	}
-	if len(dudSyms) == 0 {
-		dudSyms = specialSyms
-	}
-	grid := make([]string, total)
-	isWin := outcome.WinAmount > 0
-	if isWin && len(specialSyms) > 0 {
-		// Place at least one special symbol, rest duds.
-		winSym := specialSyms[randomInt(len(specialSyms))]
-		idxs := randomDistinctIndices(total, 1)
-		for _, i := range idxs {
-			grid[i] = winSym
-		}
-		for i := 0; i < total; i++ {
-			if grid[i] != "" {
-				continue
-			}
-			grid[i] = randomFrom(dudSyms)
-		}
-	} else {
-		// Loss: all duds (no top/special symbol).
-		for i := 0; i < total; i++ {
-			grid[i] = randomFrom(dudSyms)
-		}
+	switch cfg.Mechanic.Type {
+	case scratch.MechanicMatchN, scratch.MechanicSymbolHunt, scratch.MechanicTargetMatch:
+		return cfg.layout().Reveal(src, outcome)
+	default:
+		return scratch.RevealMap{Cells: outcome.Symbols[:]}, nil
 	}
-	return grid
 }
 
-// Variant B: Target Match – placeholder: treat as loss-only safe grid for now.
-// Extend with explicit winning/your-number zones when you introduce such games.
-func generateTargetMatchRevealMap(cfg *ScratchConfig, outcome *scratch.Outcome) []string {
-	rows, cols := cfg.Mechanic.Rows, cfg.Mechanic.Cols
-	if rows <= 0 {
-		rows = 3
-	}
-	if cols <= 0 {
-		cols = 3
-	}
-	total := rows * cols
-	if total <= 0 {
-		total = 9
-	}
-	var dudSyms []string
-	for _, s := range cfg.Symbols {
-		if s.Category == "dud" {
-			dudSyms = append(dudSyms, s.ID)
-		}
-	}
-	if len(dudSyms) == 0 {
-		for _, s := range cfg.Symbols {
-			dudSyms = append(dudSyms, s.ID)
-		}
+// cellPrizes converts the per-cell prize multipliers of a reveal map to amounts for bet.
+func cellPrizes(m scratch.RevealMap, bet float64) []float64 {
+	if m.Prizes == nil {
+		return nil
 	}
-	grid := make([]string, total)
-	for i := 0; i < total; i++ {
-		grid[i] = randomFrom(dudSyms)
+	out := make([]float64, len(m.Prizes))
+	for i, p := range m.Prizes {
+		out[i] = p * bet
 	}
-	return grid
+	return out
 }
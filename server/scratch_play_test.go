@@ -0,0 +1,127 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games/scratch"
+)
+
+// playScratch posts body to /api/scratch/play.
+func playScratch(s *Server, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.handleScratchPlay(w, httptest.NewRequest(http.MethodPost, "/api/scratch/play", strings.NewReader(body)))
+	return w
+}
+
+func TestHandleScratchPlay_RetryReplaysTicket(t *testing.T) {
+	plat := &fakePlatform{}
+	s := newTestServer(t, plat)
+	addLimitedGame(t, s, "MATCH")
+	const body = `{"session_id":"sess","gameId":"MATCH","betAmount":1,"currency":"USD","roundId":"k1"}`
+	first := playScratch(s, body)
+	if first.Code != http.StatusOK {
+		t.Fatalf("play: %d %s", first.Code, first.Body)
+	}
+	retry := playScratch(s, body)
+	if retry.Code != http.StatusOK || retry.Body.String() != first.Body.String() {
+		t.Errorf("retry answered %d %s, want %s", retry.Code, retry.Body, first.Body)
+	}
+	if plat.bets != 1 {
+		t.Errorf("bets %d", plat.bets)
+	}
+
+	reused := playScratch(s, strings.Replace(body, `"betAmount":1`, `"betAmount":2`, 1))
+	if reused.Code != http.StatusConflict {
+		t.Errorf("key reused for another bet: %d %s", reused.Code, reused.Body)
+	}
+	other := playScratch(s, strings.Replace(body, `"sess"`, `"other"`, 1))
+	var a, b ScratchResolvedOutcome
+	json.Unmarshal(first.Body.Bytes(), &a)
+	json.Unmarshal(other.Body.Bytes(), &b)
+	if other.Code != http.StatusOK || b.RoundID == a.RoundID {
+		t.Errorf("same key in another session: %d, round %s", other.Code, b.RoundID)
+	}
+	if plat.bets != 2 {
+		t.Errorf("bets %d", plat.bets)
+	}
+}
+
+func TestScratchEngine_ReplayWithoutRevealReturnsResult(t *testing.T) {
+	plat := &fakePlatform{}
+	s := newTestServer(t, plat)
+	addLimitedGame(t, s, "MATCH")
+	eng := &scratchEngine{s: s}
+	bet := testBet("k1")
+	bet.GameID = "MATCH"
+	rnd, _, err := s.startRound(context.Background(), eng, bet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, _ := s.results.GetByRoundID(rnd.RoundID)
+	if res == nil {
+		t.Fatal("round not recorded")
+	}
+	// The config version the ticket was shown with is gone: the grid cannot be rebuilt.
+	res.ConfigVersion = "missing"
+	replayed, err := eng.Replay(context.Background(), res)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, ok := replayed.State.(*ScratchResolvedOutcome)
+	if !ok {
+		t.Fatalf("replay state %T", replayed.State)
+	}
+	if out.RoundID != rnd.RoundID || out.TierID != res.Tier || out.FinalPrize != res.WinAmount || out.RevealMap == nil || len(out.RevealMap) != 0 {
+		t.Errorf("replayed outcome %+v for result %+v", out, res)
+	}
+}
+
+func TestScratchEngine_LimitedComboTicketSplitsWins(t *testing.T) {
+	plat := &fakePlatform{}
+	s := newTestServer(t, plat)
+	math := &gamemath.GameMath{
+		ModelID:      "COMBO",
+		ModelVersion: "1",
+		MathMode:     gamemath.MathModeLimited,
+		WinLogic:     gamemath.WinLogicMulti,
+		TotalTickets: 1,
+		PrizeTable: []gamemath.PrizeTier{{Tier: "T1+T2", Multiplier: 7, Weight: 1, Components: []gamemath.PrizeComponent{
+			{Tier: "T1", Multiplier: 2, Boost: 2},
+			{Tier: "T2", Multiplier: 5},
+		}}},
+	}
+	if err := math.Seal(); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.gameMath.Register(math); err != nil {
+		t.Fatal(err)
+	}
+	s.scratchConfigs.mu.Lock()
+	s.scratchConfigs.byGame = map[string]*ScratchConfig{"COMBO": nil}
+	s.scratchConfigs.mu.Unlock()
+
+	bet := testBet("c1")
+	bet.GameID = "COMBO"
+	rnd, _, err := s.startRound(context.Background(), &scratchEngine{s: s}, bet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, _ := s.results.GetByRoundID(rnd.RoundID)
+	if res == nil {
+		t.Fatal("round not recorded")
+	}
+	for _, wins := range [][]scratch.Win{rnd.View.(ScratchRoundStartResponse).Wins, resultWins(res)} {
+		if len(wins) != 2 || wins[0].Tier != "T1" || wins[0].Boost != 2 || wins[0].Amount != 2 || wins[1].Tier != "T2" || wins[1].Amount != 5 {
+			t.Errorf("wins %+v, want T1 (2x boost) and T2", wins)
+		}
+	}
+	if rnd.Win != 7 {
+		t.Errorf("win %v", rnd.Win)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"io"
 	"os"
 	"path/filepath"
@@ -13,14 +14,17 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
 	"time"
 
 	rgsdb "github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server"
 	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/config"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/fair"
 	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
 	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games"
 	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/operator"
 	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/platform"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
 	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/round"
 
 	"github.com/google/uuid"
@@ -36,8 +40,25 @@ type Server struct {
 	store      *round.Store
 	results    *round.ResultsStore
 	crashStore *round.CrashStore
+	crashTable *crashTable
+	crashMath  gamemath.CrashMath // sealed; registered in crashModels by Run
+	picks      *round.PickStore
 	gameMath   *gamemath.Store
+	pools      *gamemath.Pools
 	registry   *games.Registry
+	rng        rng.Source
+	fair       *fair.Store // nil unless provably fair mode is on
+
+	scratchConfigs scratchConfigCache
+	configVersions *round.ConfigStore       // scratch configs rounds were played with, by version
+	crashHistory   *round.CrashHistoryStore // finished shared crash rounds
+	crashModels    *gamemath.CrashMathStore // every crash math version this server has run
+	crashChain     *fair.ChainStore         // seed chain of the shared crash rounds
+	unreconciled   *round.UnreconciledStore // rounds held until an operator reconciles them
+	inflight       roundLocks               // round ids with a request in progress
+	pages          map[string]gamePage      // games with a built-in page, by game id
+	reloadMu       sync.Mutex               // serializes Reload
+	ownNotify      ownNotifications         // config notifications this instance caused
 }
 
 func New(cfg *config.Config) *Server {
@@ -53,23 +74,65 @@ func New(cfg *config.Config) *Server {
 		store:      round.NewStore(cfg.DataDir),
 		results:    round.NewResultsStore(cfg.DataDir),
 		crashStore: round.NewCrashStore(cfg.DataDir),
+		crashMath:  crashMath(cfg),
+		picks:      round.NewPickStore(cfg.DataDir),
 		gameMath:   gamemath.NewStore(cfg.DataDir),
+		pools:      newTicketPools(cfg.DataDir),
 		registry:   games.NewRegistry(),
+		rng:        newServerRNG(cfg.RNGSeed),
+
+		configVersions: round.NewConfigStore(cfg.DataDir),
+		crashHistory:   round.NewCrashHistoryStore(cfg.DataDir),
+		crashModels:    gamemath.NewCrashMathStore(cfg.DataDir),
+		unreconciled:   round.NewUnreconciledStore(cfg.DataDir),
+	}
+	srv.gameMath.SetPinBackend(newMathPins(srv.results))
+	srv.crashChain = fair.NewChainStore(cfg.DataDir, srv.rng, 0)
+	if cfg.CrashSalt != "" {
+		if _, err := srv.crashChain.SetSalt(cfg.CrashSalt); err != nil {
+			log.Printf("crash: RGS_CRASH_SALT: %v", err)
+		}
+	}
+	srv.crashTable = newCrashTable(srv, cfg.CrashBettingWindow)
+	if cfg.ProvablyFair {
+		srv.fair = fair.NewStore(cfg.DataDir, rng.Crypto())
 	}
 	// Load any DB-backed game math (game_math table) into the in-memory store.
-	srv.loadGameMathFromDB()
+	_ = srv.loadGameMathFromDB()
 	srv.loadLuckyStarMath()
+	srv.registerEngines()
 	return srv
 }
 
-// bundleMathFile is the prizeTable part of the Luis bundle math.json format.
-type bundleMathFile struct {
-	PrizeTable []struct {
-		ID          string  `json:"id"`
-		Value       float64 `json:"value"`
-		Probability float64 `json:"probability"`
-		IsWin       bool    `json:"isWin"`
-	} `json:"prizeTable"`
+// crashMath is the crash model of cfg, sealed with its content hash. Unset values take the
+// defaults; config.Load has already refused invalid ones, and Run refuses to start with a model
+// that still fails (see registerCrashMath).
+func crashMath(cfg *config.Config) gamemath.CrashMath {
+	m := gamemath.CrashMath{
+		ModelID:       gamemath.CrashModelID,
+		ModelVersion:  cfg.CrashMathVersion,
+		HouseEdge:     cfg.CrashHouseEdge,
+		MaxMultiplier: cfg.CrashMaxMultiplier,
+		GrowthRate:    cfg.CrashGrowthRate,
+	}.WithDefaults()
+	if m.ModelVersion == "" {
+		m.ModelVersion = gamemath.DefaultCrashModelVersion
+	}
+	if err := m.Seal(); err != nil {
+		log.Printf("crash math: %v", err)
+	}
+	return m
+}
+
+// registerCrashMath registers the crash math in crashModels and logs its version and hash.
+func (s *Server) registerCrashMath() error {
+	if err := s.crashModels.Register(s.crashMath); err != nil {
+		return err
+	}
+	log.Printf("crash math %s@%s (house_edge %v, max_multiplier %v, growth_rate %v, content_hash %s)",
+		s.crashMath.ModelID, s.crashMath.ModelVersion, s.crashMath.HouseEdge, s.crashMath.MaxMultiplier,
+		s.crashMath.GrowthRate, s.crashMath.Integrity.ContentHash)
+	return nil
 }
 
 const luckyStarModelID = "lucky_star"
@@ -81,69 +144,116 @@ func (s *Server) loadLuckyStarMath() {
 	if err != nil {
 		return
 	}
-	var bundle bundleMathFile
-	if err := json.Unmarshal(data, &bundle); err != nil {
+	math, err := s.importStoredMath(data, luckyStarModelID, luckyStarModelID)
+	if err != nil {
+		log.Printf("lucky_star: %v", err)
 		return
 	}
-	if len(bundle.PrizeTable) == 0 {
+	if err := s.gameMath.Register(math); err != nil {
+		log.Printf("lucky_star: failed to register game math: %v", err)
 		return
 	}
-	// Convert probability to weight (integer). Scale by 1e9 so we keep resolution.
-	// Only include win tiers from the bundle (isWin true); we inject a LOSE tier so the game is fair (house edge).
-	var tiers []gamemath.PrizeTier
-	for _, p := range bundle.PrizeTable {
-		if p.Probability <= 0 || !p.IsWin {
-			continue
+	log.Printf("lucky_star: registered game math with %d tiers from %s", len(math.PrizeTable), mathPath)
+}
+
+// importBundleMath converts a bundle math.json in any supported format (gamemath.Import) to the
+// model registered as modelID for gameID. Import checks the content hash the document declares.
+// Bundle-format tables that list only winning tiers get a LOSE tier sized for the game's target
+// RTP (see targetRTP); the server derived that table itself, so it is resealed, keeping the
+// verified bundle hash as Integrity.SourceHash. Targets the prize table cannot reach are refused.
+func (s *Server) importBundleMath(data []byte, gameID, modelID string) (*gamemath.GameMath, error) {
+	imp, err := gamemath.Import(data)
+	if err != nil {
+		return nil, err
+	}
+	return s.fitBundleMath(imp, gameID, modelID)
+}
+
+// importStoredMath is importBundleMath for math the server already had before content hashes
+// were required (game_math rows, bundles on disk): a document without a hash is sealed on load
+// instead of refused (gamemath.ImportLegacy). A wrong hash is still refused.
+func (s *Server) importStoredMath(data []byte, gameID, modelID string) (*gamemath.GameMath, error) {
+	imp, err := gamemath.ImportLegacy(data)
+	if err != nil {
+		return nil, err
+	}
+	return s.fitBundleMath(imp, gameID, modelID)
+}
+
+// fitBundleMath finishes importBundleMath and importStoredMath.
+func (s *Server) fitBundleMath(imp *gamemath.Imported, gameID, modelID string) (*gamemath.GameMath, error) {
+	for _, note := range imp.Notes {
+		log.Printf("game_math: import %s (%s format): %s", modelID, imp.Format, note)
+	}
+	math := imp.Math
+	math.ModelID = modelID
+	if imp.Format == gamemath.FormatBundle && !hasLosingTier(math) {
+		target := s.targetRTP(gameID, imp.TargetRTP)
+		if _, err := gamemath.FitTargetRTP(math, target); err != nil {
+			return nil, err
 		}
-		weight := int64(p.Probability * 1e9)
-		if weight < 1 {
-			weight = 1
+		report, err := gamemath.Analyze(math)
+		if err != nil {
+			return nil, err
 		}
-		tiers = append(tiers, gamemath.PrizeTier{
-			Tier:       p.ID,
-			Multiplier: p.Value,
-			Weight:     weight,
-		})
-	}
-	if len(tiers) == 0 {
-		return
+		math.Stats = &gamemath.GameStats{
+			ComputedRTP: report.RTP,
+			HitRate:     report.HitRate,
+			Variance:    report.Variance,
+			MaxWin:      report.MaxWin,
+		}
+		source := math.Integrity.ContentHash
+		if err := math.Seal(); err != nil {
+			return nil, fmt.Errorf("hash game math: %w", err)
+		}
+		math.Integrity.SourceHash = source
+		log.Printf("game_math: %s fitted to target RTP %.4f%%: RTP %.4f%%, hit rate %.4f%%",
+			modelID, target*100, report.RTP*100, report.HitRate*100)
 	}
-	// Inject LOSE tier so the house wins most rounds (professional house edge).
-	// ~70% lose / 30% win so the house consistently comes out ahead.
-	var totalWinWeight int64
-	for _, t := range tiers {
-		totalWinWeight += t.Weight
+	return math, nil
+}
+
+// targetRTP is the RTP a win-only bundle table for gameID is fitted to, most specific first:
+// RGS_GAME_TARGET_RTP for the game, the rtp the bundle declares, the operator-wide
+// RGS_TARGET_RTP, then gamemath.DefaultTargetRTP.
+func (s *Server) targetRTP(gameID string, declared float64) float64 {
+	if rtp, ok := s.cfg.GameTargetRTP[gameID]; ok && gameID != "" {
+		return rtp
 	}
-	if totalWinWeight > 0 {
-		loseWeight := totalWinWeight * 70 / 30 // 70% lose, 30% win
-		tiers = append(tiers, gamemath.PrizeTier{
-			Tier:       "LOSE",
-			Multiplier: 0,
-			Weight:     loseWeight,
-		})
+	if declared > 0 {
+		return declared
 	}
-	math := &gamemath.GameMath{
-		SchemaVersion: 1,
-		ModelID:       luckyStarModelID,
-		ModelVersion:  "1.0",
-		Mechanic:      gamemath.Mechanic{Type: "match_3", MatchCount: 3},
-		MathMode:      "UNLIMITED",
-		WinLogic:      "SINGLE_WIN",
-		PrizeTable:    tiers,
+	if s.cfg.TargetRTP > 0 {
+		return s.cfg.TargetRTP
 	}
-	if err := s.gameMath.Register(math); err != nil {
-		log.Printf("lucky_star: failed to register game math: %v", err)
-		return
+	return gamemath.DefaultTargetRTP
+}
+
+// hasLosingTier reports whether any tier pays nothing.
+func hasLosingTier(math *gamemath.GameMath) bool {
+	for _, t := range math.PrizeTable {
+		if t.Multiplier == 0 {
+			return true
+		}
 	}
-	log.Printf("lucky_star: registered game math with %d tiers from %s", len(tiers), mathPath)
+	return false
 }
 
 func (s *Server) Run() error {
+	if err := s.registerCrashMath(); err != nil {
+		return err
+	}
 	mux := http.NewServeMux()
 	mux.HandleFunc("GET /health", s.health)
 	mux.HandleFunc("POST /api/openai/images", s.handleOpenAIImages)
 	mux.HandleFunc("POST /api/scratch/play", s.handleScratchPlay)
+	mux.HandleFunc("POST /api/scratch/play/batch", s.handleScratchPlayBatch)
 	mux.HandleFunc("GET /api/scratch/symbols", s.handleScratchSymbols)
+	// Pick-One / Pick-N: buy, open cells one by one, resume after a reconnect.
+	mux.HandleFunc("POST /api/scratch/pick", s.handleScratchPickStart)
+	mux.HandleFunc("GET /api/scratch/pick", s.handleListScratchPicks)
+	mux.HandleFunc("GET /api/scratch/pick/{roundId}", s.handleGetScratchPick)
+	mux.HandleFunc("POST /api/scratch/pick/{roundId}", s.handleScratchPick)
 	mux.HandleFunc("GET /rgs/balance", s.getBalance)
 	mux.HandleFunc("POST /rgs/round/start", s.roundStart)
 	mux.HandleFunc("POST /rgs/round/end", s.roundEnd)
@@ -156,6 +266,33 @@ func (s *Server) Run() error {
 	mux.HandleFunc("GET /rgs/games/list", s.handleGamesList)
 	// Admin: import standalone HTML + assets bundles generated from GameCrafter.
 	mux.HandleFunc("POST /rgs/admin/games/import-zip", s.handleImportZip)
+	// Admin: math report, versions, activation and rollback.
+	mux.HandleFunc("GET /rgs/admin/math/{modelId}/report", s.handleGetMathReport)
+	mux.HandleFunc("GET /rgs/admin/math/{modelId}/versions", s.handleListMathVersions)
+	mux.HandleFunc("POST /rgs/admin/math/{modelId}/activate", s.handleActivateMath)
+	mux.HandleFunc("POST /rgs/admin/math/{modelId}/rollback", s.handleRollbackMath)
+	// Provably fair: session seeds, verification and the salt of the crash seed chain.
+	mux.HandleFunc("GET /rgs/fair/sessions/{sessionId}", s.handleGetFairSession)
+	mux.HandleFunc("POST /rgs/fair/sessions/{sessionId}/rotate", s.handleRotateFairSeeds)
+	mux.HandleFunc("POST /rgs/fair/verify", s.handleFairVerify)
+	mux.HandleFunc("POST /rgs/admin/crash/salt", s.handleSaltCrashChain)
+	// Admin: ticket pools (series) for LIMITED scratch math.
+	mux.HandleFunc("GET /rgs/admin/math/{modelId}/pool", s.handleGetTicketPool)
+	mux.HandleFunc("POST /rgs/admin/math/{modelId}/pool", s.handleOpenTicketPool)
+	// Admin: rebuild the reveal a settled scratch round was shown with.
+	mux.HandleFunc("GET /rgs/admin/rounds/{roundId}/reveal", s.handleGetRoundReveal)
+	// Admin: rounds held for reconciliation (see startRound).
+	mux.HandleFunc("GET /rgs/admin/rounds/unreconciled", s.handleListUnreconciledRounds)
+	mux.HandleFunc("POST /rgs/admin/rounds/{roundId}/reconcile", s.handleReconcileRound)
+	// Shared crash rounds: live state (server-sent events) and past crash points.
+	mux.HandleFunc("GET /rgs/crash/live", s.handleCrashLive)
+	mux.HandleFunc("GET /rgs/crash/history", s.handleCrashHistory)
+	// Admin: re-read game_math, scratch_games and games (also done automatically, see watchConfig).
+	mux.HandleFunc("POST /rgs/admin/reload", s.handleReload)
+	s.watchConfig(context.Background())
+	go s.expirePickRounds(context.Background())
+	go s.pruneMathPins(context.Background())
+	go s.crashTable.run(context.Background())
 
 	port := s.cfg.RGSPort
 	if port <= 0 {
@@ -351,7 +488,7 @@ func (s *Server) roundStart(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	round := s.store.Create(req.RoundID, betID, req.Currency, req.Amount)
+	round := s.store.Create(req.RoundID, betID, req.Currency, req.Amount, rng.NewSeed(s.rng))
 	writeJSON(w, http.StatusOK, roundStartResponse{
 		RoundID:       round.RoundID,
 		CurrentNumber: round.CurrentNumber,
@@ -404,14 +541,13 @@ func (s *Server) roundEnd(w http.ResponseWriter, r *http.Request) {
 	}
 	defer s.store.Delete(req.RoundID)
 
-	nextNum := round.NextNumber()
-	current := rnd.CurrentNumber
-	win := (req.Choice == "higher" && nextNum > current) || (req.Choice == "lower" && nextNum < current)
-	push := nextNum == current
+	src := rng.NewRecorder(rnd.Source())
+	nextNum := round.NextNumberFrom(src)
+	outcome := round.Resolve(rnd.CurrentNumber, nextNum, req.Choice)
 
-	var outcome string
 	var delta float64
-	if push {
+	switch outcome {
+	case round.OutcomePush:
 		status, err := s.client.Rollback(req.Token, rnd.BetID)
 		if err != nil {
 			code := status
@@ -421,18 +557,15 @@ func (s *Server) roundEnd(w http.ResponseWriter, r *http.Request) {
 			writeJSON(w, code, roundEndResponse{Error: err.Error()})
 			return
 		}
-		outcome = "push"
 		delta = 0
-	} else if win {
+	case round.OutcomeWin:
 		_, err := s.client.Win(req.Token, rnd.Currency, rnd.Amount, "", "")
 		if err != nil {
 			writeJSON(w, http.StatusBadGateway, roundEndResponse{Error: err.Error()})
 			return
 		}
-		outcome = "win"
 		delta = rnd.Amount
-	} else {
-		outcome = "lose"
+	default:
 		delta = -rnd.Amount
 	}
 
@@ -444,6 +577,8 @@ func (s *Server) roundEnd(w http.ResponseWriter, r *http.Request) {
 		NextNumber:   nextNum,
 		BalanceDelta: delta,
 		SettledAt:    time.Now(),
+		RNGSeed:      rnd.RNGSeed,
+		RNGDraws:     src.Draws(),
 	})
 
 	writeJSON(w, http.StatusOK, roundEndResponse{
@@ -453,11 +588,17 @@ func (s *Server) roundEnd(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
-func (s *Server) getBalance(w http.ResponseWriter, r *http.Request) {
+// bearerToken returns the Authorization header without its "Bearer " prefix.
+func bearerToken(r *http.Request) string {
 	token := r.Header.Get("Authorization")
 	if token != "" && strings.HasPrefix(token, "Bearer ") {
 		token = strings.TrimSpace(token[7:])
 	}
+	return token
+}
+
+func (s *Server) getBalance(w http.ResponseWriter, r *http.Request) {
+	token := bearerToken(r)
 	if token == "" {
 		token = r.URL.Query().Get("token")
 	}
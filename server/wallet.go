@@ -0,0 +1,212 @@
+package server
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/google/uuid"
+
+	rgsdb "github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games"
+)
+
+// wallet moves one round's money through the operator transaction API when configured,
+// otherwise the platform client. Errors are *games.Error.
+type wallet struct {
+	s    *Server
+	bet  *games.Bet
+	name string // game name for platform transactions, e.g. "Scratch"
+	// Operator mode: the session's player, resolved by openWallet.
+	db         *sql.DB
+	userID     string
+	accountID  string
+	gameCode   string
+	operatorID int
+}
+
+// openWallet prepares the wallet of bet's session. In operator mode it resolves the session's
+// player; an unknown session fails with 401.
+func (s *Server) openWallet(ctx context.Context, bet *games.Bet, name string) (*wallet, error) {
+	w := &wallet{s: s, bet: bet, name: name}
+	if s.operator == nil {
+		return w, nil
+	}
+	db, err := rgsdb.GetDB()
+	if err != nil || db == nil {
+		return nil, games.Errorf(http.StatusBadGateway, "TECHNICAL_ERROR", "database unavailable")
+	}
+	var dbGameCode string
+	err = db.QueryRowContext(ctx, `
+        SELECT gs.user_id, u.username, gs.game_id, gs.operator_id
+        FROM game_sessions gs
+        JOIN users u ON gs.user_id = u.id
+        WHERE gs.session_id = $1
+      `, bet.SessionID).Scan(&w.userID, &w.accountID, &dbGameCode, &w.operatorID)
+	if err != nil {
+		return nil, games.Errorf(http.StatusUnauthorized, "INVALID_SESSION", "invalid session")
+	}
+	w.db = db
+	w.gameCode = bet.GameCode
+	if w.gameCode == "" {
+		w.gameCode = bet.GameID
+	}
+	if w.gameCode == "scratch" && dbGameCode != "" {
+		w.gameCode = dbGameCode
+	}
+	return w, nil
+}
+
+// checkSession checks sessionID is a live player session: known to game_sessions in operator
+// mode, accepted by the platform otherwise. An unknown session fails with 401.
+func (s *Server) checkSession(ctx context.Context, sessionID string) error {
+	if s.operator != nil {
+		_, err := s.openWallet(ctx, &games.Bet{SessionID: sessionID}, "")
+		return err
+	}
+	if s.client == nil {
+		return nil
+	}
+	if _, status, err := s.client.GetBalance(sessionID); err != nil {
+		switch status {
+		case http.StatusUnauthorized, http.StatusForbidden, http.StatusNotFound:
+			return games.Errorf(http.StatusUnauthorized, "INVALID_SESSION", "invalid session")
+		}
+		return games.Errorf(http.StatusBadGateway, "TECHNICAL_ERROR", err.Error())
+	}
+	return nil
+}
+
+// debit takes the bet and sets bet.BetRef.
+func (w *wallet) debit() error {
+	bet := w.bet
+	if w.s.operator == nil {
+		if w.s.client == nil {
+			return nil
+		}
+		betID, status, err := w.s.client.Bet(bet.SessionID, bet.Currency, bet.Amount, w.name, "")
+		if err != nil {
+			if status == 0 {
+				status = http.StatusBadGateway
+			}
+			return games.Errorf(status, "BET_FAILED", err.Error())
+		}
+		bet.BetRef = betID
+		return nil
+	}
+	txID := uuid.New().String()
+	resp, err := w.s.operator.Debit(w.userID, bet.SessionID, bet.RoundID, txID, w.gameCode, bet.DeviceType, "1.0", bet.Amount, "")
+	if err != nil || resp.Code != 0 {
+		msg := "debit failed"
+		if resp != nil && resp.Message != "" {
+			msg = resp.Message
+		} else if err != nil {
+			msg = err.Error()
+		}
+		return games.Errorf(http.StatusBadGateway, "BET_FAILED", msg)
+	}
+	bet.BetRef = txID
+	return nil
+}
+
+// credit pays win and closes the round in the wallet. The operator is always told the round is
+// completed (a zero credit on a loss); the platform client is only called for a win.
+func (w *wallet) credit(ctx context.Context, win float64) error {
+	bet := w.bet
+	if w.s.operator == nil {
+		if w.s.client == nil || win <= 0 {
+			return nil
+		}
+		if _, err := w.s.client.Win(bet.SessionID, bet.Currency, win, w.name, ""); err != nil {
+			return games.Errorf(http.StatusBadGateway, "WIN_FAILED", err.Error())
+		}
+		return nil
+	}
+	resp, err := w.s.operator.Credit(w.userID, bet.SessionID, bet.RoundID, uuid.New().String(), w.gameCode, bet.DeviceType, "1.0", "completed", "", win)
+	if err != nil || resp.Code != 0 {
+		msg := "credit failed"
+		if resp != nil && resp.Message != "" {
+			msg = resp.Message
+		} else if err != nil {
+			msg = err.Error()
+		}
+		return games.Errorf(http.StatusBadGateway, "WIN_FAILED", msg)
+	}
+	netResult := win - bet.Amount
+	if netResult == 0 {
+		netResult = -bet.Amount
+	}
+	// Use rgs_wallet_transactions (game_crafter wallet_transactions is for crypto only)
+	_, _ = w.db.ExecContext(ctx, `
+        INSERT INTO rgs_wallet_transactions (
+          transaction_id,
+          account_id,
+          session_id,
+          round_id,
+          game_id,
+          type,
+          status,
+          amount,
+          currency,
+          bet_amount,
+          win_amount,
+          net_result,
+          user_id,
+          operator_id
+        ) VALUES (
+          $1, $2, $3, $4, $5, 'debit_and_credit', 'completed',
+          $6, $7, $8, $9, $10, $11, $12
+        )
+      `,
+		bet.BetRef,
+		w.accountID,
+		bet.SessionID,
+		bet.RoundID,
+		w.gameCode,
+		bet.Amount,
+		bet.Currency,
+		bet.Amount,
+		win,
+		netResult,
+		w.userID,
+		w.operatorID,
+	)
+	return nil
+}
+
+// refund gives the debited bet back (operator refund, platform rollback).
+func (w *wallet) refund() error {
+	bet := w.bet
+	if w.s.operator == nil {
+		if w.s.client == nil {
+			return nil
+		}
+		_, err := w.s.client.Rollback(bet.SessionID, bet.BetRef)
+		return err
+	}
+	resp, err := w.s.operator.Refund(w.userID, bet.SessionID, bet.RoundID, uuid.New().String(), w.gameCode, bet.DeviceType, "1.0", bet.Amount)
+	if err == nil && resp.Code != 0 {
+		err = errors.New(resp.Message)
+	}
+	return err
+}
+
+// creditOrRefund credits win; if that fails the bet is refunded, so the round is all or
+// nothing. It reports whether the round was undone (refunded) along with the credit error.
+func (w *wallet) creditOrRefund(ctx context.Context, win float64) (bool, error) {
+	err := w.credit(ctx, win)
+	if err == nil {
+		return false, nil
+	}
+	if rerr := w.refund(); rerr != nil {
+		log.Printf("wallet: round %s: credit failed and refund of debit %s failed (%v): reconcile manually", w.bet.RoundID, w.bet.BetRef, rerr)
+		return false, err
+	}
+	var gerr *games.Error
+	if errors.As(err, &gerr) {
+		return true, games.Errorf(gerr.Status, gerr.Code, gerr.Msg+" (bet refunded)")
+	}
+	return true, err
+}
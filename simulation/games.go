@@ -0,0 +1,135 @@
+package simulation
+
+import (
+	"fmt"
+	"sync/atomic"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games/crash"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games/scratch"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/round"
+)
+
+// Scratch simulates UNLIMITED scratch rounds through scratch.GenerateWithMath. The report's
+// ExpectedRTP comes from gamemath.Analyze.
+func Scratch(math *gamemath.GameMath, opts Options) (Report, error) {
+	analysis, err := gamemath.Analyze(math)
+	if err != nil {
+		return Report{}, err
+	}
+	var failed atomic.Bool
+	r, err := Run("scratch", opts, func(src rng.Source) (float64, bool) {
+		o, ok := scratch.GenerateWithMathFrom(src, 1, math)
+		if !ok {
+			failed.Store(true)
+		}
+		return o.WinAmount, false
+	})
+	if err != nil {
+		return Report{}, err
+	}
+	if failed.Load() {
+		return Report{}, fmt.Errorf("scratch: GenerateWithMath failed for model %q", math.ModelID)
+	}
+	r.Model = math.ModelID
+	if math.ModelVersion != "" {
+		r.Model += "@" + math.ModelVersion
+	}
+	r.ExpectedRTP = &analysis.RTP
+	return r, nil
+}
+
+// Crash simulates a player who cashes out at cashoutStep every round (crash.Multiplier(step))
+// on crash math m. The bet wins when the crash step is at least cashoutStep, as an auto-cashout
+// does in the server. The report's ExpectedRTP comes from gamemath.AnalyzeCrash.
+func Crash(m gamemath.CrashMath, cashoutStep int, opts Options) (Report, error) {
+	analysis, err := gamemath.AnalyzeCrash(m, crash.Multiplier(cashoutStep))
+	if err != nil {
+		return Report{}, err
+	}
+	r, err := Run("crash", opts, func(src rng.Source) (float64, bool) {
+		if cashoutStep > crash.GenerateCrashStepFrom(m, src) {
+			return 0, false
+		}
+		return crash.Multiplier(cashoutStep), false
+	})
+	if err != nil {
+		return Report{}, err
+	}
+	r.Model = fmt.Sprintf("cashout@%.2fx (house edge %g, max %gx)", crash.Multiplier(cashoutStep), m.HouseEdge, m.MaxMultiplier)
+	r.ExpectedRTP = &analysis.RTP
+	return r, nil
+}
+
+// Hi/Lo strategies.
+const (
+	HiLoOptimal = "optimal" // higher below the midpoint, lower above it
+	HiLoHigher  = "higher"
+	HiLoLower   = "lower"
+)
+
+// HiLoWinReturn is the return on a Hi/Lo win: the stake plus an equal win (balanceDelta = +amount).
+const HiLoWinReturn = 2.0
+
+// HiLo simulates Hi/Lo rounds: the opening number and the next number come from
+// round.NextNumber and the guess is settled with round.Resolve. Pushes return the stake.
+func HiLo(strategy string, opts Options) (Report, error) {
+	choose, err := hiLoStrategy(strategy)
+	if err != nil {
+		return Report{}, err
+	}
+	r, err := Run("hilo", opts, func(src rng.Source) (float64, bool) {
+		current := round.NextNumberFrom(src)
+		switch round.Resolve(current, round.NextNumberFrom(src), choose(current)) {
+		case round.OutcomeWin:
+			return HiLoWinReturn, false
+		case round.OutcomePush:
+			return 1, true
+		default:
+			return 0, false
+		}
+	})
+	if err != nil {
+		return Report{}, err
+	}
+	r.Model = strategy
+	expected := HiLoExpectedRTP(choose)
+	r.ExpectedRTP = &expected
+	return r, nil
+}
+
+// HiLoExpectedRTP enumerates every (current, next) pair to get the exact RTP of a strategy.
+func HiLoExpectedRTP(choose func(current int) string) float64 {
+	var sum float64
+	var n int
+	for current := round.MinNumber; current <= round.MaxNumber; current++ {
+		for next := round.MinNumber; next <= round.MaxNumber; next++ {
+			switch round.Resolve(current, next, choose(current)) {
+			case round.OutcomeWin:
+				sum += HiLoWinReturn
+			case round.OutcomePush:
+				sum++
+			}
+			n++
+		}
+	}
+	return sum / float64(n)
+}
+
+func hiLoStrategy(name string) (func(current int) string, error) {
+	switch name {
+	case HiLoOptimal, "":
+		return func(current int) string {
+			// Numbers are 1..10: guessing higher from 1..5 and lower from 6..10 wins most often.
+			if current <= 5 {
+				return "higher"
+			}
+			return "lower"
+		}, nil
+	case HiLoHigher, HiLoLower:
+		return func(int) string { return name }, nil
+	default:
+		return nil, fmt.Errorf("unknown hilo strategy %q", name)
+	}
+}
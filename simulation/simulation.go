@@ -0,0 +1,283 @@
+// Package simulation runs game logic many times and reports the observed return to player,
+// hit frequency, win distribution and losing streaks. It drives the same functions the
+// server uses to resolve rounds (scratch.GenerateWithMath, crash.GenerateCrashStep,
+// round.NextNumber/Resolve), so a report describes what players actually get.
+package simulation
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math"
+	"runtime"
+	"sync"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/rng"
+)
+
+// z95 is the two-sided 95% normal quantile used for confidence intervals.
+const z95 = 1.959963984540054
+
+// Bucket is one win-multiplier bin of the histogram. Max is exclusive; 0 means unbounded.
+type Bucket struct {
+	Label string  `json:"label"`
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max,omitempty"`
+	Count int64   `json:"count"`
+	Share float64 `json:"share"`
+}
+
+// defaultBuckets splits returns (payout / bet) into bins; a return of exactly 0 is "lose".
+var defaultBuckets = []Bucket{
+	{Label: "0x", Min: 0, Max: 0},
+	{Label: "<1x", Min: 0, Max: 1},
+	{Label: "1x-2x", Min: 1, Max: 2},
+	{Label: "2x-5x", Min: 2, Max: 5},
+	{Label: "5x-10x", Min: 5, Max: 10},
+	{Label: "10x-20x", Min: 10, Max: 20},
+	{Label: "20x-50x", Min: 20, Max: 50},
+	{Label: "50x-100x", Min: 50, Max: 100},
+	{Label: ">=100x", Min: 100},
+}
+
+func bucketIndex(ret float64) int {
+	if ret == 0 {
+		return 0
+	}
+	for i := 1; i < len(defaultBuckets); i++ {
+		b := defaultBuckets[i]
+		if ret >= b.Min && (b.Max == 0 || ret < b.Max) {
+			return i
+		}
+	}
+	return len(defaultBuckets) - 1
+}
+
+// Report is the result of a simulation run. Returns are payout / bet, so RTP 0.95 means
+// 95% of stakes come back.
+type Report struct {
+	Game         string  `json:"game"`
+	Model        string  `json:"model,omitempty"`
+	Rounds       int64   `json:"rounds"`
+	RTP          float64 `json:"rtp"`
+	RTPLow       float64 `json:"rtp_ci95_low"`
+	RTPHigh      float64 `json:"rtp_ci95_high"`
+	StdDev       float64 `json:"std_dev"`
+	HitFrequency float64 `json:"hit_frequency"`
+	MaxReturn    float64 `json:"max_return"`
+	// LongestLosingStreak counts consecutive rounds returning less than the stake. Pushes
+	// neither extend nor break a streak.
+	LongestLosingStreak int64    `json:"longest_losing_streak"`
+	Histogram           []Bucket `json:"histogram"`
+	// ExpectedRTP is the theoretical RTP when known (analyzer or closed form).
+	ExpectedRTP *float64 `json:"expected_rtp,omitempty"`
+}
+
+// Accumulator collects per-round returns. Not safe for concurrent use; merge per-worker
+// accumulators with Merge.
+type Accumulator struct {
+	n, hits    int64
+	sum, sumSq float64
+	max        float64
+	streak     int64 // current losing streak
+	longest    int64
+	headStreak int64 // losing rounds before the first non-losing round (for Merge)
+	sawNonLoss bool
+	counts     []int64
+}
+
+func NewAccumulator() *Accumulator {
+	return &Accumulator{counts: make([]int64, len(defaultBuckets))}
+}
+
+// Add records one round that returned ret times the stake. push marks a returned stake
+// (Hi/Lo tie), which does not affect streaks.
+func (a *Accumulator) Add(ret float64, push bool) {
+	a.n++
+	a.sum += ret
+	a.sumSq += ret * ret
+	if ret > a.max {
+		a.max = ret
+	}
+	if ret > 0 && !push {
+		a.hits++
+	}
+	a.counts[bucketIndex(ret)]++
+	switch {
+	case push:
+	case ret < 1:
+		a.streak++
+		if !a.sawNonLoss {
+			a.headStreak++
+		}
+		if a.streak > a.longest {
+			a.longest = a.streak
+		}
+	default:
+		a.sawNonLoss = true
+		a.streak = 0
+	}
+}
+
+// Merge appends b's rounds after a's. Streaks that span the boundary are joined.
+func (a *Accumulator) Merge(b *Accumulator) {
+	joined := a.streak + b.headStreak
+	if joined > a.longest {
+		a.longest = joined
+	}
+	if b.longest > a.longest {
+		a.longest = b.longest
+	}
+	if !a.sawNonLoss {
+		a.headStreak += b.headStreak
+	}
+	if b.sawNonLoss {
+		a.streak = b.streak
+		a.sawNonLoss = true
+	} else {
+		a.streak += b.streak
+	}
+	a.n += b.n
+	a.hits += b.hits
+	a.sum += b.sum
+	a.sumSq += b.sumSq
+	if b.max > a.max {
+		a.max = b.max
+	}
+	for i := range a.counts {
+		a.counts[i] += b.counts[i]
+	}
+}
+
+// Report summarizes the accumulated rounds.
+func (a *Accumulator) Report(game string) Report {
+	r := Report{
+		Game:                game,
+		Rounds:              a.n,
+		MaxReturn:           a.max,
+		LongestLosingStreak: a.longest,
+		Histogram:           make([]Bucket, len(defaultBuckets)),
+	}
+	copy(r.Histogram, defaultBuckets)
+	if a.n == 0 {
+		return r
+	}
+	n := float64(a.n)
+	r.RTP = a.sum / n
+	variance := a.sumSq/n - r.RTP*r.RTP
+	if variance < 0 {
+		variance = 0
+	}
+	r.StdDev = math.Sqrt(variance)
+	half := z95 * r.StdDev / math.Sqrt(n)
+	r.RTPLow, r.RTPHigh = r.RTP-half, r.RTP+half
+	r.HitFrequency = float64(a.hits) / n
+	for i := range r.Histogram {
+		r.Histogram[i].Count = a.counts[i]
+		r.Histogram[i].Share = float64(a.counts[i]) / n
+	}
+	return r
+}
+
+// RoundFunc plays one round at a stake of 1, drawing from src, and returns the payout and
+// whether it pushed.
+type RoundFunc func(src rng.Source) (ret float64, push bool)
+
+// Options control a simulation run.
+type Options struct {
+	Rounds  int64
+	Workers int // 0 = GOMAXPROCS
+	// Seed (hex) makes the run repeatable: worker i draws from rng.NewSeeded(seed || i).
+	// The same seed and worker count always produce the same report. Empty uses crypto/rand.
+	Seed string
+}
+
+// workerSource returns the random source for worker i.
+func (o Options) workerSource(i int) (rng.Source, error) {
+	if o.Seed == "" {
+		return rng.Default, nil
+	}
+	seed, err := hex.DecodeString(o.Seed)
+	if err != nil || len(seed) == 0 {
+		return nil, fmt.Errorf("simulation: invalid hex seed %q", o.Seed)
+	}
+	return rng.NewSeeded(binary.BigEndian.AppendUint32(seed, uint32(i))), nil
+}
+
+// Run plays opts.Rounds rounds across worker goroutines and returns the merged report.
+func Run(game string, opts Options, play RoundFunc) (Report, error) {
+	rounds, workers := opts.Rounds, opts.Workers
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+	if int64(workers) > rounds {
+		workers = int(rounds)
+	}
+	if workers < 1 {
+		workers = 1
+	}
+	accs := make([]*Accumulator, workers)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		src, err := opts.workerSource(w)
+		if err != nil {
+			return Report{}, err
+		}
+		share := rounds / int64(workers)
+		if int64(w) < rounds%int64(workers) {
+			share++
+		}
+		accs[w] = NewAccumulator()
+		wg.Add(1)
+		go func(acc *Accumulator, src rng.Source, share int64) {
+			defer wg.Done()
+			for i := int64(0); i < share; i++ {
+				acc.Add(play(src))
+			}
+		}(accs[w], src, share)
+	}
+	wg.Wait()
+	total := accs[0]
+	for _, acc := range accs[1:] {
+		total.Merge(acc)
+	}
+	return total.Report(game), nil
+}
+
+// Contains reports whether the expected RTP lies inside the 95% confidence interval.
+func (r Report) Contains(expected float64) bool {
+	return expected >= r.RTPLow && expected <= r.RTPHigh
+}
+
+// String renders the report for humans.
+func (r Report) String() string {
+	s := fmt.Sprintf("game:                  %s\n", r.Game)
+	if r.Model != "" {
+		s += fmt.Sprintf("model:                 %s\n", r.Model)
+	}
+	s += fmt.Sprintf("rounds:                %d\n", r.Rounds)
+	s += fmt.Sprintf("rtp:                   %.4f%% (95%% CI %.4f%% .. %.4f%%)\n", r.RTP*100, r.RTPLow*100, r.RTPHigh*100)
+	if r.ExpectedRTP != nil {
+		verdict := "inside CI"
+		if !r.Contains(*r.ExpectedRTP) {
+			verdict = "OUTSIDE CI"
+		}
+		s += fmt.Sprintf("expected rtp:          %.4f%% (%s)\n", *r.ExpectedRTP*100, verdict)
+	}
+	s += fmt.Sprintf("std dev:               %.4f\n", r.StdDev)
+	s += fmt.Sprintf("hit frequency:         %.4f%% (1 in %.2f)\n", r.HitFrequency*100, oneIn(r.HitFrequency))
+	s += fmt.Sprintf("max return:            %.2fx\n", r.MaxReturn)
+	s += fmt.Sprintf("longest losing streak: %d\n", r.LongestLosingStreak)
+	s += "win distribution:\n"
+	for _, b := range r.Histogram {
+		s += fmt.Sprintf("  %-10s %12d  %8.4f%%\n", b.Label, b.Count, b.Share*100)
+	}
+	return s
+}
+
+func oneIn(p float64) float64 {
+	if p <= 0 {
+		return 0
+	}
+	return 1 / p
+}
@@ -0,0 +1,110 @@
+package simulation
+
+import (
+	"math"
+	"testing"
+
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/gamemath"
+	"github.com/Ashenafi-pixel/gamecrafter-remote-gaming-server/games/crash"
+)
+
+// within5Sigma is a deliberately loose check (false failure rate ~1e-6) so tests stay stable.
+func within5Sigma(t *testing.T, r Report, expected float64) {
+	t.Helper()
+	se := r.StdDev / math.Sqrt(float64(r.Rounds))
+	if math.Abs(r.RTP-expected) > 5*se {
+		t.Errorf("%s: observed RTP %.5f, expected %.5f (se %.5f)", r.Game, r.RTP, expected, se)
+	}
+}
+
+func TestAccumulator_StreaksAcrossMerge(t *testing.T) {
+	a, b := NewAccumulator(), NewAccumulator()
+	for _, ret := range []float64{2, 0, 0} {
+		a.Add(ret, false)
+	}
+	b.Add(0, false)
+	b.Add(1, true) // push: neither breaks nor extends
+	b.Add(0, false)
+	b.Add(5, false)
+	a.Merge(b)
+	r := a.Report("t")
+	if r.LongestLosingStreak != 4 {
+		t.Errorf("longest streak %d want 4", r.LongestLosingStreak)
+	}
+	if r.Rounds != 7 || r.HitFrequency != 2.0/7 || r.MaxReturn != 5 {
+		t.Errorf("report %+v", r)
+	}
+	if r.Histogram[0].Count != 4 {
+		t.Errorf("0x bucket %d want 4", r.Histogram[0].Count)
+	}
+}
+
+func TestScratch_MatchesAnalyzer(t *testing.T) {
+	m := &gamemath.GameMath{
+		ModelID: "sim",
+		PrizeTable: []gamemath.PrizeTier{
+			{Tier: "LOSE", Multiplier: 0, Weight: 700},
+			{Tier: "T1", Multiplier: 2, Weight: 250},
+			{Tier: "T2", Multiplier: 5, Weight: 45},
+			{Tier: "T3", Multiplier: 20, Weight: 5},
+		},
+	}
+	r, err := Scratch(m, Options{Rounds: 200_000, Workers: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.ExpectedRTP == nil || math.Abs(*r.ExpectedRTP-0.825) > 1e-9 {
+		t.Fatalf("expected rtp %v want 0.825", r.ExpectedRTP)
+	}
+	within5Sigma(t, r, *r.ExpectedRTP)
+	if math.Abs(r.HitFrequency-0.3) > 0.01 {
+		t.Errorf("hit frequency %.4f want ~0.30", r.HitFrequency)
+	}
+}
+
+func TestCrash_MatchesAnalyzer(t *testing.T) {
+	m := gamemath.CrashMath{HouseEdge: 0.03, MaxMultiplier: 50, GrowthRate: gamemath.DefaultCrashGrowthRate}
+	for _, step := range []int{100, crash.MaxStep(m)} {
+		r, err := Crash(m, step, Options{Rounds: 200_000, Workers: 4})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if r.ExpectedRTP == nil || math.Abs(*r.ExpectedRTP-0.97) > 1e-9 {
+			t.Fatalf("step %d: expected rtp %v want 0.97", step, r.ExpectedRTP)
+		}
+		within5Sigma(t, r, *r.ExpectedRTP)
+	}
+	r, err := Crash(m, 0, Options{Rounds: 1000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.RTP != 1 || *r.ExpectedRTP != 1 {
+		t.Errorf("cashing out at step 0 always returns the stake, got %v (expected %v)", r.RTP, *r.ExpectedRTP)
+	}
+}
+
+func TestHiLo_MatchesEnumeration(t *testing.T) {
+	r, err := HiLo(HiLoOptimal, Options{Rounds: 200_000, Workers: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	within5Sigma(t, r, *r.ExpectedRTP)
+	if _, err := HiLo("sideways", Options{Rounds: 1}); err == nil {
+		t.Error("unknown strategy should fail")
+	}
+}
+
+func TestRun_SeedIsRepeatable(t *testing.T) {
+	opts := Options{Rounds: 20_000, Workers: 3, Seed: "00112233445566778899aabbccddeeff"}
+	a, err := HiLo(HiLoOptimal, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, _ := HiLo(HiLoOptimal, opts)
+	if a.RTP != b.RTP || a.LongestLosingStreak != b.LongestLosingStreak {
+		t.Errorf("same seed gave different reports: %v/%d vs %v/%d", a.RTP, a.LongestLosingStreak, b.RTP, b.LongestLosingStreak)
+	}
+	if _, err := HiLo(HiLoOptimal, Options{Rounds: 1, Seed: "zz"}); err == nil {
+		t.Error("invalid seed should fail")
+	}
+}